@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ferg-cod3s/conexus/internal/config"
+	"github.com/ferg-cod3s/conexus/internal/connectors"
+	"github.com/ferg-cod3s/conexus/internal/migrate"
+	"github.com/ferg-cod3s/conexus/internal/vectorstore/sqlite"
+)
+
+// Exit codes for the db subcommand.
+const (
+	dbExitOK      = 0
+	dbExitPending = 1
+	dbExitError   = 2
+)
+
+// componentStatus is the migration status of one component of the database.
+type componentStatus struct {
+	Component  string           `json:"component"`
+	Version    int              `json:"version"`
+	Latest     int              `json:"latest"`
+	Migrations []migrate.Status `json:"migrations"`
+}
+
+// runDB implements `conexus db migrate|status`.
+func runDB(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "Usage: conexus db <migrate|status> [flags]")
+		return dbExitError
+	}
+
+	switch args[0] {
+	case "migrate":
+		return runDBMigrate(args[1:], stdout, stderr)
+	case "status":
+		return runDBStatus(args[1:], stdout, stderr)
+	default:
+		fmt.Fprintf(stderr, "Unknown db command %q. Usage: conexus db <migrate|status> [flags]\n", args[0])
+		return dbExitError
+	}
+}
+
+// runDBStatus implements `conexus db status [--json]`. It lists the applied
+// and pending migrations without changing the database, and exits with
+// dbExitPending when some are pending.
+func runDBStatus(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("db status", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	jsonOutput := fs.Bool("json", false, "print the status as JSON")
+	if err := fs.Parse(args); err != nil {
+		return dbExitError
+	}
+
+	ctx := context.Background()
+	cfg, err := config.Load(ctx)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to load configuration: %v\n", err)
+		return dbExitError
+	}
+
+	statuses, err := databaseStatus(ctx, cfg.Database.Path)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to read migration status: %v\n", err)
+		return dbExitError
+	}
+
+	if *jsonOutput {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(statuses); err != nil {
+			fmt.Fprintf(stderr, "Failed to encode status: %v\n", err)
+			return dbExitError
+		}
+	} else {
+		printDatabaseStatus(stdout, cfg.Database.Path, statuses)
+	}
+
+	for _, status := range statuses {
+		if status.Version < status.Latest {
+			return dbExitPending
+		}
+	}
+	return dbExitOK
+}
+
+// runDBMigrate implements `conexus db migrate`. It opens the stores, which
+// back up the database and apply their pending migrations, and reports what
+// was applied.
+func runDBMigrate(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("db migrate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	if err := fs.Parse(args); err != nil {
+		return dbExitError
+	}
+
+	ctx := context.Background()
+	cfg, err := config.Load(ctx)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to load configuration: %v\n", err)
+		return dbExitError
+	}
+
+	start := time.Now()
+	before, err := databaseStatus(ctx, cfg.Database.Path)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to read migration status: %v\n", err)
+		return dbExitError
+	}
+
+	store, err := openVectorStore(cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to migrate vector store: %v\n", err)
+		return dbExitError
+	}
+	store.Close()
+	connectorStore, err := connectors.NewStore(cfg.Database.Path)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to migrate connector store: %v\n", err)
+		return dbExitError
+	}
+	connectorStore.Close()
+
+	applied := 0
+	for _, status := range before {
+		backupPath := migrate.BackupPath(cfg.Database.Path, status.Component, status.Version)
+		if info, err := os.Stat(backupPath); err == nil && !info.ModTime().Before(start.Truncate(time.Second)) {
+			fmt.Fprintf(stdout, "Backed up %s to %s\n", status.Component, backupPath)
+		}
+		for _, migration := range status.Migrations {
+			if !migration.Applied {
+				fmt.Fprintf(stdout, "Applied %s migration %d (%s)\n", status.Component, migration.Version, migration.Name)
+				applied++
+			}
+		}
+	}
+	if applied == 0 {
+		fmt.Fprintln(stdout, "Database is up to date.")
+	}
+	return dbExitOK
+}
+
+// databaseStatus returns the migration status of each component of the
+// database at path. A database that does not exist has applied nothing.
+func databaseStatus(ctx context.Context, path string) ([]componentStatus, error) {
+	var db *sql.DB
+	if _, err := os.Stat(path); err == nil {
+		if db, err = sql.Open("sqlite", path); err != nil {
+			return nil, fmt.Errorf("open database: %w", err)
+		}
+	} else if os.IsNotExist(err) {
+		// Report against an empty database rather than creating the file
+		if db, err = sql.Open("sqlite", ":memory:"); err != nil {
+			return nil, fmt.Errorf("open database: %w", err)
+		}
+	} else {
+		return nil, fmt.Errorf("stat database: %w", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	var statuses []componentStatus
+	for _, newMigrator := range []func(*sql.DB) (*migrate.Migrator, error){sqlite.NewMigrator, connectors.NewMigrator} {
+		m, err := newMigrator(db)
+		if err != nil {
+			return nil, err
+		}
+		migrations, err := m.Status(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", m.Component(), err)
+		}
+		version, err := m.Version(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", m.Component(), err)
+		}
+		statuses = append(statuses, componentStatus{
+			Component:  m.Component(),
+			Version:    version,
+			Latest:     m.Latest(),
+			Migrations: migrations,
+		})
+	}
+	return statuses, nil
+}
+
+// printDatabaseStatus writes a human-readable summary of the migration
+// status.
+func printDatabaseStatus(w io.Writer, path string, statuses []componentStatus) {
+	fmt.Fprintf(w, "Database %s\n", path)
+	pending := 0
+	for _, status := range statuses {
+		fmt.Fprintf(w, "\n%s: version %d of %d\n", status.Component, status.Version, status.Latest)
+		for _, migration := range status.Migrations {
+			state := "pending"
+			switch {
+			case migration.Applied && migration.AppliedAt.IsZero():
+				state = "applied"
+			case migration.Applied:
+				state = "applied " + migration.AppliedAt.Local().Format(time.RFC3339)
+			default:
+				pending++
+			}
+			fmt.Fprintf(w, "  %4d %-24s %s\n", migration.Version, migration.Name, state)
+		}
+	}
+
+	if pending > 0 {
+		fmt.Fprintf(w, "\n%d migrations pending. Run `conexus db migrate` to apply them.\n", pending)
+	} else {
+		fmt.Fprintln(w, "\nDatabase is up to date.")
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/ferg-cod3s/conexus/internal/config"
+	"github.com/ferg-cod3s/conexus/internal/embedding"
+	"github.com/ferg-cod3s/conexus/internal/indexer"
+	"github.com/ferg-cod3s/conexus/internal/observability"
+	"github.com/ferg-cod3s/conexus/internal/rerank"
+	"github.com/ferg-cod3s/conexus/internal/search"
+	"github.com/ferg-cod3s/conexus/internal/vectorstore/sqlite"
+)
+
+// indexerStatePath is where the index controller persists its Merkle state.
+const indexerStatePath = "./data/indexer_state.json"
+
+// Exit codes for the doctor subcommand.
+const (
+	doctorExitHealthy  = 0
+	doctorExitProblems = 1
+	doctorExitError    = 2
+)
+
+// runDoctor implements `conexus doctor [--repair] [--root DIR] [--json]`.
+// It checks that the embedding model is available, checks the index for
+// consistency problems and optionally repairs them.
+func runDoctor(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	repair := fs.Bool("repair", false, "fix every problem found")
+	root := fs.String("root", "", "root directory of the indexed codebase (default: indexer.root_path)")
+	jsonOutput := fs.Bool("json", false, "print the report as JSON")
+	if err := fs.Parse(args); err != nil {
+		return doctorExitError
+	}
+
+	ctx := context.Background()
+	cfg, err := config.Load(ctx)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to load configuration: %v\n", err)
+		return doctorExitError
+	}
+
+	rootPath := cfg.Indexer.RootPath
+	if *root != "" {
+		rootPath = *root
+	}
+	if rootPath, err = filepath.Abs(rootPath); err != nil {
+		fmt.Fprintf(stderr, "Invalid root path: %v\n", err)
+		return doctorExitError
+	}
+
+	store, err := openVectorStore(cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to open vector store: %v\n", err)
+		return doctorExitError
+	}
+	defer store.Close()
+
+	embedder, err := createEmbedder(cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to create embedder: %v\n", err)
+		return doctorExitError
+	}
+
+	if checker, ok := embedder.(embedding.ModelChecker); ok {
+		if err := checker.CheckModel(ctx); err != nil {
+			fmt.Fprintf(stderr, "Embedding model check failed: %v\n", err)
+			return doctorExitError
+		}
+		if !*jsonOutput {
+			fmt.Fprintf(stdout, "Embedding model %s: ok (%d dimensions)\n\n", embedder.Model(), embedder.Dimensions())
+		}
+	}
+
+	opts := indexer.DefaultIndexOptions(rootPath, embedder, store)
+
+	idx := indexer.NewIndexController(indexerStatePath)
+	if err := idx.SetChunkHeaderTemplate(cfg.Indexer.ChunkHeaderTemplate); err != nil {
+		fmt.Fprintf(stderr, "Invalid chunk header template: %v\n", err)
+		return doctorExitError
+	}
+	if err := idx.SetSecretHandling(indexer.SecretHandling(cfg.Indexer.SecretHandling)); err != nil {
+		fmt.Fprintf(stderr, "Invalid secret handling: %v\n", err)
+		return doctorExitError
+	}
+	idx.SetNotebookOutputSize(cfg.Indexer.NotebookOutputSize)
+
+	report, err := idx.Doctor(ctx, opts, *repair)
+	if err != nil {
+		fmt.Fprintf(stderr, "Consistency check failed: %v\n", err)
+		return doctorExitError
+	}
+
+	if *jsonOutput {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fmt.Fprintf(stderr, "Failed to encode report: %v\n", err)
+			return doctorExitError
+		}
+	} else {
+		printDoctorReport(stdout, report)
+	}
+
+	if report.Healthy() || report.Repaired {
+		return doctorExitHealthy
+	}
+	return doctorExitProblems
+}
+
+// printDoctorReport writes a human-readable summary of a doctor report.
+func printDoctorReport(w io.Writer, report *indexer.DoctorReport) {
+	sections := []struct {
+		title string
+		items []string
+	}{
+		{"Chunks for files that no longer exist", report.OrphanedFiles},
+		{"Files on disk with no chunks", report.UnindexedFiles},
+		{"Vectors with the wrong dimension", report.DimensionMismatches},
+		{"Documents missing from full-text index", report.FTSMissing},
+		{"Out-of-date full-text rows", report.FTSStale},
+		{"Full-text rows without a document", report.FTSOrphaned},
+		{"HNSW nodes without a document", report.HNSWOrphaned},
+	}
+
+	for _, section := range sections {
+		status := "ok"
+		if len(section.items) > 0 {
+			status = fmt.Sprintf("%d found", len(section.items))
+		}
+		fmt.Fprintf(w, "%-42s %s\n", section.title+":", status)
+		for _, item := range section.items {
+			fmt.Fprintf(w, "  - %s\n", item)
+		}
+	}
+
+	switch {
+	case report.Healthy():
+		fmt.Fprintln(w, "\nIndex is healthy.")
+	case report.Repaired:
+		fmt.Fprintf(w, "\nRepaired %d problems.\n", report.IssueCount())
+	default:
+		fmt.Fprintf(w, "\nFound %d problems. Run `conexus doctor --repair` to fix them.\n", report.IssueCount())
+	}
+}
+
+// createEmbedder builds the embedder described by the configuration.
+func createEmbedder(cfg *config.Config) (embedding.Embedder, error) {
+	provider, err := embedding.Get(cfg.Embedding.Provider)
+	if err != nil {
+		return nil, fmt.Errorf("get embedding provider %q: %w", cfg.Embedding.Provider, err)
+	}
+
+	providerConfig := make(map[string]interface{})
+	for k, v := range cfg.Embedding.Config {
+		providerConfig[k] = v
+	}
+	providerConfig["model"] = cfg.Embedding.Model
+	providerConfig["dimensions"] = cfg.Embedding.Dimensions
+
+	return provider.Create(providerConfig)
+}
+
+// createReranker builds the reranker described by the configuration, or
+// returns nil when none is configured. Its requests are recorded with
+// metrics, if any.
+func createReranker(cfg *config.Config, metrics *observability.MetricsCollector) (search.Reranker, error) {
+	if cfg.Rerank.Provider == "" {
+		return nil, nil
+	}
+	provider, err := rerank.Get(cfg.Rerank.Provider)
+	if err != nil {
+		return nil, fmt.Errorf("get rerank provider %q: %w", cfg.Rerank.Provider, err)
+	}
+
+	providerConfig := make(map[string]interface{})
+	for k, v := range cfg.Rerank.Config {
+		providerConfig[k] = v
+	}
+	if cfg.Rerank.Model != "" {
+		providerConfig["model"] = cfg.Rerank.Model
+	}
+
+	reranker, err := provider.Create(providerConfig)
+	if err != nil {
+		return nil, err
+	}
+	if r, ok := reranker.(*rerank.Reranker); ok && metrics != nil {
+		r.SetMetrics(metrics)
+	}
+	return reranker, nil
+}
+
+// openVectorStore opens the SQLite vector store described by the configuration.
+func openVectorStore(cfg *config.Config) (*sqlite.Store, error) {
+	store, err := sqlite.NewStore(cfg.Database.Path)
+	if err != nil {
+		return nil, err
+	}
+	encoding, err := sqlite.ParseVectorEncoding(cfg.Database.VectorEncoding)
+	if err == nil {
+		err = store.SetVectorEncoding(encoding)
+	}
+	if err == nil {
+		err = store.SetQuantization(sqlite.QuantizationConfig{
+			Type:       sqlite.Quantization(cfg.Database.Quantization),
+			Subvectors: cfg.Database.PQSubvectors,
+		})
+	}
+	if err != nil {
+		// #nosec G104 - Best-effort cleanup in error path, primary error already captured
+		store.Close()
+		return nil, err
+	}
+	return store, nil
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/ferg-cod3s/conexus/internal/middleware"
 	"github.com/ferg-cod3s/conexus/internal/observability"
 	"github.com/ferg-cod3s/conexus/internal/protocol"
+	"github.com/ferg-cod3s/conexus/internal/search"
 	"github.com/ferg-cod3s/conexus/internal/security"
 	"github.com/ferg-cod3s/conexus/internal/security/auth"
 	"github.com/ferg-cod3s/conexus/internal/security/ratelimit"
@@ -37,6 +38,17 @@ const Version = "0.1.3-alpha"
 func main() {
 	ctx := context.Background()
 
+	// Run CLI subcommands instead of the server
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		os.Exit(runDoctor(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "index" {
+		os.Exit(runIndex(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "db" {
+		os.Exit(runDB(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	// Load configuration
 	cfg, err := config.Load(ctx)
 	if err != nil {
@@ -136,7 +148,7 @@ func main() {
 	}
 
 	// Initialize vector store (SQLite)
-	vectorStore, err := sqlite.NewStore(cfg.Database.Path)
+	vectorStore, err := openVectorStore(cfg)
 	if err != nil {
 		logger.Error("Failed to initialize vector store", "error", err)
 		os.Exit(1)
@@ -152,24 +164,7 @@ func main() {
 	defer connectorStore.Close()
 
 	// Initialize embedder from configuration
-	provider, err := embedding.Get(cfg.Embedding.Provider)
-	if err != nil {
-		logger.Error("Failed to get embedding provider", "provider", cfg.Embedding.Provider, "error", err)
-		os.Exit(1)
-	}
-
-	// Prepare provider configuration
-	providerConfig := make(map[string]interface{})
-	for k, v := range cfg.Embedding.Config {
-		providerConfig[k] = v
-	}
-
-	// Add common config fields
-	providerConfig["model"] = cfg.Embedding.Model
-	providerConfig["dimensions"] = cfg.Embedding.Dimensions
-
-	// Create embedder instance
-	embedder, err := provider.Create(providerConfig)
+	embedder, err := createEmbedder(cfg)
 	if err != nil {
 		logger.Error("Failed to create embedder", "provider", cfg.Embedding.Provider, "error", err)
 		os.Exit(1)
@@ -182,19 +177,57 @@ func main() {
 	)
 
 	// Initialize indexer controller
-	idx := indexer.NewIndexController("./data/indexer_state.json")
+	idx := indexer.NewIndexController(indexerStatePath)
+	idx.SetVectorStore(vectorStore)
+	if err := idx.SetChunkHeaderTemplate(cfg.Indexer.ChunkHeaderTemplate); err != nil {
+		logger.Error("Invalid chunk header template", "error", err)
+		os.Exit(1)
+	}
+	if err := idx.SetSecretHandling(indexer.SecretHandling(cfg.Indexer.SecretHandling)); err != nil {
+		logger.Error("Invalid secret handling", "error", err)
+		os.Exit(1)
+	}
+	idx.SetNotebookOutputSize(cfg.Indexer.NotebookOutputSize)
+
+	// Resume an indexing run interrupted by a crash
+	if cp, err := vectorStore.LoadCheckpoint(ctx); err != nil {
+		logger.Warn("Failed to load indexing checkpoint", "error", err)
+	} else if cp != nil {
+		logger.Info("Resuming interrupted indexing run",
+			"run_id", cp.RunID,
+			"completed", len(cp.Completed),
+			"pending", len(cp.Pending),
+		)
+		if err := idx.Start(ctx, indexer.DefaultIndexOptions(cp.RootPath, embedder, vectorStore)); err != nil {
+			logger.Warn("Failed to resume indexing run", "error", err)
+		}
+	}
 
 	// Initialize error handler
 	errorHandler := observability.NewErrorHandler(logger, metrics, cfg.Observability.Sentry.Enabled)
 
+	// Initialize reranker, used by both transports
+	reranker, err := createReranker(cfg, metrics)
+	if err != nil {
+		logger.Error("Failed to create reranker", "provider", cfg.Rerank.Provider, "error", err)
+		os.Exit(1)
+	}
+	if reranker != nil {
+		logger.Info("Reranker initialized", "provider", cfg.Rerank.Provider)
+	}
+
 	// Check if we're running in HTTP mode (explicit CONEXUS_PORT env var)
 	// Default is stdio mode for MCP compatibility
 	if os.Getenv("CONEXUS_PORT") != "" && cfg.Server.Port > 0 {
-		runHTTPServer(ctx, cfg, vectorStore, connectorStore, embedder, logger, metrics, tracerProvider, idx)
+		runHTTPServer(ctx, cfg, vectorStore, connectorStore, embedder, reranker, logger, metrics, tracerProvider, idx)
 	} else {
 		// Run in stdio mode (default MCP behavior)
 		logger.Info("Running in stdio mode (MCP over stdin/stdout)")
 		mcpServer := mcp.NewServer(os.Stdin, os.Stdout, vectorStore, connectorStore, embedder, metrics, errorHandler, idx)
+		mcpServer.SetRootPath(cfg.Indexer.RootPath)
+		if reranker != nil {
+			mcpServer.SetReranker(reranker)
+		}
 		if err := mcpServer.Serve(); err != nil {
 			logger.Error("Server failed", "error", err)
 			os.Exit(1)
@@ -240,6 +273,7 @@ func runHTTPServer(
 	vectorStore *sqlite.Store,
 	connectorStore connectors.ConnectorStore,
 	embedder embedding.Embedder,
+	reranker search.Reranker,
 	logger *observability.Logger,
 	metrics *observability.MetricsCollector,
 	tracerProvider *observability.TracerProvider,
@@ -272,6 +306,13 @@ func runHTTPServer(
 	errorHandler := observability.NewErrorHandler(logger, metrics, false)
 	webhookHandler := webhooks.NewWebhookHandler(connectorStore, embedder, vectorStore, errorHandler)
 
+	// Tools shared with the stdio transport
+	tools := mcp.NewServer(nil, nil, vectorStore, connectorStore, embedder, metrics, errorHandler, idx)
+	tools.SetRootPath(cfg.Indexer.RootPath)
+	if reranker != nil {
+		tools.SetReranker(reranker)
+	}
+
 	// Initialize JWT manager if authentication is enabled
 	var jwtManager *auth.JWTManager
 	var authMiddleware *middleware.AuthMiddleware
@@ -321,7 +362,7 @@ func runHTTPServer(
 		}
 
 		// Handle JSON-RPC request/response with observability
-		handleJSONRPC(w, r.WithContext(requestCtx), vectorStore, connectorStore, embedder, logger, metrics, tracerProvider, idx, cfg.Indexer.RootPath)
+		handleJSONRPC(w, r.WithContext(requestCtx), vectorStore, connectorStore, embedder, logger, metrics, tracerProvider, tools)
 	})
 
 	// GitHub webhook endpoint
@@ -553,8 +594,7 @@ func handleJSONRPC(
 	logger *observability.Logger,
 	metrics *observability.MetricsCollector,
 	tracerProvider *observability.TracerProvider,
-	idx indexer.IndexController,
-	rootPath string,
+	tools *mcp.Server,
 ) {
 	ctx := r.Context()
 	startTime := time.Now()
@@ -599,14 +639,13 @@ func handleJSONRPC(
 
 	// Create MCP handler (using dummy reader/writer since we handle HTTP directly)
 	mcpHandler := &mcpHTTPHandler{
-		indexer:        idx,
+		tools:          tools,
 		vectorStore:    vectorStore,
 		connectorStore: connectorStore,
 		embedder:       embedder,
 		logger:         logger,
 		metrics:        metrics,
 		tracerProvider: tracerProvider,
-		rootPath:       rootPath,
 	}
 
 	// Handle the method
@@ -643,14 +682,13 @@ func handleJSONRPC(
 
 // mcpHTTPHandler implements protocol.Handler for HTTP transport with observability.
 type mcpHTTPHandler struct {
-	indexer        indexer.IndexController
+	tools          *mcp.Server
 	vectorStore    *sqlite.Store
 	connectorStore connectors.ConnectorStore
 	embedder       embedding.Embedder
 	logger         *observability.Logger
 	metrics        *observability.MetricsCollector
 	tracerProvider *observability.TracerProvider
-	rootPath       string
 }
 
 func (h *mcpHTTPHandler) Handle(ctx context.Context, method string, params json.RawMessage) (interface{}, error) {
@@ -680,12 +718,10 @@ func (h *mcpHTTPHandler) Handle(ctx context.Context, method string, params json.
 
 		// Route to appropriate handler
 		switch req.Name {
-		case mcp.ToolContextSearch:
-			return h.handleContextSearch(ctx, req.Arguments)
+		case mcp.ToolContextSearch, mcp.ToolContextIndexControl:
+			return h.tools.CallTool(ctx, req.Name, req.Arguments)
 		case mcp.ToolContextGetRelatedInfo:
 			return h.handleGetRelatedInfo(ctx, req.Arguments)
-		case mcp.ToolContextIndexControl:
-			return h.handleIndexControl(ctx, req.Arguments)
 		case mcp.ToolContextConnectorManagement:
 			return h.handleConnectorManagement(ctx, req.Arguments)
 		default:
@@ -784,138 +820,6 @@ func sendJSONRPCError(w http.ResponseWriter, id interface{}, code int, message s
 	json.NewEncoder(w).Encode(resp)
 }
 
-func (h *mcpHTTPHandler) handleContextSearch(ctx context.Context, args json.RawMessage) (interface{}, error) {
-	var req mcp.SearchRequest
-	startTime := time.Now()
-
-	if err := json.Unmarshal(args, &req); err != nil {
-		return nil, &protocol.Error{
-			Code:    protocol.InvalidParams,
-			Message: fmt.Sprintf("invalid search request: %v", err),
-		}
-	}
-
-	// Validate required fields
-	if req.Query == "" {
-		return nil, &protocol.Error{
-			Code:    protocol.InvalidParams,
-			Message: "query is required",
-		}
-	}
-
-	// Set defaults
-	topK := req.TopK
-	if topK <= 0 {
-		topK = 20
-	}
-	if topK > 100 {
-		topK = 100
-	}
-	offset := req.Offset
-	if offset < 0 {
-		offset = 0
-	}
-
-	// Generate query embedding
-	queryVec, err := h.embedder.Embed(ctx, req.Query)
-	if err != nil {
-		return nil, &protocol.Error{
-			Code:    protocol.InternalError,
-			Message: fmt.Sprintf("failed to generate query embedding: %v", err),
-		}
-	}
-
-	// Prepare search options
-	opts := vectorstore.SearchOptions{
-		Limit:   topK,
-		Offset:  offset,
-		Filters: make(map[string]interface{}),
-	}
-
-	// Apply filters
-	if req.Filters != nil {
-		if len(req.Filters.SourceTypes) > 0 {
-			opts.Filters["source_types"] = req.Filters.SourceTypes
-		}
-		if req.Filters.DateRange != nil {
-			opts.Filters["date_range"] = map[string]string{
-				"from": req.Filters.DateRange.From,
-				"to":   req.Filters.DateRange.To,
-			}
-		}
-		// Apply work context filters
-		if req.Filters.WorkContext != nil {
-			if req.Filters.WorkContext.ActiveFile != "" {
-				opts.Filters["related_files"] = req.Filters.WorkContext.ActiveFile
-			}
-			if req.Filters.WorkContext.GitBranch != "" {
-				opts.Filters["git_branch"] = req.Filters.WorkContext.GitBranch
-			}
-			if len(req.Filters.WorkContext.OpenTicketIDs) > 0 {
-				opts.Filters["ticket_ids"] = req.Filters.WorkContext.OpenTicketIDs
-			}
-		}
-	}
-
-	// Apply work context from request (overrides filter)
-	if req.WorkContext != nil {
-		if req.WorkContext.ActiveFile != "" {
-			opts.Filters["boost_file"] = req.WorkContext.ActiveFile
-		}
-		if req.WorkContext.GitBranch != "" {
-			opts.Filters["git_branch"] = req.WorkContext.GitBranch
-		}
-		if len(req.WorkContext.OpenTicketIDs) > 0 {
-			opts.Filters["boost_tickets"] = req.WorkContext.OpenTicketIDs
-		}
-	}
-
-	// Perform hybrid search (combines vector + BM25)
-	results, searchErr := h.vectorStore.SearchHybrid(ctx, req.Query, queryVec.Vector, opts)
-	if searchErr != nil {
-		return nil, &protocol.Error{
-			Code:    protocol.InternalError,
-			Message: fmt.Sprintf("search failed: %v", searchErr),
-		}
-	}
-
-	queryTime := float64(time.Since(startTime).Milliseconds())
-
-	// Get total count for pagination
-	totalCount, countErr := h.vectorStore.Count(ctx)
-	if countErr != nil {
-		// Log error but don't fail the request
-		totalCount = int64(len(results))
-	}
-
-	// Convert results to response format
-	searchResults := make([]mcp.SearchResultItem, 0, len(results))
-	for _, r := range results {
-		// Extract source type from metadata
-		sourceType := "file" // default
-		if st, ok := r.Document.Metadata["source_type"].(string); ok {
-			sourceType = st
-		}
-
-		searchResults = append(searchResults, mcp.SearchResultItem{
-			ID:         r.Document.ID,
-			Content:    r.Document.Content,
-			Score:      r.Score,
-			SourceType: sourceType,
-			Metadata:   r.Document.Metadata,
-		})
-	}
-
-	return mcp.SearchResponse{
-		Results:    searchResults,
-		TotalCount: len(searchResults),
-		QueryTime:  queryTime,
-		Offset:     offset,
-		Limit:      topK,
-		HasMore:    int64(offset+len(results)) < totalCount,
-	}, nil
-}
-
 func (h *mcpHTTPHandler) handleGetRelatedInfo(ctx context.Context, args json.RawMessage) (interface{}, error) {
 	var req mcp.GetRelatedInfoRequest
 	if err := json.Unmarshal(args, &req); err != nil {
@@ -959,7 +863,7 @@ func (h *mcpHTTPHandler) handleGetRelatedInfo(ctx context.Context, args json.Raw
 	} else {
 		// Ticket ID flow: search for ticket-related content
 		query = fmt.Sprintf("ticket:%s", req.TicketID)
-		opts.Filters["ticket_ids"] = []string{req.TicketID}
+		opts.Filter = vectorstore.Contains("ticket_ids", req.TicketID)
 	}
 
 	// Generate query embedding
@@ -1059,228 +963,6 @@ func truncateString(s string, maxLen int) string {
 	return s[:maxLen] + "..."
 }
 
-func (h *mcpHTTPHandler) handleIndexControl(ctx context.Context, args json.RawMessage) (interface{}, error) {
-	var req mcp.IndexControlRequest
-	if err := json.Unmarshal(args, &req); err != nil {
-		return nil, &protocol.Error{
-			Code:    protocol.InvalidParams,
-			Message: fmt.Sprintf("invalid request: %v", err),
-		}
-	}
-
-	// Validate action
-	validActions := map[string]bool{
-		"start":         true,
-		"stop":          true,
-		"status":        true,
-		"force_reindex": true,
-		"reindex_paths": true,
-	}
-
-	if !validActions[req.Action] {
-		return nil, &protocol.Error{
-			Code:    protocol.InvalidParams,
-			Message: fmt.Sprintf("invalid action: %s", req.Action),
-		}
-	}
-
-	// Check if indexer is available
-	if h.indexer == nil {
-		return nil, &protocol.Error{
-			Code:    protocol.InternalError,
-			Message: "index controller not available",
-		}
-	}
-
-	switch req.Action {
-	case "status":
-		// Get document count
-		count, err := h.vectorStore.Count(ctx)
-		if err != nil {
-			return nil, &protocol.Error{
-				Code:    protocol.InternalError,
-				Message: fmt.Sprintf("failed to get document count: %v", err),
-			}
-		}
-
-		// Get indexer status
-		idxStatus := h.indexer.GetStatus()
-
-		// Convert to response format
-		var startTime, estimatedEnd string
-		if !idxStatus.StartTime.IsZero() {
-			startTime = idxStatus.StartTime.Format(time.RFC3339)
-		}
-		if !idxStatus.EstimatedEnd.IsZero() {
-			estimatedEnd = idxStatus.EstimatedEnd.Format(time.RFC3339)
-		}
-
-		var metrics *mcp.IndexMetrics
-		if idxStatus.Metrics.TotalFiles > 0 {
-			metrics = &mcp.IndexMetrics{
-				TotalFiles:      idxStatus.Metrics.TotalFiles,
-				IndexedFiles:    idxStatus.Metrics.IndexedFiles,
-				SkippedFiles:    idxStatus.Metrics.SkippedFiles,
-				TotalChunks:     idxStatus.Metrics.TotalChunks,
-				Duration:        idxStatus.Metrics.Duration.Seconds(),
-				BytesProcessed:  idxStatus.Metrics.BytesProcessed,
-				StateSize:       idxStatus.Metrics.StateSize,
-				IncrementalSave: idxStatus.Metrics.IncrementalSave.Seconds(),
-			}
-		}
-
-		details := map[string]interface{}{}
-		details["documents_indexed"] = count
-		details["indexer_available"] = true
-
-		return mcp.IndexControlResponse{
-			Status:  "ok",
-			Message: fmt.Sprintf("Index contains %d documents", count),
-			Details: details,
-			IndexStatus: &mcp.IndexStatus{
-				IsIndexing:     idxStatus.IsIndexing,
-				Phase:          idxStatus.Phase,
-				Progress:       idxStatus.Progress,
-				FilesProcessed: idxStatus.FilesProcessed,
-				TotalFiles:     idxStatus.TotalFiles,
-				ChunksCreated:  idxStatus.ChunksCreated,
-				StartTime:      startTime,
-				EstimatedEnd:   estimatedEnd,
-				LastError:      idxStatus.LastError,
-				Metrics:        metrics,
-			},
-		}, nil
-
-	case "start":
-		// Use configured root path
-		rootPath := h.rootPath
-		if rootPath == "" {
-			rootPath = "."
-		}
-
-		// Load ignore patterns
-		ignorePatterns := []string{".git"}
-		if gitignore, err := indexer.LoadGitignore(filepath.Join(rootPath, ".gitignore"), rootPath); err == nil {
-			ignorePatterns = append(ignorePatterns, gitignore...)
-		}
-
-		opts := indexer.IndexOptions{
-			RootPath:       rootPath,
-			IgnorePatterns: ignorePatterns,
-			MaxFileSize:    1024 * 1024, // 1MB
-			IncludeGitInfo: true,
-			Embedder:       h.embedder,
-			VectorStore:    h.vectorStore,
-		}
-
-		if err := h.indexer.Start(ctx, opts); err != nil {
-			return nil, &protocol.Error{
-				Code:    protocol.InternalError,
-				Message: fmt.Sprintf("failed to start indexing: %v", err),
-			}
-		}
-
-		return mcp.IndexControlResponse{
-			Status:  "ok",
-			Message: "Background indexing started",
-		}, nil
-
-	case "stop":
-		if err := h.indexer.Stop(ctx); err != nil {
-			return nil, &protocol.Error{
-				Code:    protocol.InternalError,
-				Message: fmt.Sprintf("failed to stop indexing: %v", err),
-			}
-		}
-
-		return mcp.IndexControlResponse{
-			Status:  "ok",
-			Message: "Indexing stopped",
-		}, nil
-
-	case "force_reindex":
-		// Use configured root path
-		rootPath := h.rootPath
-		if rootPath == "" {
-			rootPath = "."
-		}
-
-		// Load ignore patterns
-		ignorePatterns := []string{".git"}
-		if gitignore, err := indexer.LoadGitignore(filepath.Join(rootPath, ".gitignore"), rootPath); err == nil {
-			ignorePatterns = append(ignorePatterns, gitignore...)
-		}
-
-		opts := indexer.IndexOptions{
-			RootPath:       rootPath,
-			IgnorePatterns: ignorePatterns,
-			MaxFileSize:    1024 * 1024, // 1MB
-			IncludeGitInfo: true,
-			Embedder:       h.embedder,
-			VectorStore:    h.vectorStore,
-		}
-
-		if err := h.indexer.ForceReindex(ctx, opts); err != nil {
-			return nil, &protocol.Error{
-				Code:    protocol.InternalError,
-				Message: fmt.Sprintf("failed to start force reindex: %v", err),
-			}
-		}
-
-		return mcp.IndexControlResponse{
-			Status:  "ok",
-			Message: "Force reindex started",
-		}, nil
-
-	case "reindex_paths":
-		if len(req.Paths) == 0 {
-			return nil, &protocol.Error{
-				Code:    protocol.InvalidParams,
-				Message: "paths are required for reindex_paths action",
-			}
-		}
-
-		// Use configured root path
-		rootPath := h.rootPath
-		if rootPath == "" {
-			rootPath = "."
-		}
-
-		// Load ignore patterns
-		ignorePatterns := []string{".git"}
-		if gitignore, err := indexer.LoadGitignore(filepath.Join(rootPath, ".gitignore"), rootPath); err == nil {
-			ignorePatterns = append(ignorePatterns, gitignore...)
-		}
-
-		opts := indexer.IndexOptions{
-			RootPath:       rootPath,
-			IgnorePatterns: ignorePatterns,
-			MaxFileSize:    1024 * 1024, // 1MB
-			IncludeGitInfo: true,
-			Embedder:       h.embedder,
-			VectorStore:    h.vectorStore,
-		}
-
-		if err := h.indexer.ReindexPaths(ctx, opts, req.Paths); err != nil {
-			return nil, &protocol.Error{
-				Code:    protocol.InternalError,
-				Message: fmt.Sprintf("failed to start selective reindex: %v", err),
-			}
-		}
-
-		return mcp.IndexControlResponse{
-			Status:  "ok",
-			Message: fmt.Sprintf("Reindexing %d paths", len(req.Paths)),
-		}, nil
-
-	default:
-		return nil, &protocol.Error{
-			Code:    protocol.InternalError,
-			Message: fmt.Sprintf("unimplemented action: %s", req.Action),
-		}
-	}
-}
-
 func (h *mcpHTTPHandler) handleConnectorManagement(ctx context.Context, args json.RawMessage) (interface{}, error) {
 	var req mcp.ConnectorManagementRequest
 	if err := json.Unmarshal(args, &req); err != nil {
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ferg-cod3s/conexus/internal/config"
+	"github.com/ferg-cod3s/conexus/internal/indexer"
+	"github.com/ferg-cod3s/conexus/internal/snapshot"
+)
+
+// Exit codes for the index subcommand.
+const (
+	indexExitOK    = 0
+	indexExitError = 1
+	indexExitUsage = 2
+)
+
+// runIndex implements `conexus index export|import`.
+func runIndex(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "Usage: conexus index <export|import> [flags]")
+		return indexExitUsage
+	}
+
+	switch args[0] {
+	case "export":
+		return runIndexExport(args[1:], stdout, stderr)
+	case "import":
+		return runIndexImport(args[1:], stdout, stderr)
+	default:
+		fmt.Fprintf(stderr, "Unknown index command %q. Usage: conexus index <export|import> [flags]\n", args[0])
+		return indexExitUsage
+	}
+}
+
+// runIndexExport implements `conexus index export [--output FILE]`. It
+// writes the index as a compressed bundle that other checkouts can import.
+func runIndexExport(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("index export", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	output := fs.String("output", "conexus-index.tar.gz", "bundle file to write")
+	if err := fs.Parse(args); err != nil {
+		return indexExitUsage
+	}
+
+	ctx := context.Background()
+	cfg, err := config.Load(ctx)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to load configuration: %v\n", err)
+		return indexExitError
+	}
+
+	embedder, err := createEmbedder(cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to create embedder: %v\n", err)
+		return indexExitError
+	}
+
+	store, err := openVectorStore(cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to open vector store: %v\n", err)
+		return indexExitError
+	}
+	defer store.Close()
+
+	// Write to a temporary file so a failed export never leaves a partial bundle
+	tmp, err := os.CreateTemp(filepath.Dir(*output), ".conexus-export-*")
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to create bundle: %v\n", err)
+		return indexExitError
+	}
+	defer os.Remove(tmp.Name())
+
+	manifest, err := snapshot.Export(ctx, tmp, snapshot.ExportOptions{
+		Store:     store,
+		Embedder:  snapshot.IdentityOf(cfg.Embedding.Provider, embedder),
+		StatePath: indexerStatePath,
+	})
+	if err != nil {
+		// #nosec G104 - Best-effort cleanup in error path, primary error already captured
+		tmp.Close()
+		fmt.Fprintf(stderr, "Export failed: %v\n", err)
+		return indexExitError
+	}
+	if err := tmp.Close(); err != nil {
+		fmt.Fprintf(stderr, "Failed to write bundle: %v\n", err)
+		return indexExitError
+	}
+	if err := os.Rename(tmp.Name(), *output); err != nil {
+		fmt.Fprintf(stderr, "Failed to write bundle: %v\n", err)
+		return indexExitError
+	}
+
+	fmt.Fprintf(stdout, "Exported %d documents to %s (format v%d, embedder %s)\n",
+		manifest.Documents, *output, manifest.FormatVersion, manifest.Embedder)
+	return indexExitOK
+}
+
+// runIndexImport implements `conexus index import [--root DIR] FILE`. It
+// replaces the local index with a bundle, then indexes the changes between
+// the bundle and the local checkout.
+func runIndexImport(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("index import", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	root := fs.String("root", "", "root directory of the local checkout (default: indexer.root_path)")
+	if err := fs.Parse(args); err != nil {
+		return indexExitUsage
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "Usage: conexus index import [--root DIR] FILE")
+		return indexExitUsage
+	}
+	bundlePath := fs.Arg(0)
+
+	ctx := context.Background()
+	cfg, err := config.Load(ctx)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to load configuration: %v\n", err)
+		return indexExitError
+	}
+
+	rootPath := cfg.Indexer.RootPath
+	if *root != "" {
+		rootPath = *root
+	}
+	if rootPath, err = filepath.Abs(rootPath); err != nil {
+		fmt.Fprintf(stderr, "Invalid root path: %v\n", err)
+		return indexExitError
+	}
+
+	embedder, err := createEmbedder(cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to create embedder: %v\n", err)
+		return indexExitError
+	}
+
+	idx := indexer.NewIndexer(indexerStatePath)
+	if err := idx.SetChunkHeaderTemplate(cfg.Indexer.ChunkHeaderTemplate); err != nil {
+		fmt.Fprintf(stderr, "Invalid chunk header template: %v\n", err)
+		return indexExitError
+	}
+	if err := idx.SetSecretHandling(indexer.SecretHandling(cfg.Indexer.SecretHandling)); err != nil {
+		fmt.Fprintf(stderr, "Invalid secret handling: %v\n", err)
+		return indexExitError
+	}
+	idx.SetNotebookOutputSize(cfg.Indexer.NotebookOutputSize)
+
+	// #nosec G304 - Bundle path is supplied by the user running the command
+	bundle, err := os.Open(bundlePath)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to open bundle: %v\n", err)
+		return indexExitError
+	}
+	defer bundle.Close()
+
+	store, err := openVectorStore(cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to open vector store: %v\n", err)
+		return indexExitError
+	}
+	defer store.Close()
+
+	manifest, err := snapshot.Import(ctx, bundle, snapshot.ImportOptions{
+		Store:     store,
+		Embedder:  snapshot.IdentityOf(cfg.Embedding.Provider, embedder),
+		StatePath: indexerStatePath,
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "Import failed: %v\n", err)
+		return indexExitError
+	}
+	fmt.Fprintf(stdout, "Imported %d documents built %s\n", manifest.Documents, manifest.CreatedAt.Local().Format(time.RFC3339))
+
+	// Bring the baseline up to date with the local checkout
+	opts := indexer.DefaultIndexOptions(rootPath, embedder, store)
+
+	previousState, err := idx.LoadState(ctx)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to load indexer state: %v\n", err)
+		return indexExitError
+	}
+	chunks, newState, err := idx.IndexIncremental(ctx, opts, previousState)
+	if err != nil {
+		fmt.Fprintf(stderr, "Incremental update failed: %v\n", err)
+		return indexExitError
+	}
+	if err := idx.SaveState(ctx, newState); err != nil {
+		fmt.Fprintf(stderr, "Failed to save indexer state: %v\n", err)
+		return indexExitError
+	}
+
+	fmt.Fprintf(stdout, "Updated %d chunks from local changes\n", len(chunks))
+	return indexExitOK
+}
@@ -13,6 +13,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/ferg-cod3s/conexus/internal/indexer"
 	"github.com/ferg-cod3s/conexus/internal/validation"
 	"gopkg.in/yaml.v3"
 )
@@ -23,6 +24,7 @@ type Config struct {
 	Database      DatabaseConfig      `json:"database" yaml:"database"`
 	Indexer       IndexerConfig       `json:"indexer" yaml:"indexer"`
 	Embedding     EmbeddingConfig     `json:"embedding" yaml:"embedding"`
+	Rerank        RerankConfig        `json:"rerank" yaml:"rerank"`
 	Logging       LoggingConfig       `json:"logging" yaml:"logging"`
 	Auth          AuthConfig          `json:"auth" yaml:"auth"`
 	Security      SecurityConfig      `json:"security" yaml:"security"`
@@ -40,14 +42,20 @@ type ServerConfig struct {
 
 // DatabaseConfig holds database configuration.
 type DatabaseConfig struct {
-	Path string `json:"path" yaml:"path"`
+	Path           string `json:"path" yaml:"path"`
+	VectorEncoding string `json:"vector_encoding" yaml:"vector_encoding"` // Encoding of stored vectors: float32 or float16 (empty = float32)
+	Quantization   string `json:"quantization" yaml:"quantization"`       // Compression of in-memory graph vectors: none, int8 or pq (empty = none)
+	PQSubvectors   int    `json:"pq_subvectors" yaml:"pq_subvectors"`     // Subvectors per vector for pq (0 = dimensions/8)
 }
 
 // IndexerConfig holds indexer configuration.
 type IndexerConfig struct {
-	RootPath     string `json:"root_path" yaml:"root_path"`
-	ChunkSize    int    `json:"chunk_size" yaml:"chunk_size"`
-	ChunkOverlap int    `json:"chunk_overlap" yaml:"chunk_overlap"`
+	RootPath            string `json:"root_path" yaml:"root_path"`
+	ChunkSize           int    `json:"chunk_size" yaml:"chunk_size"`
+	ChunkOverlap        int    `json:"chunk_overlap" yaml:"chunk_overlap"`
+	ChunkHeaderTemplate string `json:"chunk_header_template" yaml:"chunk_header_template"` // Go text/template for the context embedded with each chunk (empty = built-in default)
+	SecretHandling      string `json:"secret_handling" yaml:"secret_handling"`             // What happens to detected secrets: redact, quarantine or off (empty = redact)
+	NotebookOutputSize  int    `json:"notebook_output_size" yaml:"notebook_output_size"`   // Bytes of text output kept per notebook cell (0 = strip outputs)
 }
 
 // EmbeddingConfig holds embedding provider configuration.
@@ -58,6 +66,14 @@ type EmbeddingConfig struct {
 	Config     map[string]interface{} `json:"config" yaml:"config"`
 }
 
+// RerankConfig holds reranker provider configuration. An empty provider
+// leaves search results in their fused order.
+type RerankConfig struct {
+	Provider string                 `json:"provider" yaml:"provider"` // Reranker provider: http or lexical (empty = none)
+	Model    string                 `json:"model" yaml:"model"`
+	Config   map[string]interface{} `json:"config" yaml:"config"` // Provider options, such as the url of the http provider
+}
+
 // LoggingConfig holds logging configuration.
 type LoggingConfig struct {
 	Level  string `json:"level" yaml:"level"`
@@ -239,8 +255,11 @@ const (
 
 // Valid values for validation
 var (
-	ValidLogLevels  = []string{"debug", "info", "warn", "error"}
-	ValidLogFormats = []string{"json", "text"}
+	ValidLogLevels       = []string{"debug", "info", "warn", "error"}
+	ValidLogFormats      = []string{"json", "text"}
+	ValidVectorEncodings = []string{"float32", "float16"}
+	ValidQuantizations   = []string{"none", "int8", "pq"}
+	ValidSecretHandlings = []string{"redact", "quarantine", "off"}
 )
 
 // Load loads configuration from environment variables and optional config file.
@@ -407,6 +426,17 @@ func loadEnv(cfg *Config) *Config {
 	if dbPath := os.Getenv("CONEXUS_DB_PATH"); dbPath != "" {
 		cfg.Database.Path = dbPath
 	}
+	if vectorEncoding := os.Getenv("CONEXUS_VECTOR_ENCODING"); vectorEncoding != "" {
+		cfg.Database.VectorEncoding = vectorEncoding
+	}
+	if quantization := os.Getenv("CONEXUS_VECTOR_QUANTIZATION"); quantization != "" {
+		cfg.Database.Quantization = quantization
+	}
+	if subvectors := os.Getenv("CONEXUS_PQ_SUBVECTORS"); subvectors != "" {
+		if m, err := strconv.Atoi(subvectors); err == nil {
+			cfg.Database.PQSubvectors = m
+		}
+	}
 
 	// Indexer config
 	if rootPath := os.Getenv("CONEXUS_ROOT_PATH"); rootPath != "" {
@@ -422,6 +452,17 @@ func loadEnv(cfg *Config) *Config {
 			cfg.Indexer.ChunkOverlap = co
 		}
 	}
+	if headerTemplate := os.Getenv("CONEXUS_CHUNK_HEADER_TEMPLATE"); headerTemplate != "" {
+		cfg.Indexer.ChunkHeaderTemplate = headerTemplate
+	}
+	if secretHandling := os.Getenv("CONEXUS_SECRET_HANDLING"); secretHandling != "" {
+		cfg.Indexer.SecretHandling = secretHandling
+	}
+	if outputSize := os.Getenv("CONEXUS_NOTEBOOK_OUTPUT_SIZE"); outputSize != "" {
+		if size, err := strconv.Atoi(outputSize); err == nil {
+			cfg.Indexer.NotebookOutputSize = size
+		}
+	}
 
 	// Embedding config
 	if provider := os.Getenv("CONEXUS_EMBEDDING_PROVIDER"); provider != "" {
@@ -436,6 +477,20 @@ func loadEnv(cfg *Config) *Config {
 		}
 	}
 
+	// Rerank config
+	if provider := os.Getenv("CONEXUS_RERANK_PROVIDER"); provider != "" {
+		cfg.Rerank.Provider = provider
+	}
+	if model := os.Getenv("CONEXUS_RERANK_MODEL"); model != "" {
+		cfg.Rerank.Model = model
+	}
+	if url := os.Getenv("CONEXUS_RERANK_URL"); url != "" {
+		if cfg.Rerank.Config == nil {
+			cfg.Rerank.Config = make(map[string]interface{})
+		}
+		cfg.Rerank.Config["url"] = url
+	}
+
 	// Logging config
 	if logLevel := os.Getenv("CONEXUS_LOG_LEVEL"); logLevel != "" {
 		cfg.Logging.Level = logLevel
@@ -815,6 +870,15 @@ func merge(base, override *Config) *Config {
 	if override.Database.Path != "" {
 		result.Database.Path = override.Database.Path
 	}
+	if override.Database.VectorEncoding != "" {
+		result.Database.VectorEncoding = override.Database.VectorEncoding
+	}
+	if override.Database.Quantization != "" {
+		result.Database.Quantization = override.Database.Quantization
+	}
+	if override.Database.PQSubvectors != 0 {
+		result.Database.PQSubvectors = override.Database.PQSubvectors
+	}
 
 	// Indexer
 	if override.Indexer.RootPath != "" {
@@ -826,6 +890,15 @@ func merge(base, override *Config) *Config {
 	if override.Indexer.ChunkOverlap != 0 {
 		result.Indexer.ChunkOverlap = override.Indexer.ChunkOverlap
 	}
+	if override.Indexer.ChunkHeaderTemplate != "" {
+		result.Indexer.ChunkHeaderTemplate = override.Indexer.ChunkHeaderTemplate
+	}
+	if override.Indexer.SecretHandling != "" {
+		result.Indexer.SecretHandling = override.Indexer.SecretHandling
+	}
+	if override.Indexer.NotebookOutputSize != 0 {
+		result.Indexer.NotebookOutputSize = override.Indexer.NotebookOutputSize
+	}
 
 	// Embedding
 	if override.Embedding.Provider != "" {
@@ -841,6 +914,17 @@ func merge(base, override *Config) *Config {
 		result.Embedding.Config = override.Embedding.Config
 	}
 
+	// Rerank
+	if override.Rerank.Provider != "" {
+		result.Rerank.Provider = override.Rerank.Provider
+	}
+	if override.Rerank.Model != "" {
+		result.Rerank.Model = override.Rerank.Model
+	}
+	if override.Rerank.Config != nil {
+		result.Rerank.Config = override.Rerank.Config
+	}
+
 	// Logging
 	if override.Logging.Level != "" {
 		result.Logging.Level = override.Logging.Level
@@ -1104,6 +1188,15 @@ func (c *Config) Validate() error {
 	if c.Database.Path == "" {
 		return fmt.Errorf("database path cannot be empty")
 	}
+	if c.Database.VectorEncoding != "" && !contains(ValidVectorEncodings, c.Database.VectorEncoding) {
+		return fmt.Errorf("invalid vector encoding: %s (valid: %v)", c.Database.VectorEncoding, ValidVectorEncodings)
+	}
+	if c.Database.Quantization != "" && !contains(ValidQuantizations, c.Database.Quantization) {
+		return fmt.Errorf("invalid quantization: %s (valid: %v)", c.Database.Quantization, ValidQuantizations)
+	}
+	if c.Database.PQSubvectors < 0 {
+		return fmt.Errorf("pq subvectors cannot be negative: %d", c.Database.PQSubvectors)
+	}
 
 	// Validate indexer config
 	if c.Indexer.RootPath == "" {
@@ -1119,6 +1212,15 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("chunk overlap (%d) must be less than chunk size (%d)",
 			c.Indexer.ChunkOverlap, c.Indexer.ChunkSize)
 	}
+	if _, err := indexer.ParseChunkHeaderTemplate(c.Indexer.ChunkHeaderTemplate); err != nil {
+		return err
+	}
+	if c.Indexer.SecretHandling != "" && !contains(ValidSecretHandlings, c.Indexer.SecretHandling) {
+		return fmt.Errorf("invalid secret handling: %s (valid: %v)", c.Indexer.SecretHandling, ValidSecretHandlings)
+	}
+	if c.Indexer.NotebookOutputSize < 0 {
+		return fmt.Errorf("notebook output size cannot be negative: %d", c.Indexer.NotebookOutputSize)
+	}
 
 	// Validate logging config
 	if !contains(ValidLogLevels, c.Logging.Level) {
@@ -35,14 +35,20 @@ func TestLoadEnv(t *testing.T) {
 		{
 			name: "all env vars",
 			envVars: map[string]string{
-				"CONEXUS_HOST":          "127.0.0.1",
-				"CONEXUS_PORT":          "9090",
-				"CONEXUS_DB_PATH":       "/custom/db.sqlite",
-				"CONEXUS_ROOT_PATH":     "/custom/root",
-				"CONEXUS_CHUNK_SIZE":    "1024",
-				"CONEXUS_CHUNK_OVERLAP": "100",
-				"CONEXUS_LOG_LEVEL":     "debug",
-				"CONEXUS_LOG_FORMAT":    "text",
+				"CONEXUS_HOST":                  "127.0.0.1",
+				"CONEXUS_PORT":                  "9090",
+				"CONEXUS_DB_PATH":               "/custom/db.sqlite",
+				"CONEXUS_VECTOR_ENCODING":       "float16",
+				"CONEXUS_VECTOR_QUANTIZATION":   "pq",
+				"CONEXUS_PQ_SUBVECTORS":         "48",
+				"CONEXUS_ROOT_PATH":             "/custom/root",
+				"CONEXUS_CHUNK_SIZE":            "1024",
+				"CONEXUS_CHUNK_OVERLAP":         "100",
+				"CONEXUS_CHUNK_HEADER_TEMPLATE": "{{.FilePath}}",
+				"CONEXUS_SECRET_HANDLING":       "quarantine",
+				"CONEXUS_NOTEBOOK_OUTPUT_SIZE":  "2000",
+				"CONEXUS_LOG_LEVEL":             "debug",
+				"CONEXUS_LOG_FORMAT":            "text",
 			},
 			expected: &Config{
 				Server: ServerConfig{
@@ -50,12 +56,18 @@ func TestLoadEnv(t *testing.T) {
 					Port: 9090,
 				},
 				Database: DatabaseConfig{
-					Path: "/custom/db.sqlite",
+					Path:           "/custom/db.sqlite",
+					VectorEncoding: "float16",
+					Quantization:   "pq",
+					PQSubvectors:   48,
 				},
 				Indexer: IndexerConfig{
-					RootPath:     "/custom/root",
-					ChunkSize:    1024,
-					ChunkOverlap: 100,
+					RootPath:            "/custom/root",
+					ChunkSize:           1024,
+					ChunkOverlap:        100,
+					ChunkHeaderTemplate: "{{.FilePath}}",
+					SecretHandling:      "quarantine",
+					NotebookOutputSize:  2000,
 				},
 				Embedding: EmbeddingConfig{
 					Provider:   DefaultEmbeddingProvider,
@@ -413,6 +425,20 @@ func TestLoadEnv(t *testing.T) {
 	}
 }
 
+func TestLoadEnv_Rerank(t *testing.T) {
+	clearEnv(t)
+	os.Setenv("CONEXUS_RERANK_PROVIDER", "http")
+	os.Setenv("CONEXUS_RERANK_MODEL", "bge-reranker-base")
+	os.Setenv("CONEXUS_RERANK_URL", "http://localhost:8080/rerank")
+	t.Cleanup(func() { clearEnv(t) })
+
+	cfg := loadEnv(defaults())
+
+	assert.Equal(t, "http", cfg.Rerank.Provider)
+	assert.Equal(t, "bge-reranker-base", cfg.Rerank.Model)
+	assert.Equal(t, "http://localhost:8080/rerank", cfg.Rerank.Config["url"])
+}
+
 func TestLoadFile(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -674,6 +700,76 @@ func TestValidate(t *testing.T) {
 			expectError: true,
 			errorMsg:    "chunk size must be positive",
 		},
+		{
+			name: "invalid vector encoding",
+			cfg: func() *Config {
+				cfg := defaults()
+				cfg.Database.VectorEncoding = "int8"
+				return cfg
+			}(),
+			expectError: true,
+			errorMsg:    "invalid vector encoding",
+		},
+		{
+			name: "invalid quantization",
+			cfg: func() *Config {
+				cfg := defaults()
+				cfg.Database.Quantization = "int4"
+				return cfg
+			}(),
+			expectError: true,
+			errorMsg:    "invalid quantization",
+		},
+		{
+			name: "negative pq subvectors",
+			cfg: func() *Config {
+				cfg := defaults()
+				cfg.Database.PQSubvectors = -1
+				return cfg
+			}(),
+			expectError: true,
+			errorMsg:    "pq subvectors cannot be negative",
+		},
+		{
+			name: "invalid chunk header template",
+			cfg: func() *Config {
+				cfg := defaults()
+				cfg.Indexer.ChunkHeaderTemplate = "{{.FilePath"
+				return cfg
+			}(),
+			expectError: true,
+			errorMsg:    "parse chunk header template",
+		},
+		{
+			name: "chunk header template with unknown field",
+			cfg: func() *Config {
+				cfg := defaults()
+				cfg.Indexer.ChunkHeaderTemplate = "{{.Author}}"
+				return cfg
+			}(),
+			expectError: true,
+			errorMsg:    "invalid chunk header template",
+		},
+		{
+			name: "invalid secret handling",
+			cfg: func() *Config {
+				cfg := defaults()
+				cfg.Indexer.SecretHandling = "mask"
+				return cfg
+			}(),
+			expectError: true,
+			errorMsg:    "invalid secret handling",
+		},
+		{
+			name: "negative notebook output size",
+			cfg: func() *Config {
+				cfg := defaults()
+				cfg.Indexer.NotebookOutputSize = -1
+				return cfg
+			}(),
+			expectError: true,
+			errorMsg:    "notebook output size cannot be negative",
+		},
 		{
 			name: "negative chunk overlap",
 			cfg: &Config{
@@ -1411,9 +1507,18 @@ func clearEnv(t *testing.T) {
 		"CONEXUS_HOST",
 		"CONEXUS_PORT",
 		"CONEXUS_DB_PATH",
+		"CONEXUS_VECTOR_ENCODING",
+		"CONEXUS_VECTOR_QUANTIZATION",
+		"CONEXUS_PQ_SUBVECTORS",
 		"CONEXUS_ROOT_PATH",
 		"CONEXUS_CHUNK_SIZE",
 		"CONEXUS_CHUNK_OVERLAP",
+		"CONEXUS_CHUNK_HEADER_TEMPLATE",
+		"CONEXUS_SECRET_HANDLING",
+		"CONEXUS_NOTEBOOK_OUTPUT_SIZE",
+		"CONEXUS_RERANK_PROVIDER",
+		"CONEXUS_RERANK_MODEL",
+		"CONEXUS_RERANK_URL",
 		"CONEXUS_LOG_LEVEL",
 		"CONEXUS_LOG_FORMAT",
 		"CONEXUS_CONFIG_FILE",
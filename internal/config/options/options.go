@@ -0,0 +1,119 @@
+// Package options reads typed values from the free-form provider
+// configuration maps used by the embedding and rerank registries.
+package options
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// String returns the string value of key, or "" if unset.
+func String(config map[string]interface{}, key string) (string, error) {
+	switch v := config[key].(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	default:
+		return "", fmt.Errorf("%s must be a string, got %T", key, v)
+	}
+}
+
+// Int returns the integer value of key, or 0 if unset. JSON numbers
+// decode as float64.
+func Int(config map[string]interface{}, key string) (int, error) {
+	switch v := config[key].(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return v, nil
+	case float64:
+		if v != float64(int(v)) {
+			return 0, fmt.Errorf("%s must be an integer, got %g", key, v)
+		}
+		return int(v), nil
+	default:
+		return 0, fmt.Errorf("%s must be an integer, got %T", key, v)
+	}
+}
+
+// Float returns the numeric value of key, or 0 if unset.
+func Float(config map[string]interface{}, key string) (float64, error) {
+	switch v := config[key].(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	default:
+		return 0, fmt.Errorf("%s must be a number, got %T", key, v)
+	}
+}
+
+// Bool returns the boolean value of key, or def if unset.
+func Bool(config map[string]interface{}, key string, def bool) (bool, error) {
+	switch v := config[key].(type) {
+	case nil:
+		return def, nil
+	case bool:
+		return v, nil
+	default:
+		return false, fmt.Errorf("%s must be a boolean, got %T", key, v)
+	}
+}
+
+// Duration returns the duration value of key, given as a string such
+// as "30s" or a number of seconds, or 0 if unset.
+func Duration(config map[string]interface{}, key string) (time.Duration, error) {
+	switch v := config[key].(type) {
+	case nil:
+		return 0, nil
+	case string:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", key, err)
+		}
+		return d, nil
+	case int:
+		return time.Duration(v) * time.Second, nil
+	case float64:
+		return time.Duration(v * float64(time.Second)), nil
+	default:
+		return 0, fmt.Errorf("%s must be a duration, got %T", key, v)
+	}
+}
+
+// APIKey resolves an API key from config: api_key itself, the file
+// named by api_key_file (such as a mounted secret), or the environment
+// variable named by api_key_env, falling back to defaultEnv. It returns ""
+// when none is set.
+func APIKey(config map[string]interface{}, defaultEnv string) (string, error) {
+	key, err := String(config, "api_key")
+	if err != nil || key != "" {
+		return key, err
+	}
+
+	file, err := String(config, "api_key_file")
+	if err != nil {
+		return "", err
+	}
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("read api_key_file: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	env, err := String(config, "api_key_env")
+	if err != nil {
+		return "", err
+	}
+	if env == "" {
+		env = defaultEnv
+	}
+	return os.Getenv(env), nil
+}
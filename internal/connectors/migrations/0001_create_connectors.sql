@@ -0,0 +1,16 @@
+-- Connectors table
+CREATE TABLE IF NOT EXISTS connectors (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	type TEXT NOT NULL,
+	config TEXT NOT NULL,  -- JSON-encoded config
+	status TEXT NOT NULL DEFAULT 'active',
+	created_at INTEGER NOT NULL,
+	updated_at INTEGER NOT NULL
+);
+
+-- Index for faster lookups by type
+CREATE INDEX IF NOT EXISTS idx_connectors_type ON connectors(type);
+
+-- Index for faster lookups by status
+CREATE INDEX IF NOT EXISTS idx_connectors_status ON connectors(status);
@@ -4,13 +4,17 @@ package connectors
 import (
 	"context"
 	"database/sql"
+	"embed"
 	"encoding/json"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"time"
 
 	_ "modernc.org/sqlite" // Pure Go SQLite driver
+
+	"github.com/ferg-cod3s/conexus/internal/migrate"
 )
 
 // Connector represents a connector configuration
@@ -50,6 +54,12 @@ func NewStore(path string) (*Store, error) {
 		}
 	}
 
+	// Only databases that existed before are backed up before migrating
+	backupPath := ""
+	if _, err := os.Stat(path); err == nil && path != ":memory:" {
+		backupPath = path
+	}
+
 	db, err := sql.Open("sqlite", path)
 	if err != nil {
 		return nil, fmt.Errorf("open database: %w", err)
@@ -62,38 +72,44 @@ func NewStore(path string) (*Store, error) {
 
 	store := &Store{db: db}
 
-	// Initialize schema
-	if err := store.initSchema(); err != nil {
-		// #nosec G104 - Best-effort cleanup in error path, primary error (schema init) already captured
+	if err := store.migrate(context.Background(), backupPath); err != nil {
+		// #nosec G104 - Best-effort cleanup in error path, primary error (migration) already captured
 		db.Close()
-		return nil, fmt.Errorf("init schema: %w", err)
+		return nil, fmt.Errorf("migrate database: %w", err)
 	}
 
 	return store, nil
 }
 
-// initSchema creates the required tables and indexes.
-func (s *Store) initSchema() error {
-	schema := `
-	-- Connectors table
-	CREATE TABLE IF NOT EXISTS connectors (
-		id TEXT PRIMARY KEY,
-		name TEXT NOT NULL,
-		type TEXT NOT NULL,
-		config TEXT NOT NULL,  -- JSON-encoded config
-		status TEXT NOT NULL DEFAULT 'active',
-		created_at INTEGER NOT NULL,
-		updated_at INTEGER NOT NULL
-	);
-
-	-- Index for faster lookups by type
-	CREATE INDEX IF NOT EXISTS idx_connectors_type ON connectors(type);
-
-	-- Index for faster lookups by status
-	CREATE INDEX IF NOT EXISTS idx_connectors_status ON connectors(status);
-	`
-
-	_, err := s.db.Exec(schema)
+// migrations holds the SQL migrations of the connector tables, applied in
+// the order of their version prefix.
+//
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// NewMigrator returns the migrator of the connector tables in db. Stores
+// apply it when opened; it is exported to report the status of a database.
+func NewMigrator(db *sql.DB) (*migrate.Migrator, error) {
+	dir, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("open migrations: %w", err)
+	}
+	loaded, err := migrate.Load(dir)
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(db, "connectors", loaded...)
+}
+
+// migrate creates or upgrades the connector tables, backing up the database
+// file at path first when it existed before.
+func (s *Store) migrate(ctx context.Context, path string) error {
+	m, err := NewMigrator(s.db)
+	if err != nil {
+		return err
+	}
+	m.DatabasePath = path
+	_, err = m.Up(ctx)
 	return err
 }
 
@@ -2,6 +2,7 @@ package connectors
 
 import (
 	"context"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -21,6 +22,31 @@ func TestNewStore(t *testing.T) {
 	assert.Empty(t, connectors)
 }
 
+func TestNewStore_Migrations(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "conexus.db")
+
+	store, err := NewStore(path)
+	require.NoError(t, err)
+	migrator, err := NewMigrator(store.db)
+	require.NoError(t, err)
+	statuses, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, statuses)
+	for _, status := range statuses {
+		assert.True(t, status.Applied, status.Name)
+	}
+	require.NoError(t, store.Close())
+
+	// Reopening applies nothing, so no backup is taken
+	store, err = NewStore(path)
+	require.NoError(t, err)
+	defer store.Close()
+	backups, err := filepath.Glob(path + ".*.bak")
+	require.NoError(t, err)
+	assert.Empty(t, backups)
+}
+
 func TestStore_Add(t *testing.T) {
 	store, err := NewStore(":memory:")
 	require.NoError(t, err)
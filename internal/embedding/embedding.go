@@ -30,6 +30,13 @@ type Embedder interface {
 	Model() string
 }
 
+// ModelChecker is implemented by embedders whose model lives on a server
+// and may be missing, such as a local model that has not been pulled.
+type ModelChecker interface {
+	// CheckModel verifies that the model can produce embeddings.
+	CheckModel(ctx context.Context) error
+}
+
 // Provider is a factory for creating embedders with specific configurations.
 type Provider interface {
 	// Name returns the provider identifier (e.g., "openai", "voyage", "mock").
@@ -0,0 +1,247 @@
+package embedding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ferg-cod3s/conexus/internal/config/options"
+)
+
+// Defaults of the Ollama embedder.
+const (
+	DefaultOllamaBaseURL   = "http://localhost:11434"
+	DefaultOllamaModel     = "nomic-embed-text"
+	DefaultOllamaBatchSize = 32
+	DefaultOllamaTimeout   = 60 * time.Second
+)
+
+// OllamaConfig configures an OllamaEmbedder.
+type OllamaConfig struct {
+	BaseURL    string        // Server root (default: $OLLAMA_HOST, then DefaultOllamaBaseURL)
+	Model      string        // Model name (default: DefaultOllamaModel)
+	Dimensions int           // Vector size reported until the model's is detected; 0 if unknown
+	BatchSize  int           // Texts per request (default: DefaultOllamaBatchSize)
+	Timeout    time.Duration // Limit of each request, which includes loading the model (default: DefaultOllamaTimeout)
+}
+
+// OllamaEmbedder generates embeddings with a local Ollama server's /api/embed
+// endpoint, so no source code leaves the machine. The vector size is that of
+// the model, detected from the first response.
+type OllamaEmbedder struct {
+	cfg        OllamaConfig
+	httpClient *http.Client
+
+	mu       sync.RWMutex
+	detected int // Vector size of the model; 0 until the first response
+}
+
+// NewOllama creates a new Ollama embedder. It does not contact the server;
+// use CheckModel to verify the model is available.
+func NewOllama(cfg OllamaConfig) *OllamaEmbedder {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = ollamaHost()
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	if cfg.Model == "" {
+		cfg.Model = DefaultOllamaModel
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultOllamaBatchSize
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultOllamaTimeout
+	}
+
+	return &OllamaEmbedder{
+		cfg:        cfg,
+		httpClient: &http.Client{},
+	}
+}
+
+// ollamaHost returns the server address from OLLAMA_HOST, as the Ollama CLI
+// reads it, or DefaultOllamaBaseURL.
+func ollamaHost() string {
+	host := os.Getenv("OLLAMA_HOST")
+	switch {
+	case host == "":
+		return DefaultOllamaBaseURL
+	case strings.Contains(host, "://"):
+		return host
+	default:
+		return "http://" + host
+	}
+}
+
+// Embed generates an embedding for a single text input.
+func (o *OllamaEmbedder) Embed(ctx context.Context, text string) (*Embedding, error) {
+	embeddings, err := o.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// EmbedBatch generates embeddings for multiple texts, BatchSize texts per
+// request.
+func (o *OllamaEmbedder) EmbedBatch(ctx context.Context, texts []string) ([]*Embedding, error) {
+	for i, text := range texts {
+		if text == "" {
+			return nil, fmt.Errorf("failed to embed text at index %d: cannot embed empty text", i)
+		}
+	}
+
+	embeddings := make([]*Embedding, 0, len(texts))
+	for start := 0; start < len(texts); start += o.cfg.BatchSize {
+		batch := texts[start:min(start+o.cfg.BatchSize, len(texts))]
+
+		vectors, err := o.request(ctx, batch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed texts %d-%d: %w", start, start+len(batch)-1, err)
+		}
+		for i, vector := range vectors {
+			embeddings = append(embeddings, &Embedding{
+				Text:   batch[i],
+				Vector: vector,
+				Model:  o.Model(),
+			})
+		}
+	}
+	return embeddings, nil
+}
+
+// Dimensions returns the vector size of the model once a response has shown
+// it, and the configured size before.
+func (o *OllamaEmbedder) Dimensions() int {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	if o.detected > 0 {
+		return o.detected
+	}
+	return o.cfg.Dimensions
+}
+
+// Model returns the model identifier.
+func (o *OllamaEmbedder) Model() string {
+	return "ollama/" + o.cfg.Model
+}
+
+// CheckModel verifies that the server is reachable and the model is pulled,
+// and detects the model's vector size.
+func (o *OllamaEmbedder) CheckModel(ctx context.Context) error {
+	var info struct{}
+	err := postJSON(ctx, o.httpClient, o.cfg.Timeout, o.cfg.BaseURL+"/api/show", "", map[string]string{"model": o.cfg.Model}, &info)
+	if err != nil {
+		return o.explain(err)
+	}
+	_, err = o.request(ctx, []string{"conexus dimension probe"})
+	return err
+}
+
+// ollamaEmbedRequest is the body posted to /api/embed.
+type ollamaEmbedRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// ollamaEmbedResponse is the body of a successful /api/embed response.
+type ollamaEmbedResponse struct {
+	Embeddings [][]float32 `json:"embeddings"`
+}
+
+// request embeds one batch of inputs.
+func (o *OllamaEmbedder) request(ctx context.Context, inputs []string) ([]Vector, error) {
+	var resp ollamaEmbedResponse
+	err := postJSON(ctx, o.httpClient, o.cfg.Timeout, o.cfg.BaseURL+"/api/embed", "", ollamaEmbedRequest{Model: o.cfg.Model, Input: inputs}, &resp)
+	if err != nil {
+		return nil, o.explain(err)
+	}
+
+	if len(resp.Embeddings) != len(inputs) {
+		return nil, fmt.Errorf("response has %d embeddings for %d inputs", len(resp.Embeddings), len(inputs))
+	}
+	vectors := make([]Vector, len(inputs))
+	for i, embedding := range resp.Embeddings {
+		if err := o.detect(len(embedding)); err != nil {
+			return nil, err
+		}
+		vectors[i] = embedding
+	}
+	return vectors, nil
+}
+
+// detect records the vector size of the model from its first response and
+// checks later ones against it.
+func (o *OllamaEmbedder) detect(dimensions int) error {
+	if dimensions == 0 {
+		return fmt.Errorf("model %s returned an empty embedding", o.cfg.Model)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.detected == 0 {
+		o.detected = dimensions
+	} else if dimensions != o.detected {
+		return fmt.Errorf("model %s returned %d dimensions, earlier %d", o.cfg.Model, dimensions, o.detected)
+	}
+	return nil
+}
+
+// explain turns the errors of a missing server or model into ones saying how
+// to fix them.
+func (o *OllamaEmbedder) explain(err error) error {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return fmt.Errorf("cannot reach Ollama at %s; is it running (`ollama serve`)? %w", o.cfg.BaseURL, err)
+	}
+	var statusErr *statusError
+	if errors.As(err, &statusErr) && statusErr.code == http.StatusNotFound {
+		return fmt.Errorf("model %q is not available on %s; pull it with `ollama pull %s`: %w", o.cfg.Model, o.cfg.BaseURL, o.cfg.Model, err)
+	}
+	return err
+}
+
+// OllamaProvider implements Provider for Ollama servers.
+type OllamaProvider struct{}
+
+// Name returns the provider identifier.
+func (p *OllamaProvider) Name() string {
+	return "ollama"
+}
+
+// Create instantiates an Ollama embedder. The configuration takes:
+//   - base_url: the server root (default: $OLLAMA_HOST or
+//     http://localhost:11434)
+//   - model (default: nomic-embed-text)
+//   - dimensions: the size reported until the model's is detected
+//   - batch_size and timeout (a duration such as "60s", or seconds)
+func (p *OllamaProvider) Create(config map[string]interface{}) (Embedder, error) {
+	var cfg OllamaConfig
+	var err error
+	if cfg.BaseURL, err = options.String(config, "base_url"); err != nil {
+		return nil, err
+	}
+	if cfg.Model, err = options.String(config, "model"); err != nil {
+		return nil, err
+	}
+	if cfg.Dimensions, err = options.Int(config, "dimensions"); err != nil {
+		return nil, err
+	}
+	if cfg.BatchSize, err = options.Int(config, "batch_size"); err != nil {
+		return nil, err
+	}
+	if cfg.Timeout, err = options.Duration(config, "timeout"); err != nil {
+		return nil, err
+	}
+	if cfg.Dimensions < 0 || cfg.BatchSize < 0 || cfg.Timeout < 0 {
+		return nil, fmt.Errorf("dimensions, batch_size and timeout cannot be negative")
+	}
+
+	return NewOllama(cfg), nil
+}
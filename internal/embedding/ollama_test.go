@@ -0,0 +1,161 @@
+package embedding
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ollamaServer stands in for an Ollama server with the given models pulled,
+// embedding each input as [len(input), 0...].
+type ollamaServer struct {
+	*httptest.Server
+	models map[string]int // Pulled models and their vector sizes
+
+	mu       sync.Mutex
+	requests []ollamaEmbedRequest
+}
+
+func newOllamaServer(t *testing.T, models map[string]int) *ollamaServer {
+	t.Helper()
+	s := &ollamaServer{models: models}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *ollamaServer) handle(w http.ResponseWriter, r *http.Request) {
+	var req ollamaEmbedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	dimensions, ok := s.models[req.Model]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "model \"" + req.Model + "\" not found, try pulling it first"})
+		return
+	}
+
+	switch r.URL.Path {
+	case "/api/show":
+		json.NewEncoder(w).Encode(map[string]interface{}{"details": map[string]string{"family": "nomic-bert"}})
+	case "/api/embed":
+		s.mu.Lock()
+		s.requests = append(s.requests, req)
+		s.mu.Unlock()
+
+		embeddings := make([][]float32, len(req.Input))
+		for i, input := range req.Input {
+			embeddings[i] = make([]float32, dimensions)
+			embeddings[i][0] = float32(len(input))
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"model": req.Model, "embeddings": embeddings})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *ollamaServer) recorded() []ollamaEmbedRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ollamaEmbedRequest(nil), s.requests...)
+}
+
+func TestOllamaEmbedder_EmbedBatch(t *testing.T) {
+	server := newOllamaServer(t, map[string]int{"nomic-embed-text": 6})
+	embedder := NewOllama(OllamaConfig{BaseURL: server.URL + "/", Dimensions: 768, BatchSize: 2})
+
+	// The configured size stands until the model's is known
+	assert.Equal(t, 768, embedder.Dimensions())
+	assert.Equal(t, "ollama/nomic-embed-text", embedder.Model())
+
+	embeddings, err := embedder.EmbedBatch(context.Background(), []string{"a", "bb", "ccc"})
+	require.NoError(t, err)
+	require.Len(t, embeddings, 3)
+	for i, emb := range embeddings {
+		assert.Len(t, emb.Vector, 6)
+		assert.Equal(t, float32(i+1), emb.Vector[0])
+		assert.Equal(t, "ollama/nomic-embed-text", emb.Model)
+	}
+	assert.Equal(t, 6, embedder.Dimensions())
+
+	requests := server.recorded()
+	require.Len(t, requests, 2)
+	assert.Equal(t, []string{"a", "bb"}, requests[0].Input)
+	assert.Equal(t, []string{"ccc"}, requests[1].Input)
+
+	_, err = embedder.Embed(context.Background(), "")
+	assert.ErrorContains(t, err, "empty text")
+}
+
+func TestOllamaEmbedder_CheckModel(t *testing.T) {
+	server := newOllamaServer(t, map[string]int{"mxbai-embed-large": 4})
+
+	embedder := NewOllama(OllamaConfig{BaseURL: server.URL, Model: "mxbai-embed-large"})
+	require.NoError(t, embedder.CheckModel(context.Background()))
+	assert.Equal(t, 4, embedder.Dimensions())
+
+	missing := NewOllama(OllamaConfig{BaseURL: server.URL, Model: "all-minilm"})
+	err := missing.CheckModel(context.Background())
+	assert.ErrorContains(t, err, "ollama pull all-minilm")
+	assert.ErrorContains(t, err, `model "all-minilm" not found, try pulling it first`)
+
+	_, err = missing.Embed(context.Background(), "text")
+	assert.ErrorContains(t, err, "ollama pull all-minilm")
+}
+
+func TestOllamaEmbedder_ServerDown(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	embedder := NewOllama(OllamaConfig{BaseURL: url, Timeout: time.Second})
+	err := embedder.CheckModel(context.Background())
+	assert.ErrorContains(t, err, "cannot reach Ollama at "+url)
+
+	_, err = embedder.Embed(context.Background(), "text")
+	assert.ErrorContains(t, err, "ollama serve")
+}
+
+func TestOllamaEmbedder_InconsistentDimensions(t *testing.T) {
+	embedder := NewOllama(OllamaConfig{})
+	require.NoError(t, embedder.detect(4))
+	assert.ErrorContains(t, embedder.detect(8), "returned 8 dimensions, earlier 4")
+	assert.ErrorContains(t, embedder.detect(0), "empty embedding")
+}
+
+func TestOllamaProvider_Create(t *testing.T) {
+	provider := &OllamaProvider{}
+	assert.Equal(t, "ollama", provider.Name())
+
+	t.Setenv("OLLAMA_HOST", "127.0.0.1:11500")
+	embedder, err := provider.Create(map[string]interface{}{"dimensions": float64(768), "timeout": "2m"})
+	require.NoError(t, err)
+	ollama := embedder.(*OllamaEmbedder)
+	assert.Equal(t, "http://127.0.0.1:11500", ollama.cfg.BaseURL)
+	assert.Equal(t, DefaultOllamaModel, ollama.cfg.Model)
+	assert.Equal(t, 2*time.Minute, ollama.cfg.Timeout)
+	assert.Equal(t, 768, embedder.Dimensions())
+
+	embedder, err = provider.Create(map[string]interface{}{"base_url": "http://gpu-box:11434", "model": "bge-m3"})
+	require.NoError(t, err)
+	assert.Equal(t, "http://gpu-box:11434", embedder.(*OllamaEmbedder).cfg.BaseURL)
+	assert.Equal(t, "ollama/bge-m3", embedder.Model())
+
+	for _, config := range []map[string]interface{}{
+		{"model": 3},
+		{"batch_size": -1},
+		{"timeout": "later"},
+	} {
+		_, err := provider.Create(config)
+		assert.Error(t, err, "%v", config)
+	}
+}
@@ -0,0 +1,374 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ferg-cod3s/conexus/internal/config/options"
+)
+
+// Defaults of the OpenAI embedder.
+const (
+	DefaultOpenAIBaseURL    = "https://api.openai.com/v1"
+	DefaultOpenAIModel      = "text-embedding-3-small"
+	DefaultOpenAIAPIKeyEnv  = "OPENAI_API_KEY"
+	DefaultOpenAIBatchSize  = 64
+	DefaultOpenAIMaxRetries = 3
+	DefaultOpenAITimeout    = 30 * time.Second
+	DefaultOpenAIRetryDelay = 500 * time.Millisecond
+)
+
+// maxRetryDelay caps the wait between retries.
+const maxRetryDelay = 30 * time.Second
+
+// maxErrorBody is the length of a response body quoted in errors.
+const maxErrorBody = 512
+
+// OpenAIConfig configures an OpenAIEmbedder.
+type OpenAIConfig struct {
+	BaseURL        string        // API root; /embeddings is appended (default: DefaultOpenAIBaseURL)
+	APIKey         string        // Sent as a bearer token when set
+	Model          string        // Model name (default: DefaultOpenAIModel)
+	Dimensions     int           // Vector size; responses of another size are rejected
+	SendDimensions bool          // Request Dimensions from the model, for models that can shorten their vectors
+	BatchSize      int           // Texts per request (default: DefaultOpenAIBatchSize)
+	MaxRetries     int           // Retries of a request failing with 429 or 5xx; negative disables them (default: DefaultOpenAIMaxRetries)
+	Timeout        time.Duration // Limit of each request attempt (default: DefaultOpenAITimeout)
+	RetryDelay     time.Duration // Wait before the first retry, doubled on each one (default: DefaultOpenAIRetryDelay)
+}
+
+// OpenAIEmbedder generates embeddings with an OpenAI-compatible
+// /v1/embeddings endpoint, such as OpenAI's own or one served by vLLM,
+// LM Studio, LocalAI or Text Embeddings Inference.
+type OpenAIEmbedder struct {
+	cfg        OpenAIConfig
+	httpClient *http.Client
+}
+
+// NewOpenAI creates a new OpenAI embedder.
+func NewOpenAI(cfg OpenAIConfig) (*OpenAIEmbedder, error) {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultOpenAIBaseURL
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	if cfg.Model == "" {
+		cfg.Model = DefaultOpenAIModel
+	}
+	if cfg.Dimensions <= 0 {
+		return nil, fmt.Errorf("dimensions must be positive, got %d", cfg.Dimensions)
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultOpenAIBatchSize
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = DefaultOpenAIMaxRetries
+	} else if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultOpenAITimeout
+	}
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = DefaultOpenAIRetryDelay
+	}
+
+	return &OpenAIEmbedder{
+		cfg:        cfg,
+		httpClient: &http.Client{},
+	}, nil
+}
+
+// Embed generates an embedding for a single text input.
+func (o *OpenAIEmbedder) Embed(ctx context.Context, text string) (*Embedding, error) {
+	embeddings, err := o.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// EmbedBatch generates embeddings for multiple texts, BatchSize texts per
+// request.
+func (o *OpenAIEmbedder) EmbedBatch(ctx context.Context, texts []string) ([]*Embedding, error) {
+	for i, text := range texts {
+		if text == "" {
+			return nil, fmt.Errorf("failed to embed text at index %d: cannot embed empty text", i)
+		}
+	}
+
+	embeddings := make([]*Embedding, 0, len(texts))
+	for start := 0; start < len(texts); start += o.cfg.BatchSize {
+		batch := texts[start:min(start+o.cfg.BatchSize, len(texts))]
+
+		vectors, err := withRetries(ctx, o.cfg.MaxRetries, o.cfg.RetryDelay, func() ([]Vector, error) {
+			return o.request(ctx, batch)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed texts %d-%d: %w", start, start+len(batch)-1, err)
+		}
+		for i, vector := range vectors {
+			embeddings = append(embeddings, &Embedding{
+				Text:   batch[i],
+				Vector: vector,
+				Model:  o.Model(),
+			})
+		}
+	}
+	return embeddings, nil
+}
+
+// Dimensions returns the vector dimensionality.
+func (o *OpenAIEmbedder) Dimensions() int {
+	return o.cfg.Dimensions
+}
+
+// Model returns the model identifier.
+func (o *OpenAIEmbedder) Model() string {
+	return "openai/" + o.cfg.Model
+}
+
+// openAIRequest is the body posted to /embeddings.
+type openAIRequest struct {
+	Model          string   `json:"model"`
+	Input          []string `json:"input"`
+	EncodingFormat string   `json:"encoding_format"`
+	Dimensions     int      `json:"dimensions,omitempty"`
+}
+
+// openAIResponse is the body of a successful response.
+type openAIResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// request embeds one batch of inputs.
+func (o *OpenAIEmbedder) request(ctx context.Context, inputs []string) ([]Vector, error) {
+	body := openAIRequest{
+		Model:          o.cfg.Model,
+		Input:          inputs,
+		EncodingFormat: "float",
+	}
+	if o.cfg.SendDimensions {
+		body.Dimensions = o.cfg.Dimensions
+	}
+
+	var resp openAIResponse
+	if err := postJSON(ctx, o.httpClient, o.cfg.Timeout, o.cfg.BaseURL+"/embeddings", o.cfg.APIKey, body, &resp); err != nil {
+		return nil, err
+	}
+
+	if len(resp.Data) != len(inputs) {
+		return nil, fmt.Errorf("response has %d embeddings for %d inputs", len(resp.Data), len(inputs))
+	}
+	sort.Slice(resp.Data, func(i, j int) bool {
+		return resp.Data[i].Index < resp.Data[j].Index
+	})
+	vectors := make([]Vector, len(inputs))
+	for i, d := range resp.Data {
+		if d.Index != i {
+			return nil, fmt.Errorf("response is missing the embedding of input %d", i)
+		}
+		if len(d.Embedding) != o.cfg.Dimensions {
+			return nil, fmt.Errorf("model %s returned %d dimensions, configured for %d", o.cfg.Model, len(d.Embedding), o.cfg.Dimensions)
+		}
+		vectors[i] = d.Embedding
+	}
+	return vectors, nil
+}
+
+// statusError is a non-2xx response of an embedding endpoint.
+type statusError struct {
+	status     string
+	code       int
+	message    string
+	retryAfter time.Duration
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("endpoint returned %s: %s", e.status, e.message)
+}
+
+// retryable reports whether the request may succeed when repeated.
+func (e *statusError) retryable() bool {
+	return e.code == http.StatusTooManyRequests || e.code >= 500
+}
+
+// postJSON posts body to url within timeout and decodes the response into
+// out. Non-2xx responses return a *statusError.
+func postJSON(ctx context.Context, client *http.Client, timeout time.Duration, url, apiKey string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshal request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &statusError{
+			status:     resp.Status,
+			code:       resp.StatusCode,
+			message:    errorMessage(data),
+			retryAfter: retryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// errorMessage extracts the message of an error response, which APIs return
+// as {"error": {"message": ...}}, {"error": ...} or plain text.
+func errorMessage(data []byte) string {
+	var body struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(data, &body) == nil && body.Error != nil {
+		var nested struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body.Error, &nested) == nil && nested.Message != "" {
+			return nested.Message
+		}
+		var message string
+		if json.Unmarshal(body.Error, &message) == nil && message != "" {
+			return message
+		}
+	}
+
+	message := strings.TrimSpace(string(data))
+	if len(message) > maxErrorBody {
+		message = message[:maxErrorBody] + "..."
+	}
+	return message
+}
+
+// retryAfter parses a Retry-After header given in seconds.
+func retryAfter(header string) time.Duration {
+	seconds, err := strconv.Atoi(header)
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// withRetries calls fn until it succeeds, fails permanently or has been
+// retried maxRetries times. Rate limits, server errors and failed or timed
+// out attempts are retried with exponential backoff starting at delay, or
+// after the server's Retry-After when longer.
+func withRetries[T any](ctx context.Context, maxRetries int, delay time.Duration, fn func() (T, error)) (T, error) {
+	for attempt := 0; ; attempt++ {
+		result, err := fn()
+		if err == nil || attempt >= maxRetries || ctx.Err() != nil {
+			return result, err
+		}
+
+		wait := delay << attempt
+		var statusErr *statusError
+		if errors.As(err, &statusErr) {
+			if !statusErr.retryable() {
+				return result, err
+			}
+			wait = max(wait, statusErr.retryAfter)
+		}
+		wait = min(wait, maxRetryDelay)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			var zero T
+			return zero, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// OpenAIProvider implements Provider for OpenAI-compatible embedding APIs.
+type OpenAIProvider struct{}
+
+// Name returns the provider identifier.
+func (p *OpenAIProvider) Name() string {
+	return "openai"
+}
+
+// Create instantiates an OpenAI embedder. The configuration takes:
+//   - base_url: the API root (default: https://api.openai.com/v1)
+//   - model and dimensions
+//   - send_dimensions: whether to request the dimensions from the model
+//     (default: true on api.openai.com, false elsewhere)
+//   - api_key, api_key_file naming a file holding it, or api_key_env naming
+//     the variable holding it (default: OPENAI_API_KEY)
+//   - batch_size, max_retries, timeout and retry_delay (durations such as
+//     "30s", or seconds)
+//
+// An API key is required for api.openai.com; other servers may not need one.
+func (p *OpenAIProvider) Create(config map[string]interface{}) (Embedder, error) {
+	var cfg OpenAIConfig
+	var err error
+	if cfg.BaseURL, err = options.String(config, "base_url"); err != nil {
+		return nil, err
+	}
+	if cfg.Model, err = options.String(config, "model"); err != nil {
+		return nil, err
+	}
+	if cfg.Dimensions, err = options.Int(config, "dimensions"); err != nil {
+		return nil, err
+	}
+	if cfg.BatchSize, err = options.Int(config, "batch_size"); err != nil {
+		return nil, err
+	}
+	if cfg.MaxRetries, err = options.Int(config, "max_retries"); err != nil {
+		return nil, err
+	}
+	if cfg.Timeout, err = options.Duration(config, "timeout"); err != nil {
+		return nil, err
+	}
+	if cfg.RetryDelay, err = options.Duration(config, "retry_delay"); err != nil {
+		return nil, err
+	}
+	if cfg.APIKey, err = options.APIKey(config, DefaultOpenAIAPIKeyEnv); err != nil {
+		return nil, err
+	}
+
+	hosted := cfg.BaseURL == "" || strings.HasPrefix(cfg.BaseURL, DefaultOpenAIBaseURL)
+	if cfg.SendDimensions, err = options.Bool(config, "send_dimensions", hosted); err != nil {
+		return nil, err
+	}
+	if hosted && cfg.APIKey == "" {
+		return nil, fmt.Errorf("api key is required for the openai provider: set api_key, api_key_file or %s", DefaultOpenAIAPIKeyEnv)
+	}
+
+	return NewOpenAI(cfg)
+}
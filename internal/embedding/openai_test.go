@@ -0,0 +1,287 @@
+package embedding
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openAIServer stands in for an /v1/embeddings endpoint. Each input is
+// embedded as [len(input), index, 0...]; the data is returned in reverse
+// order to check that embeddings are matched by index. fail, if set, may
+// answer a request itself.
+type openAIServer struct {
+	*httptest.Server
+	dimensions int
+
+	mu       sync.Mutex
+	requests []openAIRequest
+	headers  []http.Header
+	fail     func(w http.ResponseWriter, attempt int) bool
+}
+
+func newOpenAIServer(t *testing.T, dimensions int) *openAIServer {
+	t.Helper()
+	s := &openAIServer{dimensions: dimensions}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *openAIServer) handle(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/embeddings" {
+		http.NotFound(w, r)
+		return
+	}
+	var req openAIRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	s.headers = append(s.headers, r.Header.Clone())
+	attempt := len(s.requests)
+	fail := s.fail
+	s.mu.Unlock()
+	if fail != nil && fail(w, attempt) {
+		return
+	}
+
+	type datum struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	}
+	var data []datum
+	for i := len(req.Input) - 1; i >= 0; i-- {
+		vector := make([]float32, s.dimensions)
+		vector[0] = float32(len(req.Input[i]))
+		vector[1] = float32(i)
+		data = append(data, datum{Index: i, Embedding: vector})
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"object": "list", "data": data})
+}
+
+func (s *openAIServer) recorded() ([]openAIRequest, []http.Header) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]openAIRequest(nil), s.requests...), append([]http.Header(nil), s.headers...)
+}
+
+func newTestOpenAI(t *testing.T, server *openAIServer, cfg OpenAIConfig) *OpenAIEmbedder {
+	t.Helper()
+	cfg.BaseURL = server.URL + "/v1/"
+	if cfg.Dimensions == 0 {
+		cfg.Dimensions = server.dimensions
+	}
+	if cfg.RetryDelay == 0 {
+		cfg.RetryDelay = time.Millisecond
+	}
+	embedder, err := NewOpenAI(cfg)
+	require.NoError(t, err)
+	return embedder
+}
+
+func TestOpenAIEmbedder_Embed(t *testing.T) {
+	server := newOpenAIServer(t, 4)
+	embedder := newTestOpenAI(t, server, OpenAIConfig{APIKey: "sk-test", Model: "text-embedding-3-small"})
+
+	emb, err := embedder.Embed(context.Background(), "hello")
+	require.NoError(t, err)
+
+	assert.Equal(t, Vector{5, 0, 0, 0}, emb.Vector)
+	assert.Equal(t, "hello", emb.Text)
+	assert.Equal(t, "openai/text-embedding-3-small", emb.Model)
+	assert.Equal(t, 4, embedder.Dimensions())
+
+	requests, headers := server.recorded()
+	require.Len(t, requests, 1)
+	assert.Equal(t, "text-embedding-3-small", requests[0].Model)
+	assert.Equal(t, "float", requests[0].EncodingFormat)
+	assert.Zero(t, requests[0].Dimensions)
+	assert.Equal(t, "Bearer sk-test", headers[0].Get("Authorization"))
+
+	_, err = embedder.Embed(context.Background(), "")
+	assert.ErrorContains(t, err, "empty text")
+}
+
+func TestOpenAIEmbedder_EmbedBatch(t *testing.T) {
+	server := newOpenAIServer(t, 3)
+	embedder := newTestOpenAI(t, server, OpenAIConfig{BatchSize: 2, SendDimensions: true})
+
+	embeddings, err := embedder.EmbedBatch(context.Background(), []string{"a", "bb", "ccc", "dddd", "eeeee"})
+	require.NoError(t, err)
+	require.Len(t, embeddings, 5)
+
+	// Embeddings follow the inputs, whatever order the response lists them in
+	for i, emb := range embeddings {
+		assert.Equal(t, float32(i+1), emb.Vector[0])
+		assert.Equal(t, float32(i%2), emb.Vector[1])
+	}
+
+	requests, headers := server.recorded()
+	require.Len(t, requests, 3)
+	assert.Equal(t, []string{"a", "bb"}, requests[0].Input)
+	assert.Equal(t, []string{"eeeee"}, requests[2].Input)
+	assert.Equal(t, 3, requests[0].Dimensions)
+	assert.Empty(t, headers[0].Get("Authorization"))
+
+	empty, err := embedder.EmbedBatch(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+}
+
+func TestOpenAIEmbedder_Retries(t *testing.T) {
+	server := newOpenAIServer(t, 2)
+	server.fail = func(w http.ResponseWriter, attempt int) bool {
+		switch attempt {
+		case 1:
+			http.Error(w, `{"error": {"message": "Rate limit reached"}}`, http.StatusTooManyRequests)
+		case 2:
+			http.Error(w, "upstream unavailable", http.StatusBadGateway)
+		default:
+			return false
+		}
+		return true
+	}
+	embedder := newTestOpenAI(t, server, OpenAIConfig{})
+
+	_, err := embedder.Embed(context.Background(), "retry me")
+	require.NoError(t, err)
+	requests, _ := server.recorded()
+	assert.Len(t, requests, 3)
+}
+
+func TestOpenAIEmbedder_Errors(t *testing.T) {
+	t.Run("retries are bounded", func(t *testing.T) {
+		server := newOpenAIServer(t, 2)
+		server.fail = func(w http.ResponseWriter, attempt int) bool {
+			http.Error(w, `{"error": {"message": "Rate limit reached"}}`, http.StatusTooManyRequests)
+			return true
+		}
+		embedder := newTestOpenAI(t, server, OpenAIConfig{MaxRetries: 2})
+
+		_, err := embedder.Embed(context.Background(), "text")
+		assert.ErrorContains(t, err, "429 Too Many Requests: Rate limit reached")
+		requests, _ := server.recorded()
+		assert.Len(t, requests, 3)
+	})
+
+	t.Run("client errors are not retried", func(t *testing.T) {
+		server := newOpenAIServer(t, 2)
+		server.fail = func(w http.ResponseWriter, attempt int) bool {
+			http.Error(w, `{"error": {"message": "Incorrect API key provided"}}`, http.StatusUnauthorized)
+			return true
+		}
+		embedder := newTestOpenAI(t, server, OpenAIConfig{})
+
+		_, err := embedder.Embed(context.Background(), "text")
+		assert.ErrorContains(t, err, "Incorrect API key provided")
+		requests, _ := server.recorded()
+		assert.Len(t, requests, 1)
+	})
+
+	t.Run("timed out attempts are retried", func(t *testing.T) {
+		server := newOpenAIServer(t, 2)
+		server.fail = func(w http.ResponseWriter, attempt int) bool {
+			if attempt == 1 {
+				time.Sleep(100 * time.Millisecond)
+			}
+			return false
+		}
+		embedder := newTestOpenAI(t, server, OpenAIConfig{Timeout: 20 * time.Millisecond})
+
+		_, err := embedder.Embed(context.Background(), "text")
+		require.NoError(t, err)
+	})
+
+	t.Run("dimension mismatch", func(t *testing.T) {
+		server := newOpenAIServer(t, 2)
+		embedder := newTestOpenAI(t, server, OpenAIConfig{Dimensions: 3})
+
+		_, err := embedder.Embed(context.Background(), "text")
+		assert.ErrorContains(t, err, "returned 2 dimensions, configured for 3")
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		server := newOpenAIServer(t, 2)
+		embedder := newTestOpenAI(t, server, OpenAIConfig{})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := embedder.Embed(ctx, "text")
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestOpenAIProvider_Create(t *testing.T) {
+	server := newOpenAIServer(t, 8)
+	provider := &OpenAIProvider{}
+	assert.Equal(t, "openai", provider.Name())
+
+	t.Run("compatible server", func(t *testing.T) {
+		keyFile := filepath.Join(t.TempDir(), "key")
+		require.NoError(t, os.WriteFile(keyFile, []byte("sk-file\n"), 0o600))
+
+		embedder, err := provider.Create(map[string]interface{}{
+			"base_url":     server.URL + "/v1",
+			"model":        "nomic-embed-text",
+			"dimensions":   8,
+			"api_key_file": keyFile,
+			"batch_size":   float64(16),
+			"timeout":      "5s",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "openai/nomic-embed-text", embedder.Model())
+
+		_, err = embedder.Embed(context.Background(), "text")
+		require.NoError(t, err)
+		requests, headers := server.recorded()
+		// Only api.openai.com is sent the dimensions by default
+		assert.Zero(t, requests[len(requests)-1].Dimensions)
+		assert.Equal(t, "Bearer sk-file", headers[len(headers)-1].Get("Authorization"))
+	})
+
+	t.Run("hosted api requires a key", func(t *testing.T) {
+		t.Setenv(DefaultOpenAIAPIKeyEnv, "")
+		_, err := provider.Create(map[string]interface{}{"dimensions": 1536})
+		assert.ErrorContains(t, err, "api key is required")
+
+		t.Setenv("MY_OPENAI_KEY", "sk-env")
+		embedder, err := provider.Create(map[string]interface{}{"dimensions": 1536, "api_key_env": "MY_OPENAI_KEY"})
+		require.NoError(t, err)
+		assert.Equal(t, "openai/text-embedding-3-small", embedder.Model())
+		assert.True(t, embedder.(*OpenAIEmbedder).cfg.SendDimensions)
+		assert.Equal(t, "sk-env", embedder.(*OpenAIEmbedder).cfg.APIKey)
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		base := server.URL + "/v1"
+		for _, config := range []map[string]interface{}{
+			{"base_url": base},
+			{"base_url": base, "dimensions": 8, "timeout": "soon"},
+			{"base_url": base, "dimensions": 8, "batch_size": "many"},
+			{"base_url": base, "dimensions": 8, "send_dimensions": "yes"},
+			{"base_url": base, "dimensions": 8, "api_key_file": "/nonexistent/key"},
+		} {
+			_, err := provider.Create(config)
+			assert.Error(t, err, "%v", config)
+		}
+	})
+}
+
+func TestRetryAfter(t *testing.T) {
+	assert.Equal(t, 2*time.Second, retryAfter("2"))
+	assert.Zero(t, retryAfter(""))
+	assert.Zero(t, retryAfter("Wed, 21 Oct 2015 07:28:00 GMT"))
+}
@@ -113,10 +113,18 @@ func (r *Registry) Clear() {
 }
 
 func init() {
-	// Register the mock provider (MVP - only provider available)
 	if err := Register(&MockProvider{}); err != nil {
 		panic(fmt.Sprintf("failed to register mock provider: %v", err))
 	}
+	if err := Register(&OpenAIProvider{}); err != nil {
+		panic(fmt.Sprintf("failed to register openai provider: %v", err))
+	}
+	if err := Register(&OllamaProvider{}); err != nil {
+		panic(fmt.Sprintf("failed to register ollama provider: %v", err))
+	}
+	if err := Register(&StaticProvider{}); err != nil {
+		panic(fmt.Sprintf("failed to register static provider: %v", err))
+	}
 
 	// Anthropic provider moved to post-MVP (Phase 6)
 	// TODO: Re-enable when Anthropic releases embedding API
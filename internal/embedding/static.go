@@ -0,0 +1,370 @@
+package embedding
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ferg-cod3s/conexus/internal/config/options"
+)
+
+// DefaultSIFA is the smoothing parameter of SIF weighting: tokens much more
+// frequent than it count for little.
+const DefaultSIFA = 1e-3
+
+// eulerGamma approximates harmonic numbers, H(n) ≈ ln n + γ.
+const eulerGamma = 0.5772156649
+
+// StaticConfig configures a StaticEmbedder.
+type StaticConfig struct {
+	ModelPath       string  // Token embedding table: GloVe or word2vec text format, optionally gzipped (required)
+	Dimensions      int     // Expected vector size; 0 accepts the table's
+	MaxTokens       int     // Rows of the table loaded, most frequent first; 0 loads all
+	SIF             bool    // Weight tokens by a / (a + p(token)) instead of equally
+	SIFA            float64 // a of SIF weighting (default: DefaultSIFA)
+	FrequenciesPath string  // "token count" lines giving p(token); without it p follows Zipf's law over the table's order
+}
+
+// StaticEmbedder embeds text by mean-pooling the vectors of its tokens from a
+// static token-embedding table, such as GloVe or a distilled model2vec
+// model. It needs no network or GPU, and unlike the mock embedder similar
+// texts get similar vectors.
+type StaticEmbedder struct {
+	model      string
+	dimensions int
+	vectors    map[string]Vector
+	weights    map[string]float32 // SIF weight per token; nil weighs all tokens equally
+}
+
+// NewStatic loads the table at cfg.ModelPath.
+func NewStatic(cfg StaticConfig) (*StaticEmbedder, error) {
+	if cfg.ModelPath == "" {
+		return nil, fmt.Errorf("model_path is required for the static provider")
+	}
+	if cfg.SIFA <= 0 {
+		cfg.SIFA = DefaultSIFA
+	}
+
+	vectors, order, dimensions, err := loadStaticTable(cfg.ModelPath, cfg.MaxTokens)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Dimensions > 0 && cfg.Dimensions != dimensions {
+		return nil, fmt.Errorf("%s has %d dimensions, configured for %d", cfg.ModelPath, dimensions, cfg.Dimensions)
+	}
+
+	s := &StaticEmbedder{
+		model:      tableName(cfg.ModelPath),
+		dimensions: dimensions,
+		vectors:    vectors,
+	}
+	if cfg.SIF {
+		probabilities, err := tokenProbabilities(cfg.FrequenciesPath, order)
+		if err != nil {
+			return nil, err
+		}
+		s.weights = make(map[string]float32, len(probabilities))
+		for token, p := range probabilities {
+			s.weights[token] = float32(cfg.SIFA / (cfg.SIFA + p))
+		}
+	}
+	return s, nil
+}
+
+// Embed generates an embedding for a single text input: the weighted mean of
+// its token vectors, normalized to unit length. Text without a known token
+// embeds as the zero vector.
+func (s *StaticEmbedder) Embed(ctx context.Context, text string) (*Embedding, error) {
+	if text == "" {
+		return nil, fmt.Errorf("cannot embed empty text")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	vector := make(Vector, s.dimensions)
+	for _, token := range codeTokens(text) {
+		tokenVector, ok := s.vectors[token]
+		if !ok {
+			continue
+		}
+		weight := float32(1)
+		if s.weights != nil {
+			if w, ok := s.weights[token]; ok {
+				weight = w
+			}
+		}
+		for i, v := range tokenVector {
+			vector[i] += weight * v
+		}
+	}
+
+	// Normalizing makes dividing by the token count unnecessary
+	return &Embedding{
+		Text:   text,
+		Vector: normalize(vector),
+		Model:  s.Model(),
+	}, nil
+}
+
+// EmbedBatch generates embeddings for multiple texts.
+func (s *StaticEmbedder) EmbedBatch(ctx context.Context, texts []string) ([]*Embedding, error) {
+	embeddings := make([]*Embedding, len(texts))
+	for i, text := range texts {
+		emb, err := s.Embed(ctx, text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed text at index %d: %w", i, err)
+		}
+		embeddings[i] = emb
+	}
+	return embeddings, nil
+}
+
+// Dimensions returns the vector dimensionality.
+func (s *StaticEmbedder) Dimensions() int {
+	return s.dimensions
+}
+
+// Model returns the model identifier, taken from the table's file name.
+func (s *StaticEmbedder) Model() string {
+	return "static/" + s.model
+}
+
+// tableName returns the file name of a table without its extensions.
+func tableName(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), ".gz")
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// codeTokens splits text into lowercase tokens, breaking identifiers at
+// underscores and case changes, so "parseHTTPRequest" gives "parse", "http"
+// and "request". A compound identifier also yields itself, in case the table
+// knows it whole.
+func codeTokens(text string) []string {
+	var tokens []string
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		runes := []rune(word)
+		start := 0
+		parts := 0
+		for i := 1; i < len(runes); i++ {
+			prev, r := runes[i-1], runes[i]
+			if unicode.IsUpper(r) && (unicode.IsLower(prev) || unicode.IsDigit(prev) ||
+				unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				tokens = append(tokens, strings.ToLower(string(runes[start:i])))
+				start = i
+				parts++
+			}
+		}
+		tokens = append(tokens, strings.ToLower(string(runes[start:])))
+		if parts > 0 {
+			tokens = append(tokens, strings.ToLower(word))
+		}
+	}
+	return tokens
+}
+
+// loadStaticTable reads a table of "token v1 ... vn" lines, skipping a
+// word2vec "count dimensions" header. Tokens are lowercased; of tokens
+// differing only in case the first, most frequent one is kept. It returns the
+// vectors, the tokens in file order and the vector size.
+func loadStaticTable(path string, maxTokens int) (map[string]Vector, []string, int, error) {
+	r, closeFile, err := openTable(path)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	defer closeFile()
+
+	vectors := make(map[string]Vector)
+	var order []string
+	dimensions := 0
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if line == 1 && len(fields) == 2 && isInteger(fields[0]) && isInteger(fields[1]) {
+			continue
+		}
+		if dimensions == 0 {
+			dimensions = len(fields) - 1
+			if dimensions < 1 {
+				return nil, nil, 0, fmt.Errorf("%s:%d: no vector after token %q", path, line, fields[0])
+			}
+		}
+		if len(fields) <= dimensions {
+			return nil, nil, 0, fmt.Errorf("%s:%d: %d values, expected %d", path, line, len(fields)-1, dimensions)
+		}
+
+		// Some tables have tokens containing spaces; the vector is the last fields
+		split := len(fields) - dimensions
+		token := strings.ToLower(strings.Join(fields[:split], " "))
+		vector := make(Vector, dimensions)
+		for i, field := range fields[split:] {
+			v, err := strconv.ParseFloat(field, 32)
+			if err != nil {
+				return nil, nil, 0, fmt.Errorf("%s:%d: %w", path, line, err)
+			}
+			vector[i] = float32(v)
+		}
+
+		if _, seen := vectors[token]; !seen {
+			vectors[token] = vector
+			order = append(order, token)
+			if maxTokens > 0 && len(order) >= maxTokens {
+				break
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, 0, fmt.Errorf("read %s: %w", path, err)
+	}
+	if len(order) == 0 {
+		return nil, nil, 0, fmt.Errorf("%s has no token vectors", path)
+	}
+	return vectors, order, dimensions, nil
+}
+
+// openTable opens path, decompressing it when it ends in .gz.
+func openTable(path string) (io.Reader, func(), error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open embedding table: %w", err)
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return file, func() { file.Close() }, nil
+	}
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("open embedding table: %w", err)
+	}
+	return gz, func() {
+		gz.Close()
+		file.Close()
+	}, nil
+}
+
+// tokenProbabilities estimates p(token) for the tokens of a table, in file
+// order. With a frequencies file of "token count" lines p is the token's
+// share of the counts, and unlisted tokens get the smallest share seen.
+// Without one, the table is taken to be ordered by frequency and p follows
+// Zipf's law, p(rank) = 1 / (rank * H(n)).
+func tokenProbabilities(frequenciesPath string, order []string) (map[string]float64, error) {
+	probabilities := make(map[string]float64, len(order))
+
+	if frequenciesPath == "" {
+		harmonic := math.Log(float64(len(order))) + eulerGamma
+		for i, token := range order {
+			probabilities[token] = 1 / (float64(i+1) * harmonic)
+		}
+		return probabilities, nil
+	}
+
+	r, closeFile, err := openTable(frequenciesPath)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFile()
+
+	counts := make(map[string]float64)
+	var total float64
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected \"token count\"", frequenciesPath, line)
+		}
+		count, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil || count < 0 {
+			return nil, fmt.Errorf("%s:%d: invalid count %q", frequenciesPath, line, fields[1])
+		}
+		counts[strings.ToLower(fields[0])] += count
+		total += count
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", frequenciesPath, err)
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("%s has no token counts", frequenciesPath)
+	}
+
+	rarest := math.Inf(1)
+	for _, count := range counts {
+		if count > 0 {
+			rarest = min(rarest, count/total)
+		}
+	}
+	for _, token := range order {
+		if count := counts[token]; count > 0 {
+			probabilities[token] = count / total
+		} else {
+			probabilities[token] = rarest
+		}
+	}
+	return probabilities, nil
+}
+
+// isInteger reports whether s is a decimal integer.
+func isInteger(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+// StaticProvider implements Provider for static token-embedding tables.
+type StaticProvider struct{}
+
+// Name returns the provider identifier.
+func (p *StaticProvider) Name() string {
+	return "static"
+}
+
+// Create loads a static embedder. The configuration takes:
+//   - model_path: the token-embedding table (required)
+//   - dimensions: the expected vector size
+//   - max_tokens: rows loaded, most frequent first
+//   - sif: weight tokens by frequency (default false), with sif_a and
+//     frequencies_path
+func (p *StaticProvider) Create(config map[string]interface{}) (Embedder, error) {
+	var cfg StaticConfig
+	var err error
+	if cfg.ModelPath, err = options.String(config, "model_path"); err != nil {
+		return nil, err
+	}
+	if cfg.Dimensions, err = options.Int(config, "dimensions"); err != nil {
+		return nil, err
+	}
+	if cfg.MaxTokens, err = options.Int(config, "max_tokens"); err != nil {
+		return nil, err
+	}
+	if cfg.SIF, err = options.Bool(config, "sif", false); err != nil {
+		return nil, err
+	}
+	if cfg.SIFA, err = options.Float(config, "sif_a"); err != nil {
+		return nil, err
+	}
+	if cfg.FrequenciesPath, err = options.String(config, "frequencies_path"); err != nil {
+		return nil, err
+	}
+	if cfg.MaxTokens < 0 || cfg.SIFA < 0 {
+		return nil, fmt.Errorf("max_tokens and sif_a cannot be negative")
+	}
+
+	return NewStatic(cfg)
+}
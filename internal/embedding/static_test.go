@@ -0,0 +1,192 @@
+package embedding
+
+import (
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticTable is a word2vec-style table, ordered by frequency like real ones.
+const staticTable = `7 3
+the 0 0 1
+Auth 1 0 0
+auth 5 5 5
+token 0.9 0.1 0
+session 0.8 0.2 0
+parse 0 1 0
+query 0 0.9 0.1
+`
+
+func writeTable(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func newTestStatic(t *testing.T, cfg StaticConfig) *StaticEmbedder {
+	t.Helper()
+	if cfg.ModelPath == "" {
+		cfg.ModelPath = writeTable(t, "code-vectors.txt", staticTable)
+	}
+	embedder, err := NewStatic(cfg)
+	require.NoError(t, err)
+	return embedder
+}
+
+func embedVector(t *testing.T, embedder Embedder, text string) Vector {
+	t.Helper()
+	emb, err := embedder.Embed(context.Background(), text)
+	require.NoError(t, err)
+	return emb.Vector
+}
+
+func dot(a, b Vector) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+func TestStaticEmbedder_Embed(t *testing.T) {
+	embedder := newTestStatic(t, StaticConfig{Dimensions: 3})
+	assert.Equal(t, 3, embedder.Dimensions())
+	assert.Equal(t, "static/code-vectors", embedder.Model())
+
+	authToken := embedVector(t, embedder, "func validateAuthToken()")
+	session := embedVector(t, embedder, "session token")
+	parse := embedVector(t, embedder, "parse_query")
+
+	// Related code lands close, unrelated code far
+	assert.Greater(t, dot(authToken, session), float32(0.95))
+	assert.Less(t, dot(authToken, parse), float32(0.2))
+	assert.InDelta(t, 1.0, dot(parse, parse), 1e-5)
+
+	// The first of tokens differing in case wins
+	assert.Equal(t, Vector{1, 0, 0}, embedVector(t, embedder, "AUTH"))
+
+	assert.Equal(t, Vector{0, 0, 0}, embedVector(t, embedder, "unknown words only"))
+	_, err := embedder.Embed(context.Background(), "")
+	assert.Error(t, err)
+
+	embeddings, err := embedder.EmbedBatch(context.Background(), []string{"auth", "query"})
+	require.NoError(t, err)
+	require.Len(t, embeddings, 2)
+	assert.Equal(t, "static/code-vectors", embeddings[1].Model)
+}
+
+func TestStaticEmbedder_SIF(t *testing.T) {
+	plain := newTestStatic(t, StaticConfig{})
+	sif := newTestStatic(t, StaticConfig{SIF: true, SIFA: 0.01})
+
+	// Without weighting the frequent "the" pulls the vector as much as "auth"
+	assert.InDelta(t, 0.707, embedVector(t, plain, "the auth")[2], 1e-3)
+	// With it the most frequent token counts for less
+	assert.Less(t, embedVector(t, sif, "the auth")[2], float32(0.5))
+
+	// Counts from a frequencies file override the table's order
+	frequencies := writeTable(t, "counts.txt", "the 10\nauth 1000\n")
+	counted := newTestStatic(t, StaticConfig{SIF: true, SIFA: 0.01, FrequenciesPath: frequencies})
+	assert.Greater(t, embedVector(t, counted, "the auth")[2], float32(0.9))
+}
+
+func TestStaticEmbedder_TableFormats(t *testing.T) {
+	t.Run("glove without header", func(t *testing.T) {
+		path := writeTable(t, "glove.6B.2d.txt", "parse 1 0\n\nquery 0 1\n")
+		embedder := newTestStatic(t, StaticConfig{ModelPath: path})
+		assert.Equal(t, 2, embedder.Dimensions())
+		assert.Equal(t, "static/glove.6B.2d", embedder.Model())
+	})
+
+	t.Run("gzipped", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "vectors.txt.gz")
+		file, err := os.Create(path)
+		require.NoError(t, err)
+		gz := gzip.NewWriter(file)
+		_, err = gz.Write([]byte(staticTable))
+		require.NoError(t, err)
+		require.NoError(t, gz.Close())
+		require.NoError(t, file.Close())
+
+		embedder := newTestStatic(t, StaticConfig{ModelPath: path})
+		assert.Equal(t, "static/vectors", embedder.Model())
+		assert.Equal(t, Vector{0, 1, 0}, embedVector(t, embedder, "parse"))
+	})
+
+	t.Run("tokens with spaces", func(t *testing.T) {
+		path := writeTable(t, "vectors.txt", "a 1 0\n. . . 0 1\n")
+		embedder := newTestStatic(t, StaticConfig{ModelPath: path})
+		assert.Contains(t, embedder.vectors, ". . .")
+	})
+
+	t.Run("max tokens", func(t *testing.T) {
+		embedder := newTestStatic(t, StaticConfig{MaxTokens: 2})
+		assert.Len(t, embedder.vectors, 2)
+		assert.Equal(t, Vector{0, 0, 0}, embedVector(t, embedder, "parse"))
+	})
+
+	t.Run("invalid tables", func(t *testing.T) {
+		for name, content := range map[string]string{
+			"empty":          "",
+			"no vector":      "token\n",
+			"short row":      "a 1 2 3\nb 1 2\n",
+			"invalid number": "a 1 x\n",
+			"header only":    "10 300\n",
+		} {
+			_, err := NewStatic(StaticConfig{ModelPath: writeTable(t, "table.txt", content)})
+			assert.Error(t, err, name)
+		}
+
+		_, err := NewStatic(StaticConfig{ModelPath: filepath.Join(t.TempDir(), "missing.txt")})
+		assert.Error(t, err)
+		_, err = NewStatic(StaticConfig{})
+		assert.ErrorContains(t, err, "model_path is required")
+	})
+
+	t.Run("dimension mismatch", func(t *testing.T) {
+		_, err := NewStatic(StaticConfig{ModelPath: writeTable(t, "t.txt", staticTable), Dimensions: 300})
+		assert.ErrorContains(t, err, "has 3 dimensions, configured for 300")
+	})
+}
+
+func TestCodeTokens(t *testing.T) {
+	assert.Equal(t,
+		[]string{"parse", "http", "request", "parsehttprequest", "snake", "case", "v2", "client", "v2client"},
+		codeTokens("parseHTTPRequest(snake_case) v2Client"))
+	assert.Empty(t, codeTokens("  {}; "))
+}
+
+func TestStaticProvider_Create(t *testing.T) {
+	provider, err := Get("static")
+	require.NoError(t, err)
+
+	path := writeTable(t, "vectors.txt", staticTable)
+	embedder, err := provider.Create(map[string]interface{}{
+		"model_path": path,
+		"dimensions": float64(3),
+		"sif":        true,
+		"sif_a":      0.01,
+		"max_tokens": 5,
+	})
+	require.NoError(t, err)
+	static := embedder.(*StaticEmbedder)
+	assert.Len(t, static.vectors, 5)
+	assert.NotNil(t, static.weights)
+
+	for _, config := range []map[string]interface{}{
+		{},
+		{"model_path": path, "sif": "yes"},
+		{"model_path": path, "sif_a": "small"},
+		{"model_path": path, "max_tokens": -1},
+		{"model_path": path, "sif": true, "frequencies_path": "/nonexistent/counts.txt"},
+	} {
+		_, err := provider.Create(config)
+		assert.Error(t, err, "%v", config)
+	}
+}
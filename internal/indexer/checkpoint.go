@@ -0,0 +1,359 @@
+package indexer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ferg-cod3s/conexus/internal/security"
+	"github.com/ferg-cod3s/conexus/internal/validation"
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+)
+
+// checkpointBatchSize is the number of files embedded and committed per checkpoint.
+const checkpointBatchSize = 50
+
+// indexFile is a file selected for a checkpointed run.
+type indexFile struct {
+	path    string
+	relPath string
+	info    os.FileInfo
+}
+
+// SetVectorStore configures the store that HealthCheck compares against the
+// persisted Merkle state.
+func (idx *DefaultIndexer) SetVectorStore(store vectorstore.VectorStore) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.store = store
+}
+
+// vectorStore returns the store configured for health checks.
+func (idx *DefaultIndexer) vectorStore() vectorstore.VectorStore {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.store
+}
+
+// IndexWithCheckpoints performs a full index that commits documents in batches,
+// each together with a checkpoint of the completed files. If the store holds a
+// checkpoint for the same root, the run resumes from it and skips files whose
+// content has not changed since they were committed. The Merkle state is saved
+// and the checkpoint cleared only once every file has been committed.
+//
+// Stores that do not implement vectorstore.CheckpointStore fall back to Index.
+func (idx *DefaultIndexer) IndexWithCheckpoints(ctx context.Context, opts IndexOptions) (*IndexStats, error) {
+	stats := &IndexStats{StartTime: time.Now()}
+
+	store, ok := opts.VectorStore.(vectorstore.CheckpointStore)
+	if !ok || opts.Embedder == nil {
+		chunks, err := idx.Index(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		stats.TotalChunks = len(chunks)
+		stats.EndTime = time.Now()
+		return stats, nil
+	}
+	idx.SetVectorStore(opts.VectorStore)
+
+	cp, err := store.LoadCheckpoint(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load checkpoint: %w", err)
+	}
+	if cp == nil || cp.RootPath != opts.RootPath {
+		cp = &vectorstore.IndexCheckpoint{
+			RunID:     fmt.Sprintf("run-%d", time.Now().UnixNano()),
+			RootPath:  opts.RootPath,
+			StartedAt: stats.StartTime,
+		}
+		if err := store.SaveCheckpoint(ctx, *cp); err != nil {
+			return nil, fmt.Errorf("save checkpoint: %w", err)
+		}
+	}
+
+	// Hash before reading files so changes made during the run are picked up
+	// by the next incremental pass rather than silently recorded as indexed.
+	state, err := idx.merkleTree.Hash(ctx, opts.RootPath, opts.IgnorePatterns)
+	if err != nil {
+		return nil, fmt.Errorf("hash current state: %w", err)
+	}
+
+	files, err := idx.collectFiles(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("walk file system: %w", err)
+	}
+	stats.TotalFiles = len(files)
+
+	symbols := make(map[string][]string)
+	batch := make([]indexFile, 0, checkpointBatchSize)
+	for i, file := range files {
+		batch = append(batch, file)
+		if len(batch) < checkpointBatchSize && i < len(files)-1 {
+			continue
+		}
+		if err := idx.commitCheckpointBatch(ctx, store, cp, batch, opts, stats, symbols); err != nil {
+			return nil, err
+		}
+		batch = batch[:0]
+	}
+
+	if err := idx.commitPackageSummaries(ctx, store, files, symbols, opts); err != nil {
+		return nil, err
+	}
+
+	if err := idx.SaveState(ctx, state); err != nil {
+		return nil, fmt.Errorf("save state: %w", err)
+	}
+	if err := store.ClearCheckpoint(ctx); err != nil {
+		return nil, fmt.Errorf("clear checkpoint: %w", err)
+	}
+
+	stats.EndTime = time.Now()
+	return stats, nil
+}
+
+// collectFiles walks the root and returns the files eligible for indexing.
+func (idx *DefaultIndexer) collectFiles(ctx context.Context, opts IndexOptions) ([]indexFile, error) {
+	var files []indexFile
+
+	err := idx.walker.Walk(ctx, opts.RootPath, opts.IgnorePatterns, func(path string, info os.FileInfo) error {
+		if info.IsDir() || info.Size() == 0 {
+			return nil
+		}
+		if opts.MaxFileSize > 0 && info.Size() > opts.MaxFileSize {
+			return nil
+		}
+
+		// G304: Validate path before the file is read
+		if _, err := security.ValidatePathWithinBase(path, opts.RootPath); err != nil {
+			if errors.Is(err, security.ErrPathTraversal) {
+				return fmt.Errorf("security: path traversal detected for %s: %w", path, err)
+			}
+			return fmt.Errorf("path validation failed for %s: %w", path, err)
+		}
+
+		relPath, err := filepath.Rel(opts.RootPath, path)
+		if err != nil {
+			return fmt.Errorf("get relative path: %w", err)
+		}
+		if err := validation.IsPathSafe(relPath); err != nil {
+			return fmt.Errorf("path validation failed for %s: %w", relPath, err)
+		}
+
+		files = append(files, indexFile{path: path, relPath: relPath, info: info})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].relPath < files[j].relPath
+	})
+	return files, nil
+}
+
+// commitCheckpointBatch chunks and embeds a batch of files and commits the
+// resulting documents together with the updated checkpoint. Files already
+// completed with identical content are skipped. The symbols of chunked files
+// are recorded in symbols for the package summaries.
+func (idx *DefaultIndexer) commitCheckpointBatch(ctx context.Context, store vectorstore.CheckpointStore, cp *vectorstore.IndexCheckpoint, batch []indexFile, opts IndexOptions, stats *IndexStats, symbols map[string][]string) error {
+	type pendingFile struct {
+		indexFile
+		content []byte
+		hash    string
+	}
+
+	var pending []pendingFile
+	for _, file := range batch {
+		// #nosec G304 - Path validated in collectFiles with ValidatePathWithinBase
+		content, err := os.ReadFile(file.path)
+		if err != nil {
+			return fmt.Errorf("read file %s: %w", file.path, err)
+		}
+		sum := sha256.Sum256(content)
+		hash := hex.EncodeToString(sum[:])
+
+		if cp.Completed[file.relPath] == hash {
+			stats.SkippedFiles++
+			continue
+		}
+		pending = append(pending, pendingFile{indexFile: file, content: content, hash: hash})
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	paths := make([]string, len(pending))
+	for i, file := range pending {
+		paths[i] = file.relPath
+	}
+	if err := store.SaveCheckpoint(ctx, vectorstore.IndexCheckpoint{
+		RunID:    cp.RunID,
+		RootPath: cp.RootPath,
+		Pending:  paths,
+	}); err != nil {
+		return fmt.Errorf("save checkpoint: %w", err)
+	}
+
+	completed := make(map[string]string, len(pending))
+	var docs []vectorstore.Document
+	for _, file := range pending {
+		chunks := idx.chunkFile(ctx, file.content, file.relPath, file.info, opts)
+		if len(chunks) > 0 {
+			symbols[file.relPath] = chunkSymbols(chunks)
+		}
+		for _, chunk := range chunks {
+			doc, err := idx.embedChunk(ctx, opts.Embedder, chunk)
+			if err != nil {
+				return err
+			}
+			docs = append(docs, doc)
+		}
+		completed[file.relPath] = file.hash
+		stats.BytesProcessed += int64(len(file.content))
+	}
+
+	if err := store.CommitCheckpointBatch(ctx, completed, docs); err != nil {
+		return fmt.Errorf("commit checkpoint batch: %w", err)
+	}
+
+	if cp.Completed == nil {
+		cp.Completed = make(map[string]string)
+	}
+	for path, hash := range completed {
+		cp.Completed[path] = hash
+	}
+	stats.IndexedFiles += len(pending)
+	stats.TotalChunks += len(docs)
+
+	idx.mu.Lock()
+	idx.status.FilesProcessed = stats.IndexedFiles + stats.SkippedFiles
+	idx.status.TotalFiles = stats.TotalFiles
+	idx.status.ChunksCreated = stats.TotalChunks
+	if stats.TotalFiles > 0 {
+		idx.status.Progress = float64(idx.status.FilesProcessed) / float64(stats.TotalFiles) * 100
+	}
+	idx.mu.Unlock()
+
+	return nil
+}
+
+// commitPackageSummaries embeds and commits a summary chunk for every directory
+// containing indexed files. Files skipped by a resumed run are described from
+// their stored chunks.
+func (idx *DefaultIndexer) commitPackageSummaries(ctx context.Context, store vectorstore.CheckpointStore, files []indexFile, symbols map[string][]string, opts IndexOptions) error {
+	paths := make([]string, len(files))
+	for i, file := range files {
+		paths[i] = file.relPath
+	}
+	summaries, err := packageSummaries(ctx, filesByDir(paths), symbols, opts.VectorStore)
+	if err != nil {
+		return fmt.Errorf("summarize packages: %w", err)
+	}
+
+	docs := make([]vectorstore.Document, 0, len(summaries))
+	for _, chunk := range summaries {
+		doc, err := idx.embedChunk(ctx, opts.Embedder, chunk)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, doc)
+	}
+
+	if err := store.CommitCheckpointBatch(ctx, nil, docs); err != nil {
+		return fmt.Errorf("commit package summaries: %w", err)
+	}
+	return nil
+}
+
+// resumeInterruptedRun finishes a checkpointed run left behind by a crash.
+// It reports whether a run was resumed.
+func (idx *DefaultIndexer) resumeInterruptedRun(ctx context.Context, opts IndexOptions) (bool, error) {
+	store, ok := opts.VectorStore.(vectorstore.CheckpointStore)
+	if !ok || opts.Embedder == nil {
+		return false, nil
+	}
+
+	cp, err := store.LoadCheckpoint(ctx)
+	if err != nil {
+		return false, fmt.Errorf("load checkpoint: %w", err)
+	}
+	if cp == nil || cp.RootPath != opts.RootPath {
+		return false, nil
+	}
+
+	if _, err := idx.IndexWithCheckpoints(ctx, opts); err != nil {
+		return true, fmt.Errorf("resume run %s: %w", cp.RunID, err)
+	}
+	return true, nil
+}
+
+// repairStateDivergence compares the persisted Merkle state with the vector
+// store. Files recorded in the state without any stored chunks are dropped from
+// the state so the next incremental pass indexes them again. An interrupted
+// checkpointed run is reported as an error, since resuming it requires an
+// embedder; the run resumes automatically on the next Start.
+func (idx *DefaultIndexer) repairStateDivergence(ctx context.Context, store vectorstore.VectorStore) error {
+	if cpStore, ok := store.(vectorstore.CheckpointStore); ok && !idx.isRunning() {
+		cp, err := cpStore.LoadCheckpoint(ctx)
+		if err != nil {
+			return fmt.Errorf("load checkpoint: %w", err)
+		}
+		if cp != nil {
+			return fmt.Errorf("indexing run %s was interrupted with %d files completed and %d pending; restart indexing to resume",
+				cp.RunID, len(cp.Completed), len(cp.Pending))
+		}
+	}
+
+	state, err := idx.LoadState(ctx)
+	if err != nil {
+		return err
+	}
+	if len(state) == 0 {
+		return nil
+	}
+
+	files, err := stateFiles(state)
+	if err != nil {
+		return err
+	}
+	indexed, err := store.ListIndexedFiles(ctx)
+	if err != nil {
+		return fmt.Errorf("list indexed files: %w", err)
+	}
+	inStore := make(map[string]bool, len(indexed))
+	for _, path := range indexed {
+		inStore[filepath.ToSlash(path)] = true
+	}
+
+	var missing []string
+	for path, size := range files {
+		if size > 0 && !inStore[path] {
+			missing = append(missing, path)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	repaired, err := removeStateFiles(state, missing)
+	if err != nil {
+		return err
+	}
+	return idx.SaveState(ctx, repaired)
+}
+
+// isRunning reports whether background indexing is active.
+func (idx *DefaultIndexer) isRunning() bool {
+	idx.runningMu.RLock()
+	defer idx.runningMu.RUnlock()
+	return idx.running
+}
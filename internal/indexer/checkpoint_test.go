@@ -0,0 +1,186 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ferg-cod3s/conexus/internal/embedding"
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+)
+
+// countingEmbedder counts Embed calls and fails once the limit is reached.
+type countingEmbedder struct {
+	*embedding.MockEmbedder
+	calls int
+	limit int      // 0 = unlimited
+	texts []string // Texts embedded so far
+}
+
+func (e *countingEmbedder) Embed(ctx context.Context, text string) (*embedding.Embedding, error) {
+	if e.limit > 0 && e.calls >= e.limit {
+		return nil, fmt.Errorf("embedder unavailable")
+	}
+	e.calls++
+	e.texts = append(e.texts, text)
+	return e.MockEmbedder.Embed(ctx, text)
+}
+
+func writeCheckpointFixture(t *testing.T, root string, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		path := filepath.Join(root, fmt.Sprintf("file%03d.txt", i))
+		require.NoError(t, os.WriteFile(path, []byte(fmt.Sprintf("contents of file %d\n", i)), 0644))
+	}
+}
+
+func TestIndexWithCheckpoints_ResumesAfterFailure(t *testing.T) {
+	root := t.TempDir()
+	writeCheckpointFixture(t, root, 2*checkpointBatchSize+10)
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	ctx := context.Background()
+
+	store := vectorstore.NewMemoryStore()
+	opts := IndexOptions{
+		RootPath:    root,
+		VectorStore: store,
+		Embedder:    &countingEmbedder{MockEmbedder: embedding.NewMock(8), limit: checkpointBatchSize + 5},
+	}
+
+	// First run dies partway through the second batch
+	_, err := NewIndexer(statePath).IndexWithCheckpoints(ctx, opts)
+	require.Error(t, err)
+
+	cp, err := store.LoadCheckpoint(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, cp)
+	assert.Len(t, cp.Completed, checkpointBatchSize)
+	assert.Len(t, cp.Pending, checkpointBatchSize)
+	_, err = os.Stat(statePath)
+	assert.True(t, os.IsNotExist(err), "state must not be saved before the run completes")
+
+	// Restarted run only embeds files that were not committed
+	embedder := &countingEmbedder{MockEmbedder: embedding.NewMock(8)}
+	opts.Embedder = embedder
+	stats, err := NewIndexer(statePath).IndexWithCheckpoints(ctx, opts)
+	require.NoError(t, err)
+
+	// Remaining files plus the package summary
+	assert.Equal(t, checkpointBatchSize+10+1, embedder.calls)
+	assert.Equal(t, checkpointBatchSize, stats.SkippedFiles)
+	assert.Equal(t, checkpointBatchSize+10, stats.IndexedFiles)
+
+	count, err := store.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2*checkpointBatchSize+10+1), count)
+
+	cp, err = store.LoadCheckpoint(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, cp, "checkpoint is cleared after a completed run")
+	_, err = os.Stat(statePath)
+	assert.NoError(t, err)
+}
+
+func TestIndexWithCheckpoints_ReindexesFilesChangedSinceCommit(t *testing.T) {
+	root := t.TempDir()
+	writeCheckpointFixture(t, root, 3)
+	ctx := context.Background()
+
+	store := vectorstore.NewMemoryStore()
+	require.NoError(t, store.SaveCheckpoint(ctx, vectorstore.IndexCheckpoint{
+		RunID:    "run-1",
+		RootPath: root,
+		Completed: map[string]string{
+			"file000.txt": "outdated-hash",
+		},
+	}))
+
+	embedder := &countingEmbedder{MockEmbedder: embedding.NewMock(8)}
+	stats, err := NewIndexer(filepath.Join(t.TempDir(), "state.json")).IndexWithCheckpoints(ctx, IndexOptions{
+		RootPath:    root,
+		VectorStore: store,
+		Embedder:    embedder,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, stats.IndexedFiles)
+	assert.Equal(t, 0, stats.SkippedFiles)
+	assert.Equal(t, 3+1, embedder.calls, "every file plus the package summary")
+}
+
+func TestIndexWithCheckpoints_FallsBackWithoutCheckpointStore(t *testing.T) {
+	root := t.TempDir()
+	writeCheckpointFixture(t, root, 2)
+
+	stats, err := NewIndexer(filepath.Join(t.TempDir(), "state.json")).IndexWithCheckpoints(context.Background(), IndexOptions{
+		RootPath: root,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2+1, stats.TotalChunks, "both files plus the package summary")
+}
+
+func TestHealthCheck_ReportsInterruptedRun(t *testing.T) {
+	ctx := context.Background()
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	require.NoError(t, os.WriteFile(statePath, []byte(`{"root":null}`), 0600))
+
+	store := vectorstore.NewMemoryStore()
+	require.NoError(t, store.SaveCheckpoint(ctx, vectorstore.IndexCheckpoint{
+		RunID:    "run-1",
+		RootPath: "/repo",
+		Pending:  []string{"a.go"},
+	}))
+
+	idx := NewIndexer(statePath)
+	idx.SetVectorStore(store)
+
+	err := idx.HealthCheck(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "run-1 was interrupted")
+}
+
+func TestHealthCheck_RepairsStateWithoutStoredChunks(t *testing.T) {
+	root := t.TempDir()
+	writeCheckpointFixture(t, root, 3)
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	ctx := context.Background()
+
+	store := vectorstore.NewMemoryStore()
+	opts := IndexOptions{
+		RootPath:    root,
+		VectorStore: store,
+		Embedder:    embedding.NewMock(8),
+	}
+	idx := NewIndexer(statePath)
+	_, err := idx.IndexWithCheckpoints(ctx, opts)
+	require.NoError(t, err)
+
+	// Simulate a store that lost a file's chunks
+	chunks, err := store.GetFileChunks(ctx, "file001.txt")
+	require.NoError(t, err)
+	require.NotEmpty(t, chunks)
+	for _, chunk := range chunks {
+		require.NoError(t, store.Delete(ctx, chunk.ID))
+	}
+
+	require.NoError(t, idx.HealthCheck(ctx))
+
+	// The repaired state makes the next incremental pass restore the file
+	state, err := idx.LoadState(ctx)
+	require.NoError(t, err)
+	files, err := stateFiles(state)
+	require.NoError(t, err)
+	assert.NotContains(t, files, "file001.txt")
+	assert.Contains(t, files, "file000.txt")
+
+	_, _, err = idx.IndexIncremental(ctx, opts, state)
+	require.NoError(t, err)
+	chunks, err = store.GetFileChunks(ctx, "file001.txt")
+	require.NoError(t, err)
+	assert.NotEmpty(t, chunks)
+}
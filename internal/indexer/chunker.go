@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"go/ast"
 	"go/parser"
+	"go/printer"
 	"go/token"
 	"path/filepath"
 	"regexp"
@@ -145,6 +146,9 @@ func (c *CodeChunker) chunkGoCode(ctx context.Context, content string, filePath
 				Metadata: map[string]string{
 					"function_name": fn.Name.Name,
 					"receiver":      c.getReceiverName(fn),
+					"package":       file.Name.Name,
+					"signature":     goSignature(fset, fn),
+					"doc_comment":   strings.TrimSpace(fn.Doc.Text()),
 				},
 				Hash:      generateContentHash(fnContent),
 				IndexedAt: time.Now(),
@@ -175,6 +179,9 @@ func (c *CodeChunker) chunkGoCode(ctx context.Context, content string, filePath
 							EndLine:   endPos.Line - 1,
 							Metadata: map[string]string{
 								"struct_name": typeSpec.Name.Name,
+								"package":     file.Name.Name,
+								"signature":   "type " + typeSpec.Name.Name + " struct",
+								"doc_comment": strings.TrimSpace(goTypeDoc(genDecl, typeSpec).Text()),
 							},
 							Hash:      generateContentHash(structContent),
 							IndexedAt: time.Now(),
@@ -610,6 +617,25 @@ func (c *CodeChunker) createCodeChunk(content, filePath, language string, chunkT
 	}
 }
 
+// goSignature renders a Go function declaration without its body.
+func goSignature(fset *token.FileSet, fn *ast.FuncDecl) string {
+	var b strings.Builder
+	decl := &ast.FuncDecl{Recv: fn.Recv, Name: fn.Name, Type: fn.Type}
+	if err := printer.Fprint(&b, fset, decl); err != nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// goTypeDoc returns the doc comment of a type, which is attached to the
+// declaration when the type is declared on its own.
+func goTypeDoc(genDecl *ast.GenDecl, typeSpec *ast.TypeSpec) *ast.CommentGroup {
+	if typeSpec.Doc == nil && len(genDecl.Specs) == 1 {
+		return genDecl.Doc
+	}
+	return typeSpec.Doc
+}
+
 // getReceiverName extracts the receiver name from a Go function declaration.
 func (c *CodeChunker) getReceiverName(fn *ast.FuncDecl) string {
 	if fn.Recv == nil || len(fn.Recv.List) == 0 {
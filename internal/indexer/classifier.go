@@ -0,0 +1,175 @@
+package indexer
+
+import (
+	"bytes"
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// FileClass categorizes a file by its origin so low-value content can be
+// excluded from search results by default.
+type FileClass string
+
+const (
+	FileClassSource    FileClass = "source"    // Hand-written project code or docs
+	FileClassGenerated FileClass = "generated" // Tool output such as protobuf stubs or lockfiles
+	FileClassVendored  FileClass = "vendored"  // Third-party code copied into the tree
+	FileClassMinified  FileClass = "minified"  // Minified bundles or very-long-line files
+	FileClassBinary    FileClass = "binary"    // Non-text content
+)
+
+// ClassificationMetadataKey is the chunk metadata key holding the FileClass.
+const ClassificationMetadataKey = "classification"
+
+// generatedHeaderRegex matches common "generated file" markers, including the
+// Go convention "Code generated ... DO NOT EDIT."
+var generatedHeaderRegex = regexp.MustCompile(`(?i)(code generated .* do not edit|@generated|auto-?generated|do not edit this file|generated by protoc)`)
+
+// vendoredDirs are directory names that hold third-party code.
+var vendoredDirs = map[string]bool{
+	"vendor":           true,
+	"vendors":          true,
+	"node_modules":     true,
+	"third_party":      true,
+	"third-party":      true,
+	"thirdparty":       true,
+	"bower_components": true,
+	".yarn":            true,
+}
+
+// generatedFiles are exact file names produced by package managers.
+var generatedFiles = map[string]bool{
+	"package-lock.json":   true,
+	"yarn.lock":           true,
+	"pnpm-lock.yaml":      true,
+	"go.sum":              true,
+	"cargo.lock":          true,
+	"poetry.lock":         true,
+	"pipfile.lock":        true,
+	"gemfile.lock":        true,
+	"composer.lock":       true,
+	"npm-shrinkwrap.json": true,
+}
+
+// generatedSuffixes are file name suffixes produced by code generators.
+var generatedSuffixes = []string{
+	".pb.go",
+	".pb.gw.go",
+	"_pb2.py",
+	"_pb2_grpc.py",
+	".pb.h",
+	".pb.cc",
+	"_grpc.pb.go",
+	"_generated.go",
+	".gen.go",
+	".g.dart",
+	".designer.cs",
+}
+
+// FileClassifier detects generated, vendored, minified and binary files.
+type FileClassifier struct {
+	headerLines   int // Number of leading lines scanned for generated markers
+	maxLineLength int // Lines longer than this mark a file as minified
+	sniffBytes    int // Number of leading bytes inspected for binary content
+}
+
+// NewFileClassifier creates a classifier with default thresholds.
+func NewFileClassifier() *FileClassifier {
+	return &FileClassifier{
+		headerLines:   20,
+		maxLineLength: 1000,
+		sniffBytes:    8000,
+	}
+}
+
+// Classify determines the FileClass of a file from its relative path and content.
+func (c *FileClassifier) Classify(relPath string, content []byte) FileClass {
+	if c.isBinary(content) {
+		return FileClassBinary
+	}
+
+	relPath = strings.ToLower(strings.ReplaceAll(relPath, "\\", "/"))
+	if isVendoredPath(relPath) {
+		return FileClassVendored
+	}
+	if isGeneratedName(path.Base(relPath)) || c.hasGeneratedHeader(content) {
+		return FileClassGenerated
+	}
+	if c.isMinified(relPath, content) {
+		return FileClassMinified
+	}
+
+	return FileClassSource
+}
+
+// isBinary reports whether the leading bytes contain NULs or are not valid UTF-8.
+func (c *FileClassifier) isBinary(content []byte) bool {
+	sample := content
+	if len(sample) > c.sniffBytes {
+		sample = sample[:c.sniffBytes]
+		// Avoid flagging a multi-byte rune cut at the sample boundary
+		for i := 0; i < utf8.UTFMax && !utf8.Valid(sample); i++ {
+			sample = sample[:len(sample)-1]
+		}
+	}
+
+	if bytes.IndexByte(sample, 0) >= 0 {
+		return true
+	}
+	return !utf8.Valid(sample)
+}
+
+// hasGeneratedHeader scans the first lines of a file for generator markers.
+func (c *FileClassifier) hasGeneratedHeader(content []byte) bool {
+	lines := bytes.SplitN(content, []byte("\n"), c.headerLines+1)
+	if len(lines) > c.headerLines {
+		lines = lines[:c.headerLines]
+	}
+	for _, line := range lines {
+		if generatedHeaderRegex.Match(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// isMinified reports whether a file is a minified bundle or has very long lines.
+func (c *FileClassifier) isMinified(relPath string, content []byte) bool {
+	base := path.Base(relPath)
+	if strings.Contains(base, ".min.") || strings.HasSuffix(base, ".bundle.js") {
+		return true
+	}
+
+	for _, line := range bytes.Split(content, []byte("\n")) {
+		if len(line) > c.maxLineLength {
+			return true
+		}
+	}
+	return false
+}
+
+// isVendoredPath reports whether any directory component names a vendored tree.
+func isVendoredPath(relPath string) bool {
+	parts := strings.Split(relPath, "/")
+	for _, dir := range parts[:len(parts)-1] {
+		if vendoredDirs[dir] {
+			return true
+		}
+	}
+	return false
+}
+
+// isGeneratedName reports whether a lowercase file name is a known generated artifact.
+func isGeneratedName(base string) bool {
+	if generatedFiles[base] {
+		return true
+	}
+	for _, suffix := range generatedSuffixes {
+		if strings.HasSuffix(base, suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package indexer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileClassifierClassify(t *testing.T) {
+	classifier := NewFileClassifier()
+
+	tests := []struct {
+		name     string
+		path     string
+		content  string
+		expected FileClass
+	}{
+		{"plain go", "internal/app/main.go", "package main\n\nfunc main() {}\n", FileClassSource},
+		{"go generated header", "api/types.go", "// Code generated by mockgen. DO NOT EDIT.\n\npackage api\n", FileClassGenerated},
+		{"protobuf suffix", "api/service.pb.go", "package api\n", FileClassGenerated},
+		{"python protobuf", "proto/service_pb2.py", "import grpc\n", FileClassGenerated},
+		{"at-generated marker", "src/schema.ts", "/**\n * @generated\n */\nexport {}\n", FileClassGenerated},
+		{"lockfile", "web/package-lock.json", "{}\n", FileClassGenerated},
+		{"go.sum", "go.sum", "github.com/x/y v1.0.0 h1:abc=\n", FileClassGenerated},
+		{"vendor dir", "vendor/github.com/x/y/y.go", "package y\n", FileClassVendored},
+		{"third_party dir", "lib/third_party/zlib/zlib.c", "int x;\n", FileClassVendored},
+		{"min suffix", "static/app.min.js", "var a=1;\n", FileClassMinified},
+		{"long line", "static/app.js", "var a=" + strings.Repeat("1", 2000) + ";\n", FileClassMinified},
+		{"nul bytes", "assets/logo.png", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR", FileClassBinary},
+		{"invalid utf8", "data/blob.bin", "\xff\xfe\xfd\xfc", FileClassBinary},
+		{"vendor file name only", "docs/vendor.md", "# Vendors\n", FileClassSource},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, classifier.Classify(tt.path, []byte(tt.content)))
+		})
+	}
+}
+
+func TestFileClassifierHeaderOnlyScansLeadingLines(t *testing.T) {
+	classifier := NewFileClassifier()
+
+	content := strings.Repeat("// comment\n", 50) + "// Code generated by tool. DO NOT EDIT.\n"
+	assert.Equal(t, FileClassSource, classifier.Classify("pkg/doc.go", []byte(content)))
+}
+
+func TestIndexTagsClassification(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	files := map[string]string{
+		"main.go":           "package main\n\nfunc main() {}\n",
+		"types.pb.go":       "// Code generated by protoc-gen-go. DO NOT EDIT.\npackage main\n",
+		"assets/image.bin":  "\x00\x01\x02\x03",
+		"lib/third_party/x": "hello\n",
+	}
+	for name, content := range files {
+		full := filepath.Join(tmpDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(full), 0755))
+		require.NoError(t, os.WriteFile(full, []byte(content), 0644))
+	}
+
+	idx := NewIndexer(filepath.Join(tmpDir, "state.json"))
+	chunks, err := idx.Index(context.Background(), IndexOptions{RootPath: tmpDir})
+	require.NoError(t, err)
+
+	classes := make(map[string]string)
+	for _, chunk := range chunks {
+		classes[chunk.FilePath] = chunk.Metadata[ClassificationMetadataKey]
+		if chunk.FilePath == "assets/image.bin" {
+			assert.NotContains(t, chunk.Content, "\x00", "binary content should not be indexed verbatim")
+		}
+	}
+
+	assert.Equal(t, "source", classes["main.go"])
+	assert.Equal(t, "generated", classes["types.pb.go"])
+	assert.Equal(t, "binary", classes[filepath.Join("assets", "image.bin")])
+	assert.Equal(t, "vendored", classes[filepath.Join("lib", "third_party", "x")])
+}
@@ -4,9 +4,13 @@ package indexer
 import (
 	"context"
 	"fmt"
+	"os"
 	"sync"
 	"time"
 
+	"github.com/ferg-cod3s/conexus/internal/embedding"
+	"github.com/ferg-cod3s/conexus/internal/observability/audit"
+	"github.com/ferg-cod3s/conexus/internal/security/secrets"
 	"github.com/ferg-cod3s/conexus/internal/vectorstore"
 )
 
@@ -70,6 +74,13 @@ func (c *DefaultIndexController) Start(ctx context.Context, opts IndexOptions) e
 
 		// Perform indexing in background with a separate context
 		indexCtx := context.Background()
+
+		// Checkpointed runs store documents as they go and resume after a crash
+		if idx, ok := c.indexer.(*DefaultIndexer); ok && supportsCheckpoints(opts) {
+			c.runCheckpointed(indexCtx, idx, opts)
+			return
+		}
+
 		chunks, err := c.indexer.Index(indexCtx, opts)
 		if err != nil {
 			c.updateStatus(IndexStatus{
@@ -92,33 +103,15 @@ func (c *DefaultIndexController) Start(ctx context.Context, opts IndexOptions) e
 
 			docs := make([]vectorstore.Document, 0, len(chunks))
 			for _, chunk := range chunks {
-				// Generate embedding
-				vec, err := opts.Embedder.Embed(ctx, chunk.Content)
+				// Generate embedding with the chunk's context header
+				text, hash := c.embeddingInput(chunk)
+				vec, err := opts.Embedder.Embed(ctx, text)
 				if err != nil {
 					// Log error but continue with other chunks
 					continue
 				}
 
-				// Convert metadata
-				metadata := make(map[string]interface{})
-				for k, v := range chunk.Metadata {
-					metadata[k] = v
-				}
-				metadata["file_path"] = chunk.FilePath
-				metadata["language"] = chunk.Language
-				metadata["chunk_type"] = string(chunk.Type)
-				metadata["start_line"] = chunk.StartLine
-				metadata["end_line"] = chunk.EndLine
-				metadata["indexed_at"] = chunk.IndexedAt.Format(time.RFC3339)
-
-				docs = append(docs, vectorstore.Document{
-					ID:        chunk.ID,
-					Content:   chunk.Content,
-					Vector:    vec.Vector,
-					Metadata:  metadata,
-					CreatedAt: chunk.IndexedAt,
-					UpdatedAt: chunk.IndexedAt,
-				})
+				docs = append(docs, indexedDocument(chunk, vec.Vector, hash))
 			}
 
 			// Store in batches
@@ -197,7 +190,7 @@ func (c *DefaultIndexController) Stop(ctx context.Context) error {
 // ForceReindex performs a complete reindex of the codebase.
 func (c *DefaultIndexController) ForceReindex(ctx context.Context, opts IndexOptions) error {
 	// Clear any existing state
-	if err := c.clearState(); err != nil {
+	if err := c.clearState(ctx, opts); err != nil {
 		return fmt.Errorf("failed to clear state: %w", err)
 	}
 
@@ -268,32 +261,13 @@ func (c *DefaultIndexController) ReindexPaths(ctx context.Context, opts IndexOpt
 			// Store chunks if vector store and embedder are provided
 			if opts.VectorStore != nil && opts.Embedder != nil {
 				for _, chunk := range chunks {
-					vec, err := opts.Embedder.Embed(ctx, chunk.Content)
+					text, hash := c.embeddingInput(chunk)
+					vec, err := opts.Embedder.Embed(ctx, text)
 					if err != nil {
 						continue
 					}
 
-					metadata := make(map[string]interface{})
-					for k, v := range chunk.Metadata {
-						metadata[k] = v
-					}
-					metadata["file_path"] = chunk.FilePath
-					metadata["language"] = chunk.Language
-					metadata["chunk_type"] = string(chunk.Type)
-					metadata["start_line"] = chunk.StartLine
-					metadata["end_line"] = chunk.EndLine
-					metadata["indexed_at"] = chunk.IndexedAt.Format(time.RFC3339)
-
-					doc := vectorstore.Document{
-						ID:        chunk.ID,
-						Content:   chunk.Content,
-						Vector:    vec.Vector,
-						Metadata:  metadata,
-						CreatedAt: chunk.IndexedAt,
-						UpdatedAt: chunk.IndexedAt,
-					}
-
-					if err := opts.VectorStore.Upsert(ctx, doc); err != nil {
+					if err := opts.VectorStore.Upsert(ctx, indexedDocument(chunk, vec.Vector, hash)); err != nil {
 						// Log error but continue
 						continue
 					}
@@ -355,9 +329,136 @@ func (c *DefaultIndexController) HealthCheck(ctx context.Context) error {
 		return fmt.Errorf("indexing in error state: %s", status.LastError)
 	}
 
+	// Detect and repair drift between the Merkle state and the vector store
+	if idx, ok := c.indexer.(*DefaultIndexer); ok && !status.IsIndexing {
+		if store := idx.vectorStore(); store != nil {
+			if err := idx.repairStateDivergence(ctx, store); err != nil {
+				return fmt.Errorf("state/store divergence: %w", err)
+			}
+		}
+	}
+
 	return nil
 }
 
+// Doctor runs a deep consistency check of the index, optionally repairing
+// the problems found. It refuses to run while indexing is in progress.
+func (c *DefaultIndexController) Doctor(ctx context.Context, opts IndexOptions, repair bool) (*DoctorReport, error) {
+	c.runningMu.RLock()
+	running := c.running
+	c.runningMu.RUnlock()
+	if running {
+		return nil, fmt.Errorf("indexing is already running")
+	}
+
+	doctor, ok := c.indexer.(Doctor)
+	if !ok {
+		return nil, fmt.Errorf("indexer does not support consistency checks")
+	}
+	return doctor.Doctor(ctx, opts, repair)
+}
+
+// IndexHistory indexes the git history not yet indexed using the underlying indexer.
+func (c *DefaultIndexController) IndexHistory(ctx context.Context, opts IndexOptions) (*HistoryStats, error) {
+	history, ok := c.indexer.(HistoryIndexer)
+	if !ok {
+		return nil, fmt.Errorf("indexer does not support git history indexing")
+	}
+	return history.IndexHistory(ctx, opts)
+}
+
+// IndexRepository indexes an additional repository using the underlying indexer.
+func (c *DefaultIndexController) IndexRepository(ctx context.Context, repo vectorstore.Repository, opts IndexOptions) (*RepositoryStats, error) {
+	repos, ok := c.indexer.(RepositoryIndexer)
+	if !ok {
+		return nil, fmt.Errorf("indexer does not support repositories")
+	}
+	return repos.IndexRepository(ctx, repo, opts)
+}
+
+// RemoveRepository removes an additional repository using the underlying indexer.
+func (c *DefaultIndexController) RemoveRepository(ctx context.Context, repoID string, store vectorstore.VectorStore) error {
+	repos, ok := c.indexer.(RepositoryIndexer)
+	if !ok {
+		return fmt.Errorf("indexer does not support repositories")
+	}
+	return repos.RemoveRepository(ctx, repoID, store)
+}
+
+// SetVectorStore forwards the vector store used for health checks to the underlying indexer.
+func (c *DefaultIndexController) SetVectorStore(store vectorstore.VectorStore) {
+	if idx, ok := c.indexer.(*DefaultIndexer); ok {
+		idx.SetVectorStore(store)
+	}
+}
+
+// SetChunkHeaderTemplate forwards the chunk header template to the underlying indexer.
+func (c *DefaultIndexController) SetChunkHeaderTemplate(text string) error {
+	if idx, ok := c.indexer.(*DefaultIndexer); ok {
+		return idx.SetChunkHeaderTemplate(text)
+	}
+	return nil
+}
+
+// SetSecretHandling forwards the default secret handling to the underlying indexer.
+func (c *DefaultIndexController) SetSecretHandling(mode SecretHandling) error {
+	if idx, ok := c.indexer.(*DefaultIndexer); ok {
+		return idx.SetSecretHandling(mode)
+	}
+	return nil
+}
+
+// SetNotebookOutputSize forwards the notebook output cap to the underlying indexer.
+func (c *DefaultIndexController) SetNotebookOutputSize(size int) {
+	if idx, ok := c.indexer.(*DefaultIndexer); ok {
+		idx.SetNotebookOutputSize(size)
+	}
+}
+
+// indexedDocument converts an embedded chunk to a document with the metadata of
+// chunkToDocument, so every indexing path stores the same keys.
+func indexedDocument(chunk Chunk, vector embedding.Vector, hash string) vectorstore.Document {
+	doc := chunkToDocument(chunk, vector)
+	doc.Metadata["indexed_at"] = chunk.IndexedAt.Format(time.RFC3339)
+	if hash != "" {
+		doc.Metadata[HeaderHashMetadataKey] = hash
+	}
+	return doc
+}
+
+// embeddingInput returns the text to embed for a chunk and the hash of its
+// header, falling back to the raw content for other indexers.
+func (c *DefaultIndexController) embeddingInput(chunk Chunk) (string, string) {
+	if idx, ok := c.indexer.(*DefaultIndexer); ok {
+		return idx.embeddingInput(chunk)
+	}
+	return chunk.Content, ""
+}
+
+// SecretsReport returns secrets detected by the underlying indexer.
+func (c *DefaultIndexController) SecretsReport() SecretsReport {
+	if reporter, ok := c.indexer.(SecretsReporter); ok {
+		return reporter.SecretsReport()
+	}
+	return SecretsReport{GeneratedAt: time.Now(), Findings: []secrets.Finding{}}
+}
+
+// RedactSecrets redacts content like the underlying indexer, or returns it
+// unchanged when the indexer detects no secrets.
+func (c *DefaultIndexController) RedactSecrets(content string) string {
+	if redactor, ok := c.indexer.(SecretRedactor); ok {
+		return redactor.RedactSecrets(content)
+	}
+	return content
+}
+
+// SetAuditLogger forwards the audit logger to the underlying indexer.
+func (c *DefaultIndexController) SetAuditLogger(logger *audit.Logger) {
+	if idx, ok := c.indexer.(*DefaultIndexer); ok {
+		idx.SetAuditLogger(logger)
+	}
+}
+
 // updateStatus safely updates the internal status.
 func (c *DefaultIndexController) updateStatus(status IndexStatus) {
 	c.statusMu.Lock()
@@ -365,9 +466,72 @@ func (c *DefaultIndexController) updateStatus(status IndexStatus) {
 	c.status = status
 }
 
-// clearState removes any persisted indexing state.
-func (c *DefaultIndexController) clearState() error {
-	// This would clear any persisted state files
-	// For now, just return nil as the indexer handles this internally
+// runCheckpointed performs a checkpointed full index, resuming an interrupted
+// run for the same root if one exists.
+func (c *DefaultIndexController) runCheckpointed(ctx context.Context, idx *DefaultIndexer, opts IndexOptions) {
+	startTime := time.Now()
+	c.updateStatus(IndexStatus{
+		IsIndexing: true,
+		Phase:      "indexing",
+		StartTime:  startTime,
+	})
+
+	stats, err := idx.IndexWithCheckpoints(ctx, opts)
+	if err != nil {
+		c.updateStatus(IndexStatus{
+			IsIndexing: false,
+			Phase:      "error",
+			Progress:   0,
+			LastError:  err.Error(),
+		})
+		return
+	}
+
+	c.updateStatus(IndexStatus{
+		IsIndexing:     false,
+		Phase:          "completed",
+		Progress:       100,
+		FilesProcessed: stats.IndexedFiles + stats.SkippedFiles,
+		TotalFiles:     stats.TotalFiles,
+		ChunksCreated:  stats.TotalChunks,
+		StartTime:      startTime,
+		EstimatedEnd:   time.Now(),
+		Metrics:        stats.ToMetrics(),
+	})
+}
+
+// supportsCheckpoints reports whether opts allow a checkpointed run.
+func supportsCheckpoints(opts IndexOptions) bool {
+	_, ok := opts.VectorStore.(vectorstore.CheckpointStore)
+	return ok && opts.Embedder != nil
+}
+
+// clearState removes any persisted indexing state so the next run starts
+// from scratch instead of resuming an interrupted one.
+func (c *DefaultIndexController) clearState(ctx context.Context, opts IndexOptions) error {
+	if store, ok := opts.VectorStore.(vectorstore.CheckpointStore); ok {
+		if err := store.ClearCheckpoint(ctx); err != nil {
+			return fmt.Errorf("clear checkpoint: %w", err)
+		}
+	}
+
+	idx, ok := c.indexer.(*DefaultIndexer)
+	if !ok {
+		return nil
+	}
+	info, err := os.Stat(idx.statePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("stat state file: %w", err)
+	}
+	// Only a state file is removed, never a directory given as the state path
+	if info.IsDir() {
+		return nil
+	}
+	if err := os.Remove(idx.statePath); err != nil {
+		return fmt.Errorf("remove state file: %w", err)
+	}
 	return nil
 }
@@ -10,6 +10,9 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/ferg-cod3s/conexus/internal/embedding"
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
 )
 
 func TestNewIndexController(t *testing.T) {
@@ -300,3 +303,33 @@ func TestIndexControllerWithLargeFile(t *testing.T) {
 	status := controller.GetStatus()
 	assert.Greater(t, status.FilesProcessed, 0)
 }
+
+func TestIndexControllerStoresIndexerMetadata(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.go"), []byte("package p\n\nfunc One() {}\n"), 0644))
+
+	controller := NewIndexController(filepath.Join(t.TempDir(), "state.json"))
+	require.NoError(t, controller.SetChunkHeaderTemplate("{{.FilePath}}{{if .Signature}} {{.Signature}}{{end}}"))
+	store := vectorstore.NewMemoryStore()
+	opts := IndexOptions{RootPath: root, Embedder: embedding.NewMock(8), VectorStore: store}
+
+	// Selective reindexing embeds and stores chunks in the controller
+	ctx := context.Background()
+	require.NoError(t, controller.ReindexPaths(ctx, opts, []string{root}))
+	require.Eventually(t, func() bool {
+		return controller.GetStatus().Phase == "completed"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	docs, err := store.GetFileChunks(ctx, "a.go")
+	require.NoError(t, err)
+	require.NotEmpty(t, docs)
+	for _, doc := range docs {
+		assert.NotEmpty(t, doc.Metadata["type"], doc.ID)
+	}
+
+	// Headers recomputed from the stored metadata match those embedded
+	idx := controller.indexer.(*DefaultIndexer)
+	count, err := idx.ReembedStaleHeaders(ctx, opts)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
@@ -0,0 +1,259 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ferg-cod3s/conexus/internal/validation"
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+)
+
+// DoctorReport lists inconsistencies found by a deep index consistency check.
+type DoctorReport struct {
+	CheckedAt           time.Time `json:"checked_at"`
+	OrphanedFiles       []string  `json:"orphaned_files"`       // Files with chunks that no longer exist on disk
+	UnindexedFiles      []string  `json:"unindexed_files"`      // Files on disk that have no chunks
+	DimensionMismatches []string  `json:"dimension_mismatches"` // Documents whose vector size differs from the embedder
+	FTSMissing          []string  `json:"fts_missing"`          // Documents without a full-text search row
+	FTSStale            []string  `json:"fts_stale"`            // Documents whose full-text search row is out of date
+	FTSOrphaned         []string  `json:"fts_orphaned"`         // Full-text search rows without a document
+	HNSWOrphaned        []string  `json:"hnsw_orphaned"`        // HNSW nodes without a document
+	Repaired            bool      `json:"repaired"`             // Whether the problems were repaired
+}
+
+// IssueCount returns the total number of problems in the report.
+func (r *DoctorReport) IssueCount() int {
+	return len(r.OrphanedFiles) + len(r.UnindexedFiles) + len(r.DimensionMismatches) +
+		len(r.FTSMissing) + len(r.FTSStale) + len(r.FTSOrphaned) + len(r.HNSWOrphaned)
+}
+
+// Healthy reports whether no problems were found.
+func (r *DoctorReport) Healthy() bool {
+	return r.IssueCount() == 0
+}
+
+// Doctor is implemented by indexers that support deep consistency checks.
+type Doctor interface {
+	// Doctor checks the index against the file system and the store's internal
+	// structures. With repair set, every problem found is fixed.
+	Doctor(ctx context.Context, opts IndexOptions, repair bool) (*DoctorReport, error)
+}
+
+// Doctor checks the index against the file system and the store's internal
+// structures. With repair set, integrity problems are fixed, chunks of deleted
+// files are removed, and files without chunks are indexed (which requires
+// opts.Embedder).
+func (idx *DefaultIndexer) Doctor(ctx context.Context, opts IndexOptions, repair bool) (*DoctorReport, error) {
+	store := opts.VectorStore
+	if store == nil {
+		return nil, fmt.Errorf("vector store is required")
+	}
+
+	report := &DoctorReport{CheckedAt: time.Now()}
+
+	integrity, err := idx.checkIntegrity(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	report.DimensionMismatches = integrity.DimensionMismatches
+	report.FTSMissing = integrity.FTSMissing
+	report.FTSStale = integrity.FTSStale
+	report.FTSOrphaned = integrity.FTSOrphaned
+	report.HNSWOrphaned = integrity.HNSWOrphaned
+
+	report.OrphanedFiles, err = findOrphanedFiles(ctx, store, opts.RootPath)
+	if err != nil {
+		return nil, err
+	}
+	unindexed, err := idx.findUnindexedFiles(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	report.UnindexedFiles = make([]string, len(unindexed))
+	for i, file := range unindexed {
+		report.UnindexedFiles[i] = file.relPath
+	}
+
+	if !repair || report.Healthy() {
+		return report, nil
+	}
+
+	if checker, ok := store.(vectorstore.IntegrityChecker); ok {
+		if err := checker.RepairIntegrity(ctx, integrity); err != nil {
+			return nil, fmt.Errorf("repair store integrity: %w", err)
+		}
+	} else {
+		for _, id := range integrity.DimensionMismatches {
+			if err := store.Delete(ctx, id); err != nil {
+				return nil, fmt.Errorf("delete document %s: %w", id, err)
+			}
+		}
+	}
+
+	for _, path := range report.OrphanedFiles {
+		if err := deleteFileDocuments(ctx, store, path); err != nil {
+			return nil, err
+		}
+	}
+
+	// Documents dropped for mismatched dimensions leave their files without
+	// chunks, so look again before re-indexing.
+	unindexed, err = idx.findUnindexedFiles(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(unindexed) > 0 && opts.Embedder == nil {
+		return nil, fmt.Errorf("embedder is required to index %d files without chunks", len(unindexed))
+	}
+	for _, file := range unindexed {
+		if err := idx.indexFile(ctx, file, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	report.Repaired = true
+	return report, nil
+}
+
+// checkIntegrity runs the store's integrity checks, or a dimension check over
+// all indexed files for stores without an IntegrityChecker.
+func (idx *DefaultIndexer) checkIntegrity(ctx context.Context, opts IndexOptions) (*vectorstore.IntegrityReport, error) {
+	dimensions := 0
+	if opts.Embedder != nil {
+		dimensions = opts.Embedder.Dimensions()
+	}
+
+	if checker, ok := opts.VectorStore.(vectorstore.IntegrityChecker); ok {
+		report, err := checker.CheckIntegrity(ctx, dimensions)
+		if err != nil {
+			return nil, fmt.Errorf("check store integrity: %w", err)
+		}
+		return report, nil
+	}
+
+	report := &vectorstore.IntegrityReport{}
+	if dimensions <= 0 {
+		return report, nil
+	}
+
+	files, err := opts.VectorStore.ListIndexedFiles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list indexed files: %w", err)
+	}
+	for _, path := range files {
+		docs, err := opts.VectorStore.GetFileChunks(ctx, path)
+		if err != nil {
+			return nil, fmt.Errorf("get chunks for %s: %w", path, err)
+		}
+		for _, doc := range docs {
+			if len(doc.Vector) != dimensions {
+				report.DimensionMismatches = append(report.DimensionMismatches, doc.ID)
+			}
+		}
+	}
+	sort.Strings(report.DimensionMismatches)
+	return report, nil
+}
+
+// findOrphanedFiles returns indexed files that no longer exist under root.
+func findOrphanedFiles(ctx context.Context, store vectorstore.VectorStore, root string) ([]string, error) {
+	files, err := store.ListIndexedFiles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list indexed files: %w", err)
+	}
+
+	var orphaned []string
+	for _, path := range files {
+		// Only relative paths belong to the file system index
+		if filepath.IsAbs(path) || validation.IsPathSafe(path) != nil {
+			continue
+		}
+		if _, err := os.Lstat(filepath.Join(root, path)); err != nil {
+			if os.IsNotExist(err) {
+				orphaned = append(orphaned, path)
+				continue
+			}
+			return nil, fmt.Errorf("stat %s: %w", path, err)
+		}
+	}
+	return orphaned, nil
+}
+
+// findUnindexedFiles returns files under the root that have no chunks in the
+// store but would produce chunks if indexed.
+func (idx *DefaultIndexer) findUnindexedFiles(ctx context.Context, opts IndexOptions) ([]indexFile, error) {
+	indexed, err := opts.VectorStore.ListIndexedFiles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list indexed files: %w", err)
+	}
+	inStore := make(map[string]bool, len(indexed))
+	for _, path := range indexed {
+		inStore[path] = true
+	}
+
+	files, err := idx.collectFiles(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("walk file system: %w", err)
+	}
+
+	var unindexed []indexFile
+	for _, file := range files {
+		if inStore[file.relPath] {
+			continue
+		}
+		content, err := readIndexFile(file)
+		if err != nil {
+			return nil, err
+		}
+		// Only look at the chunks: checking must not touch the store or audit log
+		chunks := idx.splitFile(ctx, content, file.relPath, file.info, idx.secretMode(opts)).chunks
+		// Files that yield no chunks (e.g. fully quarantined) are expected to be absent
+		if len(chunks) > 0 {
+			unindexed = append(unindexed, file)
+		}
+	}
+	return unindexed, nil
+}
+
+// indexFile chunks, embeds and stores a single file.
+func (idx *DefaultIndexer) indexFile(ctx context.Context, file indexFile, opts IndexOptions) error {
+	content, err := readIndexFile(file)
+	if err != nil {
+		return err
+	}
+	chunks := idx.chunkFile(ctx, content, file.relPath, file.info, opts)
+	if err := idx.storeVectors(ctx, chunks, opts); err != nil {
+		return fmt.Errorf("index %s: %w", file.relPath, err)
+	}
+	return nil
+}
+
+// readIndexFile reads a collected file.
+func readIndexFile(file indexFile) ([]byte, error) {
+	// #nosec G304 - Path validated in collectFiles with ValidatePathWithinBase
+	content, err := os.ReadFile(file.path)
+	if err != nil {
+		return nil, fmt.Errorf("read file %s: %w", file.path, err)
+	}
+	return content, nil
+}
+
+// deleteFileDocuments removes every document stored for a file, and its
+// text from the trigram index.
+func deleteFileDocuments(ctx context.Context, store vectorstore.VectorStore, path string) error {
+	docs, err := store.GetFileChunks(ctx, path)
+	if err != nil {
+		return fmt.Errorf("get chunks for %s: %w", path, err)
+	}
+	for _, doc := range docs {
+		if err := store.Delete(ctx, doc.ID); err != nil {
+			return fmt.Errorf("delete document %s: %w", doc.ID, err)
+		}
+	}
+	removeFileText(ctx, store, path)
+	return nil
+}
@@ -0,0 +1,116 @@
+package indexer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ferg-cod3s/conexus/internal/embedding"
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+)
+
+func TestDoctor_HealthyIndex(t *testing.T) {
+	root := t.TempDir()
+	writeCheckpointFixture(t, root, 3)
+	ctx := context.Background()
+
+	opts := IndexOptions{
+		RootPath:    root,
+		VectorStore: vectorstore.NewMemoryStore(),
+		Embedder:    embedding.NewMock(8),
+	}
+	idx := NewIndexer(filepath.Join(t.TempDir(), "state.json"))
+	_, err := idx.IndexWithCheckpoints(ctx, opts)
+	require.NoError(t, err)
+
+	report, err := idx.Doctor(ctx, opts, false)
+	require.NoError(t, err)
+	assert.True(t, report.Healthy())
+	assert.False(t, report.Repaired)
+}
+
+func TestDoctor_DetectsAndRepairs(t *testing.T) {
+	root := t.TempDir()
+	writeCheckpointFixture(t, root, 3)
+	ctx := context.Background()
+
+	store := vectorstore.NewMemoryStore()
+	opts := IndexOptions{
+		RootPath:    root,
+		VectorStore: store,
+		Embedder:    embedding.NewMock(8),
+	}
+	idx := NewIndexer(filepath.Join(t.TempDir(), "state.json"))
+	_, err := idx.IndexWithCheckpoints(ctx, opts)
+	require.NoError(t, err)
+
+	// A deleted file, a new file, and a vector from a different embedder
+	require.NoError(t, os.Remove(filepath.Join(root, "file000.txt")))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "new.txt"), []byte("new content\n"), 0644))
+	require.NoError(t, store.Upsert(ctx, vectorstore.Document{
+		ID:       "legacy",
+		Content:  "contents of file 1",
+		Vector:   embedding.Vector{0.1, 0.2},
+		Metadata: map[string]interface{}{"file_path": "file001.txt"},
+	}))
+
+	report, err := idx.Doctor(ctx, opts, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"file000.txt"}, report.OrphanedFiles)
+	assert.Equal(t, []string{"new.txt"}, report.UnindexedFiles)
+	assert.Equal(t, []string{"legacy"}, report.DimensionMismatches)
+	assert.Equal(t, 3, report.IssueCount())
+
+	report, err = idx.Doctor(ctx, opts, true)
+	require.NoError(t, err)
+	assert.True(t, report.Repaired)
+
+	report, err = idx.Doctor(ctx, opts, false)
+	require.NoError(t, err)
+	assert.True(t, report.Healthy(), "problems remain after repair: %+v", report)
+
+	chunks, err := store.GetFileChunks(ctx, "new.txt")
+	require.NoError(t, err)
+	assert.NotEmpty(t, chunks)
+}
+
+func TestDoctor_CheckLeavesStoreUntouched(t *testing.T) {
+	root := t.TempDir()
+	writeCheckpointFixture(t, root, 2)
+	ctx := context.Background()
+
+	store := &trigramStore{MemoryStore: vectorstore.NewMemoryStore(), texts: map[string]string{}}
+	opts := IndexOptions{
+		RootPath:    root,
+		VectorStore: store,
+		Embedder:    embedding.NewMock(8),
+	}
+	idx := NewIndexer(filepath.Join(t.TempDir(), "state.json"))
+	_, err := idx.IndexWithCheckpoints(ctx, opts)
+	require.NoError(t, err)
+	indexed := len(store.texts)
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "settings.env"), []byte(secretFixture), 0644))
+	report, err := idx.Doctor(ctx, opts, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"settings.env"}, report.UnindexedFiles)
+
+	// Only a repair indexes the file and records its secret
+	assert.Len(t, store.texts, indexed)
+	assert.Equal(t, 0, idx.SecretsReport().TotalFindings)
+
+	_, err = idx.Doctor(ctx, opts, true)
+	require.NoError(t, err)
+	assert.Contains(t, store.texts, "settings.env")
+	assert.Equal(t, 1, idx.SecretsReport().TotalFindings)
+}
+
+func TestDoctor_RequiresVectorStore(t *testing.T) {
+	idx := NewIndexer(filepath.Join(t.TempDir(), "state.json"))
+	_, err := idx.Doctor(context.Background(), IndexOptions{RootPath: t.TempDir()}, false)
+	assert.Error(t, err)
+}
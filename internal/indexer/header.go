@@ -0,0 +1,302 @@
+package indexer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/ferg-cod3s/conexus/internal/embedding"
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+)
+
+// DefaultChunkHeaderTemplate renders the context prepended to a chunk's content
+// when it is embedded. Fields are those of ChunkHeader.
+const DefaultChunkHeaderTemplate = `File: {{.FilePath}}
+{{- if .Package}}
+Package: {{.Package}}{{end}}
+{{- if .EnclosingType}}
+In: {{.EnclosingType}}{{end}}
+{{- if .Signature}}
+Signature: {{.Signature}}{{end}}
+{{- if .DocComment}}
+Doc: {{.DocComment}}{{end}}`
+
+// HeaderHashMetadataKey records the hash of the header a document was embedded
+// with, so documents can be re-embedded when the header template changes.
+const HeaderHashMetadataKey = "header_hash"
+
+// maxHeaderDocLength caps the doc comment included in a header.
+const maxHeaderDocLength = 300
+
+// ChunkHeader is the context available to a chunk header template.
+type ChunkHeader struct {
+	FilePath      string
+	Language      string
+	Package       string // Go package name, or the module path for other languages
+	EnclosingType string // Type, class or document section enclosing the chunk
+	Symbol        string // Name of the function, type or section
+	Signature     string
+	DocComment    string
+}
+
+// ParseChunkHeaderTemplate parses a chunk header template. An empty text
+// selects DefaultChunkHeaderTemplate.
+func ParseChunkHeaderTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = DefaultChunkHeaderTemplate
+	}
+	tmpl, err := template.New("chunk_header").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse chunk header template: %w", err)
+	}
+	if err := tmpl.Execute(&strings.Builder{}, ChunkHeader{}); err != nil {
+		return nil, fmt.Errorf("invalid chunk header template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// SetChunkHeaderTemplate sets the template for the context header embedded with
+// each chunk. Documents embedded with a different header are re-embedded by the
+// next incremental pass.
+func (idx *DefaultIndexer) SetChunkHeaderTemplate(text string) error {
+	tmpl, err := ParseChunkHeaderTemplate(text)
+	if err != nil {
+		return err
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.headerTemplate = tmpl
+	idx.headersChecked = false
+	return nil
+}
+
+// embeddingInput returns the text to embed for a chunk and the hash of its
+// header. Package summaries are embedded without a header.
+func (idx *DefaultIndexer) embeddingInput(chunk Chunk) (string, string) {
+	if chunk.Type == ChunkTypePackage {
+		return chunk.Content, ""
+	}
+
+	metadata := make(map[string]string, len(chunk.Metadata)+3)
+	for k, v := range chunk.Metadata {
+		metadata[k] = v
+	}
+	metadata["file_path"] = chunk.FilePath
+	metadata["language"] = chunk.Language
+	metadata["type"] = string(chunk.Type)
+
+	header := idx.renderHeader(newChunkHeader(metadata, chunk.Content))
+	return embeddingText(header, chunk.Content), headerHash(header)
+}
+
+// embedChunk embeds a chunk with its context header and converts it to a document.
+func (idx *DefaultIndexer) embedChunk(ctx context.Context, embedder embedding.Embedder, chunk Chunk) (vectorstore.Document, error) {
+	text, hash := idx.embeddingInput(chunk)
+	vec, err := embedder.Embed(ctx, text)
+	if err != nil {
+		return vectorstore.Document{}, fmt.Errorf("embed chunk %s: %w", chunk.ID, err)
+	}
+
+	doc := chunkToDocument(chunk, vec.Vector)
+	if hash != "" {
+		doc.Metadata[HeaderHashMetadataKey] = hash
+	}
+	return doc, nil
+}
+
+// ReembedStaleHeaders re-embeds stored documents whose header differs from the
+// one the current template renders, e.g. after the template was changed. It
+// returns the number of documents re-embedded.
+func (idx *DefaultIndexer) ReembedStaleHeaders(ctx context.Context, opts IndexOptions) (int, error) {
+	if opts.VectorStore == nil || opts.Embedder == nil {
+		return 0, nil
+	}
+
+	files, err := opts.VectorStore.ListIndexedFiles(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list indexed files: %w", err)
+	}
+
+	reembedded := 0
+	for _, path := range files {
+		docs, err := opts.VectorStore.GetFileChunks(ctx, path)
+		if err != nil {
+			return reembedded, fmt.Errorf("get chunks for %s: %w", path, err)
+		}
+
+		var stale []vectorstore.Document
+		for _, doc := range docs {
+			metadata := make(map[string]string, len(doc.Metadata))
+			for k, v := range doc.Metadata {
+				if s, ok := v.(string); ok {
+					metadata[k] = s
+				}
+			}
+			header := idx.renderHeader(newChunkHeader(metadata, doc.Content))
+			hash := headerHash(header)
+			if metadata[HeaderHashMetadataKey] == hash {
+				continue
+			}
+
+			vec, err := opts.Embedder.Embed(ctx, embeddingText(header, doc.Content))
+			if err != nil {
+				return reembedded, fmt.Errorf("embed chunk %s: %w", doc.ID, err)
+			}
+			doc.Vector = vec.Vector
+			doc.Metadata[HeaderHashMetadataKey] = hash
+			stale = append(stale, doc)
+		}
+
+		if len(stale) == 0 {
+			continue
+		}
+		if err := opts.VectorStore.UpsertBatch(ctx, stale); err != nil {
+			return reembedded, fmt.Errorf("upsert batch: %w", err)
+		}
+		reembedded += len(stale)
+	}
+	return reembedded, nil
+}
+
+// reembedStaleHeadersOnce runs ReembedStaleHeaders the first time it is called
+// after the header template was set.
+func (idx *DefaultIndexer) reembedStaleHeadersOnce(ctx context.Context, opts IndexOptions) error {
+	idx.mu.RLock()
+	checked := idx.headersChecked
+	idx.mu.RUnlock()
+	if checked || opts.VectorStore == nil || opts.Embedder == nil {
+		return nil
+	}
+
+	if _, err := idx.ReembedStaleHeaders(ctx, opts); err != nil {
+		return err
+	}
+
+	idx.mu.Lock()
+	idx.headersChecked = true
+	idx.mu.Unlock()
+	return nil
+}
+
+// renderHeader executes the configured header template.
+func (idx *DefaultIndexer) renderHeader(header ChunkHeader) string {
+	idx.mu.RLock()
+	tmpl := idx.headerTemplate
+	idx.mu.RUnlock()
+
+	if tmpl == nil {
+		var err error
+		if tmpl, err = ParseChunkHeaderTemplate(""); err != nil {
+			return ""
+		}
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, header); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// newChunkHeader collects the header context from a chunk's metadata, as
+// produced by chunkToDocument, and its content.
+func newChunkHeader(metadata map[string]string, content string) ChunkHeader {
+	header := ChunkHeader{
+		FilePath:      metadata["file_path"],
+		Language:      metadata["language"],
+		Package:       metadata["package"],
+		EnclosingType: metadata["enclosing_type"],
+		Symbol:        symbolName(metadata),
+		Signature:     metadata["signature"],
+		DocComment:    metadata["doc_comment"],
+	}
+
+	if header.Package == "" && header.FilePath != "" {
+		header.Package = modulePath(header.FilePath, header.Language)
+	}
+
+	chunkType := metadata["type"]
+	if chunkType == "" {
+		// Stored by index controllers that recorded the type as chunk_type
+		chunkType = metadata["chunk_type"]
+	}
+	if header.Signature == "" {
+		switch ChunkType(chunkType) {
+		case ChunkTypeFunction, ChunkTypeClass, ChunkTypeStruct, ChunkTypeInterface:
+			header.Signature = firstCodeLine(content)
+		}
+	}
+
+	header.DocComment = strings.Join(strings.Fields(header.DocComment), " ")
+	if len(header.DocComment) > maxHeaderDocLength {
+		header.DocComment = strings.TrimSpace(header.DocComment[:maxHeaderDocLength]) + "…"
+	}
+	return header
+}
+
+// annotateEnclosingTypes records on each chunk the type, class or document
+// section enclosing it, based on the links set by linkChunks.
+func annotateEnclosingTypes(chunks []Chunk) {
+	byID := make(map[string]Chunk, len(chunks))
+	for _, chunk := range chunks {
+		byID[chunk.ID] = chunk
+	}
+
+	for i := range chunks {
+		enclosing := chunks[i].Metadata["receiver"]
+		if parent, ok := byID[chunks[i].Metadata[ParentIDMetadataKey]]; ok && enclosing == "" {
+			switch parent.Type {
+			case ChunkTypeStruct, ChunkTypeClass, ChunkTypeInterface:
+				enclosing = symbolName(parent.Metadata)
+			case ChunkTypeSection:
+				enclosing = parent.Metadata["section_path"]
+			}
+		}
+		if enclosing != "" {
+			chunks[i].Metadata["enclosing_type"] = enclosing
+		}
+	}
+}
+
+// modulePath derives a module name from a file path: dotted for Python,
+// the directory for everything else.
+func modulePath(relPath, language string) string {
+	relPath = filepath.ToSlash(relPath)
+	if language == "python" {
+		return strings.ReplaceAll(strings.TrimSuffix(relPath, filepath.Ext(relPath)), "/", ".")
+	}
+	if dir := filepath.ToSlash(filepath.Dir(relPath)); dir != "." {
+		return dir
+	}
+	return ""
+}
+
+// firstCodeLine returns the first non-blank line of content without a trailing
+// block opener, as a best-effort signature.
+func firstCodeLine(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return strings.TrimSpace(strings.TrimRight(line, "{:"))
+		}
+	}
+	return ""
+}
+
+// embeddingText joins a header and chunk content into the text to embed.
+func embeddingText(header, content string) string {
+	if header == "" {
+		return content
+	}
+	return header + "\n\n" + content
+}
+
+// headerHash fingerprints a rendered header.
+func headerHash(header string) string {
+	sum := sha256.Sum256([]byte(header))
+	return hex.EncodeToString(sum[:8])
+}
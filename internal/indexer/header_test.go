@@ -0,0 +1,103 @@
+package indexer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ferg-cod3s/conexus/internal/embedding"
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+)
+
+func TestParseChunkHeaderTemplate(t *testing.T) {
+	tmpl, err := ParseChunkHeaderTemplate("")
+	require.NoError(t, err)
+	assert.NotNil(t, tmpl)
+
+	_, err = ParseChunkHeaderTemplate("{{.FilePath")
+	assert.Error(t, err)
+
+	_, err = ParseChunkHeaderTemplate("{{.Unknown}}")
+	assert.Error(t, err, "unknown fields are rejected up front")
+}
+
+func TestEmbedChunk_HeaderGivesContext(t *testing.T) {
+	chunks, err := NewCodeChunker(2000, 200).Chunk(context.Background(), "package shapes\n\ntype Circle struct{}\n\n// Area returns the area.\n"+
+		"func (c *Circle) Area() float64 {\n\treturn 0\n}\n", "shapes/circle.go")
+	require.NoError(t, err)
+	chunks = linkChunks(chunks, "shapes/circle.go")
+	annotateEnclosingTypes(chunks)
+
+	var area Chunk
+	for _, chunk := range chunks {
+		if chunk.Metadata["function_name"] == "Area" {
+			area = chunk
+		}
+	}
+	require.NotEmpty(t, area.ID)
+
+	embedder := &countingEmbedder{MockEmbedder: embedding.NewMock(8)}
+	doc, err := NewIndexer("").embedChunk(context.Background(), embedder, area)
+	require.NoError(t, err)
+
+	require.Len(t, embedder.texts, 1)
+	text := embedder.texts[0]
+	assert.Contains(t, text, "File: shapes/circle.go")
+	assert.Contains(t, text, "Package: shapes")
+	assert.Contains(t, text, "In: Circle")
+	assert.Contains(t, text, "Signature: func (c *Circle) Area() float64")
+	assert.Contains(t, text, "Doc: Area returns the area.")
+	assert.True(t, strings.HasSuffix(text, area.Content))
+
+	assert.Equal(t, area.Content, doc.Content, "stored content stays raw")
+	assert.NotEmpty(t, doc.Metadata[HeaderHashMetadataKey])
+}
+
+func TestEmbedChunk_CustomTemplate(t *testing.T) {
+	idx := NewIndexer("")
+	require.NoError(t, idx.SetChunkHeaderTemplate("// {{.FilePath}}"))
+
+	embedder := &countingEmbedder{MockEmbedder: embedding.NewMock(8)}
+	_, err := idx.embedChunk(context.Background(), embedder, Chunk{ID: "a", Content: "body", FilePath: "a.txt"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"// a.txt\n\nbody"}, embedder.texts)
+}
+
+func TestReembedStaleHeaders(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.go"), []byte("package p\n\nfunc One() {}\n\nfunc Two() {}\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "notes.txt"), []byte("plain notes"), 0644))
+
+	ctx := context.Background()
+	store := vectorstore.NewMemoryStore()
+	idx := NewIndexer(filepath.Join(t.TempDir(), "state.json"))
+	require.NoError(t, idx.SetChunkHeaderTemplate("{{.FilePath}}"))
+
+	opts := IndexOptions{RootPath: root, Embedder: embedding.NewMock(8), VectorStore: store}
+	_, err := idx.Index(ctx, opts)
+	require.NoError(t, err)
+
+	count, err := idx.ReembedStaleHeaders(ctx, opts)
+	require.NoError(t, err)
+	assert.Zero(t, count, "headers match the template they were embedded with")
+
+	// Only chunks with a signature render differently under the new template
+	require.NoError(t, idx.SetChunkHeaderTemplate("{{.FilePath}}{{if .Signature}} {{.Signature}}{{end}}"))
+	embedder := &countingEmbedder{MockEmbedder: embedding.NewMock(8)}
+	opts.Embedder = embedder
+	count, err = idx.ReembedStaleHeaders(ctx, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	for _, text := range embedder.texts {
+		assert.Contains(t, text, "func ")
+	}
+
+	count, err = idx.ReembedStaleHeaders(ctx, opts)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
@@ -0,0 +1,314 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+)
+
+// Metadata keys recording a chunk's place in the file → type → method and
+// section → subsection hierarchy.
+const (
+	ParentIDMetadataKey   = vectorstore.ParentIDKey
+	ChunkLevelMetadataKey = vectorstore.ChunkLevelKey
+	SymbolsMetadataKey    = "symbols"
+)
+
+// maxSummarySymbols caps the symbols listed per file in summary chunks.
+const maxSummarySymbols = 20
+
+// symbolMetadataKeys are the metadata keys chunkers use to name a chunk.
+var symbolMetadataKeys = []string{"function_name", "struct_name", "type_name", "interface_name", "heading"}
+
+// linkChunks records the parent of every chunk of a file. Go methods belong to
+// the struct chunk of their receiver, other chunks to the smallest chunk whose
+// line range encloses them. When a file yields more than one chunk, a file
+// summary chunk is added as the parent of the top-level chunks; top-level
+// chunks of single-chunk files and file summaries belong to the package
+// summary of their directory. Links set by the chunker are kept.
+func linkChunks(chunks []Chunk, relPath string) []Chunk {
+	if len(chunks) == 0 {
+		return chunks
+	}
+
+	structs := make(map[string]string)
+	for _, chunk := range chunks {
+		if chunk.Type == ChunkTypeStruct || chunk.Type == ChunkTypeClass {
+			if name := symbolName(chunk.Metadata); name != "" {
+				structs[name] = chunk.ID
+			}
+		}
+	}
+
+	topLevelParent := packageSummaryID(filepath.Dir(relPath))
+	if len(chunks) > 1 {
+		topLevelParent = fileSummaryID(relPath)
+	}
+
+	for i := range chunks {
+		if chunks[i].Metadata == nil {
+			chunks[i].Metadata = make(map[string]string)
+		}
+		if chunks[i].Metadata[ParentIDMetadataKey] != "" {
+			continue
+		}
+
+		parent := structs[chunks[i].Metadata["receiver"]]
+		if parent == "" {
+			parent = enclosingChunk(chunks, i)
+		}
+		if parent == "" {
+			parent = topLevelParent
+		}
+		chunks[i].Metadata[ParentIDMetadataKey] = parent
+	}
+
+	if len(chunks) > 1 {
+		chunks = append([]Chunk{fileSummaryChunk(chunks, relPath)}, chunks...)
+	}
+	return chunks
+}
+
+// enclosingChunk returns the ID of the smallest other chunk whose line range
+// contains chunks[i], or "" if there is none.
+func enclosingChunk(chunks []Chunk, i int) string {
+	child := chunks[i]
+	best := ""
+	bestSpan := 0
+	for j, other := range chunks {
+		if j == i || other.StartLine > child.StartLine || other.EndLine < child.EndLine {
+			continue
+		}
+		span := other.EndLine - other.StartLine
+		// Identical ranges would link the chunks to each other
+		if span == child.EndLine-child.StartLine {
+			continue
+		}
+		if best == "" || span < bestSpan {
+			best = other.ID
+			bestSpan = span
+		}
+	}
+	return best
+}
+
+// fileSummaryChunk describes a file by its path, language and symbols.
+func fileSummaryChunk(chunks []Chunk, relPath string) Chunk {
+	symbols := chunkSymbols(chunks)
+	language := chunks[0].Language
+	endLine := 0
+	for _, chunk := range chunks {
+		if chunk.EndLine > endLine {
+			endLine = chunk.EndLine
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "File: %s\nLanguage: %s\n", relPath, language)
+	if len(symbols) > 0 {
+		fmt.Fprintf(&b, "Symbols: %s\n", strings.Join(symbols, ", "))
+	}
+	content := b.String()
+
+	return Chunk{
+		ID:        fileSummaryID(relPath),
+		Content:   content,
+		FilePath:  relPath,
+		Language:  language,
+		Type:      ChunkTypeFile,
+		StartLine: 1,
+		EndLine:   endLine,
+		Metadata: map[string]string{
+			ParentIDMetadataKey:   packageSummaryID(filepath.Dir(relPath)),
+			ChunkLevelMetadataKey: string(ChunkTypeFile),
+			SymbolsMetadataKey:    strings.Join(symbols, ","),
+		},
+		Hash:      generateContentHash(content),
+		IndexedAt: time.Now(),
+	}
+}
+
+// packageSummaryChunk describes a directory by the files it contains and
+// their symbols.
+func packageSummaryChunk(dir string, files []string, symbols map[string][]string) Chunk {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Package: %s\nFiles:\n", filepath.ToSlash(dir))
+	for _, file := range files {
+		if names := symbols[file]; len(names) > 0 {
+			fmt.Fprintf(&b, "- %s: %s\n", filepath.Base(file), strings.Join(names, ", "))
+		} else {
+			fmt.Fprintf(&b, "- %s\n", filepath.Base(file))
+		}
+	}
+	content := b.String()
+
+	return Chunk{
+		ID:       packageSummaryID(dir),
+		Content:  content,
+		FilePath: dir,
+		Type:     ChunkTypePackage,
+		Metadata: map[string]string{
+			ChunkLevelMetadataKey: string(ChunkTypePackage),
+		},
+		Hash:      generateContentHash(content),
+		IndexedAt: time.Now(),
+	}
+}
+
+// packageSummaries builds a summary chunk for every directory in dirs, which
+// maps a directory to the files it contains. Symbols are taken from known,
+// falling back to the chunks already stored for files not indexed in this run.
+func packageSummaries(ctx context.Context, dirs map[string][]string, known map[string][]string, store vectorstore.VectorStore) ([]Chunk, error) {
+	names := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		names = append(names, dir)
+	}
+	sort.Strings(names)
+
+	summaries := make([]Chunk, 0, len(names))
+	for _, dir := range names {
+		files := append([]string(nil), dirs[dir]...)
+		sort.Strings(files)
+
+		symbols := make(map[string][]string, len(files))
+		for _, file := range files {
+			if names, ok := known[file]; ok {
+				symbols[file] = names
+				continue
+			}
+			if store == nil {
+				continue
+			}
+			docs, err := store.GetFileChunks(ctx, file)
+			if err != nil {
+				return nil, fmt.Errorf("get chunks for %s: %w", file, err)
+			}
+			symbols[file] = documentSymbols(docs)
+		}
+		summaries = append(summaries, packageSummaryChunk(dir, files, symbols))
+	}
+	return summaries, nil
+}
+
+// filesByDir groups file paths by their directory.
+func filesByDir(paths []string) map[string][]string {
+	dirs := make(map[string][]string)
+	for _, path := range paths {
+		dir := filepath.Dir(path)
+		dirs[dir] = append(dirs[dir], path)
+	}
+	return dirs
+}
+
+// fileSymbols returns the symbols of each file that produced chunks.
+func fileSymbols(chunks []Chunk) map[string][]string {
+	byFile := make(map[string][]Chunk)
+	for _, chunk := range chunks {
+		if chunk.Type == ChunkTypePackage {
+			continue
+		}
+		byFile[chunk.FilePath] = append(byFile[chunk.FilePath], chunk)
+	}
+
+	symbols := make(map[string][]string, len(byFile))
+	for path, fileChunks := range byFile {
+		symbols[path] = chunkSymbols(fileChunks)
+	}
+	return symbols
+}
+
+// chunkSymbols returns the distinct symbol names of chunks in source order,
+// capped at maxSummarySymbols.
+func chunkSymbols(chunks []Chunk) []string {
+	ordered := append([]Chunk(nil), chunks...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].StartLine < ordered[j].StartLine
+	})
+
+	var symbols []string
+	seen := make(map[string]bool)
+	for _, chunk := range ordered {
+		name := symbolName(chunk.Metadata)
+		if name == "" || seen[name] || chunk.Metadata[ChunkLevelMetadataKey] != "" {
+			continue
+		}
+		seen[name] = true
+		symbols = append(symbols, name)
+		if len(symbols) == maxSummarySymbols {
+			break
+		}
+	}
+	return symbols
+}
+
+// documentSymbols returns the symbol names of stored chunks, preferring the
+// file summary when one exists.
+func documentSymbols(docs []vectorstore.Document) []string {
+	chunks := make([]Chunk, 0, len(docs))
+	for _, doc := range docs {
+		metadata := make(map[string]string, len(symbolMetadataKeys)+1)
+		for _, key := range append([]string{ChunkLevelMetadataKey, SymbolsMetadataKey}, symbolMetadataKeys...) {
+			if value, ok := doc.Metadata[key].(string); ok {
+				metadata[key] = value
+			}
+		}
+		if metadata[ChunkLevelMetadataKey] == string(ChunkTypeFile) {
+			if metadata[SymbolsMetadataKey] == "" {
+				return nil
+			}
+			return strings.Split(metadata[SymbolsMetadataKey], ",")
+		}
+		chunks = append(chunks, Chunk{Metadata: metadata})
+	}
+	return chunkSymbols(chunks)
+}
+
+// symbolName returns the name a chunker gave to a chunk, if any.
+func symbolName(metadata map[string]string) string {
+	for _, key := range symbolMetadataKeys {
+		if name := metadata[key]; name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// fileSummaryID returns the chunk ID of a file's summary.
+func fileSummaryID(relPath string) string {
+	return generateChunkID(relPath, string(ChunkTypeFile), "", 0)
+}
+
+// packageSummaryID returns the chunk ID of a directory's summary.
+func packageSummaryID(dir string) string {
+	return generateChunkID(filepath.ToSlash(dir), string(ChunkTypePackage), "", 0)
+}
+
+// changedDirs returns the directories containing changed paths, each with the
+// indexable files it holds in the current state. Directories left without
+// files are omitted.
+func changedDirs(currentState []byte, changedPaths []string, maxFileSize int64) (map[string][]string, error) {
+	affected := make(map[string]bool, len(changedPaths))
+	for _, path := range changedPaths {
+		affected[filepath.Dir(path)] = true
+	}
+
+	files, err := stateFiles(currentState)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for path, size := range files {
+		if size == 0 || (maxFileSize > 0 && size > maxFileSize) {
+			continue
+		}
+		if affected[filepath.Dir(path)] {
+			paths = append(paths, path)
+		}
+	}
+	return filesByDir(paths), nil
+}
@@ -0,0 +1,144 @@
+package indexer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ferg-cod3s/conexus/internal/embedding"
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+)
+
+const hierarchyGoSource = `package shapes
+
+type Circle struct {
+	Radius float64
+}
+
+func (c *Circle) Area() float64 {
+	return 3.14 * c.Radius * c.Radius
+}
+
+func NewCircle(r float64) *Circle {
+	return &Circle{Radius: r}
+}
+`
+
+// contentChunks drops the generated package summaries from indexing results.
+func contentChunks(chunks []Chunk) []Chunk {
+	var content []Chunk
+	for _, chunk := range chunks {
+		if chunk.Type != ChunkTypePackage {
+			content = append(content, chunk)
+		}
+	}
+	return content
+}
+
+func chunksByID(chunks []Chunk) map[string]Chunk {
+	byID := make(map[string]Chunk, len(chunks))
+	for _, chunk := range chunks {
+		byID[chunk.ID] = chunk
+	}
+	return byID
+}
+
+func TestLinkChunks_GoFileTypeMethod(t *testing.T) {
+	chunks, err := NewCodeChunker(2000, 200).Chunk(context.Background(), hierarchyGoSource, "shapes/circle.go")
+	require.NoError(t, err)
+
+	linked := linkChunks(chunks, "shapes/circle.go")
+	require.Len(t, linked, len(chunks)+1)
+
+	summary := linked[0]
+	assert.Equal(t, ChunkTypeFile, summary.Type)
+	assert.Equal(t, fileSummaryID("shapes/circle.go"), summary.ID)
+	assert.Equal(t, packageSummaryID("shapes"), summary.Metadata[ParentIDMetadataKey])
+	assert.Contains(t, summary.Content, "Area")
+	assert.Contains(t, summary.Content, "Circle")
+
+	byID := chunksByID(linked)
+	for _, chunk := range linked[1:] {
+		parent := byID[chunk.Metadata[ParentIDMetadataKey]]
+		switch symbolName(chunk.Metadata) {
+		case "Area":
+			assert.Equal(t, ChunkTypeStruct, parent.Type, "methods belong to their receiver")
+			assert.Equal(t, "Circle", parent.Metadata["struct_name"])
+		default:
+			assert.Equal(t, summary.ID, parent.ID, "top-level declarations belong to the file")
+		}
+	}
+}
+
+func TestLinkChunks_SingleChunkBelongsToPackage(t *testing.T) {
+	chunks := linkChunks([]Chunk{{ID: "a", StartLine: 1, EndLine: 3}}, filepath.Join("pkg", "a.txt"))
+
+	require.Len(t, chunks, 1)
+	assert.Equal(t, packageSummaryID("pkg"), chunks[0].Metadata[ParentIDMetadataKey])
+}
+
+func TestLinkChunks_NestedRanges(t *testing.T) {
+	chunks := linkChunks([]Chunk{
+		{ID: "outer", StartLine: 1, EndLine: 20},
+		{ID: "middle", StartLine: 5, EndLine: 15},
+		{ID: "inner", StartLine: 6, EndLine: 8},
+	}, "a.py")
+
+	byID := chunksByID(chunks)
+	assert.Equal(t, "middle", byID["inner"].Metadata[ParentIDMetadataKey])
+	assert.Equal(t, "outer", byID["middle"].Metadata[ParentIDMetadataKey])
+	assert.Equal(t, fileSummaryID("a.py"), byID["outer"].Metadata[ParentIDMetadataKey])
+}
+
+func TestIndex_GeneratesPackageSummaries(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "shapes"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "shapes", "circle.go"), []byte(hierarchyGoSource), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "shapes", "notes.txt"), []byte("notes"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n"), 0644))
+
+	chunks, err := NewIndexer(filepath.Join(t.TempDir(), "state.json")).Index(context.Background(), IndexOptions{RootPath: root})
+	require.NoError(t, err)
+
+	byID := chunksByID(chunks)
+	shapes, ok := byID[packageSummaryID("shapes")]
+	require.True(t, ok, "summary for shapes")
+	assert.Equal(t, ChunkTypePackage, shapes.Type)
+	assert.Contains(t, shapes.Content, "- circle.go: Circle, Area, NewCircle")
+	assert.Contains(t, shapes.Content, "- notes.txt\n")
+
+	_, ok = byID[packageSummaryID(".")]
+	assert.True(t, ok, "summary for the root directory")
+
+	doc := chunkToDocument(shapes, nil)
+	assert.Equal(t, "shapes", doc.Metadata["package_path"])
+	assert.NotContains(t, doc.Metadata, "file_path", "package summaries are not listed as indexed files")
+}
+
+func TestIndexIncremental_RefreshesPackageSummary(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.go"), []byte("package p\n\nfunc One() {}\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "b.go"), []byte("package p\n\nfunc Two() {}\n"), 0644))
+
+	ctx := context.Background()
+	store := vectorstore.NewMemoryStore()
+	idx := NewIndexer(filepath.Join(t.TempDir(), "state.json"))
+	opts := IndexOptions{RootPath: root, Embedder: embedding.NewMock(8), VectorStore: store}
+
+	_, state, err := idx.IndexIncremental(ctx, opts, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.go"), []byte("package p\n\nfunc Three() {}\n"), 0644))
+	_, _, err = idx.IndexIncremental(ctx, opts, state)
+	require.NoError(t, err)
+
+	summary, err := store.Get(ctx, packageSummaryID("."))
+	require.NoError(t, err)
+	assert.Contains(t, summary.Content, "- a.go: Three")
+	assert.Contains(t, summary.Content, "- b.go: Two", "unchanged files are described from the store")
+	assert.NotContains(t, summary.Content, "One")
+}
@@ -0,0 +1,499 @@
+package indexer
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ferg-cod3s/conexus/internal/enrichment"
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+)
+
+// CommitSourceType is the source_type of documents indexed from the git history.
+const CommitSourceType = "commit"
+
+// Document types produced by the history indexer.
+const (
+	commitDocumentType   = "commit"
+	diffHunkDocumentType = "diff_hunk"
+)
+
+const (
+	maxHunkLength  = 4000 // Characters of a diff hunk kept in its document
+	maxCommitHunks = 100  // Hunks indexed per commit; the rest are skipped
+)
+
+// Field and record separators of the git log format.
+const (
+	gitRecordSeparator = "\x1e"
+	gitFieldSeparator  = "\x1f"
+)
+
+// gitLogFormat prints hash, author name, author email, author date and raw
+// message, each terminated by gitFieldSeparator, ahead of the commit's patch.
+const gitLogFormat = "--format=" + gitRecordSeparator + "%H%x1f%an%x1f%ae%x1f%aI%x1f%B%x1f"
+
+// commitHashPattern matches full SHA-1 and SHA-256 object names.
+var commitHashPattern = regexp.MustCompile(`^[0-9a-f]{40}([0-9a-f]{24})?$`)
+
+// hunkHeaderPattern matches a unified diff hunk header such as "@@ -1,3 +1,4 @@".
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,(\d+))? @@`)
+
+// HistoryStats summarizes a history indexing run.
+type HistoryStats struct {
+	Commits    int    // Commits indexed in this run
+	Hunks      int    // Diff hunks indexed in this run
+	LastCommit string // Most recent commit indexed so far
+}
+
+// HistoryIndexer is implemented by indexers that can index the git history.
+type HistoryIndexer interface {
+	// IndexHistory indexes the commits reachable from HEAD that were not indexed
+	// by a previous run.
+	IndexHistory(ctx context.Context, opts IndexOptions) (*HistoryStats, error)
+}
+
+// gitCommit is a commit read from git log.
+type gitCommit struct {
+	Hash        string
+	Author      string
+	AuthorEmail string
+	Date        time.Time
+	Message     string
+	Files       []fileDiff
+}
+
+// Subject returns the first line of the commit message.
+func (c gitCommit) Subject() string {
+	subject, _, _ := strings.Cut(c.Message, "\n")
+	return subject
+}
+
+// TouchedPaths returns the paths changed by the commit, using the old path of
+// deleted files.
+func (c gitCommit) TouchedPaths() []string {
+	paths := make([]string, 0, len(c.Files))
+	for _, file := range c.Files {
+		paths = append(paths, file.Path)
+	}
+	return paths
+}
+
+// fileDiff is the change a commit made to one file.
+type fileDiff struct {
+	Path    string
+	OldPath string // Previous path of renamed files
+	Binary  bool
+	Hunks   []diffHunk
+}
+
+// diffHunk is one hunk of a unified diff.
+type diffHunk struct {
+	Header    string
+	StartLine int // First line of the hunk in the new file
+	EndLine   int // Last line of the hunk in the new file
+	Lines     []string
+}
+
+// IndexHistory reads commits from the local git repository at opts.RootPath
+// and stores each commit message and each per-file diff hunk as a document
+// with source_type "commit". Only commits after the last one indexed are
+// read; the last indexed commit is saved after every commit, so an
+// interrupted run continues where it stopped.
+func (idx *DefaultIndexer) IndexHistory(ctx context.Context, opts IndexOptions) (*HistoryStats, error) {
+	if opts.VectorStore == nil {
+		return nil, fmt.Errorf("vector store is required")
+	}
+	if opts.Embedder == nil {
+		return nil, fmt.Errorf("embedder is required")
+	}
+
+	idx.historyMu.Lock()
+	defer idx.historyMu.Unlock()
+
+	head, err := gitHead(ctx, opts.RootPath)
+	if err != nil {
+		return nil, err
+	}
+
+	since, err := idx.lastIndexedCommit(ctx, opts.VectorStore)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &HistoryStats{LastCommit: since}
+	if head == "" || head == since {
+		return stats, nil
+	}
+
+	extractor := enrichment.NewStoryExtractor()
+	err = readCommits(ctx, opts.RootPath, since, func(commit gitCommit) error {
+		docs := idx.redactCommitSecrets(ctx, commit, commitDocuments(commit, extractor, idx.classifier), idx.secretMode(opts))
+		for i := range docs {
+			vec, err := opts.Embedder.Embed(ctx, docs[i].Content)
+			if err != nil {
+				return fmt.Errorf("embed %s: %w", docs[i].ID, err)
+			}
+			docs[i].Vector = vec.Vector
+		}
+		if err := opts.VectorStore.UpsertBatch(ctx, docs); err != nil {
+			return fmt.Errorf("store commit %s: %w", commit.Hash, err)
+		}
+		if err := idx.saveLastIndexedCommit(commit.Hash); err != nil {
+			return err
+		}
+
+		stats.Commits++
+		stats.Hunks += len(docs) - 1
+		stats.LastCommit = commit.Hash
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+	return stats, nil
+}
+
+// historyStatePath returns where the last indexed commit is recorded.
+func (idx *DefaultIndexer) historyStatePath() string {
+	return HistoryStatePath(idx.statePath)
+}
+
+// HistoryStatePath returns where an indexer with the given Merkle state path
+// records the last indexed commit.
+func HistoryStatePath(statePath string) string {
+	return statePath + ".history"
+}
+
+// lastIndexedCommit returns the commit a previous run stopped at, or "" to
+// index the history from the start. A recorded commit whose document is no
+// longer stored is ignored, so a cleared store is fully re-indexed.
+func (idx *DefaultIndexer) lastIndexedCommit(ctx context.Context, store vectorstore.VectorStore) (string, error) {
+	data, err := os.ReadFile(idx.historyStatePath())
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("read history state: %w", err)
+	}
+
+	hash := strings.TrimSpace(string(data))
+	if !commitHashPattern.MatchString(hash) {
+		return "", nil
+	}
+	if _, err := store.Get(ctx, commitDocumentID(hash)); err != nil {
+		return "", nil
+	}
+	return hash, nil
+}
+
+// saveLastIndexedCommit records the most recent commit indexed.
+func (idx *DefaultIndexer) saveLastIndexedCommit(hash string) error {
+	if err := idx.ensureStateDir(); err != nil {
+		return fmt.Errorf("ensure state dir: %w", err)
+	}
+	if err := os.WriteFile(idx.historyStatePath(), []byte(hash+"\n"), 0600); err != nil {
+		return fmt.Errorf("write history state: %w", err)
+	}
+	return nil
+}
+
+// gitHead returns the commit HEAD points to, or "" for a repository without
+// commits.
+func gitHead(ctx context.Context, root string) (string, error) {
+	if _, err := runGit(ctx, root, "rev-parse", "--git-dir"); err != nil {
+		return "", fmt.Errorf("%s is not a git repository: %w", root, err)
+	}
+	out, err := runGit(ctx, root, "rev-parse", "--verify", "--quiet", "HEAD^{commit}")
+	if err != nil {
+		return "", nil
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// runGit runs a git command in root and returns its output.
+func runGit(ctx context.Context, root string, args ...string) ([]byte, error) {
+	// #nosec G204 - Fixed git subcommands; commit hashes are validated by commitHashPattern
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", root}, args...)...)
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return out, nil
+}
+
+// readCommits streams the non-merge commits after since (or all commits when
+// since is empty) up to HEAD, oldest first, calling fn for each.
+func readCommits(ctx context.Context, root, since string, fn func(gitCommit) error) error {
+	revision := "HEAD"
+	if since != "" {
+		if !commitHashPattern.MatchString(since) {
+			return fmt.Errorf("invalid commit hash %q", since)
+		}
+		revision = since + "..HEAD"
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// #nosec G204 - Fixed arguments; revision is built from a validated commit hash
+	cmd := exec.CommandContext(ctx, "git", "-C", root, "-c", "core.quotePath=false",
+		"log", "--reverse", "--no-merges", "--no-color", "--no-ext-diff", "--no-textconv",
+		"--unified=3", "--patch", gitLogFormat, revision, "--")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("git log: %w", err)
+	}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("git log: %w", err)
+	}
+
+	reader := bufio.NewReader(stdout)
+	var fnErr error
+	for {
+		record, err := reader.ReadString(gitRecordSeparator[0])
+		record = strings.TrimSuffix(record, gitRecordSeparator)
+		if strings.TrimSpace(record) != "" {
+			commit, parseErr := parseCommit(record)
+			if parseErr != nil {
+				fnErr = parseErr
+			} else {
+				fnErr = fn(commit)
+			}
+			if fnErr != nil {
+				cancel()
+				break
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			fnErr = fmt.Errorf("read git log: %w", err)
+			cancel()
+			break
+		}
+	}
+
+	waitErr := cmd.Wait()
+	if fnErr != nil {
+		return fnErr
+	}
+	if waitErr != nil {
+		return fmt.Errorf("git log: %s", strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// parseCommit parses one git log record written with gitLogFormat.
+func parseCommit(record string) (gitCommit, error) {
+	fields := strings.SplitN(record, gitFieldSeparator, 6)
+	if len(fields) != 6 {
+		return gitCommit{}, fmt.Errorf("malformed git log record")
+	}
+
+	date, err := time.Parse(time.RFC3339, fields[3])
+	if err != nil {
+		return gitCommit{}, fmt.Errorf("parse date of commit %s: %w", fields[0], err)
+	}
+
+	return gitCommit{
+		Hash:        fields[0],
+		Author:      fields[1],
+		AuthorEmail: fields[2],
+		Date:        date,
+		Message:     strings.TrimSpace(fields[4]),
+		Files:       parseDiff(fields[5]),
+	}, nil
+}
+
+// parseDiff splits the patch of a commit into per-file hunks.
+func parseDiff(patch string) []fileDiff {
+	var files []fileDiff
+	var file *fileDiff
+	var hunk *diffHunk
+
+	for _, line := range strings.Split(patch, "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			files = append(files, fileDiff{Path: diffGitPath(line)})
+			file = &files[len(files)-1]
+			hunk = nil
+		case file == nil:
+			continue
+		case hunk == nil && strings.HasPrefix(line, "rename from "):
+			file.OldPath = strings.TrimPrefix(line, "rename from ")
+		case hunk == nil && strings.HasPrefix(line, "rename to "):
+			file.Path = strings.TrimPrefix(line, "rename to ")
+		case hunk == nil && strings.HasPrefix(line, "--- a/"):
+			file.Path = strings.TrimPrefix(line, "--- a/")
+		case hunk == nil && strings.HasPrefix(line, "+++ b/"):
+			file.Path = strings.TrimPrefix(line, "+++ b/")
+		case hunk == nil && strings.HasPrefix(line, "Binary files "):
+			file.Binary = true
+		case strings.HasPrefix(line, "@@ "):
+			m := hunkHeaderPattern.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			start, _ := strconv.Atoi(m[1])
+			count := 1
+			if m[2] != "" {
+				count, _ = strconv.Atoi(m[2])
+			}
+			end := start + count - 1
+			if end < start {
+				end = start
+			}
+			file.Hunks = append(file.Hunks, diffHunk{Header: line, StartLine: start, EndLine: end})
+			hunk = &file.Hunks[len(file.Hunks)-1]
+		case hunk != nil && line != "":
+			hunk.Lines = append(hunk.Lines, line)
+		}
+	}
+	return files
+}
+
+// diffGitPath extracts the new path from a "diff --git a/x b/x" line.
+func diffGitPath(line string) string {
+	rest := strings.TrimPrefix(line, "diff --git ")
+	if i := strings.LastIndex(rest, " b/"); i >= 0 {
+		return rest[i+len(" b/"):]
+	}
+	return rest
+}
+
+// commitDocuments converts a commit into a document for its message followed
+// by one document per diff hunk, linked to the message document as parent.
+// Hunks of files the classifier does not find to be source, such as vendored
+// or generated ones, are skipped. Vectors are left empty.
+func commitDocuments(commit gitCommit, extractor *enrichment.StoryExtractor, classifier *FileClassifier) []vectorstore.Document {
+	refs := extractor.ExtractStoryReferences(commit.Message)
+	touched := commit.TouchedPaths()
+
+	metadata := func(docType string) map[string]interface{} {
+		m := map[string]interface{}{
+			"source_type":  CommitSourceType,
+			"type":         docType,
+			"commit_hash":  commit.Hash,
+			"author":       commit.Author,
+			"author_email": commit.AuthorEmail,
+			"date":         commit.Date.UTC().Format(time.RFC3339),
+		}
+		if len(refs["issues"]) > 0 {
+			m["issue_ids"] = refs["issues"]
+		}
+		if len(refs["prs"]) > 0 {
+			m["pr_numbers"] = refs["prs"]
+		}
+		return m
+	}
+	document := func(id, content string, metadata map[string]interface{}) vectorstore.Document {
+		return vectorstore.Document{
+			ID:        id,
+			Content:   content,
+			Metadata:  metadata,
+			CreatedAt: commit.Date,
+			UpdatedAt: commit.Date,
+			StoryIDs:  refs["issues"],
+			PRNumbers: refs["prs"],
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(commit.Message)
+	if len(touched) > 0 {
+		b.WriteString("\n\nFiles:\n")
+		for _, path := range touched {
+			fmt.Fprintf(&b, "- %s\n", path)
+		}
+	}
+	messageMetadata := metadata(commitDocumentType)
+	messageMetadata["touched_paths"] = touched
+	docs := []vectorstore.Document{document(commitDocumentID(commit.Hash), strings.TrimSpace(b.String()), messageMetadata)}
+
+	short := shortHash(commit.Hash)
+	hunks := 0
+	for _, file := range commit.Files {
+		if file.Binary || !isSourceDiff(classifier, file) {
+			continue
+		}
+		for i, hunk := range file.Hunks {
+			if hunks == maxCommitHunks {
+				return docs
+			}
+			hunks++
+
+			content := fmt.Sprintf("Commit %s: %s\nFile: %s\n%s\n%s",
+				short, commit.Subject(), file.Path, hunk.Header, strings.Join(hunk.Lines, "\n"))
+			content = truncateUTF8(content, maxHunkLength)
+
+			hunkMetadata := metadata(diffHunkDocumentType)
+			hunkMetadata[ParentIDMetadataKey] = commitDocumentID(commit.Hash)
+			hunkMetadata["diff_path"] = file.Path
+			hunkMetadata["touched_paths"] = []string{file.Path}
+			hunkMetadata["start_line"] = hunk.StartLine
+			hunkMetadata["end_line"] = hunk.EndLine
+			if file.OldPath != "" {
+				hunkMetadata["old_path"] = file.OldPath
+			}
+			docs = append(docs, document(generateChunkID(commit.Hash, diffHunkDocumentType, file.Path, i), content, hunkMetadata))
+		}
+	}
+	return docs
+}
+
+// shortHash abbreviates a commit hash for display.
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
+
+// isSourceDiff reports whether the classifier finds a changed file to be
+// source, judging by its path and the lines of its hunks.
+func isSourceDiff(classifier *FileClassifier, file fileDiff) bool {
+	if classifier == nil {
+		return true
+	}
+	var b strings.Builder
+	for _, hunk := range file.Hunks {
+		for _, line := range hunk.Lines {
+			b.WriteString(line[1:])
+			b.WriteByte('\n')
+		}
+	}
+	return classifier.Classify(file.Path, []byte(b.String())) == FileClassSource
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a rune.
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// commitDocumentID returns the document ID of a commit's message.
+func commitDocumentID(hash string) string {
+	return generateChunkID(hash, commitDocumentType, "", 0)
+}
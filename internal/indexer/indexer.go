@@ -5,6 +5,7 @@ package indexer
 import (
 	"context"
 	"io/fs"
+	"path/filepath"
 	"time"
 
 	"github.com/ferg-cod3s/conexus/internal/embedding"
@@ -43,6 +44,9 @@ const (
 	ChunkTypeComment   ChunkType = "comment"
 	ChunkTypeParagraph ChunkType = "paragraph"  // For docs
 	ChunkTypeCodeBlock ChunkType = "code_block" // For embedded code in docs
+	ChunkTypeSection   ChunkType = "section"    // Heading-delimited section of a document
+	ChunkTypeFile      ChunkType = "file"       // Generated summary of a file
+	ChunkTypePackage   ChunkType = "package"    // Generated summary of a directory or package
 	ChunkTypeUnknown   ChunkType = "unknown"
 )
 
@@ -56,6 +60,26 @@ type IndexOptions struct {
 	ChunkOverlap   int                     // Overlap between chunks (tokens)
 	Embedder       embedding.Embedder      // Optional: Embedder for generating vectors
 	VectorStore    vectorstore.VectorStore // Optional: VectorStore for storing vectors
+	SecretHandling SecretHandling          // How detected secrets are handled (default: redact)
+}
+
+// DefaultIndexOptions returns the options of an indexing run over rootPath:
+// files up to 1MB outside .git and the root's .gitignore, with git metadata.
+// Runs that start, resume, update or check the index all use them, so each
+// sees the same files.
+func DefaultIndexOptions(rootPath string, embedder embedding.Embedder, store vectorstore.VectorStore) IndexOptions {
+	ignorePatterns := []string{".git"}
+	if gitignore, err := LoadGitignore(filepath.Join(rootPath, ".gitignore"), rootPath); err == nil {
+		ignorePatterns = append(ignorePatterns, gitignore...)
+	}
+	return IndexOptions{
+		RootPath:       rootPath,
+		IgnorePatterns: ignorePatterns,
+		MaxFileSize:    1024 * 1024, // 1MB
+		IncludeGitInfo: true,
+		Embedder:       embedder,
+		VectorStore:    store,
+	}
 }
 
 // Indexer walks a file system and produces chunks with metadata.
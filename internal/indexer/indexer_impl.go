@@ -10,11 +10,15 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
+	"text/template"
 	"time"
 
 	"github.com/ferg-cod3s/conexus/internal/embedding"
+	"github.com/ferg-cod3s/conexus/internal/observability/audit"
 	"github.com/ferg-cod3s/conexus/internal/security"
+	"github.com/ferg-cod3s/conexus/internal/security/secrets"
 	"github.com/ferg-cod3s/conexus/internal/validation"
 	"github.com/ferg-cod3s/conexus/internal/vectorstore"
 )
@@ -24,9 +28,27 @@ type DefaultIndexer struct {
 	walker     Walker
 	merkleTree MerkleTree
 	chunkers   []Chunker
+	classifier *FileClassifier
 	statePath  string // Where to persist merkle tree state
 	status     IndexStatus
-	mu         sync.RWMutex // Protects status updates
+	store      vectorstore.VectorStore // Store checked against the state by HealthCheck
+	mu         sync.RWMutex            // Protects status, store and header template updates
+
+	// Context header embedded with each chunk
+	headerTemplate *template.Template
+	headersChecked bool           // Whether stored headers were compared with headerTemplate
+	secretHandling SecretHandling // Used when IndexOptions leaves SecretHandling empty
+
+	notebookOutputSize int // Cap on the text output kept per notebook cell
+
+	historyMu sync.Mutex // Serializes git history indexing runs
+	reposMu   sync.Mutex // Serializes repository indexing runs
+
+	// Secret detection
+	secretScanner  *secrets.Scanner
+	secretFindings map[string][]secrets.Finding // Findings keyed by relative file path
+	auditLogger    *audit.Logger
+	secretsMu      sync.RWMutex
 
 	// Background indexing control
 	indexingCtx    context.Context
@@ -42,8 +64,15 @@ func NewIndexer(statePath string) *DefaultIndexer {
 	return &DefaultIndexer{
 		walker:     NewFileWalker(1024 * 1024), // 1MB max file size default
 		merkleTree: NewMerkleTree(NewFileWalker(0)),
-		chunkers:   []Chunker{NewCodeChunker(2000, 200)}, // Code chunker with 2K chunks, 200 overlap
-		statePath:  statePath,
+		chunkers: []Chunker{
+			NewCodeChunker(2000, 200), // Code chunker with 2K chunks, 200 overlap
+			NewNotebookChunker(DefaultNotebookOutputSize),
+			NewMarkdownChunker(),
+		},
+		classifier:     NewFileClassifier(),
+		statePath:      statePath,
+		secretScanner:  secrets.NewScanner(),
+		secretFindings: make(map[string][]secrets.Finding),
 		status: IndexStatus{
 			IsIndexing: false,
 			Phase:      "idle",
@@ -100,25 +129,7 @@ func (idx *DefaultIndexer) Index(ctx context.Context, opts IndexOptions) ([]Chun
 			return fmt.Errorf("path validation failed for %s: %w", relPath, err)
 		}
 
-		// Find appropriate chunker
-		chunker := idx.findChunker(path)
-		if chunker == nil {
-			// No chunker available, create a single chunk for the whole file
-			chunk := idx.createSingleChunk(string(content), relPath, info)
-			chunks = append(chunks, chunk)
-			return nil
-		}
-
-		// Chunk the file
-		fileChunks, err := chunker.Chunk(ctx, string(content), relPath)
-		if err != nil {
-			// If chunking fails, fall back to single chunk
-			chunk := idx.createSingleChunk(string(content), relPath, info)
-			chunks = append(chunks, chunk)
-			return nil
-		}
-
-		chunks = append(chunks, fileChunks...)
+		chunks = append(chunks, idx.chunkFile(ctx, content, relPath, info, opts)...)
 		return nil
 	})
 
@@ -126,6 +137,17 @@ func (idx *DefaultIndexer) Index(ctx context.Context, opts IndexOptions) ([]Chun
 		return nil, fmt.Errorf("walk file system: %w", err)
 	}
 
+	symbols := fileSymbols(chunks)
+	paths := make([]string, 0, len(symbols))
+	for path := range symbols {
+		paths = append(paths, path)
+	}
+	summaries, err := packageSummaries(ctx, filesByDir(paths), symbols, nil)
+	if err != nil {
+		return nil, fmt.Errorf("summarize packages: %w", err)
+	}
+	chunks = append(chunks, summaries...)
+
 	// If embedder and vectorstore provided, generate and store vectors
 	if opts.Embedder != nil && opts.VectorStore != nil {
 		if err := idx.storeVectors(ctx, chunks, opts); err != nil {
@@ -220,26 +242,24 @@ func (idx *DefaultIndexer) IndexIncremental(ctx context.Context, opts IndexOptio
 		if len(content) == 0 {
 			continue
 		}
-		// Find appropriate chunker
-		chunker := idx.findChunker(fullPath)
-		if chunker == nil {
-			// No chunker available, create a single chunk for the whole file
-			chunk := idx.createSingleChunk(string(content), relPath, info)
-			chunks = append(chunks, chunk)
-			continue
-		}
+		chunks = append(chunks, idx.chunkFile(ctx, content, relPath, info, opts)...)
+	}
 
-		// Chunk the file
-		fileChunks, err := chunker.Chunk(ctx, string(content), relPath)
-		if err != nil {
-			// If chunking fails, fall back to single chunk
-			chunk := idx.createSingleChunk(string(content), relPath, info)
-			chunks = append(chunks, chunk)
-			continue
-		}
+	// Forget secrets reported for files that no longer exist
+	for relPath := range deletedPaths {
+		idx.recordSecrets(ctx, relPath, nil)
+	}
 
-		chunks = append(chunks, fileChunks...)
+	// Refresh the summaries of directories whose files changed
+	dirs, err := changedDirs(currentState, changedPaths, opts.MaxFileSize)
+	if err != nil {
+		return nil, nil, err
 	}
+	summaries, err := packageSummaries(ctx, dirs, fileSymbols(chunks), opts.VectorStore)
+	if err != nil {
+		return nil, nil, fmt.Errorf("summarize packages: %w", err)
+	}
+	chunks = append(chunks, summaries...)
 
 	// Handle vector store updates for incremental indexing
 	if opts.VectorStore != nil {
@@ -247,11 +267,24 @@ func (idx *DefaultIndexer) IndexIncremental(ctx context.Context, opts IndexOptio
 		if err := idx.deleteVectorsForPaths(ctx, deletedPaths, opts.VectorStore); err != nil {
 			return nil, nil, fmt.Errorf("delete vectors: %w", err)
 		}
+		for relPath := range deletedPaths {
+			removeFileText(ctx, opts.VectorStore, relPath)
+		}
+
+		// Delete summaries of directories left without files
+		for relPath := range deletedPaths {
+			dir := filepath.Dir(relPath)
+			if _, ok := dirs[dir]; !ok {
+				_ = opts.VectorStore.Delete(ctx, packageSummaryID(dir))
+			}
+		}
 
 		// Delete old vectors for changed files (will be replaced)
 		changedFilePaths := make(map[string]bool)
 		for _, chunk := range chunks {
-			changedFilePaths[chunk.FilePath] = true
+			if chunk.Type != ChunkTypePackage {
+				changedFilePaths[chunk.FilePath] = true
+			}
 		}
 		if err := idx.deleteVectorsForPaths(ctx, changedFilePaths, opts.VectorStore); err != nil {
 			return nil, nil, fmt.Errorf("delete old vectors: %w", err)
@@ -276,14 +309,11 @@ func (idx *DefaultIndexer) storeVectors(ctx context.Context, chunks []Chunk, opt
 
 	var docs []vectorstore.Document
 	for _, chunk := range chunks {
-		// Generate embedding
-		embedding, err := opts.Embedder.Embed(ctx, chunk.Content)
+		// Embed with the chunk's context header and convert to document
+		doc, err := idx.embedChunk(ctx, opts.Embedder, chunk)
 		if err != nil {
-			return fmt.Errorf("embed chunk %s: %w", chunk.ID, err)
+			return err
 		}
-
-		// Convert to document
-		doc := chunkToDocument(chunk, embedding.Vector)
 		docs = append(docs, doc)
 	}
 
@@ -295,48 +325,53 @@ func (idx *DefaultIndexer) storeVectors(ctx context.Context, chunks []Chunk, opt
 	return nil
 }
 
-// deleteVectorsForPaths removes vectors for the given file paths.
+// deleteVectorsForPaths removes the vectors of the given file paths of the
+// indexed root. Documents of other repositories under the same paths are kept.
 func (idx *DefaultIndexer) deleteVectorsForPaths(ctx context.Context, paths map[string]bool, store vectorstore.VectorStore) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	list := make([]string, 0, len(paths))
 	for path := range paths {
-		// Query by file path metadata
-		filter := map[string]interface{}{"file_path": path}
-		// Use empty vector for query (we only care about metadata filter)
-		opts := vectorstore.SearchOptions{
-			Limit:   10000,
-			Filters: filter,
-		}
-		results, err := store.SearchVector(ctx, nil, opts)
-		if err != nil {
-			// Non-fatal: log but continue
-			continue
-		}
-
-		// Delete all chunks for this file
-		for _, result := range results {
-			if err := store.Delete(ctx, result.Document.ID); err != nil {
-				// Non-fatal: log but continue
-				continue
-			}
-		}
+		list = append(list, path)
 	}
+	sort.Strings(list)
 
-	return nil
+	_, err := store.DeleteByFilter(ctx, pathsFilter(list))
+	return err
+}
+
+// pathsFilter matches the documents of the given files of the indexed root.
+func pathsFilter(paths []string) vectorstore.Filter {
+	return vectorstore.And(
+		vectorstore.InStrings("file_path", paths),
+		vectorstore.Eq(vectorstore.RepoIDKey, nil),
+	)
 }
 
 // chunkToDocument converts a Chunk to a vectorstore.Document.
 func chunkToDocument(chunk Chunk, vector embedding.Vector) vectorstore.Document {
+	metadata := make(map[string]interface{}, len(chunk.Metadata)+6)
+	for k, v := range chunk.Metadata {
+		metadata[k] = v
+	}
+	// Package summaries describe a directory rather than a file
+	if chunk.Type == ChunkTypePackage {
+		metadata["package_path"] = chunk.FilePath
+	} else {
+		metadata["file_path"] = chunk.FilePath
+	}
+	metadata["language"] = chunk.Language
+	metadata["type"] = string(chunk.Type)
+	metadata["start_line"] = chunk.StartLine
+	metadata["end_line"] = chunk.EndLine
+	metadata["hash"] = chunk.Hash
+
 	return vectorstore.Document{
-		ID:      chunk.ID,
-		Content: chunk.Content,
-		Vector:  vector,
-		Metadata: map[string]interface{}{
-			"file_path":  chunk.FilePath,
-			"language":   chunk.Language,
-			"type":       string(chunk.Type),
-			"start_line": chunk.StartLine,
-			"end_line":   chunk.EndLine,
-			"hash":       chunk.Hash,
-		},
+		ID:        chunk.ID,
+		Content:   chunk.Content,
+		Vector:    vector,
+		Metadata:  metadata,
 		CreatedAt: chunk.IndexedAt,
 		UpdatedAt: chunk.IndexedAt,
 	}
@@ -472,6 +507,13 @@ func (idx *DefaultIndexer) HealthCheck(ctx context.Context) error {
 		return fmt.Errorf("index has errors: %s", status.LastError)
 	}
 
+	// Detect and repair drift between the Merkle state and the vector store
+	if store := idx.vectorStore(); store != nil {
+		if err := idx.repairStateDivergence(ctx, store); err != nil {
+			return fmt.Errorf("state/store divergence: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -491,6 +533,11 @@ func (idx *DefaultIndexer) runBackgroundIndexing(opts IndexOptions) {
 		StartTime:  time.Now(),
 	})
 
+	// Finish a run interrupted by a crash before watching for changes
+	if _, err := idx.resumeInterruptedRun(idx.indexingCtx, opts); err != nil {
+		idx.updateStatusError(fmt.Sprintf("resume interrupted index failed: %v", err))
+	}
+
 	ticker := time.NewTicker(30 * time.Second) // Check for changes every 30 seconds
 	defer ticker.Stop()
 
@@ -533,8 +580,16 @@ func (idx *DefaultIndexer) runForceReindex(opts IndexOptions) {
 		return
 	}
 
-	// Perform full index
-	chunks, err := idx.Index(idx.indexingCtx, opts)
+	// A forced reindex starts a new run rather than resuming an old one
+	if store, ok := opts.VectorStore.(vectorstore.CheckpointStore); ok {
+		if err := store.ClearCheckpoint(idx.indexingCtx); err != nil {
+			idx.updateStatusError(fmt.Sprintf("failed to clear checkpoint: %v", err))
+			return
+		}
+	}
+
+	// Perform full index, checkpointing each batch so a crash can resume
+	stats, err := idx.IndexWithCheckpoints(idx.indexingCtx, opts)
 	if err != nil {
 		idx.updateStatusError(fmt.Sprintf("force reindex failed: %v", err))
 		return
@@ -544,15 +599,10 @@ func (idx *DefaultIndexer) runForceReindex(opts IndexOptions) {
 		IsIndexing:     false,
 		Phase:          "completed",
 		Progress:       100,
-		FilesProcessed: len(chunks),
-		ChunksCreated:  len(chunks),
-		Metrics: IndexMetrics{
-			TotalFiles:     len(chunks),
-			IndexedFiles:   len(chunks),
-			TotalChunks:    len(chunks),
-			Duration:       time.Since(time.Now()),
-			BytesProcessed: 0, // TODO: calculate
-		},
+		FilesProcessed: stats.IndexedFiles + stats.SkippedFiles,
+		TotalFiles:     stats.TotalFiles,
+		ChunksCreated:  stats.TotalChunks,
+		Metrics:        stats.ToMetrics(),
 	})
 }
 
@@ -613,13 +663,17 @@ func (idx *DefaultIndexer) runReindexPaths(opts IndexOptions, paths []string) {
 			continue
 		}
 
-		chunk := idx.createSingleChunk(string(content), relPath, info)
-		totalChunks++
+		chunks := idx.chunkFile(idx.indexingCtx, content, relPath, info, opts)
+		totalChunks += len(chunks)
 
-		// Store in vector store if available
+		// Replace the file's chunks in the vector store if available
 		if opts.VectorStore != nil && opts.Embedder != nil {
-			if err := idx.storeSingleChunk(idx.indexingCtx, chunk, opts); err != nil {
-				idx.updateStatusError(fmt.Sprintf("failed to store chunk for %s: %v", path, err))
+			if err := idx.deleteVectorsForPaths(idx.indexingCtx, map[string]bool{relPath: true}, opts.VectorStore); err != nil {
+				idx.updateStatusError(fmt.Sprintf("failed to delete old chunks for %s: %v", path, err))
+				continue
+			}
+			if err := idx.storeVectors(idx.indexingCtx, chunks, opts); err != nil {
+				idx.updateStatusError(fmt.Sprintf("failed to store chunks for %s: %v", path, err))
 			}
 		}
 	}
@@ -658,6 +712,11 @@ func (idx *DefaultIndexer) performIncrementalIndex(opts IndexOptions) error {
 		return fmt.Errorf("save state: %w", err)
 	}
 
+	// Re-embed chunks indexed with a different header template
+	if err := idx.reembedStaleHeadersOnce(idx.indexingCtx, opts); err != nil {
+		return fmt.Errorf("re-embed stale headers: %w", err)
+	}
+
 	// Update status
 	idx.mu.Lock()
 	idx.status.FilesProcessed += len(chunks)
@@ -668,41 +727,6 @@ func (idx *DefaultIndexer) performIncrementalIndex(opts IndexOptions) error {
 	return nil
 }
 
-// storeSingleChunk stores a single chunk in the vector store.
-func (idx *DefaultIndexer) storeSingleChunk(ctx context.Context, chunk Chunk, opts IndexOptions) error {
-	// Generate embedding
-	embedding, err := opts.Embedder.Embed(ctx, chunk.Content)
-	if err != nil {
-		return fmt.Errorf("embed chunk: %w", err)
-	}
-
-	// Convert to document
-	doc := chunkToDocument(chunk, embedding.Vector)
-
-	// Delete existing chunk for this file (if any)
-	filter := map[string]interface{}{"file_path": chunk.FilePath}
-	optsSearch := vectorstore.SearchOptions{
-		Limit:   1000,
-		Filters: filter,
-	}
-	results, err := opts.VectorStore.SearchVector(ctx, nil, optsSearch)
-	if err == nil {
-		for _, result := range results {
-			if err := opts.VectorStore.Delete(ctx, result.Document.ID); err != nil {
-				// Log but continue
-				continue
-			}
-		}
-	}
-
-	// Store new chunk
-	if err := opts.VectorStore.UpsertBatch(ctx, []vectorstore.Document{doc}); err != nil {
-		return fmt.Errorf("upsert batch: %w", err)
-	}
-
-	return nil
-}
-
 // updateStatus safely updates the indexing status.
 func (idx *DefaultIndexer) updateStatus(status IndexStatus) {
 	idx.mu.Lock()
@@ -748,6 +772,95 @@ func (idx *DefaultIndexer) findChunker(path string) Chunker {
 	return nil
 }
 
+// Helper: chunkFile splits a file into chunks with splitFile, records its
+// text in the store's trigram index and its secrets in the audit log.
+func (idx *DefaultIndexer) chunkFile(ctx context.Context, content []byte, relPath string, info os.FileInfo, opts IndexOptions) []Chunk {
+	split := idx.splitFile(ctx, content, relPath, info, idx.secretMode(opts))
+	indexFileText(ctx, opts.VectorStore, relPath, split.text)
+	idx.recordSecrets(ctx, relPath, split.findings)
+	return split.chunks
+}
+
+// splitResult is a file split into chunks, with the text recorded for it in
+// the trigram index and the secrets found in it.
+type splitResult struct {
+	chunks   []Chunk
+	text     string
+	findings []secrets.Finding
+}
+
+// Helper: splitFile classifies a file, scans it for secrets and splits it into
+// chunks tagged with its classification, without side effects. Binary files
+// get a single descriptive chunk instead of their raw bytes.
+func (idx *DefaultIndexer) splitFile(ctx context.Context, content []byte, relPath string, info os.FileInfo, secretMode SecretHandling) splitResult {
+	class := FileClassSource
+	if idx.classifier != nil {
+		class = idx.classifier.Classify(relPath, content)
+	}
+
+	var result splitResult
+	if class == FileClassBinary {
+		summary := fmt.Sprintf("[binary file %s, %d bytes]", relPath, len(content))
+		chunk := idx.createSingleChunk(summary, relPath, info)
+		chunk.Language = "binary"
+		result.chunks = []Chunk{chunk}
+	} else {
+		text, findings := idx.scanSecrets(string(content), relPath, secretMode)
+		result.text, result.findings = text, findings
+		if len(findings) > 0 {
+			// Quarantined files keep their text, but secrets never reach the index
+			result.text = secrets.Redact(string(content), findings)
+		}
+
+		if chunker := idx.findChunker(relPath); chunker == nil {
+			// No chunker available, create a single chunk for the whole file
+			result.chunks = []Chunk{idx.createSingleChunk(text, relPath, info)}
+		} else if fileChunks, err := chunker.Chunk(ctx, text, relPath); err != nil {
+			// If chunking fails, fall back to single chunk
+			result.chunks = []Chunk{idx.createSingleChunk(text, relPath, info)}
+		} else {
+			result.chunks = fileChunks
+		}
+
+		if secretMode == SecretHandlingQuarantine {
+			result.chunks = idx.quarantineChunks(result.chunks)
+		}
+	}
+	result.chunks = linkChunks(result.chunks, relPath)
+	annotateEnclosingTypes(result.chunks)
+
+	for i := range result.chunks {
+		if result.chunks[i].Metadata == nil {
+			result.chunks[i].Metadata = make(map[string]string)
+		}
+		result.chunks[i].Metadata[ClassificationMetadataKey] = string(class)
+	}
+	return result
+}
+
+// indexFileText records the text of a file in the trigram index of store, if
+// it keeps one, so context.grep only reads the files that can match. A file
+// whose text cannot be recorded is removed from the index instead, which
+// makes it a candidate for every search.
+func indexFileText(ctx context.Context, store vectorstore.VectorStore, relPath, text string) {
+	index, ok := store.(vectorstore.TrigramIndex)
+	if !ok {
+		return
+	}
+	if err := index.IndexFileText(ctx, relPath, text); err != nil {
+		// Non-fatal: a stale entry would hide matches, an absent one does not
+		_ = index.RemoveFileText(ctx, relPath)
+	}
+}
+
+// removeFileText removes a deleted file from the trigram index of store.
+func removeFileText(ctx context.Context, store vectorstore.VectorStore, relPath string) {
+	if index, ok := store.(vectorstore.TrigramIndex); ok {
+		// Non-fatal: deleted files are skipped when matches are verified
+		_ = index.RemoveFileText(ctx, relPath)
+	}
+}
+
 // Helper: createSingleChunk creates a single chunk for an entire file.
 func (idx *DefaultIndexer) createSingleChunk(content, relPath string, info os.FileInfo) Chunk {
 	hash := sha256.Sum256([]byte(content))
@@ -805,6 +918,8 @@ func detectLanguage(path string) string {
 		return "json"
 	case ".toml":
 		return "toml"
+	case ".ipynb":
+		return "jupyter"
 	default:
 		return "unknown"
 	}
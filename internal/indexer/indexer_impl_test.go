@@ -19,7 +19,7 @@ func TestNewIndexer(t *testing.T) {
 	assert.NotNil(t, idx.walker)
 	assert.NotNil(t, idx.merkleTree)
 	assert.Equal(t, "/tmp/test-state.json", idx.statePath)
-	assert.Len(t, idx.chunkers, 1) // Should have the code chunker
+	assert.Len(t, idx.chunkers, 3) // Should have the code, notebook and markdown chunkers
 }
 
 func TestIndexFullScan(t *testing.T) {
@@ -59,6 +59,7 @@ func TestIndexFullScan(t *testing.T) {
 	require.NoError(t, err)
 
 	// Verify chunks
+	chunks = contentChunks(chunks)
 	assert.Len(t, chunks, 5, "should create 5 chunks for 5 files")
 
 	// Verify chunk contents
@@ -116,6 +117,7 @@ func TestIndexWithIgnorePatterns(t *testing.T) {
 	require.NoError(t, err)
 
 	// Should only index main.go
+	chunks = contentChunks(chunks)
 	assert.Len(t, chunks, 1)
 	assert.Equal(t, "main.go", chunks[0].FilePath)
 }
@@ -144,7 +146,7 @@ func TestIndexIncremental_FirstRun(t *testing.T) {
 	require.NoError(t, err)
 
 	// Should perform full index
-	assert.Len(t, chunks, 1)
+	assert.Len(t, contentChunks(chunks), 1)
 	assert.NotNil(t, newState)
 	assert.Greater(t, len(newState), 0)
 }
@@ -215,6 +217,7 @@ func TestIndexIncremental_WithChanges(t *testing.T) {
 	require.NoError(t, err)
 
 	// Should only index the modified file
+	chunks = contentChunks(chunks)
 	assert.Len(t, chunks, 1, "should only reindex changed file")
 	assert.Equal(t, "file1.go", chunks[0].FilePath)
 	assert.NotEqual(t, state1, state2)
@@ -500,6 +503,7 @@ func TestIndexWithMaxFileSize(t *testing.T) {
 	require.NoError(t, err)
 
 	// Should only index small file
+	chunks = contentChunks(chunks)
 	assert.Len(t, chunks, 1)
 	assert.Equal(t, "small.txt", chunks[0].FilePath)
 }
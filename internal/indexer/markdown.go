@@ -0,0 +1,119 @@
+package indexer
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// markdownHeading matches an ATX heading such as "## Install".
+var markdownHeading = regexp.MustCompile(`^ {0,3}(#{1,6})[ \t]+(.+?)[ \t#]*$`)
+
+// MarkdownChunker splits Markdown documents into one chunk per heading section.
+// Each section holds the text up to the next heading of any level and is
+// linked to the section of the nearest enclosing heading.
+type MarkdownChunker struct{}
+
+// NewMarkdownChunker creates a Markdown chunker.
+func NewMarkdownChunker() *MarkdownChunker {
+	return &MarkdownChunker{}
+}
+
+// Supports returns true if this chunker handles the given file extension.
+func (c *MarkdownChunker) Supports(fileExtension string) bool {
+	switch strings.ToLower(fileExtension) {
+	case ".md", ".markdown", ".mdx":
+		return true
+	default:
+		return false
+	}
+}
+
+// markdownSection is a heading and the lines that follow it.
+type markdownSection struct {
+	heading   string
+	level     int
+	startLine int
+	lines     []string
+}
+
+// markdownSectionRef identifies an emitted section that may enclose later ones.
+type markdownSectionRef struct {
+	level int
+	id    string
+	path  string // Headings from the outermost section, joined with " > "
+}
+
+// Chunk emits one chunk per section. Text before the first heading becomes a
+// top-level section without a heading. Headings inside fenced code blocks are
+// ignored.
+func (c *MarkdownChunker) Chunk(ctx context.Context, content string, filePath string) ([]Chunk, error) {
+	var sections []*markdownSection
+	current := &markdownSection{startLine: 1}
+	fence := ""
+
+	for i, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if fence == "" && (strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")) {
+			fence = trimmed[:3]
+		} else if fence != "" && strings.HasPrefix(trimmed, fence) {
+			fence = ""
+		} else if fence == "" {
+			if m := markdownHeading.FindStringSubmatch(line); m != nil {
+				sections = append(sections, current)
+				current = &markdownSection{heading: m[2], level: len(m[1]), startLine: i + 1}
+			}
+		}
+		current.lines = append(current.lines, line)
+	}
+	sections = append(sections, current)
+
+	var chunks []Chunk
+	var open []markdownSectionRef
+	for _, s := range sections {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		text := strings.TrimRight(strings.Join(s.lines, "\n"), "\n")
+		if s.heading == "" && strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		metadata := map[string]string{}
+		id := generateChunkID(filePath, string(ChunkTypeSection), s.heading, s.startLine)
+		if s.heading != "" {
+			for len(open) > 0 && open[len(open)-1].level >= s.level {
+				open = open[:len(open)-1]
+			}
+			path := s.heading
+			if len(open) > 0 {
+				parent := open[len(open)-1]
+				metadata[ParentIDMetadataKey] = parent.id
+				path = parent.path + " > " + s.heading
+			}
+			open = append(open, markdownSectionRef{level: s.level, id: id, path: path})
+
+			metadata["heading"] = s.heading
+			metadata["heading_level"] = strconv.Itoa(s.level)
+			metadata["section_path"] = path
+		}
+
+		chunks = append(chunks, Chunk{
+			ID:        id,
+			Content:   text,
+			FilePath:  filePath,
+			Language:  "markdown",
+			Type:      ChunkTypeSection,
+			StartLine: s.startLine,
+			EndLine:   s.startLine + countLines(text) - 1,
+			Metadata:  metadata,
+			Hash:      generateContentHash(text),
+			IndexedAt: time.Now(),
+		})
+	}
+
+	return chunks, nil
+}
@@ -0,0 +1,44 @@
+package indexer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testMarkdown = "Intro text.\n\n# Guide\nOverview.\n\n## Install\nRun it.\n\n```sh\n# not a heading\n```\n\n### Linux\nUse apt.\n\n## Usage\nCall it.\n\n# Appendix\n"
+
+func TestMarkdownChunkerSupports(t *testing.T) {
+	chunker := NewMarkdownChunker()
+
+	assert.True(t, chunker.Supports(".md"))
+	assert.True(t, chunker.Supports(".MARKDOWN"))
+	assert.False(t, chunker.Supports(".go"))
+}
+
+func TestMarkdownChunker_Sections(t *testing.T) {
+	chunks, err := NewMarkdownChunker().Chunk(context.Background(), testMarkdown, "docs/guide.md")
+	require.NoError(t, err)
+	require.Len(t, chunks, 6)
+
+	assert.Equal(t, "Intro text.", chunks[0].Content)
+	assert.Empty(t, chunks[0].Metadata["heading"])
+
+	headings := make(map[string]Chunk)
+	for _, chunk := range chunks[1:] {
+		assert.Equal(t, ChunkTypeSection, chunk.Type)
+		headings[chunk.Metadata["heading"]] = chunk
+	}
+	require.Contains(t, headings, "Install")
+	assert.Contains(t, headings["Install"].Content, "# not a heading", "fenced code stays in its section")
+	assert.Equal(t, 6, headings["Install"].StartLine)
+	assert.Equal(t, 11, headings["Install"].EndLine)
+
+	assert.Equal(t, headings["Guide"].ID, headings["Install"].Metadata[ParentIDMetadataKey])
+	assert.Equal(t, headings["Install"].ID, headings["Linux"].Metadata[ParentIDMetadataKey])
+	assert.Equal(t, headings["Guide"].ID, headings["Usage"].Metadata[ParentIDMetadataKey])
+	assert.Empty(t, headings["Appendix"].Metadata[ParentIDMetadataKey])
+	assert.Equal(t, "Guide > Install > Linux", headings["Linux"].Metadata["section_path"])
+}
@@ -297,3 +297,64 @@ func computeFileHash(path string, basePath string) (string, error) {
 
 	return hex.EncodeToString(h.Sum(nil)), nil
 }
+
+// stateFiles returns the size of every file recorded in a serialized tree state,
+// keyed by relative path.
+func stateFiles(state []byte) (map[string]int64, error) {
+	var ts treeState
+	if err := json.Unmarshal(state, &ts); err != nil {
+		return nil, fmt.Errorf("failed to deserialize state: %w", err)
+	}
+
+	files := make(map[string]int64)
+	var collect func(node *treeNode)
+	collect = func(node *treeNode) {
+		if node == nil {
+			return
+		}
+		if node.IsFile {
+			files[node.Path] = node.Size
+			return
+		}
+		for _, child := range node.Children {
+			collect(child)
+		}
+	}
+	collect(ts.Root)
+
+	return files, nil
+}
+
+// removeStateFiles drops files from a serialized tree state and recomputes the
+// directory hashes, so that the next Diff reports those files as added.
+func removeStateFiles(state []byte, paths []string) ([]byte, error) {
+	var ts treeState
+	if err := json.Unmarshal(state, &ts); err != nil {
+		return nil, fmt.Errorf("failed to deserialize state: %w", err)
+	}
+	if ts.Root == nil {
+		return state, nil
+	}
+
+	for _, path := range paths {
+		parts := strings.Split(path, "/")
+		current := ts.Root
+		for _, part := range parts[:len(parts)-1] {
+			if current = current.Children[part]; current == nil {
+				break
+			}
+		}
+		if current != nil {
+			delete(current.Children, parts[len(parts)-1])
+		}
+	}
+
+	mt := &merkleTree{}
+	mt.computeDirectoryHashes(ts.Root)
+
+	data, err := json.Marshal(ts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize tree state: %w", err)
+	}
+	return data, nil
+}
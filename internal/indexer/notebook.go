@@ -0,0 +1,291 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultNotebookOutputSize is the default cap on text output kept per cell.
+// Zero strips outputs entirely.
+const DefaultNotebookOutputSize = 0
+
+// NotebookChunker splits Jupyter notebooks into one chunk per markdown or code cell.
+type NotebookChunker struct {
+	maxOutputSize int // Maximum bytes of text output kept per cell (0 = strip outputs)
+}
+
+// NewNotebookChunker creates a notebook chunker that keeps up to maxOutputSize
+// bytes of text output per code cell. Outputs are stripped when maxOutputSize <= 0.
+func NewNotebookChunker(maxOutputSize int) *NotebookChunker {
+	if maxOutputSize < 0 {
+		maxOutputSize = 0
+	}
+	return &NotebookChunker{
+		maxOutputSize: maxOutputSize,
+	}
+}
+
+// SetNotebookOutputSize sets the cap on the text output kept per notebook
+// cell, in bytes. Zero strips outputs. Call it before indexing.
+func (idx *DefaultIndexer) SetNotebookOutputSize(size int) {
+	idx.notebookOutputSize = size
+	for i, chunker := range idx.chunkers {
+		if _, ok := chunker.(*NotebookChunker); ok {
+			idx.chunkers[i] = NewNotebookChunker(size)
+		}
+	}
+}
+
+// Supports returns true if this chunker handles the given file extension.
+func (c *NotebookChunker) Supports(fileExtension string) bool {
+	return strings.ToLower(fileExtension) == ".ipynb"
+}
+
+// notebook mirrors the subset of the nbformat v4 schema used for indexing.
+type notebook struct {
+	Metadata struct {
+		Kernelspec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	} `json:"metadata"`
+	Cells []notebookCell `json:"cells"`
+}
+
+type notebookCell struct {
+	CellType       string           `json:"cell_type"`
+	Source         notebookText     `json:"source"`
+	ExecutionCount *int             `json:"execution_count"`
+	Outputs        []notebookOutput `json:"outputs"`
+}
+
+type notebookOutput struct {
+	OutputType string                  `json:"output_type"`
+	Text       notebookText            `json:"text"`
+	Data       map[string]notebookText `json:"data"`
+}
+
+// notebookText accepts both the string and list-of-lines forms allowed by nbformat.
+type notebookText string
+
+// UnmarshalJSON decodes either a JSON string or an array of strings.
+func (t *notebookText) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*t = notebookText(s)
+		return nil
+	}
+
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err != nil {
+		return fmt.Errorf("notebook text must be a string or list of strings: %w", err)
+	}
+	*t = notebookText(strings.Join(lines, ""))
+	return nil
+}
+
+// notebookBlock is a rendered cell with its position in the rendered notebook.
+type notebookBlock struct {
+	cell      notebookCell
+	index     int
+	content   string
+	startLine int
+	endLine   int
+	truncated bool
+}
+
+// Chunk parses a notebook and emits one chunk per non-empty markdown or code cell.
+// Line numbers refer to the rendered source produced by RenderNotebook.
+func (c *NotebookChunker) Chunk(ctx context.Context, content string, filePath string) ([]Chunk, error) {
+	nb, err := parseNotebook(content)
+	if err != nil {
+		return nil, err
+	}
+
+	language := notebookLanguage(nb)
+	blocks := c.renderBlocks(nb, language)
+
+	chunks := make([]Chunk, 0, len(blocks))
+	for _, b := range blocks {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		chunkType := ChunkTypeCodeBlock
+		if b.cell.CellType == "markdown" {
+			chunkType = ChunkTypeParagraph
+		}
+
+		metadata := map[string]string{
+			"cell_index":      strconv.Itoa(b.index),
+			"cell_type":       b.cell.CellType,
+			"kernel_language": language,
+		}
+		if b.cell.ExecutionCount != nil {
+			metadata["execution_count"] = strconv.Itoa(*b.cell.ExecutionCount)
+		}
+		if b.truncated {
+			metadata["output_truncated"] = "true"
+		}
+
+		chunks = append(chunks, Chunk{
+			ID:        generateChunkID(filePath, "cell", strconv.Itoa(b.index), b.startLine),
+			Content:   b.content,
+			FilePath:  filePath,
+			Language:  language,
+			Type:      chunkType,
+			StartLine: b.startLine,
+			EndLine:   b.endLine,
+			Metadata:  metadata,
+			Hash:      generateContentHash(b.content),
+			IndexedAt: time.Now(),
+		})
+	}
+
+	return chunks, nil
+}
+
+// RenderNotebook converts raw notebook JSON into readable percent-format source,
+// with markdown cells commented out and outputs stripped.
+func RenderNotebook(content string) (string, error) {
+	nb, err := parseNotebook(content)
+	if err != nil {
+		return "", err
+	}
+
+	blocks := NewNotebookChunker(0).renderBlocks(nb, notebookLanguage(nb))
+	parts := make([]string, 0, len(blocks))
+	for _, b := range blocks {
+		parts = append(parts, b.content)
+	}
+	return strings.Join(parts, "\n\n"), nil
+}
+
+// renderBlocks renders every indexable cell, tracking line numbers as if the
+// blocks were joined with a blank line between them.
+func (c *NotebookChunker) renderBlocks(nb *notebook, language string) []notebookBlock {
+	prefix := commentPrefix(language)
+
+	var blocks []notebookBlock
+	line := 1
+	for i, cell := range nb.Cells {
+		if cell.CellType != "markdown" && cell.CellType != "code" {
+			continue
+		}
+		source := strings.TrimRight(string(cell.Source), "\n")
+		if strings.TrimSpace(source) == "" {
+			continue
+		}
+
+		var sb strings.Builder
+		truncated := false
+		if cell.CellType == "markdown" {
+			sb.WriteString(prefix + " %% [markdown]\n")
+			sb.WriteString(commentLines(source, prefix))
+		} else {
+			sb.WriteString(prefix + " %%\n")
+			sb.WriteString(source)
+			if output, cut := c.cellOutput(cell); output != "" {
+				sb.WriteString("\n" + prefix + " Output:\n")
+				sb.WriteString(commentLines(output, prefix))
+				truncated = cut
+			}
+		}
+
+		rendered := sb.String()
+		lines := countLines(rendered)
+		blocks = append(blocks, notebookBlock{
+			cell:      cell,
+			index:     i,
+			content:   rendered,
+			startLine: line,
+			endLine:   line + lines - 1,
+			truncated: truncated,
+		})
+		line += lines + 1 // Blank separator line
+	}
+
+	return blocks
+}
+
+// cellOutput collects text outputs for a code cell up to the configured cap.
+// Returns the collected text and whether it was truncated.
+func (c *NotebookChunker) cellOutput(cell notebookCell) (string, bool) {
+	if c.maxOutputSize <= 0 || len(cell.Outputs) == 0 {
+		return "", false
+	}
+
+	var sb strings.Builder
+	for _, out := range cell.Outputs {
+		switch out.OutputType {
+		case "stream":
+			sb.WriteString(string(out.Text))
+		case "execute_result", "display_data":
+			if text, ok := out.Data["text/plain"]; ok {
+				sb.WriteString(string(text))
+				if !strings.HasSuffix(string(text), "\n") {
+					sb.WriteString("\n")
+				}
+			}
+		}
+	}
+
+	output := strings.TrimRight(sb.String(), "\n")
+	if len(output) <= c.maxOutputSize {
+		return output, false
+	}
+	return truncateUTF8(output, c.maxOutputSize), true
+}
+
+// parseNotebook decodes notebook JSON.
+func parseNotebook(content string) (*notebook, error) {
+	var nb notebook
+	if err := json.Unmarshal([]byte(content), &nb); err != nil {
+		return nil, fmt.Errorf("parse notebook: %w", err)
+	}
+	return &nb, nil
+}
+
+// notebookLanguage returns the kernel language declared by the notebook.
+func notebookLanguage(nb *notebook) string {
+	if lang := nb.Metadata.Kernelspec.Language; lang != "" {
+		return strings.ToLower(lang)
+	}
+	if lang := nb.Metadata.LanguageInfo.Name; lang != "" {
+		return strings.ToLower(lang)
+	}
+	return "python"
+}
+
+// commentPrefix returns the line comment marker for a kernel language.
+func commentPrefix(language string) string {
+	switch language {
+	case "javascript", "typescript", "java", "scala", "kotlin", "c++", "cpp", "c", "c#", "csharp", "go", "rust", "swift":
+		return "//"
+	case "sql", "haskell", "lua":
+		return "--"
+	case "matlab", "octave":
+		return "%"
+	default:
+		return "#"
+	}
+}
+
+// commentLines prefixes every line of text with the comment marker.
+func commentLines(text, prefix string) string {
+	lines := strings.Split(text, "\n")
+	for i, l := range lines {
+		if l == "" {
+			lines[i] = prefix
+		} else {
+			lines[i] = prefix + " " + l
+		}
+	}
+	return strings.Join(lines, "\n")
+}
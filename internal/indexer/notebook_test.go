@@ -0,0 +1,133 @@
+package indexer
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testNotebook = `{
+  "metadata": {"kernelspec": {"language": "python", "name": "python3"}},
+  "nbformat": 4,
+  "cells": [
+    {"cell_type": "markdown", "source": ["# Analysis\n", "Load the data."]},
+    {"cell_type": "code", "execution_count": 3, "source": "import pandas as pd\ndf = pd.read_csv('x.csv')",
+     "outputs": [{"output_type": "stream", "name": "stdout", "text": ["loaded 42 rows\n"]}]},
+    {"cell_type": "raw", "source": "ignored"},
+    {"cell_type": "code", "execution_count": null, "source": [], "outputs": []},
+    {"cell_type": "code", "execution_count": 4, "source": "df.head()",
+     "outputs": [{"output_type": "execute_result", "data": {"text/plain": ["   a  b\n", "0  1  2"]}}]}
+  ]
+}`
+
+func TestNotebookChunkerSupports(t *testing.T) {
+	chunker := NewNotebookChunker(0)
+
+	assert.True(t, chunker.Supports(".ipynb"))
+	assert.True(t, chunker.Supports(".IPYNB"))
+	assert.False(t, chunker.Supports(".json"))
+	assert.False(t, chunker.Supports(".py"))
+}
+
+func TestNotebookChunkerChunk(t *testing.T) {
+	chunker := NewNotebookChunker(0)
+
+	chunks, err := chunker.Chunk(context.Background(), testNotebook, "analysis.ipynb")
+	require.NoError(t, err)
+	require.Len(t, chunks, 3, "raw and empty cells should be skipped")
+
+	md := chunks[0]
+	assert.Equal(t, ChunkTypeParagraph, md.Type)
+	assert.Equal(t, "python", md.Language)
+	assert.Equal(t, "0", md.Metadata["cell_index"])
+	assert.Equal(t, "markdown", md.Metadata["cell_type"])
+	assert.Equal(t, "python", md.Metadata["kernel_language"])
+	assert.NotContains(t, md.Metadata, "execution_count")
+	assert.Equal(t, "# %% [markdown]\n# # Analysis\n# Load the data.", md.Content)
+	assert.Equal(t, 1, md.StartLine)
+	assert.Equal(t, 3, md.EndLine)
+
+	code := chunks[1]
+	assert.Equal(t, ChunkTypeCodeBlock, code.Type)
+	assert.Equal(t, "1", code.Metadata["cell_index"])
+	assert.Equal(t, "3", code.Metadata["execution_count"])
+	assert.NotContains(t, code.Content, "loaded 42 rows", "outputs should be stripped by default")
+	assert.Equal(t, 5, code.StartLine)
+	assert.Equal(t, 7, code.EndLine)
+
+	assert.Equal(t, "4", chunks[2].Metadata["cell_index"])
+}
+
+func TestNotebookChunkerKeepsOutputsUpToCap(t *testing.T) {
+	chunker := NewNotebookChunker(10)
+
+	chunks, err := chunker.Chunk(context.Background(), testNotebook, "analysis.ipynb")
+	require.NoError(t, err)
+	require.Len(t, chunks, 3)
+
+	assert.Contains(t, chunks[1].Content, "# Output:\n# loaded 42")
+	assert.NotContains(t, chunks[1].Content, "rows")
+	assert.Equal(t, "true", chunks[1].Metadata["output_truncated"])
+
+	chunker = NewNotebookChunker(1000)
+	chunks, err = chunker.Chunk(context.Background(), testNotebook, "analysis.ipynb")
+	require.NoError(t, err)
+	assert.Contains(t, chunks[2].Content, "#    a  b\n# 0  1  2")
+	assert.NotContains(t, chunks[2].Metadata, "output_truncated")
+}
+
+func TestNotebookChunkerTruncatesOutputOnRuneBoundary(t *testing.T) {
+	nb := `{"cells": [{"cell_type": "code", "source": "print(t)",
+	  "outputs": [{"output_type": "stream", "text": "température"}]}]}`
+
+	chunks, err := NewNotebookChunker(5).Chunk(context.Background(), nb, "t.ipynb")
+	require.NoError(t, err)
+	require.Len(t, chunks, 1)
+	assert.Contains(t, chunks[0].Content, "# Output:\n# temp")
+	assert.True(t, utf8.ValidString(chunks[0].Content))
+}
+
+func TestSetNotebookOutputSize(t *testing.T) {
+	idx := NewIndexer("")
+	idx.SetNotebookOutputSize(1000)
+
+	chunks, err := idx.findChunker("analysis.ipynb").Chunk(context.Background(), testNotebook, "analysis.ipynb")
+	require.NoError(t, err)
+	assert.Contains(t, chunks[1].Content, "# loaded 42 rows")
+}
+
+func TestNotebookChunkerInvalidJSON(t *testing.T) {
+	chunker := NewNotebookChunker(0)
+
+	_, err := chunker.Chunk(context.Background(), "not a notebook", "broken.ipynb")
+	assert.Error(t, err)
+}
+
+func TestRenderNotebook(t *testing.T) {
+	rendered, err := RenderNotebook(testNotebook)
+	require.NoError(t, err)
+
+	assert.False(t, strings.HasPrefix(rendered, "{"), "rendered notebook should not be raw JSON")
+
+	chunks, err := NewNotebookChunker(0).Chunk(context.Background(), testNotebook, "analysis.ipynb")
+	require.NoError(t, err)
+
+	lines := strings.Split(rendered, "\n")
+	for _, chunk := range chunks {
+		got := strings.Join(lines[chunk.StartLine-1:chunk.EndLine], "\n")
+		assert.Equal(t, chunk.Content, got, "chunk line range should match rendered source")
+	}
+}
+
+func TestNotebookCommentPrefix(t *testing.T) {
+	nb := strings.Replace(testNotebook, `"language": "python"`, `"language": "javascript"`, 1)
+
+	chunks, err := NewNotebookChunker(0).Chunk(context.Background(), nb, "app.ipynb")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(chunks[0].Content, "// %% [markdown]\n// # Analysis"))
+	assert.Equal(t, "javascript", chunks[0].Language)
+}
@@ -0,0 +1,158 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+)
+
+// RepositoryStats summarizes a repository indexing run.
+type RepositoryStats struct {
+	Repository vectorstore.Repository // The repository as recorded after the run
+	Chunks     int                    // Chunks indexed in this run
+	FullIndex  bool                   // Whether the repository had no previous state
+}
+
+// RepositoryIndexer is implemented by indexers that index additional
+// repositories next to the default root. Each repository is stored in its
+// own namespace of the vector store and keeps its own Merkle state, so
+// repositories with identical file paths do not collide.
+type RepositoryIndexer interface {
+	// IndexRepository records repo in opts.VectorStore and indexes the files
+	// changed since its previous run, or all files when the repository is new
+	// or moved to another root. opts supplies the embedder, store and file size
+	// limit; its root path and ignore patterns are replaced by the repository's.
+	IndexRepository(ctx context.Context, repo vectorstore.Repository, opts IndexOptions) (*RepositoryStats, error)
+
+	// RemoveRepository deletes a repository's documents and state.
+	RemoveRepository(ctx context.Context, repoID string, store vectorstore.VectorStore) error
+}
+
+// RepoStateDir returns the directory holding the Merkle state of
+// repositories, next to the default root's state file.
+func RepoStateDir(statePath string) string {
+	return filepath.Join(filepath.Dir(statePath), "repos")
+}
+
+// RepoStatePath returns the Merkle state file of a repository.
+func RepoStatePath(statePath, repoID string) string {
+	return filepath.Join(RepoStateDir(statePath), repoID+".json")
+}
+
+// IndexRepository records repo in the store and incrementally indexes it.
+func (idx *DefaultIndexer) IndexRepository(ctx context.Context, repo vectorstore.Repository, opts IndexOptions) (*RepositoryStats, error) {
+	if err := vectorstore.ValidateRepoID(repo.ID); err != nil {
+		return nil, err
+	}
+	repos, ok := opts.VectorStore.(vectorstore.RepositoryStore)
+	if !ok {
+		return nil, fmt.Errorf("vector store does not support repositories")
+	}
+
+	root, err := filepath.Abs(repo.RootPath)
+	if err != nil {
+		return nil, fmt.Errorf("resolve root path: %w", err)
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("repository root: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("repository root %s is not a directory", root)
+	}
+	repo.RootPath = root
+
+	idx.reposMu.Lock()
+	defer idx.reposMu.Unlock()
+
+	existing, err := repos.GetRepository(ctx, repo.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get repository: %w", err)
+	}
+	if existing != nil {
+		repo.CreatedAt = existing.CreatedAt
+		repo.LastIndexedAt = existing.LastIndexedAt
+	}
+	if existing == nil || existing.RootPath != root {
+		// State left over from a removed repository or another checkout
+		if err := os.Remove(RepoStatePath(idx.statePath, repo.ID)); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("remove stale repository state: %w", err)
+		}
+	}
+	if err := repos.AddRepository(ctx, repo); err != nil {
+		return nil, fmt.Errorf("add repository: %w", err)
+	}
+
+	ignorePatterns := append([]string{".git"}, repo.IgnorePatterns...)
+	if gitignore, err := LoadGitignore(filepath.Join(root, ".gitignore"), root); err == nil {
+		ignorePatterns = append(ignorePatterns, gitignore...)
+	}
+	repoOpts := opts
+	repoOpts.RootPath = root
+	repoOpts.IgnorePatterns = ignorePatterns
+	repoOpts.VectorStore = vectorstore.ScopeToRepo(opts.VectorStore, repo.ID)
+
+	// A separate indexer keeps the repository's state apart from the default root's
+	repoIdx := NewIndexer(RepoStatePath(idx.statePath, repo.ID))
+	idx.mu.RLock()
+	repoIdx.headerTemplate = idx.headerTemplate
+	repoIdx.secretHandling = idx.secretHandling
+	idx.mu.RUnlock()
+	repoIdx.SetNotebookOutputSize(idx.notebookOutputSize)
+
+	previousState, err := repoIdx.LoadState(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load repository state: %w", err)
+	}
+	chunks, state, err := repoIdx.IndexIncremental(ctx, repoOpts, previousState)
+	if err != nil {
+		return nil, fmt.Errorf("index repository %s: %w", repo.ID, err)
+	}
+	if err := repoIdx.SaveState(ctx, state); err != nil {
+		return nil, fmt.Errorf("save repository state: %w", err)
+	}
+
+	repo.LastIndexedAt = time.Now()
+	if err := repos.AddRepository(ctx, repo); err != nil {
+		return nil, fmt.Errorf("update repository: %w", err)
+	}
+	recorded, err := repos.GetRepository(ctx, repo.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get repository: %w", err)
+	}
+	if recorded == nil {
+		return nil, fmt.Errorf("repository %s was removed while indexing", repo.ID)
+	}
+
+	return &RepositoryStats{
+		Repository: *recorded,
+		Chunks:     len(chunks),
+		FullIndex:  len(previousState) == 0,
+	}, nil
+}
+
+// RemoveRepository deletes a repository's documents and Merkle state.
+func (idx *DefaultIndexer) RemoveRepository(ctx context.Context, repoID string, store vectorstore.VectorStore) error {
+	if err := vectorstore.ValidateRepoID(repoID); err != nil {
+		return err
+	}
+	repos, ok := store.(vectorstore.RepositoryStore)
+	if !ok {
+		return fmt.Errorf("vector store does not support repositories")
+	}
+
+	idx.reposMu.Lock()
+	defer idx.reposMu.Unlock()
+
+	if err := repos.RemoveRepository(ctx, repoID); err != nil {
+		return err
+	}
+	if err := os.Remove(RepoStatePath(idx.statePath, repoID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove repository state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package indexer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ferg-cod3s/conexus/internal/embedding"
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeRepoFile(t *testing.T, root, path, content string) {
+	t.Helper()
+	full := filepath.Join(root, path)
+	require.NoError(t, os.MkdirAll(filepath.Dir(full), 0755))
+	require.NoError(t, os.WriteFile(full, []byte(content), 0644))
+}
+
+func TestIndexRepository_NamespacesRepositories(t *testing.T) {
+	ctx := context.Background()
+	billing, auth := t.TempDir(), t.TempDir()
+	writeRepoFile(t, billing, "internal/config/config.go", "package config\n\nfunc LoadBilling() {}\n")
+	writeRepoFile(t, auth, "internal/config/config.go", "package config\n\nfunc LoadAuth() {}\n")
+	writeRepoFile(t, auth, "dist/bundle.js", "var x = 1;\n")
+
+	store := vectorstore.NewMemoryStore()
+	statePath := filepath.Join(t.TempDir(), "indexer_state.json")
+	idx := NewIndexer(statePath)
+	opts := IndexOptions{Embedder: embedding.NewMock(8), VectorStore: store}
+
+	stats, err := idx.IndexRepository(ctx, vectorstore.Repository{ID: "billing", RootPath: billing}, opts)
+	require.NoError(t, err)
+	assert.True(t, stats.FullIndex)
+	assert.Positive(t, stats.Chunks)
+	assert.False(t, stats.Repository.LastIndexedAt.IsZero())
+	assert.FileExists(t, RepoStatePath(statePath, "billing"))
+
+	_, err = idx.IndexRepository(ctx, vectorstore.Repository{ID: "auth", RootPath: auth, IgnorePatterns: []string{"dist"}}, opts)
+	require.NoError(t, err)
+
+	// Identical paths do not collide
+	billingFiles, err := store.ListRepositoryFiles(ctx, "billing")
+	require.NoError(t, err)
+	authFiles, err := store.ListRepositoryFiles(ctx, "auth")
+	require.NoError(t, err)
+	assert.Contains(t, billingFiles, "internal/config/config.go")
+	assert.Equal(t, billingFiles, authFiles, "per-repo ignore patterns exclude dist")
+	rootFiles, err := store.ListIndexedFiles(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, rootFiles)
+
+	// Both copies of the shared path are stored
+	results, err := store.SearchBM25(ctx, "package config", vectorstore.SearchOptions{
+		Limit:   10,
+		Filters: map[string]interface{}{"file_path": "internal/config/config.go"},
+	})
+	require.NoError(t, err)
+	repoIDs := make(map[interface{}]bool)
+	for _, r := range results {
+		repoIDs[r.Document.Metadata[vectorstore.RepoIDKey]] = true
+	}
+	assert.Equal(t, map[interface{}]bool{"billing": true, "auth": true}, repoIDs)
+
+	// Unchanged repositories are not re-indexed
+	stats, err = idx.IndexRepository(ctx, vectorstore.Repository{ID: "billing", RootPath: billing}, opts)
+	require.NoError(t, err)
+	assert.False(t, stats.FullIndex)
+	assert.Zero(t, stats.Chunks)
+
+	writeRepoFile(t, billing, "README.md", "# Billing\n\nHandles invoices.\n")
+	stats, err = idx.IndexRepository(ctx, vectorstore.Repository{ID: "billing", RootPath: billing}, opts)
+	require.NoError(t, err)
+	assert.Positive(t, stats.Chunks)
+
+	require.NoError(t, idx.RemoveRepository(ctx, "billing", store))
+	assert.NoFileExists(t, RepoStatePath(statePath, "billing"))
+	billingFiles, err = store.ListRepositoryFiles(ctx, "billing")
+	require.NoError(t, err)
+	assert.Empty(t, billingFiles)
+	repos, err := store.ListRepositories(ctx)
+	require.NoError(t, err)
+	require.Len(t, repos, 1)
+	assert.Equal(t, "auth", repos[0].ID)
+}
+
+func TestIndexRepository_Errors(t *testing.T) {
+	ctx := context.Background()
+	idx := NewIndexer(filepath.Join(t.TempDir(), "state.json"))
+	opts := IndexOptions{Embedder: embedding.NewMock(8), VectorStore: vectorstore.NewMemoryStore()}
+
+	_, err := idx.IndexRepository(ctx, vectorstore.Repository{ID: "../escape", RootPath: t.TempDir()}, opts)
+	assert.Error(t, err)
+
+	_, err = idx.IndexRepository(ctx, vectorstore.Repository{ID: "missing", RootPath: filepath.Join(t.TempDir(), "nope")}, opts)
+	assert.Error(t, err)
+
+	assert.Error(t, idx.RemoveRepository(ctx, "unknown", opts.VectorStore))
+}
@@ -0,0 +1,210 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ferg-cod3s/conexus/internal/observability/audit"
+	"github.com/ferg-cod3s/conexus/internal/security/secrets"
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+)
+
+// SecretHandling controls what happens to content containing detected secrets.
+type SecretHandling string
+
+const (
+	SecretHandlingRedact     SecretHandling = "redact"     // Replace secrets with redaction markers (default)
+	SecretHandlingQuarantine SecretHandling = "quarantine" // Drop chunks containing secrets
+	SecretHandlingOff        SecretHandling = "off"        // Index content verbatim
+)
+
+// SetSecretHandling sets how secrets are handled when IndexOptions leaves
+// SecretHandling empty. An empty mode restores the default, redact.
+func (idx *DefaultIndexer) SetSecretHandling(mode SecretHandling) error {
+	switch mode {
+	case "", SecretHandlingRedact, SecretHandlingQuarantine, SecretHandlingOff:
+	default:
+		return fmt.Errorf("invalid secret handling: %s", mode)
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.secretHandling = mode
+	return nil
+}
+
+// secretMode returns the secret handling of an indexing run.
+func (idx *DefaultIndexer) secretMode(opts IndexOptions) SecretHandling {
+	if opts.SecretHandling != "" {
+		return opts.SecretHandling
+	}
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	if idx.secretHandling != "" {
+		return idx.secretHandling
+	}
+	return SecretHandlingRedact
+}
+
+// SecretsReport summarizes secrets detected during indexing.
+type SecretsReport struct {
+	GeneratedAt   time.Time         `json:"generated_at"`
+	FilesAffected int               `json:"files_affected"`
+	TotalFindings int               `json:"total_findings"`
+	Findings      []secrets.Finding `json:"findings"`
+}
+
+// SecretsReporter is implemented by indexers that track detected secrets.
+type SecretsReporter interface {
+	// SecretsReport returns the secrets found in the most recent index of each file.
+	SecretsReport() SecretsReport
+}
+
+// SecretRedactor is implemented by indexers that can show text as their index
+// records it, so tools reading files from disk show no more than the index.
+type SecretRedactor interface {
+	// RedactSecrets returns content with the secrets the indexer detects
+	// redacted, unless secret handling is off.
+	RedactSecrets(content string) string
+}
+
+// RedactSecrets returns content as the index records file text: redacted in
+// both redact and quarantine modes, unchanged when secret handling is off.
+func (idx *DefaultIndexer) RedactSecrets(content string) string {
+	if idx.secretScanner == nil || idx.secretMode(IndexOptions{}) == SecretHandlingOff {
+		return content
+	}
+	findings := idx.secretScanner.Scan(content)
+	if len(findings) == 0 {
+		return content
+	}
+	return secrets.Redact(content, findings)
+}
+
+// SetAuditLogger configures the audit logger that receives secret findings.
+func (idx *DefaultIndexer) SetAuditLogger(logger *audit.Logger) {
+	idx.secretsMu.Lock()
+	defer idx.secretsMu.Unlock()
+	idx.auditLogger = logger
+}
+
+// SecretsReport returns the secrets found in the most recent index of each file.
+func (idx *DefaultIndexer) SecretsReport() SecretsReport {
+	idx.secretsMu.RLock()
+	defer idx.secretsMu.RUnlock()
+
+	report := SecretsReport{
+		GeneratedAt:   time.Now(),
+		FilesAffected: len(idx.secretFindings),
+		Findings:      []secrets.Finding{},
+	}
+	for _, findings := range idx.secretFindings {
+		report.Findings = append(report.Findings, findings...)
+	}
+	sort.Slice(report.Findings, func(i, j int) bool {
+		a, b := report.Findings[i], report.Findings[j]
+		if a.FilePath != b.FilePath {
+			return a.FilePath < b.FilePath
+		}
+		return a.Line < b.Line
+	})
+	report.TotalFindings = len(report.Findings)
+
+	return report
+}
+
+// scanSecrets looks for secrets in file content. In redact mode the returned
+// content has every finding replaced; otherwise content is returned unchanged.
+func (idx *DefaultIndexer) scanSecrets(content, relPath string, mode SecretHandling) (string, []secrets.Finding) {
+	if idx.secretScanner == nil || mode == SecretHandlingOff {
+		return content, nil
+	}
+
+	findings := idx.secretScanner.Scan(content)
+	if len(findings) == 0 {
+		return content, nil
+	}
+
+	action := "redacted"
+	if mode == SecretHandlingQuarantine {
+		action = "quarantined"
+	}
+	for i := range findings {
+		findings[i].FilePath = relPath
+		findings[i].Action = action
+	}
+
+	if mode == SecretHandlingQuarantine {
+		return content, findings
+	}
+	return secrets.Redact(content, findings), findings
+}
+
+// quarantineChunks drops chunks whose own content contains a secret. Chunks
+// are scanned rather than matched against the file's findings by line, since
+// chunkers such as the notebook's number the lines of the text they render.
+func (idx *DefaultIndexer) quarantineChunks(chunks []Chunk) []Chunk {
+	if idx.secretScanner == nil {
+		return chunks
+	}
+
+	kept := chunks[:0]
+	for _, chunk := range chunks {
+		if len(idx.secretScanner.Scan(chunk.Content)) == 0 {
+			kept = append(kept, chunk)
+		}
+	}
+	return kept
+}
+
+// redactCommitSecrets scans the documents of a commit for secrets, handles
+// them like those of files and records the findings under the commit. A
+// finding is located by the commit's short hash, or by "hash:path" in a diff
+// hunk, as git names a file at a commit; its lines are those of the
+// document. In quarantine mode hunks with secrets are dropped, but the commit
+// message is redacted, since its document records how far the history is
+// indexed.
+func (idx *DefaultIndexer) redactCommitSecrets(ctx context.Context, commit gitCommit, docs []vectorstore.Document, mode SecretHandling) []vectorstore.Document {
+	var all []secrets.Finding
+	kept := docs[:0]
+	for _, doc := range docs {
+		location := shortHash(commit.Hash)
+		if path, ok := doc.Metadata["diff_path"].(string); ok {
+			location += ":" + path
+		}
+		docMode := mode
+		if doc.ID == commitDocumentID(commit.Hash) && mode == SecretHandlingQuarantine {
+			docMode = SecretHandlingRedact
+		}
+
+		content, findings := idx.scanSecrets(doc.Content, location, docMode)
+		all = append(all, findings...)
+		if len(findings) > 0 && docMode == SecretHandlingQuarantine {
+			continue
+		}
+		doc.Content = content
+		kept = append(kept, doc)
+	}
+	idx.recordSecrets(ctx, commit.Hash, all)
+	return kept
+}
+
+// recordSecrets replaces the findings stored for a file and audits new ones.
+func (idx *DefaultIndexer) recordSecrets(ctx context.Context, relPath string, findings []secrets.Finding) {
+	idx.secretsMu.Lock()
+	defer idx.secretsMu.Unlock()
+
+	if len(findings) == 0 {
+		delete(idx.secretFindings, relPath)
+		return
+	}
+	idx.secretFindings[relPath] = findings
+
+	if idx.auditLogger != nil {
+		for _, f := range findings {
+			idx.auditLogger.LogSecretDetected(ctx, f.FilePath, f.RuleID, f.Line, f.Preview, f.Action)
+		}
+	}
+}
@@ -4,20 +4,21 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"math"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ferg-cod3s/conexus/internal/connectors"
 	"github.com/ferg-cod3s/conexus/internal/connectors/github"
-	"github.com/ferg-cod3s/conexus/internal/embedding"
 	"github.com/ferg-cod3s/conexus/internal/indexer"
 	"github.com/ferg-cod3s/conexus/internal/observability"
 	"github.com/ferg-cod3s/conexus/internal/protocol"
+	"github.com/ferg-cod3s/conexus/internal/search"
 	"github.com/ferg-cod3s/conexus/internal/vectorstore"
 )
 
@@ -64,29 +65,29 @@ func (s *Server) handleContextSearch(ctx context.Context, args json.RawMessage)
 		offset = 0
 	}
 
+	mode, err := search.ParseHybridMode(req.Mode)
+	if err == nil {
+		err = search.FusionOptions{Mode: mode, Alpha: req.Alpha, K: req.RRFK}.Validate()
+	}
+	diversity := req.diversity()
+	if err == nil && diversity != nil {
+		err = diversity.Validate()
+	}
+	if err != nil {
+		return nil, &protocol.Error{
+			Code:    protocol.InvalidParams,
+			Message: fmt.Sprintf("invalid search request: %v", err),
+		}
+	}
+
 	// Check cache first (if available)
 	var results []vectorstore.SearchResult
 	var queryTime float64
 	var cacheHit bool
+	cacheKey := searchCacheKey(req, mode, topK, offset)
 
 	if s.searchCache != nil {
-		filters := make(map[string]interface{})
-		if req.Filters != nil {
-			if len(req.Filters.SourceTypes) > 0 {
-				filters["source_types"] = req.Filters.SourceTypes
-			}
-			if req.Filters.DateRange != nil {
-				filters["date_range"] = map[string]string{
-					"from": req.Filters.DateRange.From,
-					"to":   req.Filters.DateRange.To,
-				}
-			}
-			if req.Filters.WorkContext != nil {
-				filters["work_context"] = req.Filters.WorkContext
-			}
-		}
-
-		if cached, found := s.searchCache.Get(req.Query, filters); found {
+		if cached, found := s.searchCache.Get(req.Query, cacheKey); found {
 			results = cached.Results
 			queryTime = cached.QueryTime
 			cacheHit = true
@@ -100,76 +101,33 @@ func (s *Server) handleContextSearch(ctx context.Context, args json.RawMessage)
 
 	// Perform search if not cached
 	if !cacheHit {
-		// Generate query embedding
-		queryVec, err := s.embedder.Embed(ctx, req.Query)
-		if err != nil {
-			errorCtx := observability.ExtractErrorContext(ctx, "context.search")
-			errorCtx.ErrorType = "embedding_error"
-			errorCtx.ErrorCode = protocol.InternalError
-			errorCtx.Params = args
-
-			if s.errorHandler != nil {
-				s.errorHandler.HandleError(ctx, err, errorCtx)
-			}
-
-			return nil, &protocol.Error{
-				Code:    protocol.InternalError,
-				Message: fmt.Sprintf("failed to generate query embedding: %v", err),
-			}
-		}
-
-		// Prepare search options
-		opts := vectorstore.SearchOptions{
-			Limit:   topK,
-			Offset:  offset,
-			Filters: make(map[string]interface{}),
-		}
-
-		// Apply filters
-		if req.Filters != nil {
-			if len(req.Filters.SourceTypes) > 0 {
-				opts.Filters["source_types"] = req.Filters.SourceTypes
-			}
-			if req.Filters.DateRange != nil {
-				opts.Filters["date_range"] = map[string]string{
-					"from": req.Filters.DateRange.From,
-					"to":   req.Filters.DateRange.To,
-				}
-			}
-			// Apply work context filters
-			if req.Filters.WorkContext != nil {
-				if req.Filters.WorkContext.ActiveFile != "" {
-					opts.Filters["related_files"] = req.Filters.WorkContext.ActiveFile
-				}
-				if req.Filters.WorkContext.GitBranch != "" {
-					opts.Filters["git_branch"] = req.Filters.WorkContext.GitBranch
-				}
-				if len(req.Filters.WorkContext.OpenTicketIDs) > 0 {
-					opts.Filters["ticket_ids"] = req.Filters.WorkContext.OpenTicketIDs
-				}
-				// Add story context filtering
-				if req.Filters.WorkContext.CurrentStoryID != "" {
-					opts.Filters["story_ids"] = []string{req.Filters.WorkContext.CurrentStoryID}
-				}
-			}
+		query := search.Query{
+			Text:       req.Query,
+			Filters:    make(map[string]interface{}),
+			Filter:     req.Filters.MetadataFilter(),
+			Limit:      topK + 1, // One more than a page tells whether another follows
+			Offset:     offset,
+			HybridMode: mode,
+			Alpha:      req.Alpha,
+			K:          req.RRFK,
+			Diversity:  diversity,
 		}
 
 		// Apply work context from request (overrides filter)
 		if req.WorkContext != nil {
 			if req.WorkContext.ActiveFile != "" {
-				opts.Filters["boost_file"] = req.WorkContext.ActiveFile
+				query.Filters["boost_file"] = req.WorkContext.ActiveFile
 			}
 			if req.WorkContext.GitBranch != "" {
-				opts.Filters["git_branch"] = req.WorkContext.GitBranch
+				query.Filters["git_branch"] = req.WorkContext.GitBranch
 			}
 			if len(req.WorkContext.OpenTicketIDs) > 0 {
-				opts.Filters["boost_tickets"] = req.WorkContext.OpenTicketIDs
+				query.Filters["boost_tickets"] = req.WorkContext.OpenTicketIDs
 			}
 		}
 
-		// Perform hybrid search (combines vector + BM25)
 		var searchErr error
-		results, searchErr = s.vectorStore.SearchHybrid(ctx, req.Query, queryVec.Vector, opts)
+		results, searchErr = s.searchDocuments(ctx, query)
 		if searchErr != nil {
 			errorCtx := observability.ExtractErrorContext(ctx, "context.search")
 			errorCtx.ErrorType = "search_error"
@@ -190,19 +148,7 @@ func (s *Server) handleContextSearch(ctx context.Context, args json.RawMessage)
 
 		// Cache results
 		if s.searchCache != nil {
-			filters := make(map[string]interface{})
-			if req.Filters != nil {
-				if len(req.Filters.SourceTypes) > 0 {
-					filters["source_types"] = req.Filters.SourceTypes
-				}
-				if req.Filters.DateRange != nil {
-					filters["date_range"] = req.Filters.DateRange
-				}
-				if req.Filters.WorkContext != nil {
-					filters["work_context"] = req.Filters.WorkContext
-				}
-			}
-			s.searchCache.Set(req.Query, filters, results, queryTime)
+			s.searchCache.Set(req.Query, cacheKey, results, queryTime)
 		}
 
 		// Record cache miss
@@ -211,30 +157,19 @@ func (s *Server) handleContextSearch(ctx context.Context, args json.RawMessage)
 		}
 	}
 
-	// Apply work context boosting if requested
-	if req.Filters != nil && req.Filters.WorkContext != nil && req.Filters.WorkContext.BoostActive {
-		results = s.applyWorkContextBoosting(results, req.Filters.WorkContext)
+	hasMore := len(results) > topK
+	if hasMore {
+		results = results[:topK]
 	}
 
-	// Apply semantic reranking for better relevance
-	if len(results) > 1 {
-		results = s.applySemanticReranking(ctx, results, req.Query)
+	// Match on small chunks but return their enclosing parent spans
+	if req.ExpandToParent {
+		results = vectorstore.ExpandToParents(ctx, s.vectorStore, results)
 	}
 
-	// Get total count for pagination
-	totalCount, countErr := s.vectorStore.Count(ctx)
-	if countErr != nil {
-		countErrorCtx := observability.ExtractErrorContext(ctx, "context.search")
-		countErrorCtx.ErrorType = "count_error"
-		countErrorCtx.ErrorCode = protocol.InternalError
-		countErrorCtx.Duration = time.Since(startTime)
-
-		if s.errorHandler != nil {
-			s.errorHandler.GracefulDegradation(ctx, "vector_store_count", countErr)
-		}
-
-		// Log error but don't fail the request
-		totalCount = int64(len(results))
+	// Apply work context boosting if requested
+	if req.Filters != nil && req.Filters.WorkContext != nil && req.Filters.WorkContext.BoostActive {
+		results = s.applyWorkContextBoosting(results, req.Filters.WorkContext)
 	}
 
 	// Log successful search operation
@@ -272,10 +207,80 @@ func (s *Server) handleContextSearch(ctx context.Context, args json.RawMessage)
 		QueryTime:  queryTime,
 		Offset:     offset,
 		Limit:      topK,
-		HasMore:    int64(offset+len(results)) < totalCount,
+		HasMore:    hasMore,
 	}, nil
 }
 
+// searchDocuments runs a query through the search pipeline shared by all
+// tools.
+func (s *Server) searchDocuments(ctx context.Context, query search.Query) ([]vectorstore.SearchResult, error) {
+	results, err := s.pipeline.Search(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return search.SearchResults(results, query.HybridMode), nil
+}
+
+// diversity returns the diversification requested, or nil for none. Caps
+// without diversify keep the relevance order.
+func (req SearchRequest) diversity() *search.DiversityOptions {
+	if !req.Diversify && req.MaxPerFile == 0 && req.MaxPerSourceType == 0 {
+		return nil
+	}
+	lambda := float32(1)
+	if req.Diversify {
+		lambda = req.MMRLambda
+	}
+	return &search.DiversityOptions{
+		Lambda:           lambda,
+		MaxPerFile:       req.MaxPerFile,
+		MaxPerSourceType: req.MaxPerSourceType,
+	}
+}
+
+// searchCacheKey returns the parameters that, with the query text, identify
+// the results of a context.search request.
+func searchCacheKey(req SearchRequest, mode search.HybridMode, topK, offset int) map[string]interface{} {
+	key := map[string]interface{}{
+		"top_k":  topK,
+		"offset": offset,
+		"mode":   string(mode),
+		"rrf_k":  req.RRFK,
+	}
+	if req.Alpha != nil {
+		key["alpha"] = *req.Alpha
+	}
+	if diversity := req.diversity(); diversity != nil {
+		key["diversity"] = *diversity
+	}
+	if req.WorkContext != nil {
+		key["active_file"] = req.WorkContext.ActiveFile
+		key["git_branch"] = req.WorkContext.GitBranch
+		key["open_ticket_ids"] = req.WorkContext.OpenTicketIDs
+	}
+	if req.Filters != nil {
+		if len(req.Filters.SourceTypes) > 0 {
+			key["source_types"] = req.Filters.SourceTypes
+		}
+		if req.Filters.DateRange != nil {
+			key["date_range"] = map[string]string{
+				"from": req.Filters.DateRange.From,
+				"to":   req.Filters.DateRange.To,
+			}
+		}
+		if req.Filters.WorkContext != nil {
+			key["work_context"] = *req.Filters.WorkContext
+		}
+		if len(req.Filters.Repos) > 0 {
+			key["repos"] = req.Filters.Repos
+		}
+		if len(req.Filters.Classifications) > 0 {
+			key["classifications"] = req.Filters.Classifications
+		}
+	}
+	return key
+}
+
 // handleGetRelatedInfo implements the context.get_related_info tool
 func (s *Server) handleGetRelatedInfo(ctx context.Context, args json.RawMessage) (interface{}, error) {
 	var req GetRelatedInfoRequest
@@ -295,30 +300,21 @@ func (s *Server) handleGetRelatedInfo(ctx context.Context, args json.RawMessage)
 	}
 
 	// Build search query and filters based on provided identifiers
-	var query string
-	opts := vectorstore.SearchOptions{
+	query := search.Query{
 		Limit:   20,
 		Filters: make(map[string]interface{}),
 	}
 
 	if req.FilePath != "" {
-		query = req.FilePath
-		opts.Filters["file_path"] = req.FilePath
+		query.Text = req.FilePath
+		query.Filters["file_path"] = req.FilePath
 	} else {
-		query = req.TicketID
-		opts.Filters["ticket_id"] = req.TicketID
+		query.Text = req.TicketID
+		query.Filters["ticket_id"] = req.TicketID
 	}
 
 	// Search for related documents
-	queryVec, err := s.embedder.Embed(ctx, query)
-	if err != nil {
-		return nil, &protocol.Error{
-			Code:    protocol.InternalError,
-			Message: fmt.Sprintf("failed to generate embedding: %v", err),
-		}
-	}
-
-	results, err := s.vectorStore.SearchHybrid(ctx, query, queryVec.Vector, opts)
+	results, err := s.searchDocuments(ctx, query)
 	if err != nil {
 		return nil, &protocol.Error{
 			Code:    protocol.InternalError,
@@ -413,13 +409,19 @@ func (s *Server) handleIndexControl(ctx context.Context, args json.RawMessage) (
 
 	// Validate action
 	validActions := map[string]bool{
-		"start":         true,
-		"stop":          true,
-		"status":        true,
-		"force_reindex": true,
-		"reindex_paths": true,
-		"index":         true,
-		"sync_github":   true,
+		"start":          true,
+		"stop":           true,
+		"status":         true,
+		"force_reindex":  true,
+		"reindex_paths":  true,
+		"index":          true,
+		"sync_github":    true,
+		"secrets_report": true,
+		"doctor":         true,
+		"index_history":  true,
+		"add_repo":       true,
+		"remove_repo":    true,
+		"list_repos":     true,
 	}
 
 	if !validActions[req.Action] {
@@ -498,122 +500,267 @@ func (s *Server) handleIndexControl(ctx context.Context, args json.RawMessage) (
 		}, nil
 
 	case "start":
-		// Get current working directory
-		rootPath, err := os.Getwd()
+		opts, err := s.indexOptions()
 		if err != nil {
+			return nil, err
+		}
+
+		if err := s.indexer.Start(ctx, opts); err != nil {
+			return nil, &protocol.Error{
+				Code:    protocol.InternalError,
+				Message: fmt.Sprintf("failed to start indexing: %v", err),
+			}
+		}
+
+		return IndexControlResponse{
+			Status:  "ok",
+			Message: "Background indexing started",
+		}, nil
+
+	case "secrets_report":
+		reporter, ok := s.indexer.(indexer.SecretsReporter)
+		if !ok {
 			return nil, &protocol.Error{
 				Code:    protocol.InternalError,
-				Message: fmt.Sprintf("failed to get working directory: %v", err),
+				Message: "index controller does not support secret detection",
 			}
 		}
 
-		// Load ignore patterns
-		ignorePatterns := []string{".git"}
-		if gitignore, err := loadGitignore(filepath.Join(rootPath, ".gitignore"), rootPath); err == nil {
-			ignorePatterns = append(ignorePatterns, gitignore...)
+		report := reporter.SecretsReport()
+		return IndexControlResponse{
+			Status:  "ok",
+			Message: fmt.Sprintf("%d secrets detected in %d files", report.TotalFindings, report.FilesAffected),
+			Details: map[string]interface{}{
+				"generated_at":   report.GeneratedAt.Format(time.RFC3339),
+				"files_affected": report.FilesAffected,
+				"total_findings": report.TotalFindings,
+				"findings":       report.Findings,
+			},
+		}, nil
+
+	case "doctor":
+		doctor, ok := s.indexer.(indexer.Doctor)
+		if !ok {
+			return nil, &protocol.Error{
+				Code:    protocol.InternalError,
+				Message: "index controller does not support consistency checks",
+			}
 		}
 
-		opts := indexer.IndexOptions{
-			RootPath:       rootPath,
-			IgnorePatterns: ignorePatterns,
-			MaxFileSize:    1024 * 1024, // 1MB
-			IncludeGitInfo: true,
-			Embedder:       s.embedder,
-			VectorStore:    s.vectorStore,
+		opts, err := s.indexOptions()
+		if err != nil {
+			return nil, err
 		}
 
-		if err := s.indexer.Start(ctx, opts); err != nil {
+		report, err := doctor.Doctor(ctx, opts, req.Repair)
+		if err != nil {
 			return nil, &protocol.Error{
 				Code:    protocol.InternalError,
-				Message: fmt.Sprintf("failed to start indexing: %v", err),
+				Message: fmt.Sprintf("consistency check failed: %v", err),
+			}
+		}
+
+		message := "Index is healthy"
+		if !report.Healthy() {
+			message = fmt.Sprintf("Found %d index problems", report.IssueCount())
+			if report.Repaired {
+				message = fmt.Sprintf("Repaired %d index problems", report.IssueCount())
 			}
 		}
 
 		return IndexControlResponse{
 			Status:  "ok",
-			Message: "Background indexing started",
+			Message: message,
+			Details: map[string]interface{}{
+				"checked_at":           report.CheckedAt.Format(time.RFC3339),
+				"healthy":              report.Healthy(),
+				"repaired":             report.Repaired,
+				"orphaned_files":       report.OrphanedFiles,
+				"unindexed_files":      report.UnindexedFiles,
+				"dimension_mismatches": report.DimensionMismatches,
+				"fts_missing":          report.FTSMissing,
+				"fts_stale":            report.FTSStale,
+				"fts_orphaned":         report.FTSOrphaned,
+				"hnsw_orphaned":        report.HNSWOrphaned,
+			},
 		}, nil
 
-	case "stop":
-		if err := s.indexer.Stop(ctx); err != nil {
+	case "index_history":
+		history, ok := s.indexer.(indexer.HistoryIndexer)
+		if !ok {
 			return nil, &protocol.Error{
 				Code:    protocol.InternalError,
-				Message: fmt.Sprintf("failed to stop indexing: %v", err),
+				Message: "index controller does not support git history indexing",
+			}
+		}
+
+		opts, err := s.indexOptions()
+		if err != nil {
+			return nil, err
+		}
+
+		stats, err := history.IndexHistory(ctx, opts)
+		if err != nil {
+			return nil, &protocol.Error{
+				Code:    protocol.InternalError,
+				Message: fmt.Sprintf("git history indexing failed: %v", err),
 			}
 		}
 
 		return IndexControlResponse{
 			Status:  "ok",
-			Message: "Indexing stopped",
+			Message: fmt.Sprintf("Indexed %d commits (%d diff hunks)", stats.Commits, stats.Hunks),
+			Details: map[string]interface{}{
+				"commits_indexed": stats.Commits,
+				"hunks_indexed":   stats.Hunks,
+				"last_commit":     stats.LastCommit,
+			},
 		}, nil
 
-	case "force_reindex":
-		// Get current working directory
-		rootPath, err := os.Getwd()
+	case "add_repo":
+		repos, ok := s.indexer.(indexer.RepositoryIndexer)
+		if !ok {
+			return nil, &protocol.Error{
+				Code:    protocol.InternalError,
+				Message: "index controller does not support repositories",
+			}
+		}
+		if req.RepoID == "" || req.RepoPath == "" {
+			return nil, &protocol.Error{
+				Code:    protocol.InvalidParams,
+				Message: "repo_id and repo_path are required for add_repo action",
+			}
+		}
+		if err := vectorstore.ValidateRepoID(req.RepoID); err != nil {
+			return nil, &protocol.Error{
+				Code:    protocol.InvalidParams,
+				Message: err.Error(),
+			}
+		}
+
+		stats, err := repos.IndexRepository(ctx, vectorstore.Repository{
+			ID:             req.RepoID,
+			RootPath:       req.RepoPath,
+			IgnorePatterns: req.IgnorePatterns,
+		}, indexer.IndexOptions{
+			MaxFileSize: 1024 * 1024, // 1MB
+			Embedder:    s.embedder,
+			VectorStore: s.vectorStore,
+		})
 		if err != nil {
 			return nil, &protocol.Error{
 				Code:    protocol.InternalError,
-				Message: fmt.Sprintf("failed to get working directory: %v", err),
+				Message: fmt.Sprintf("failed to index repository: %v", err),
+			}
+		}
+
+		return IndexControlResponse{
+			Status:  "ok",
+			Message: fmt.Sprintf("Indexed repository %s (%d chunks updated)", stats.Repository.ID, stats.Chunks),
+			Details: map[string]interface{}{
+				"repository":     stats.Repository,
+				"chunks_indexed": stats.Chunks,
+				"full_index":     stats.FullIndex,
+			},
+		}, nil
+
+	case "remove_repo":
+		repos, ok := s.indexer.(indexer.RepositoryIndexer)
+		if !ok {
+			return nil, &protocol.Error{
+				Code:    protocol.InternalError,
+				Message: "index controller does not support repositories",
+			}
+		}
+		if req.RepoID == "" {
+			return nil, &protocol.Error{
+				Code:    protocol.InvalidParams,
+				Message: "repo_id is required for remove_repo action",
 			}
 		}
 
-		// Load ignore patterns
-		ignorePatterns := []string{".git"}
-		if gitignore, err := loadGitignore(filepath.Join(rootPath, ".gitignore"), rootPath); err == nil {
-			ignorePatterns = append(ignorePatterns, gitignore...)
+		if err := repos.RemoveRepository(ctx, req.RepoID, s.vectorStore); err != nil {
+			return nil, &protocol.Error{
+				Code:    protocol.InternalError,
+				Message: fmt.Sprintf("failed to remove repository: %v", err),
+			}
 		}
 
-		opts := indexer.IndexOptions{
-			RootPath:       rootPath,
-			IgnorePatterns: ignorePatterns,
-			MaxFileSize:    1024 * 1024, // 1MB
-			IncludeGitInfo: true,
-			Embedder:       s.embedder,
-			VectorStore:    s.vectorStore,
+		return IndexControlResponse{
+			Status:  "ok",
+			Message: fmt.Sprintf("Removed repository %s", req.RepoID),
+		}, nil
+
+	case "list_repos":
+		repoStore, ok := s.vectorStore.(vectorstore.RepositoryStore)
+		if !ok {
+			return nil, &protocol.Error{
+				Code:    protocol.InternalError,
+				Message: "vector store does not support repositories",
+			}
 		}
 
-		if err := s.indexer.ForceReindex(ctx, opts); err != nil {
+		repos, err := repoStore.ListRepositories(ctx)
+		if err != nil {
 			return nil, &protocol.Error{
 				Code:    protocol.InternalError,
-				Message: fmt.Sprintf("failed to start force reindex: %v", err),
+				Message: fmt.Sprintf("failed to list repositories: %v", err),
 			}
 		}
+		if repos == nil {
+			repos = []vectorstore.Repository{}
+		}
 
 		return IndexControlResponse{
 			Status:  "ok",
-			Message: "Force reindex started",
+			Message: fmt.Sprintf("%d repositories indexed", len(repos)),
+			Details: map[string]interface{}{
+				"repositories": repos,
+			},
 		}, nil
 
-	case "reindex_paths":
-		if len(req.Paths) == 0 {
+	case "stop":
+		if err := s.indexer.Stop(ctx); err != nil {
 			return nil, &protocol.Error{
-				Code:    protocol.InvalidParams,
-				Message: "paths are required for reindex_paths action",
+				Code:    protocol.InternalError,
+				Message: fmt.Sprintf("failed to stop indexing: %v", err),
 			}
 		}
 
-		// Get current working directory
-		rootPath, err := os.Getwd()
+		return IndexControlResponse{
+			Status:  "ok",
+			Message: "Indexing stopped",
+		}, nil
+
+	case "force_reindex":
+		opts, err := s.indexOptions()
 		if err != nil {
+			return nil, err
+		}
+
+		if err := s.indexer.ForceReindex(ctx, opts); err != nil {
 			return nil, &protocol.Error{
 				Code:    protocol.InternalError,
-				Message: fmt.Sprintf("failed to get working directory: %v", err),
+				Message: fmt.Sprintf("failed to start force reindex: %v", err),
 			}
 		}
 
-		// Load ignore patterns
-		ignorePatterns := []string{".git"}
-		if gitignore, err := loadGitignore(filepath.Join(rootPath, ".gitignore"), rootPath); err == nil {
-			ignorePatterns = append(ignorePatterns, gitignore...)
+		return IndexControlResponse{
+			Status:  "ok",
+			Message: "Force reindex started",
+		}, nil
+
+	case "reindex_paths":
+		if len(req.Paths) == 0 {
+			return nil, &protocol.Error{
+				Code:    protocol.InvalidParams,
+				Message: "paths are required for reindex_paths action",
+			}
 		}
 
-		opts := indexer.IndexOptions{
-			RootPath:       rootPath,
-			IgnorePatterns: ignorePatterns,
-			MaxFileSize:    1024 * 1024, // 1MB
-			IncludeGitInfo: true,
-			Embedder:       s.embedder,
-			VectorStore:    s.vectorStore,
+		opts, err := s.indexOptions()
+		if err != nil {
+			return nil, err
 		}
 
 		if err := s.indexer.ReindexPaths(ctx, opts, req.Paths); err != nil {
@@ -689,8 +836,12 @@ func (s *Server) handleIndexControl(ctx context.Context, args json.RawMessage) (
 			Metadata: metadata,
 		}
 
-		// Store in vector store
-		if err := s.vectorStore.Upsert(ctx, doc); err != nil {
+		// Store in the pushed collection, apart from the indexed code
+		pushed, err := s.openCollection(ctx, vectorstore.PushedCollection)
+		if err == nil {
+			err = pushed.Upsert(ctx, doc)
+		}
+		if err != nil {
 			return nil, &protocol.Error{
 				Code:    protocol.InternalError,
 				Message: fmt.Sprintf("failed to store document: %v", err),
@@ -731,6 +882,14 @@ func (s *Server) handleIndexControl(ctx context.Context, args json.RawMessage) (
 			}
 		}
 
+		github, err := s.openCollection(ctx, vectorstore.GitHubCollection)
+		if err != nil {
+			return nil, &protocol.Error{
+				Code:    protocol.InternalError,
+				Message: fmt.Sprintf("failed to open GitHub collection: %v", err),
+			}
+		}
+
 		// Convert issues to documents and store them
 		for _, issue := range issues {
 			content := fmt.Sprintf("%s\n\n%s", issue.Title, issue.Description)
@@ -763,7 +922,7 @@ func (s *Server) handleIndexControl(ctx context.Context, args json.RawMessage) (
 			}
 			doc.Vector = embedding.Vector
 
-			if err := s.vectorStore.Upsert(ctx, doc); err != nil {
+			if err := github.Upsert(ctx, doc); err != nil {
 				return nil, &protocol.Error{
 					Code:    protocol.InternalError,
 					Message: fmt.Sprintf("failed to store issue %d: %v", issue.Number, err),
@@ -808,7 +967,7 @@ func (s *Server) handleIndexControl(ctx context.Context, args json.RawMessage) (
 			}
 			doc.Vector = embedding.Vector
 
-			if err := s.vectorStore.Upsert(ctx, doc); err != nil {
+			if err := github.Upsert(ctx, doc); err != nil {
 				return nil, &protocol.Error{
 					Code:    protocol.InternalError,
 					Message: fmt.Sprintf("failed to store PR %d: %v", pr.Number, err),
@@ -1003,9 +1162,18 @@ func (s *Server) handleConnectorManagement(ctx context.Context, args json.RawMes
 			}
 		}
 
+		// Drop the documents the connector synced, which live in collections
+		deleted, err := s.vectorStore.DeleteByFilter(ctx, vectorstore.And(vectorstore.Eq("connector_id", req.ConnectorID), vectorstore.AllCorpora()))
+		if err != nil {
+			return nil, &protocol.Error{
+				Code:    protocol.InternalError,
+				Message: fmt.Sprintf("failed to delete connector documents: %v", err),
+			}
+		}
+
 		return ConnectorManagementResponse{
 			Status:  "ok",
-			Message: fmt.Sprintf("Connector %s removed successfully", req.ConnectorID),
+			Message: fmt.Sprintf("Connector %s removed successfully, deleted %d documents", req.ConnectorID, deleted),
 			Connectors: []ConnectorInfo{{
 				ID:     existing.ID,
 				Type:   existing.Type,
@@ -1079,58 +1247,6 @@ func (s *Server) applyWorkContextBoosting(results []vectorstore.SearchResult, wo
 	return boosted
 }
 
-// applySemanticReranking improves result relevance using cross-attention
-func (s *Server) applySemanticReranking(ctx context.Context, results []vectorstore.SearchResult, query string) []vectorstore.SearchResult {
-	if len(results) <= 1 {
-		return results
-	}
-
-	// Generate query embedding for reranking
-	queryVec, err := s.embedder.Embed(ctx, query)
-	if err != nil {
-		// If embedding fails, return original results
-		return results
-	}
-
-	// Calculate semantic similarity scores
-	for i := range results {
-		docVec := results[i].Document.Vector
-		if len(docVec) > 0 && len(queryVec.Vector) > 0 {
-			// Simple cosine similarity for reranking
-			similarity := s.calculateCosineSimilarity(queryVec.Vector, docVec)
-			// Combine original score with semantic similarity (weighted average)
-			results[i].Score = results[i].Score*0.7 + float32(similarity)*0.3
-		}
-	}
-
-	// Re-sort by reranked scores
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].Score > results[j].Score
-	})
-
-	return results
-}
-
-// calculateCosineSimilarity computes cosine similarity between two vectors
-func (s *Server) calculateCosineSimilarity(a, b embedding.Vector) float64 {
-	if len(a) != len(b) {
-		return 0.0
-	}
-
-	var dotProduct, normA, normB float64
-	for i := 0; i < len(a); i++ {
-		dotProduct += float64(a[i]) * float64(b[i])
-		normA += float64(a[i]) * float64(a[i])
-		normB += float64(b[i]) * float64(b[i])
-	}
-
-	if normA == 0 || normB == 0 {
-		return 0.0
-	}
-
-	return dotProduct / (math.Sqrt(normA) * math.Sqrt(normB))
-}
-
 // min returns the minimum of two integers
 func min(a, b int) int {
 	if a < b {
@@ -1139,32 +1255,6 @@ func min(a, b int) int {
 	return b
 }
 
-// loadGitignore loads .gitignore patterns if available.
-func loadGitignore(gitignorePath, rootPath string) ([]string, error) {
-	if _, err := os.Stat(gitignorePath); os.IsNotExist(err) {
-		return nil, nil
-	}
-
-	content, err := os.ReadFile(gitignorePath)
-	if err != nil {
-		return nil, fmt.Errorf("read .gitignore: %w", err)
-	}
-
-	var patterns []string
-	lines := strings.Split(string(content), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line != "" && !strings.HasPrefix(line, "#") {
-			// Convert to absolute path for proper matching
-			if !filepath.IsAbs(line) {
-				patterns = append(patterns, line)
-			}
-		}
-	}
-
-	return patterns, nil
-}
-
 // extractStoryIDsFromIssue extracts story IDs from GitHub issue content
 func extractStoryIDsFromIssue(issue github.Issue) []string {
 	var storyIDs []string
@@ -1227,6 +1317,10 @@ func (s *Server) syncGitHubData(ctx context.Context, connector *connectors.Conne
 	return []github.Issue{}, []github.PullRequest{}, nil
 }
 
+// explainDiversity diversifies the results context.explain draws on, which
+// would otherwise often be adjacent chunks of a single file.
+var explainDiversity = search.DiversityOptions{MaxPerFile: 2}
+
 // handleContextExplain implements the context.explain tool
 func (s *Server) handleContextExplain(ctx context.Context, args json.RawMessage) (interface{}, error) {
 	var req ExplainRequest
@@ -1250,22 +1344,17 @@ func (s *Server) handleContextExplain(ctx context.Context, args json.RawMessage)
 		req.Depth = "detailed"
 	}
 
-	// Search for relevant code and documentation
-	queryVec, err := s.embedder.Embed(ctx, req.Target)
-	if err != nil {
-		return nil, &protocol.Error{
-			Code:    protocol.InternalError,
-			Message: fmt.Sprintf("failed to generate embedding: %v", err),
-		}
+	// Search with broader context for explanations, spread over files unless
+	// the caller opts out
+	query := search.Query{
+		Text:  req.Target,
+		Limit: 15, // Get more results for comprehensive explanations
 	}
-
-	// Search with broader context for explanations
-	opts := vectorstore.SearchOptions{
-		Limit:   15, // Get more results for comprehensive explanations
-		Filters: make(map[string]interface{}),
+	if req.Diversify == nil || *req.Diversify {
+		diversity := explainDiversity
+		query.Diversity = &diversity
 	}
-
-	results, err := s.vectorStore.SearchHybrid(ctx, req.Target, queryVec.Vector, opts)
+	results, err := s.searchDocuments(ctx, query)
 	if err != nil {
 		return nil, &protocol.Error{
 			Code:    protocol.InternalError,
@@ -1294,11 +1383,11 @@ func (s *Server) handleContextExplain(ctx context.Context, args json.RawMessage)
 			FilePath:   filePath,
 			StartLine:  int(startLine),
 			EndLine:    int(endLine),
-			Metadata:   result.Document.Metadata,
+			Metadata:   explainMetadata(result.Document.Metadata),
 		})
 
 		// Extract code examples from function/struct definitions
-		if chunkType, ok := result.Document.Metadata["chunk_type"].(string); ok {
+		if chunkType := chunkType(result.Document.Metadata); chunkType != "" {
 			if chunkType == "function" || chunkType == "struct" {
 				examples = append(examples, CodeExample{
 					Code:        result.Document.Content,
@@ -1352,16 +1441,21 @@ func (s *Server) handleContextGrep(ctx context.Context, args json.RawMessage) (i
 
 	startTime := time.Now()
 
-	// Use ripgrep for fast pattern matching
-	var results []GrepResult
-	var err error
+	re, query, err := compileGrepPattern(req.Pattern, req.CaseInsensitive)
+	if err != nil {
+		return nil, &protocol.Error{
+			Code:    protocol.InvalidParams,
+			Message: fmt.Sprintf("invalid pattern: %v", err),
+		}
+	}
 
+	var results []GrepResult
 	if req.Include != "" {
 		// Search in specific file types
-		results, err = s.grepInFiles(ctx, req.Pattern, req.Path, req.Include, req.CaseInsensitive, req.Context)
+		results, err = s.grepInFiles(ctx, re, query, req.Path, req.Include, req.Context)
 	} else {
 		// Search in all files
-		results, err = s.grepInFiles(ctx, req.Pattern, req.Path, "*", req.CaseInsensitive, req.Context)
+		results, err = s.grepInFiles(ctx, re, query, req.Path, "*", req.Context)
 	}
 
 	if err != nil {
@@ -1397,8 +1491,7 @@ func (s *Server) generateExplanation(target, context, depth string, results []ve
 	// Group results by type for better organization
 	var functions, structs, files []vectorstore.SearchResult
 	for _, result := range results {
-		chunkType := getStringFromMetadata(result.Document.Metadata, "chunk_type")
-		switch chunkType {
+		switch chunkType(result.Document.Metadata) {
 		case "function":
 			functions = append(functions, result)
 		case "struct":
@@ -1443,7 +1536,7 @@ func (s *Server) generateExplanation(target, context, depth string, results []ve
 			filePath := getStringFromMetadata(result.Document.Metadata, "file_path")
 			if filePath != "" {
 				explanation.WriteString(fmt.Sprintf("- **%s**: Located in %s\n",
-					getStringFromMetadata(result.Document.Metadata, "chunk_type"),
+					chunkType(result.Document.Metadata),
 					filePath))
 			}
 		}
@@ -1460,19 +1553,50 @@ func (s *Server) generateExplanation(target, context, depth string, results []ve
 	return explanation.String()
 }
 
-// grepInFiles performs pattern matching across files
-func (s *Server) grepInFiles(ctx context.Context, pattern, basePath, filePattern string, caseInsensitive bool, contextLines int) ([]GrepResult, error) {
+// compileGrepPattern compiles a grep pattern as a regular expression, or as
+// a literal string when it is not a valid one, and returns the trigram query
+// selecting the files that can match it.
+func compileGrepPattern(pattern string, caseInsensitive bool) (*regexp.Regexp, vectorstore.TrigramQuery, error) {
+	flags := ""
+	if caseInsensitive {
+		flags = "(?i)"
+	}
+	re, err := regexp.Compile(flags + pattern)
+	if err != nil {
+		if re, err = regexp.Compile(flags + regexp.QuoteMeta(pattern)); err != nil {
+			return nil, vectorstore.TrigramQuery{}, err
+		}
+	}
+	query, err := vectorstore.RegexpTrigramQuery(re.String())
+	if err != nil {
+		return nil, vectorstore.TrigramQuery{}, err
+	}
+	return re, query, nil
+}
+
+// grepInFiles performs pattern matching across files. With a trigram index
+// only the indexed files that can match are read; otherwise every file under
+// basePath is.
+func (s *Server) grepInFiles(ctx context.Context, re *regexp.Regexp, query vectorstore.TrigramQuery, basePath, filePattern string, contextLines int) ([]GrepResult, error) {
 	// Get list of files to search
-	files, err := s.getFilesToSearch(basePath, filePattern)
+	files, ok, err := s.indexedFilesToSearch(ctx, query, basePath, filePattern)
 	if err != nil {
 		return nil, err
 	}
+	if !ok {
+		if files, err = s.getFilesToSearch(basePath, filePattern); err != nil {
+			return nil, err
+		}
+	}
 
 	var results []GrepResult
 
 	// For each file, search for the pattern
 	for _, file := range files {
-		matches, err := s.grepInFile(file, pattern, caseInsensitive, contextLines)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		matches, err := s.grepInFile(file, re, contextLines)
 		if err != nil {
 			continue // Skip files with errors
 		}
@@ -1482,6 +1606,152 @@ func (s *Server) grepInFiles(ctx context.Context, pattern, basePath, filePattern
 	return results, nil
 }
 
+// indexedFilesToSearch returns the files under basePath matching filePattern
+// that may match query: the trigram index's candidates, and the files created
+// or changed since their text was indexed. It returns false when the vector
+// store keeps no trigram index, holds no files, or basePath lies outside the
+// root the server indexes.
+func (s *Server) indexedFilesToSearch(ctx context.Context, query vectorstore.TrigramQuery, basePath, filePattern string) ([]string, bool, error) {
+	index, ok := s.vectorStore.(vectorstore.TrigramIndex)
+	if !ok {
+		return nil, false, nil
+	}
+
+	root, err := s.root()
+	if err != nil {
+		return nil, false, nil
+	}
+	base, err := filepath.Abs(basePath)
+	if err != nil || !withinDir(root, base) {
+		return nil, false, nil
+	}
+
+	candidates, ok, err := index.CandidateFiles(ctx, query)
+	if err != nil {
+		return nil, false, fmt.Errorf("find candidate files: %w", err)
+	}
+	if !ok {
+		return nil, false, nil
+	}
+	search := make(map[string]bool, len(candidates))
+	for _, candidate := range candidates {
+		search[candidate] = true
+	}
+
+	// The index holds files as they were when indexed, so files it does not
+	// know or that changed since are read as well
+	indexedAt, err := index.FileTextTimes(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("list indexed files: %w", err)
+	}
+	changed, err := s.changedSinceIndexed(ctx, root, indexedAt)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, false, ctx.Err()
+		}
+		return nil, false, nil
+	}
+	for _, path := range changed {
+		search[path] = true
+	}
+
+	paths := make([]string, 0, len(search))
+	for path := range search {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var files []string
+	for _, candidate := range paths {
+		path := filepath.Join(root, filepath.FromSlash(candidate))
+		if !withinDir(base, path) {
+			continue
+		}
+		if filePattern != "*" && filePattern != "" {
+			if matched, _ := filepath.Match(filePattern, filepath.Base(path)); !matched {
+				continue
+			}
+		}
+		rel, err := filepath.Rel(base, path)
+		if err != nil {
+			continue
+		}
+		files = append(files, filepath.Join(basePath, rel))
+	}
+	return files, true, nil
+}
+
+// changedFilesTTL bounds how long context.grep reuses a walk for files changed
+// since they were indexed while the index itself stays the same.
+const changedFilesTTL = 30 * time.Second
+
+// changedFilesCache holds the files under a root created or changed since
+// their text was indexed, found by walking the root. A walk is reused until
+// the index changes or changedFilesTTL passes, so grep does not walk the whole
+// tree on every call.
+type changedFilesCache struct {
+	mu         sync.Mutex
+	root       string
+	generation indexGeneration
+	walkedAt   time.Time
+	paths      []string
+}
+
+// indexGeneration fingerprints the files of a trigram index and the times they
+// were indexed. It changes whenever a file is indexed or removed.
+type indexGeneration struct {
+	files int
+	times int64
+}
+
+func newIndexGeneration(indexedAt map[string]time.Time) indexGeneration {
+	generation := indexGeneration{files: len(indexedAt)}
+	for _, at := range indexedAt {
+		generation.times += at.UnixNano()
+	}
+	return generation
+}
+
+// changedSinceIndexed returns the slash-separated paths, relative to root, of
+// the files under root that the trigram index does not hold or that changed
+// since they were indexed.
+func (s *Server) changedSinceIndexed(ctx context.Context, root string, indexedAt map[string]time.Time) ([]string, error) {
+	generation := newIndexGeneration(indexedAt)
+	cache := &s.changedFiles
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cache.root == root && cache.generation == generation && time.Since(cache.walkedAt) < changedFilesTTL {
+		return cache.paths, nil
+	}
+
+	walkedAt := time.Now()
+	var paths []string
+	ignorePatterns := indexer.DefaultIndexOptions(root, nil, nil).IgnorePatterns
+	err := indexer.NewFileWalker(0).Walk(ctx, root, ignorePatterns, func(path string, info fs.FileInfo) error {
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if at, ok := indexedAt[rel]; !ok || info.ModTime().After(at) {
+			paths = append(paths, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cache.root, cache.generation, cache.walkedAt, cache.paths = root, generation, walkedAt, paths
+	return paths, nil
+}
+
+// withinDir reports whether path is dir or lies below it.
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 // getFilesToSearch returns list of files matching the pattern
 func (s *Server) getFilesToSearch(basePath, filePattern string) ([]string, error) {
 	var files []string
@@ -1521,25 +1791,19 @@ func (s *Server) getFilesToSearch(basePath, filePattern string) ([]string, error
 }
 
 // grepInFile searches for pattern in a single file
-func (s *Server) grepInFile(filePath, pattern string, caseInsensitive bool, contextLines int) ([]GrepResult, error) {
+func (s *Server) grepInFile(filePath string, re *regexp.Regexp, contextLines int) ([]GrepResult, error) {
 	content, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, err
 	}
-
-	lines := strings.Split(string(content), "\n")
-	var results []GrepResult
-
-	// Compile regex pattern - escape special regex characters for literal matches
-	regexPattern := regexp.QuoteMeta(pattern)
-	if caseInsensitive {
-		regexPattern = "(?i)" + regexPattern
+	text := string(content)
+	// Show every file as the index records it, whether or not it was indexed
+	if redactor, ok := s.indexer.(indexer.SecretRedactor); ok {
+		text = redactor.RedactSecrets(text)
 	}
 
-	re, err := regexp.Compile(regexPattern)
-	if err != nil {
-		return nil, err
-	}
+	lines := strings.Split(text, "\n")
+	var results []GrepResult
 
 	// Search each line
 	for i, line := range lines {
@@ -1754,6 +2018,12 @@ func (s *Server) handleGitHubSyncTrigger(ctx context.Context, args json.RawMessa
 			return
 		}
 
+		github, err := s.openCollection(ctx, vectorstore.GitHubCollection)
+		if err != nil {
+			s.errorHandler.HandleError(ctx, err, observability.ExtractErrorContext(ctx, "github_open_collection"))
+			return
+		}
+
 		// Store issues in vector store
 		for _, issue := range issues {
 			content := fmt.Sprintf("%s\n\n%s", issue.Title, issue.Description)
@@ -1786,7 +2056,7 @@ func (s *Server) handleGitHubSyncTrigger(ctx context.Context, args json.RawMessa
 			}
 			doc.Vector = embedding.Vector
 
-			if err := s.vectorStore.Upsert(ctx, doc); err != nil {
+			if err := github.Upsert(ctx, doc); err != nil {
 				s.errorHandler.HandleError(ctx, err, observability.ExtractErrorContext(ctx, "github_store_issue"))
 			}
 		}
@@ -1828,7 +2098,7 @@ func (s *Server) handleGitHubSyncTrigger(ctx context.Context, args json.RawMessa
 			}
 			doc.Vector = embedding.Vector
 
-			if err := s.vectorStore.Upsert(ctx, doc); err != nil {
+			if err := github.Upsert(ctx, doc); err != nil {
 				s.errorHandler.HandleError(ctx, err, observability.ExtractErrorContext(ctx, "github_store_pr"))
 			}
 		}
@@ -1845,6 +2115,64 @@ func (s *Server) handleGitHubSyncTrigger(ctx context.Context, args json.RawMessa
 	}, nil
 }
 
+// openCollection returns a view of a collection conexus stores pushed or
+// GitHub content in, creating it for the server's embedder if needed.
+func (s *Server) openCollection(ctx context.Context, name string) (vectorstore.VectorStore, error) {
+	return vectorstore.OpenCollection(ctx, s.vectorStore, vectorstore.Collection{
+		Name:           name,
+		EmbeddingModel: s.embedder.Model(),
+		Dimensions:     s.embedder.Dimensions(),
+	})
+}
+
+// root returns the directory the server indexes: the configured root, or
+// the working directory.
+func (s *Server) root() (string, error) {
+	root := s.rootPath
+	if root == "" {
+		root = "."
+	}
+	return filepath.Abs(root)
+}
+
+// indexOptions returns the options of an indexing run over the server's root.
+func (s *Server) indexOptions() (indexer.IndexOptions, error) {
+	root, err := s.root()
+	if err != nil {
+		return indexer.IndexOptions{}, &protocol.Error{
+			Code:    protocol.InternalError,
+			Message: fmt.Sprintf("failed to resolve index root: %v", err),
+		}
+	}
+	return indexer.DefaultIndexOptions(root, s.embedder, s.vectorStore), nil
+}
+
+// chunkType returns the type of an indexed chunk, stored as "type", or as
+// "chunk_type" by index controllers before every path stored the same keys.
+func chunkType(metadata map[string]interface{}) string {
+	if chunkType := getStringFromMetadata(metadata, "type"); chunkType != "" {
+		return chunkType
+	}
+	return getStringFromMetadata(metadata, "chunk_type")
+}
+
+// explainMetadata returns the metadata of a document explained by
+// context.explain, with its chunk type under both "type" and "chunk_type".
+// Clients that read the older "chunk_type" key keep working.
+func explainMetadata(metadata map[string]interface{}) map[string]interface{} {
+	chunkType := chunkType(metadata)
+	if chunkType == "" {
+		return metadata
+	}
+	result := make(map[string]interface{}, len(metadata)+1)
+	for k, v := range metadata {
+		result[k] = v
+	}
+	result["type"] = chunkType
+	result["chunk_type"] = chunkType
+	return result
+}
+
 // getStringFromMetadata safely extracts string from metadata
 func getStringFromMetadata(metadata map[string]interface{}, key string) string {
 	if value, ok := metadata[key].(string); ok {
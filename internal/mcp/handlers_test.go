@@ -3,6 +3,7 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -12,7 +13,10 @@ import (
 	"github.com/ferg-cod3s/conexus/internal/connectors"
 	"github.com/ferg-cod3s/conexus/internal/connectors/github"
 	"github.com/ferg-cod3s/conexus/internal/embedding"
+	"github.com/ferg-cod3s/conexus/internal/indexer"
 	"github.com/ferg-cod3s/conexus/internal/protocol"
+	"github.com/ferg-cod3s/conexus/internal/search"
+	"github.com/ferg-cod3s/conexus/internal/security/secrets"
 	"github.com/ferg-cod3s/conexus/internal/vectorstore"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -111,6 +115,39 @@ func TestHandleContextSearch_WithFilters(t *testing.T) {
 	assert.NotNil(t, resp.Results)
 }
 
+func TestHandleContextSearch_ExpandToParent(t *testing.T) {
+	store := vectorstore.NewMemoryStore()
+	server := NewServer(nil, nil, store, newMockConnectorStore(), &mockEmbedder{}, nil, nil, &mockIndexer{})
+	ctx := context.Background()
+
+	require.NoError(t, store.UpsertBatch(ctx, []vectorstore.Document{
+		{
+			ID:       "session-type",
+			Content:  "type Session struct { token string }",
+			Vector:   make(embedding.Vector, 384),
+			Metadata: map[string]interface{}{"file_path": "session.go"},
+		},
+		{
+			ID:       "session-refresh",
+			Content:  "func (s *Session) Refresh() { renew the token }",
+			Vector:   make(embedding.Vector, 384),
+			Metadata: map[string]interface{}{"file_path": "session.go", vectorstore.ParentIDKey: "session-type"},
+		},
+	}))
+
+	reqJSON, err := json.Marshal(SearchRequest{Query: "renew", TopK: 10, ExpandToParent: true})
+	require.NoError(t, err)
+
+	result, err := server.handleContextSearch(ctx, reqJSON)
+	require.NoError(t, err)
+
+	resp, ok := result.(SearchResponse)
+	require.True(t, ok)
+	// The method hit is replaced by its type, which is returned once
+	require.Len(t, resp.Results, 1)
+	assert.Equal(t, "session-type", resp.Results[0].ID)
+}
+
 func TestHandleContextSearch_InvalidJSON(t *testing.T) {
 	store := vectorstore.NewMemoryStore()
 	embedder := &mockEmbedder{}
@@ -150,6 +187,158 @@ func TestHandleContextSearch_MissingQuery(t *testing.T) {
 	assert.Contains(t, protocolErr.Message, "query is required")
 }
 
+func TestHandleContextSearch_Modes(t *testing.T) {
+	store := vectorstore.NewMemoryStore()
+	embedder := &mockEmbedder{
+		embedFunc: func(ctx context.Context, text string) (*embedding.Embedding, error) {
+			return nil, fmt.Errorf("embedding service unavailable")
+		},
+	}
+	server := NewServer(nil, nil, store, newMockConnectorStore(), embedder, nil, nil, &mockIndexer{})
+
+	ctx := context.Background()
+	require.NoError(t, store.UpsertBatch(ctx, []vectorstore.Document{
+		{ID: "auth", Content: "authentication middleware", Vector: embedding.Vector{1, 0}},
+		{ID: "db", Content: "database connection pool", Vector: embedding.Vector{0, 1}},
+	}))
+
+	run := func(req SearchRequest) (SearchResponse, error) {
+		reqJSON, err := json.Marshal(req)
+		require.NoError(t, err)
+		result, err := server.handleContextSearch(ctx, reqJSON)
+		if err != nil {
+			return SearchResponse{}, err
+		}
+		return result.(SearchResponse), nil
+	}
+
+	// Keyword search never embeds the query
+	resp, err := run(SearchRequest{Query: "authentication", Mode: "sparse"})
+	require.NoError(t, err)
+	require.NotEmpty(t, resp.Results)
+	assert.Equal(t, "auth", resp.Results[0].ID)
+
+	// Vector and fused modes need the embedder
+	for _, mode := range []string{"dense", "rrf", "weighted"} {
+		_, err = run(SearchRequest{Query: "authentication", Mode: mode, Alpha: float32Ptr(0.7)})
+		var protocolErr *protocol.Error
+		require.ErrorAs(t, err, &protocolErr, mode)
+		assert.Equal(t, protocol.InternalError, protocolErr.Code, mode)
+	}
+
+	// Unknown modes and out of range parameters are rejected up front
+	for _, req := range []SearchRequest{
+		{Query: "authentication", Mode: "linear"},
+		{Query: "authentication", Alpha: float32Ptr(1.5)},
+		{Query: "authentication", RRFK: -1},
+	} {
+		_, err = run(req)
+		var protocolErr *protocol.Error
+		require.ErrorAs(t, err, &protocolErr)
+		assert.Equal(t, protocol.InvalidParams, protocolErr.Code)
+	}
+}
+
+func TestHandleContextSearch_Classifications(t *testing.T) {
+	store := vectorstore.NewMemoryStore()
+	server := NewServer(nil, nil, store, newMockConnectorStore(), &mockEmbedder{}, nil, nil, &mockIndexer{})
+
+	ctx := context.Background()
+	require.NoError(t, store.UpsertBatch(ctx, []vectorstore.Document{
+		{ID: "pb", Content: "session token session token", Vector: embedding.Vector{1, 0}, Metadata: map[string]interface{}{"classification": "generated"}},
+		{ID: "src", Content: "session token", Vector: embedding.Vector{0, 1}, Metadata: map[string]interface{}{"classification": "source"}},
+	}))
+
+	run := func(req SearchRequest) SearchResponse {
+		reqJSON, err := json.Marshal(req)
+		require.NoError(t, err)
+		result, err := server.handleContextSearch(ctx, reqJSON)
+		require.NoError(t, err)
+		return result.(SearchResponse)
+	}
+
+	// Generated files are left out by the search itself, so they take no
+	// place on a page
+	resp := run(SearchRequest{Query: "session token", Mode: "sparse", TopK: 1})
+	require.Len(t, resp.Results, 1)
+	assert.Equal(t, "src", resp.Results[0].ID)
+	assert.False(t, resp.HasMore)
+
+	resp = run(SearchRequest{Query: "session token", Mode: "sparse", TopK: 1,
+		Filters: &SearchFilters{Classifications: []string{"generated"}}})
+	require.Len(t, resp.Results, 1)
+	assert.Equal(t, "pb", resp.Results[0].ID)
+	assert.False(t, resp.HasMore)
+
+	resp = run(SearchRequest{Query: "session token", Mode: "sparse", TopK: 1,
+		Filters: &SearchFilters{Classifications: []string{"source", "generated"}}})
+	require.Len(t, resp.Results, 1)
+	assert.True(t, resp.HasMore)
+}
+
+func TestHandleContextSearch_Diversify(t *testing.T) {
+	store := vectorstore.NewMemoryStore()
+	server := NewServer(nil, nil, store, newMockConnectorStore(), &mockEmbedder{}, nil, nil, &mockIndexer{})
+	ctx := context.Background()
+
+	require.NoError(t, store.UpsertBatch(ctx, []vectorstore.Document{
+		{ID: "a:1", Content: "cache eviction", Vector: embedding.Vector{1, 0}, Metadata: map[string]interface{}{"file_path": "a.go"}},
+		{ID: "a:2", Content: "cache eviction policy", Vector: embedding.Vector{1, 0}, Metadata: map[string]interface{}{"file_path": "a.go"}},
+		{ID: "b:1", Content: "cache warmup", Vector: embedding.Vector{0, 1}, Metadata: map[string]interface{}{"file_path": "b.go"}},
+	}))
+
+	run := func(req SearchRequest) (SearchResponse, error) {
+		reqJSON, err := json.Marshal(req)
+		require.NoError(t, err)
+		result, err := server.handleContextSearch(ctx, reqJSON)
+		if err != nil {
+			return SearchResponse{}, err
+		}
+		return result.(SearchResponse), nil
+	}
+
+	resp, err := run(SearchRequest{Query: "cache eviction", Mode: "sparse", Diversify: true, MaxPerFile: 1})
+	require.NoError(t, err)
+	files := make([]string, 0, len(resp.Results))
+	for _, r := range resp.Results {
+		files = append(files, r.Metadata["file_path"].(string))
+	}
+	assert.ElementsMatch(t, []string{"a.go", "b.go"}, files)
+
+	_, err = run(SearchRequest{Query: "cache", Diversify: true, MMRLambda: 2})
+	var protocolErr *protocol.Error
+	require.ErrorAs(t, err, &protocolErr)
+	assert.Equal(t, protocol.InvalidParams, protocolErr.Code)
+}
+
+func float32Ptr(v float32) *float32 {
+	return &v
+}
+
+func TestSearchCacheKey(t *testing.T) {
+	req := SearchRequest{Query: "auth", Filters: &SearchFilters{DateRange: &DateRange{From: "2024-01-01"}}}
+	key := searchCacheKey(req, search.HybridModeRRF, 20, 0)
+
+	// Equal requests give equal keys, even through pointers
+	same := SearchRequest{Query: "auth", Filters: &SearchFilters{DateRange: &DateRange{From: "2024-01-01"}}}
+	assert.Equal(t, fmt.Sprint(key), fmt.Sprint(searchCacheKey(same, search.HybridModeRRF, 20, 0)))
+
+	// Fusion parameters and the result window are part of the key
+	assert.NotEqual(t, fmt.Sprint(key), fmt.Sprint(searchCacheKey(req, search.HybridModeWeighted, 20, 0)))
+	assert.NotEqual(t, fmt.Sprint(key), fmt.Sprint(searchCacheKey(req, search.HybridModeRRF, 10, 0)))
+	assert.NotEqual(t, fmt.Sprint(key), fmt.Sprint(searchCacheKey(req, search.HybridModeRRF, 20, 20)))
+	req.Alpha = float32Ptr(0.8)
+	assert.NotEqual(t, fmt.Sprint(key), fmt.Sprint(searchCacheKey(req, search.HybridModeRRF, 20, 0)))
+
+	// An explicit alpha of 0 is not the default
+	require.NoError(t, json.Unmarshal([]byte(`{"query": "auth", "alpha": 0}`), &req))
+	require.NotNil(t, req.Alpha)
+	assert.NotEqual(t, fmt.Sprint(key), fmt.Sprint(searchCacheKey(req, search.HybridModeRRF, 20, 0)))
+	req.Alpha = nil
+	req.MaxPerFile = 2
+	assert.NotEqual(t, fmt.Sprint(key), fmt.Sprint(searchCacheKey(req, search.HybridModeRRF, 20, 0)))
+}
+
 func TestHandleContextSearch_TopKDefaults(t *testing.T) {
 	store := vectorstore.NewMemoryStore()
 	embedder := &mockEmbedder{}
@@ -447,6 +636,177 @@ func TestHandleIndexControl_ReindexPaths(t *testing.T) {
 	assert.Contains(t, resp.Message, "Reindexing 2 paths")
 }
 
+// secretsMockIndexer extends mockIndexer with a canned secrets report.
+type secretsMockIndexer struct {
+	mockIndexer
+	report indexer.SecretsReport
+}
+
+func (m *secretsMockIndexer) SecretsReport() indexer.SecretsReport {
+	return m.report
+}
+
+func TestHandleIndexControl_SecretsReport(t *testing.T) {
+	store := vectorstore.NewMemoryStore()
+	mockIdx := &secretsMockIndexer{
+		report: indexer.SecretsReport{
+			GeneratedAt:   time.Now(),
+			FilesAffected: 1,
+			TotalFindings: 1,
+			Findings: []secrets.Finding{
+				{RuleID: "github_token", FilePath: ".env.example", Line: 3, Preview: "ghp_********", Action: "redacted"},
+			},
+		},
+	}
+	server := NewServer(nil, nil, store, newMockConnectorStore(), &mockEmbedder{}, nil, nil, mockIdx)
+
+	result, err := server.handleIndexControl(context.Background(), json.RawMessage(`{"action": "secrets_report"}`))
+	require.NoError(t, err)
+
+	response, ok := result.(IndexControlResponse)
+	require.True(t, ok)
+	assert.Equal(t, "ok", response.Status)
+	assert.Equal(t, 1, response.Details["total_findings"])
+	findings, ok := response.Details["findings"].([]secrets.Finding)
+	require.True(t, ok)
+	assert.Equal(t, ".env.example", findings[0].FilePath)
+
+	// Controllers without secret tracking report an error
+	plain := NewServer(nil, nil, store, newMockConnectorStore(), &mockEmbedder{}, nil, nil, &mockIndexer{})
+	_, err = plain.handleIndexControl(context.Background(), json.RawMessage(`{"action": "secrets_report"}`))
+	assert.Error(t, err)
+}
+
+type doctorMockIndexer struct {
+	mockIndexer
+	opts   indexer.IndexOptions
+	repair bool
+}
+
+func (m *doctorMockIndexer) Doctor(ctx context.Context, opts indexer.IndexOptions, repair bool) (*indexer.DoctorReport, error) {
+	m.opts = opts
+	m.repair = repair
+	return &indexer.DoctorReport{
+		CheckedAt:     time.Now(),
+		OrphanedFiles: []string{"deleted.go"},
+		FTSMissing:    []string{"doc-1"},
+		Repaired:      repair,
+	}, nil
+}
+
+func TestHandleIndexControl_Doctor(t *testing.T) {
+	store := vectorstore.NewMemoryStore()
+	mockIdx := &doctorMockIndexer{}
+	server := NewServer(nil, nil, store, newMockConnectorStore(), &mockEmbedder{}, nil, nil, mockIdx)
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".gitignore"), []byte("build/\n"), 0644))
+	server.SetRootPath(root)
+
+	result, err := server.handleIndexControl(context.Background(), json.RawMessage(`{"action": "doctor"}`))
+	require.NoError(t, err)
+
+	// The configured root is checked, with the same options as indexing
+	assert.Equal(t, root, mockIdx.opts.RootPath)
+	assert.Equal(t, []string{".git", "build/"}, mockIdx.opts.IgnorePatterns)
+
+	response, ok := result.(IndexControlResponse)
+	require.True(t, ok)
+	assert.Equal(t, "Found 2 index problems", response.Message)
+	assert.Equal(t, false, response.Details["healthy"])
+	assert.Equal(t, []string{"deleted.go"}, response.Details["orphaned_files"])
+	assert.False(t, mockIdx.repair)
+
+	result, err = server.handleIndexControl(context.Background(), json.RawMessage(`{"action": "doctor", "repair": true}`))
+	require.NoError(t, err)
+	response = result.(IndexControlResponse)
+	assert.Equal(t, "Repaired 2 index problems", response.Message)
+	assert.True(t, mockIdx.repair)
+
+	// Controllers without consistency checks report an error
+	plain := NewServer(nil, nil, store, newMockConnectorStore(), &mockEmbedder{}, nil, nil, &mockIndexer{})
+	_, err = plain.handleIndexControl(context.Background(), json.RawMessage(`{"action": "doctor"}`))
+	assert.Error(t, err)
+}
+
+type historyMockIndexer struct {
+	mockIndexer
+	opts indexer.IndexOptions
+}
+
+func (m *historyMockIndexer) IndexHistory(ctx context.Context, opts indexer.IndexOptions) (*indexer.HistoryStats, error) {
+	m.opts = opts
+	return &indexer.HistoryStats{Commits: 3, Hunks: 7, LastCommit: "abc123"}, nil
+}
+
+func TestHandleIndexControl_IndexHistory(t *testing.T) {
+	store := vectorstore.NewMemoryStore()
+	mockIdx := &historyMockIndexer{}
+	server := NewServer(nil, nil, store, newMockConnectorStore(), &mockEmbedder{}, nil, nil, mockIdx)
+	root := t.TempDir()
+	server.SetRootPath(root)
+
+	result, err := server.handleIndexControl(context.Background(), json.RawMessage(`{"action": "index_history"}`))
+	require.NoError(t, err)
+
+	response, ok := result.(IndexControlResponse)
+	require.True(t, ok)
+	assert.Equal(t, "Indexed 3 commits (7 diff hunks)", response.Message)
+	assert.Equal(t, "abc123", response.Details["last_commit"])
+	assert.Equal(t, root, mockIdx.opts.RootPath)
+	assert.Equal(t, store, mockIdx.opts.VectorStore)
+
+	// Controllers without history indexing report an error
+	plain := NewServer(nil, nil, store, newMockConnectorStore(), &mockEmbedder{}, nil, nil, &mockIndexer{})
+	_, err = plain.handleIndexControl(context.Background(), json.RawMessage(`{"action": "index_history"}`))
+	assert.Error(t, err)
+}
+
+func TestHandleIndexControl_Repositories(t *testing.T) {
+	ctx := context.Background()
+	repoRoot := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(repoRoot, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644))
+
+	store := vectorstore.NewMemoryStore()
+	controller := indexer.NewIndexController(filepath.Join(t.TempDir(), "indexer_state.json"))
+	server := NewServer(nil, nil, store, newMockConnectorStore(), &mockEmbedder{}, nil, nil, controller)
+
+	addReq, err := json.Marshal(IndexControlRequest{Action: "add_repo", RepoID: "web", RepoPath: repoRoot})
+	require.NoError(t, err)
+	result, err := server.handleIndexControl(ctx, addReq)
+	require.NoError(t, err)
+	response := result.(IndexControlResponse)
+	assert.Equal(t, "ok", response.Status)
+	assert.Equal(t, true, response.Details["full_index"])
+
+	result, err = server.handleIndexControl(ctx, json.RawMessage(`{"action": "list_repos"}`))
+	require.NoError(t, err)
+	repos := result.(IndexControlResponse).Details["repositories"].([]vectorstore.Repository)
+	require.Len(t, repos, 1)
+	assert.Equal(t, "web", repos[0].ID)
+	assert.Positive(t, repos[0].Documents)
+
+	// Searching other repositories excludes this one
+	result, err = server.handleContextSearch(ctx, json.RawMessage(`{"query": "main", "filters": {"repos": ["api"]}}`))
+	require.NoError(t, err)
+	assert.Empty(t, result.(SearchResponse).Results)
+	result, err = server.handleContextSearch(ctx, json.RawMessage(`{"query": "main", "filters": {"repos": ["web"]}}`))
+	require.NoError(t, err)
+	require.NotEmpty(t, result.(SearchResponse).Results)
+	assert.Equal(t, "web", result.(SearchResponse).Results[0].Metadata[vectorstore.RepoIDKey])
+
+	_, err = server.handleIndexControl(ctx, json.RawMessage(`{"action": "add_repo", "repo_id": "Web/../x", "repo_path": "/tmp"}`))
+	assert.Error(t, err)
+	_, err = server.handleIndexControl(ctx, json.RawMessage(`{"action": "remove_repo"}`))
+	assert.Error(t, err)
+
+	result, err = server.handleIndexControl(ctx, json.RawMessage(`{"action": "remove_repo", "repo_id": "web"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "Removed repository web", result.(IndexControlResponse).Message)
+	count, err := store.Count(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
 func TestHandleIndexControl_InvalidAction(t *testing.T) {
 	store := vectorstore.NewMemoryStore()
 	embedder := &mockEmbedder{}
@@ -518,6 +878,19 @@ func TestHandleIndexControl_Index(t *testing.T) {
 	assert.Contains(t, response.Message, "Successfully indexed document")
 	assert.NotNil(t, response.Details)
 	assert.Equal(t, "/test/example.go", response.Details["document_id"])
+
+	// Pushed content is kept apart from the indexed code
+	pushed := vectorstore.ScopeToCollection(store, vectorstore.PushedCollection)
+	doc, err := pushed.Get(ctx, "/test/example.go")
+	require.NoError(t, err)
+	assert.Equal(t, content.Content, doc.Content)
+	results, err := store.SearchBM25(ctx, "Example", vectorstore.SearchOptions{Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, results)
+	filters := &SearchFilters{SourceTypes: []string{"file"}}
+	results, err = store.SearchBM25(ctx, "Example", vectorstore.SearchOptions{Limit: 10, Filter: filters.MetadataFilter()})
+	require.NoError(t, err)
+	assert.Len(t, results, 1)
 }
 
 func TestHandleConnectorManagement_List(t *testing.T) {
@@ -645,6 +1018,14 @@ func TestHandleConnectorManagement_Remove(t *testing.T) {
 	_, err = server.handleConnectorManagement(ctx, addReqJSON)
 	require.NoError(t, err)
 
+	// One document synced by the connector and one indexed from files
+	github, err := server.openCollection(ctx, vectorstore.GitHubCollection)
+	require.NoError(t, err)
+	require.NoError(t, github.Upsert(ctx, vectorstore.Document{ID: "issue-1", Content: "issue", Vector: make(embedding.Vector, embedder.Dimensions()),
+		Metadata: map[string]interface{}{"connector_id": "github-connector", "source_type": "github_issue"}}))
+	require.NoError(t, store.Upsert(ctx, vectorstore.Document{ID: "main.go:1", Content: "code", Vector: embedding.Vector{1},
+		Metadata: map[string]interface{}{"file_path": "main.go"}}))
+
 	// Now remove it
 	removeReq := ConnectorManagementRequest{
 		Action:      "remove",
@@ -664,6 +1045,11 @@ func TestHandleConnectorManagement_Remove(t *testing.T) {
 
 	assert.Equal(t, "ok", resp.Status)
 	assert.Contains(t, resp.Message, "remove")
+	assert.Contains(t, resp.Message, "deleted 1 documents")
+
+	count, err := store.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
 }
 
 func TestHandleConnectorManagement_MissingConnectorID(t *testing.T) {
@@ -753,7 +1139,7 @@ func TestHandleContextExplain_Success(t *testing.T) {
 			Metadata: map[string]interface{}{
 				"source_type":   "file",
 				"file_path":     "auth.go",
-				"chunk_type":    "function",
+				"type":          "function",
 				"function_name": "AuthenticateUser",
 				"language":      "go",
 			},
@@ -767,7 +1153,7 @@ func TestHandleContextExplain_Success(t *testing.T) {
 			Metadata: map[string]interface{}{
 				"source_type": "file",
 				"file_path":   "models.go",
-				"chunk_type":  "struct",
+				"chunk_type":  "struct", // Stored by older index controllers
 				"type_name":   "User",
 				"language":    "go",
 			},
@@ -808,6 +1194,56 @@ func TestHandleContextExplain_Success(t *testing.T) {
 	assert.NotNil(t, resp.Examples)
 	assert.NotNil(t, resp.Related)
 	assert.NotNil(t, resp.Metadata)
+
+	// Related items report their type under both the new and the old key
+	require.NotEmpty(t, resp.Related)
+	for _, item := range resp.Related {
+		assert.NotEmpty(t, item.Metadata["type"], item.ID)
+		assert.Equal(t, item.Metadata["type"], item.Metadata["chunk_type"], item.ID)
+	}
+}
+
+func TestHandleContextExplain_Diversify(t *testing.T) {
+	store := vectorstore.NewMemoryStore()
+	server := NewServer(nil, nil, store, newMockConnectorStore(), &mockEmbedder{}, nil, nil, &mockIndexer{})
+	ctx := context.Background()
+
+	// Adjacent chunks of one file all match the target
+	var docs []vectorstore.Document
+	for i := 1; i <= 4; i++ {
+		docs = append(docs, vectorstore.Document{
+			ID:       fmt.Sprintf("token.go:%d", i),
+			Content:  fmt.Sprintf("token refresh step %d", i),
+			Vector:   embedding.Vector{1, 0},
+			Metadata: map[string]interface{}{"file_path": "token.go", "source_type": "file"},
+		})
+	}
+	docs = append(docs, vectorstore.Document{
+		ID:       "session.go:1",
+		Content:  "session token store",
+		Vector:   embedding.Vector{0, 1},
+		Metadata: map[string]interface{}{"file_path": "session.go", "source_type": "file"},
+	})
+	require.NoError(t, store.UpsertBatch(ctx, docs))
+
+	relatedFiles := func(req ExplainRequest) map[string]int {
+		reqJSON, err := json.Marshal(req)
+		require.NoError(t, err)
+		result, err := server.handleContextExplain(ctx, reqJSON)
+		require.NoError(t, err)
+		files := make(map[string]int)
+		for _, item := range result.(ExplainResponse).Related {
+			files[item.FilePath]++
+		}
+		return files
+	}
+
+	// Diversified by default
+	assert.Equal(t, map[string]int{"token.go": 2, "session.go": 1}, relatedFiles(ExplainRequest{Target: "token refresh"}))
+
+	// Opting out keeps every chunk
+	off := false
+	assert.Equal(t, 4, relatedFiles(ExplainRequest{Target: "token refresh", Diversify: &off})["token.go"])
 }
 
 func TestHandleContextExplain_MissingTarget(t *testing.T) {
@@ -918,6 +1354,142 @@ func authenticateUser(username, password string) error {
 	assert.True(t, found, "Should have found func pattern")
 }
 
+// trigramMemoryStore is a MemoryStore whose trigram index returns fixed
+// candidate files and index times.
+type trigramMemoryStore struct {
+	*vectorstore.MemoryStore
+	candidates []string
+	indexedAt  map[string]time.Time
+	queries    []vectorstore.TrigramQuery
+}
+
+func (s *trigramMemoryStore) IndexFileText(ctx context.Context, path, text string) error {
+	return nil
+}
+
+func (s *trigramMemoryStore) RemoveFileText(ctx context.Context, path string) error {
+	return nil
+}
+
+func (s *trigramMemoryStore) CandidateFiles(ctx context.Context, query vectorstore.TrigramQuery) ([]string, bool, error) {
+	s.queries = append(s.queries, query)
+	return s.candidates, true, nil
+}
+
+func (s *trigramMemoryStore) FileTextTimes(ctx context.Context) (map[string]time.Time, error) {
+	return s.indexedAt, nil
+}
+
+func TestHandleContextGrep_TrigramIndex(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Chdir(tempDir)
+	files := map[string]string{
+		"a.go":           "package a\n\nfunc handleContextGrep() {}\n",
+		"b.go":           "package b\n\nfunc other() {}\n",
+		"sub/c.go":       "package sub\n\n// handleSubGrep greps sub( directories\n",
+		"sub/notes.md":   "handleNotesGrep\n",
+		"not_indexed.go": "package a\n\nfunc handleMissingGrep() {}\n",
+	}
+	for path, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	indexed := time.Now().Add(time.Hour)
+	store := &trigramMemoryStore{
+		MemoryStore: vectorstore.NewMemoryStore(),
+		candidates:  []string{"a.go", "deleted.go", "sub/c.go", "sub/notes.md"},
+		indexedAt: map[string]time.Time{
+			"a.go": indexed, "b.go": indexed, "deleted.go": indexed, "sub/c.go": indexed, "sub/notes.md": indexed,
+			"not_indexed.go": indexed,
+		},
+	}
+	server := NewServer(nil, nil, store, newMockConnectorStore(), &mockEmbedder{}, nil, nil, &mockIndexer{})
+
+	grep := func(req GrepRequest) []GrepResult {
+		t.Helper()
+		reqJSON, err := json.Marshal(req)
+		require.NoError(t, err)
+		result, err := server.handleContextGrep(context.Background(), reqJSON)
+		require.NoError(t, err)
+		return result.(GrepResponse).Results
+	}
+	resultFiles := func(results []GrepResult) []string {
+		var paths []string
+		for _, r := range results {
+			paths = append(paths, filepath.ToSlash(r.File))
+		}
+		return paths
+	}
+
+	// Only candidates are read, and matches are verified against the expression
+	results := grep(GrepRequest{Pattern: `handle\w+Grep\(`})
+	assert.Equal(t, []string{"a.go"}, resultFiles(results))
+	assert.Equal(t, 3, results[0].Line)
+	assert.Equal(t, "handleContextGrep(", results[0].Match)
+	require.Len(t, store.queries, 1)
+	assert.Equal(t, vectorstore.TrigramQuery{Op: vectorstore.TrigramAnd, Queries: []vectorstore.TrigramQuery{
+		{Op: vectorstore.TrigramLiteral, Literal: "handle"},
+		{Op: vectorstore.TrigramLiteral, Literal: "Grep("},
+	}}, store.queries[0])
+
+	// Paths and file patterns narrow the candidates
+	assert.Equal(t, []string{"sub/c.go", "sub/notes.md"}, resultFiles(grep(GrepRequest{Pattern: "handle", Path: "sub"})))
+	assert.Equal(t, []string{"sub/notes.md"}, resultFiles(grep(GrepRequest{Pattern: "handle", Path: "sub", Include: "*.md"})))
+
+	// Invalid expressions match literally
+	assert.Equal(t, []string{"sub/c.go"}, resultFiles(grep(GrepRequest{Pattern: "sub(", CaseInsensitive: true})))
+
+	// The walk for changed files is reused while the index stays the same
+	require.NoError(t, os.WriteFile("new.go", []byte("func handleNewGrep() {}\n"), 0644))
+	assert.Equal(t, []string{"a.go"}, resultFiles(grep(GrepRequest{Pattern: `handle\w+Grep\(`})))
+
+	// Files created or changed since they were indexed are read once the index changes
+	store.indexedAt["b.go"] = time.Now().Add(-time.Hour)
+	require.NoError(t, os.WriteFile("b.go", []byte("func handleEditedGrep() {}\n"), 0644))
+	assert.Equal(t, []string{"a.go", "b.go", "new.go"}, resultFiles(grep(GrepRequest{Pattern: `handle\w+Grep\(`})))
+
+	// Paths outside the indexed root are walked
+	outside := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "x.go"), []byte("func handleOutsideGrep() {}\n"), 0644))
+	assert.Equal(t, []string{filepath.ToSlash(filepath.Join(outside, "x.go"))}, resultFiles(grep(GrepRequest{Pattern: "handle", Path: outside})))
+}
+
+// redactingIndexer redacts a fixed secret the way the indexer redacts indexed text.
+type redactingIndexer struct {
+	mockIndexer
+}
+
+func (r *redactingIndexer) RedactSecrets(content string) string {
+	return strings.ReplaceAll(content, "hunter2", "[REDACTED]")
+}
+
+func TestHandleContextGrep_RedactsSecrets(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Chdir(tempDir)
+	require.NoError(t, os.WriteFile("indexed.go", []byte("password := \"hunter2\"\n"), 0644))
+	require.NoError(t, os.WriteFile("changed.go", []byte("password := \"hunter2\"\n"), 0644))
+
+	store := &trigramMemoryStore{
+		MemoryStore: vectorstore.NewMemoryStore(),
+		candidates:  []string{"indexed.go"},
+		indexedAt:   map[string]time.Time{"indexed.go": time.Now().Add(time.Hour)},
+	}
+	server := NewServer(nil, nil, store, newMockConnectorStore(), &mockEmbedder{}, nil, nil, &redactingIndexer{})
+
+	reqJSON, err := json.Marshal(GrepRequest{Pattern: "password"})
+	require.NoError(t, err)
+	result, err := server.handleContextGrep(context.Background(), reqJSON)
+	require.NoError(t, err)
+
+	// Indexed and changed files both show the redacted text
+	results := result.(GrepResponse).Results
+	require.Len(t, results, 2)
+	for _, r := range results {
+		assert.Equal(t, "password := \"[REDACTED]\"\n", r.Content, r.File)
+	}
+}
+
 func TestHandleContextGrep_MissingPattern(t *testing.T) {
 	store := vectorstore.NewMemoryStore()
 	embedder := &mockEmbedder{}
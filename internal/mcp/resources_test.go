@@ -984,3 +984,42 @@ func containsMiddle(s, substr string) bool {
 	}
 	return false
 }
+
+func TestResourcesReadNotebook(t *testing.T) {
+	vs, metrics, errorHandler, testIndexer := setupTestComponents()
+	server := NewServer(nil, nil, vs, nil, nil, metrics, errorHandler, testIndexer)
+	ctx := context.Background()
+
+	vector := make([]float32, 384)
+	cells := []vectorstore.Document{
+		{
+			ID:      "analysis.ipynb:cell:1:4",
+			Content: "# %%\nimport pandas as pd",
+			Vector:  vector,
+			Metadata: map[string]interface{}{
+				"file_path":  "analysis.ipynb",
+				"start_line": 4,
+				"end_line":   5,
+			},
+		},
+		{
+			ID:      "analysis.ipynb:cell:0:1",
+			Content: "# %% [markdown]\n# Analysis",
+			Vector:  vector,
+			Metadata: map[string]interface{}{
+				"file_path":  "analysis.ipynb",
+				"start_line": 1,
+				"end_line":   2,
+			},
+		},
+	}
+	require.NoError(t, vs.UpsertBatch(ctx, cells))
+
+	response, err := server.handleResourcesRead(ctx, json.RawMessage(`{"uri": "engine://file/analysis.ipynb"}`))
+	require.NoError(t, err)
+
+	contents := response.(map[string]interface{})["contents"].([]map[string]interface{})
+	require.Len(t, contents, 1)
+	assert.Equal(t, "text/plain", contents[0]["mimeType"])
+	assert.Equal(t, "# %% [markdown]\n# Analysis\n\n# %%\nimport pandas as pd", contents[0]["text"])
+}
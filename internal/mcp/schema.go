@@ -4,6 +4,9 @@ package mcp
 import (
 	"encoding/json"
 	"time"
+
+	"github.com/ferg-cod3s/conexus/internal/indexer"
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
 )
 
 // Tool names exposed by the MCP server
@@ -26,11 +29,21 @@ const (
 
 // SearchRequest represents the input for context.search tool
 type SearchRequest struct {
-	Query       string         `json:"query"`
-	WorkContext *WorkContext   `json:"work_context,omitempty"`
-	TopK        int            `json:"top_k,omitempty"`
-	Offset      int            `json:"offset,omitempty"` // For pagination
-	Filters     *SearchFilters `json:"filters,omitempty"`
+	Query          string         `json:"query"`
+	WorkContext    *WorkContext   `json:"work_context,omitempty"`
+	TopK           int            `json:"top_k,omitempty"`
+	Offset         int            `json:"offset,omitempty"` // For pagination
+	Filters        *SearchFilters `json:"filters,omitempty"`
+	ExpandToParent bool           `json:"expand_to_parent,omitempty"` // Return the enclosing parent span of each match
+	Mode           string         `json:"mode,omitempty"`             // Fusion mode: rrf (default), weighted, sparse or dense
+	Alpha          *float32       `json:"alpha,omitempty"`            // Weight of vector results in fused modes (default: 0.5)
+	RRFK           int            `json:"rrf_k,omitempty"`            // Rank constant of rrf mode (default: 60)
+
+	// Diversification: maximal marginal relevance and per-group caps
+	Diversify        bool    `json:"diversify,omitempty"`           // Penalize results similar to those ranked above them
+	MMRLambda        float32 `json:"mmr_lambda,omitempty"`          // Relevance against novelty when diversifying (default: 0.7)
+	MaxPerFile       int     `json:"max_per_file,omitempty"`        // Results kept per file (default: no cap)
+	MaxPerSourceType int     `json:"max_per_source_type,omitempty"` // Results kept per source type (default: no cap)
 }
 
 // WorkContext provides information about the user's current working context
@@ -42,9 +55,11 @@ type WorkContext struct {
 
 // SearchFilters defines filtering options for search
 type SearchFilters struct {
-	SourceTypes []string            `json:"source_types,omitempty"`
-	DateRange   *DateRange          `json:"date_range,omitempty"`
-	WorkContext *WorkContextFilters `json:"work_context,omitempty"`
+	SourceTypes     []string            `json:"source_types,omitempty"`
+	DateRange       *DateRange          `json:"date_range,omitempty"`
+	WorkContext     *WorkContextFilters `json:"work_context,omitempty"`
+	Classifications []string            `json:"classifications,omitempty"` // File classes to include (default: source only)
+	Repos           []string            `json:"repos,omitempty"`           // Repository IDs to search (default: all)
 }
 
 // WorkContextFilters defines filters based on work context
@@ -62,6 +77,102 @@ type DateRange struct {
 	To   string `json:"to,omitempty"`   // ISO 8601 date-time
 }
 
+// MetadataFilter converts the filters to a metadata filter on the indexed
+// documents. Without filters only source files are matched.
+func (f *SearchFilters) MetadataFilter() vectorstore.Filter {
+	if f == nil {
+		return classificationFilter(nil)
+	}
+
+	filters := []vectorstore.Filter{classificationFilter(f.Classifications)}
+	if len(f.SourceTypes) > 0 {
+		sources := make([]vectorstore.Filter, len(f.SourceTypes))
+		for i, sourceType := range f.SourceTypes {
+			sources[i] = sourceTypeFilter(sourceType)
+		}
+		// GitHub and pushed content is kept in collections, which code
+		// searches leave out unless asked for a source type
+		filters = append(filters, vectorstore.Or(sources...), vectorstore.AllCorpora())
+	}
+	if f.DateRange != nil && (f.DateRange.From != "" || f.DateRange.To != "") {
+		// Commits record their date, issues and pull requests their last update
+		from, to := dateBound(f.DateRange.From), dateBound(f.DateRange.To)
+		filters = append(filters, vectorstore.Or(
+			vectorstore.Range("date", from, to),
+			vectorstore.Range("updated_at", from, to),
+		))
+	}
+	if len(f.Repos) > 0 {
+		filters = append(filters, vectorstore.InStrings(vectorstore.RepoIDKey, f.Repos))
+	}
+	if wc := f.WorkContext; wc != nil {
+		if wc.ActiveFile != "" {
+			filters = append(filters, vectorstore.Or(
+				vectorstore.Eq("file_path", wc.ActiveFile),
+				vectorstore.Contains("related_files", wc.ActiveFile),
+			))
+		}
+		if wc.GitBranch != "" {
+			filters = append(filters, vectorstore.Eq("git_branch", wc.GitBranch))
+		}
+		if len(wc.OpenTicketIDs) > 0 {
+			tickets := make([]interface{}, len(wc.OpenTicketIDs))
+			for i, id := range wc.OpenTicketIDs {
+				tickets[i] = id
+			}
+			filters = append(filters, vectorstore.ContainsAny("ticket_ids", tickets...))
+		}
+		if wc.CurrentStoryID != "" {
+			filters = append(filters, vectorstore.Contains("story_ids", wc.CurrentStoryID))
+		}
+	}
+	return vectorstore.And(filters...)
+}
+
+// classificationFilter matches the documents of the given file classes, or of
+// source files by default. Documents indexed without a classification, such as
+// GitHub issues, count as source.
+func classificationFilter(classes []string) vectorstore.Filter {
+	if len(classes) == 0 {
+		classes = []string{string(indexer.FileClassSource)}
+	}
+	filter := vectorstore.InStrings(indexer.ClassificationMetadataKey, classes)
+	for _, class := range classes {
+		if class == string(indexer.FileClassSource) {
+			return vectorstore.Or(vectorstore.Not(vectorstore.Exists(indexer.ClassificationMetadataKey)), filter)
+		}
+	}
+	return filter
+}
+
+// sourceTypeFilter matches the documents of a source type. Indexed files
+// carry no source type; GitHub documents are "github_issue" and "github_pr".
+func sourceTypeFilter(sourceType string) vectorstore.Filter {
+	switch sourceType {
+	case "file":
+		return vectorstore.Or(
+			vectorstore.Not(vectorstore.Exists("source_type")),
+			vectorstore.Eq("source_type", sourceType),
+		)
+	case "github":
+		return vectorstore.Prefix("source_type", sourceType)
+	default:
+		return vectorstore.Eq("source_type", sourceType)
+	}
+}
+
+// dateBound returns a range bound in the UTC RFC 3339 form dates are stored
+// in, nil when unset, or the value as given when it does not parse.
+func dateBound(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC().Format(time.RFC3339)
+	}
+	return value
+}
+
 // SearchResponse represents the output of context.search tool
 type SearchResponse struct {
 	Results    []SearchResultItem `json:"results"`
@@ -126,11 +237,15 @@ type IndexContent struct {
 
 // IndexControlRequest represents the input for context.index_control tool
 type IndexControlRequest struct {
-	Action      string        `json:"action"`                 // "start", "stop", "status", "force_reindex", "reindex_paths", "index", "sync_github"
-	Connectors  []string      `json:"connectors,omitempty"`   // Connectors to use for indexing
-	ConnectorID string        `json:"connector_id,omitempty"` // Specific connector ID (for sync_github action)
-	Paths       []string      `json:"paths,omitempty"`        // Specific paths/files to reindex (for reindex_paths action)
-	Content     *IndexContent `json:"content,omitempty"`      // Content to index (for index action)
+	Action         string        `json:"action"`                    // "start", "stop", "status", "force_reindex", "reindex_paths", "index", "sync_github", "secrets_report", "doctor", "index_history", "add_repo", "remove_repo", "list_repos"
+	Connectors     []string      `json:"connectors,omitempty"`      // Connectors to use for indexing
+	ConnectorID    string        `json:"connector_id,omitempty"`    // Specific connector ID (for sync_github action)
+	Paths          []string      `json:"paths,omitempty"`           // Specific paths/files to reindex (for reindex_paths action)
+	Content        *IndexContent `json:"content,omitempty"`         // Content to index (for index action)
+	Repair         bool          `json:"repair,omitempty"`          // Fix problems found (for doctor action)
+	RepoID         string        `json:"repo_id,omitempty"`         // Repository ID (for add_repo and remove_repo actions)
+	RepoPath       string        `json:"repo_path,omitempty"`       // Repository root directory (for add_repo action)
+	IgnorePatterns []string      `json:"ignore_patterns,omitempty"` // Extra ignore patterns of the repository (for add_repo action)
 }
 
 // IndexControlResponse represents the output of context.index_control tool
@@ -192,9 +307,10 @@ type ConnectorInfo struct {
 
 // ExplainRequest represents the input for context.explain tool
 type ExplainRequest struct {
-	Target  string `json:"target"`            // The code, function name, or concept to explain
-	Context string `json:"context,omitempty"` // Additional context about what aspect to focus on
-	Depth   string `json:"depth,omitempty"`   // "brief", "detailed", "comprehensive"
+	Target    string `json:"target"`              // The code, function name, or concept to explain
+	Context   string `json:"context,omitempty"`   // Additional context about what aspect to focus on
+	Depth     string `json:"depth,omitempty"`     // "brief", "detailed", "comprehensive"
+	Diversify *bool  `json:"diversify,omitempty"` // Spread related results over files (default: true)
 }
 
 // ExplainResponse represents the output of context.explain tool
@@ -346,6 +462,52 @@ func GetToolDefinitions() []ToolDefinition {
 						"default": 0,
 						"minimum": 0
 					},
+					"expand_to_parent": {
+						"type": "boolean",
+						"default": false,
+						"description": "Match on small chunks but return the enclosing type, function or document section instead."
+					},
+					"mode": {
+						"type": "string",
+						"enum": ["rrf", "weighted", "sparse", "dense"],
+						"default": "rrf",
+						"description": "How keyword (BM25) and vector results are combined: reciprocal rank fusion, a weighted sum of normalized scores, keyword only or vector only."
+					},
+					"alpha": {
+						"type": "number",
+						"minimum": 0,
+						"maximum": 1,
+						"default": 0.5,
+						"description": "Weight of vector results against keyword results in the rrf and weighted modes."
+					},
+					"rrf_k": {
+						"type": "integer",
+						"minimum": 1,
+						"default": 60,
+						"description": "Rank constant of the rrf mode. Lower values favour the top ranks of each list."
+					},
+					"diversify": {
+						"type": "boolean",
+						"default": false,
+						"description": "Reorder results by maximal marginal relevance, so near-duplicate chunks do not crowd out other matches."
+					},
+					"mmr_lambda": {
+						"type": "number",
+						"minimum": 0,
+						"maximum": 1,
+						"default": 0.7,
+						"description": "Relevance against novelty when diversifying. 1 keeps the relevance order."
+					},
+					"max_per_file": {
+						"type": "integer",
+						"minimum": 0,
+						"description": "Maximum results from one file. No cap when omitted."
+					},
+					"max_per_source_type": {
+						"type": "integer",
+						"minimum": 0,
+						"description": "Maximum results of one source type. No cap when omitted."
+					},
 					"filters": {
 						"type": "object",
 						"properties": {
@@ -360,6 +522,16 @@ func GetToolDefinitions() []ToolDefinition {
 									"to": {"type": "string", "format": "date-time"}
 								}
 							},
+							"classifications": {
+								"type": "array",
+								"items": {"type": "string", "enum": ["source", "generated", "vendored", "minified", "binary"]},
+								"description": "File classifications to include. Generated, vendored, minified and binary files are excluded unless listed here."
+							},
+							"repos": {
+								"type": "array",
+								"items": {"type": "string"},
+								"description": "Only search the repositories with these IDs. The default root and all repositories are searched when omitted."
+							},
 							"work_context": {
 								"type": "object",
 								"properties": {
@@ -400,7 +572,7 @@ func GetToolDefinitions() []ToolDefinition {
 				"properties": {
 					"action": {
 						"type": "string",
-						"enum": ["start", "stop", "status", "force_reindex", "reindex_paths"]
+						"enum": ["start", "stop", "status", "force_reindex", "reindex_paths", "secrets_report", "doctor", "index_history", "add_repo", "remove_repo", "list_repos"]
 					},
 					"connectors": {
 						"type": "array",
@@ -410,6 +582,23 @@ func GetToolDefinitions() []ToolDefinition {
 						"type": "array",
 						"items": {"type": "string"},
 						"description": "Specific paths/files to reindex (required for reindex_paths action)"
+					},
+					"repair": {
+						"type": "boolean",
+						"description": "Fix the problems found by the doctor action"
+					},
+					"repo_id": {
+						"type": "string",
+						"description": "Repository ID: lowercase letters, digits, '.', '_' or '-' (required for add_repo and remove_repo actions)"
+					},
+					"repo_path": {
+						"type": "string",
+						"description": "Root directory of the repository (required for add_repo action)"
+					},
+					"ignore_patterns": {
+						"type": "array",
+						"items": {"type": "string"},
+						"description": "Patterns to ignore in the repository in addition to .git and its .gitignore (for add_repo action)"
 					}
 				},
 				"required": ["action"]
@@ -449,6 +638,11 @@ func GetToolDefinitions() []ToolDefinition {
 						"type": "string",
 						"enum": ["brief", "detailed", "comprehensive"],
 						"default": "detailed"
+					},
+					"diversify": {
+						"type": "boolean",
+						"default": true,
+						"description": "Spread the related results over files instead of returning adjacent chunks of one file."
 					}
 				},
 				"required": ["target"]
@@ -456,13 +650,13 @@ func GetToolDefinitions() []ToolDefinition {
 		},
 		{
 			Name:        ToolContextGrep,
-			Description: "Performs fast, exact pattern matching across codebase using ripgrep. Use this for finding specific strings, function calls, or code patterns.",
+			Description: "Performs fast, exact pattern matching across the codebase, using the trigram index to read only the indexed files that can match. Use this for finding specific strings, function calls, or code patterns.",
 			InputSchema: json.RawMessage(`{
 				"type": "object",
 				"properties": {
 					"pattern": {
 						"type": "string",
-						"description": "The pattern to search for (supports regex)"
+						"description": "The pattern to search for (a Go regular expression; invalid ones match literally)"
 					},
 					"path": {
 						"type": "string",
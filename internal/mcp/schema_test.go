@@ -6,6 +6,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
 )
 
 func TestGetToolDefinitions(t *testing.T) {
@@ -366,3 +368,70 @@ func TestToolDefinition_ContextGrep(t *testing.T) {
 	require.True(t, ok)
 	assert.Contains(t, required, "pattern")
 }
+
+func TestSearchFilters_MetadataFilter(t *testing.T) {
+	filters := &SearchFilters{
+		SourceTypes: []string{"file", "github"},
+		DateRange:   &DateRange{From: "2024-01-01T00:00:00+02:00"},
+		Repos:       []string{"api"},
+		WorkContext: &WorkContextFilters{
+			ActiveFile:    "internal/mcp/handlers.go",
+			OpenTicketIDs: []string{"PROJ-1"},
+		},
+	}
+	filter := filters.MetadataFilter()
+	require.NoError(t, filter.Validate())
+
+	for _, tt := range []struct {
+		metadata map[string]interface{}
+		want     bool
+	}{
+		{map[string]interface{}{
+			"source_type": "github_pr", "updated_at": "2024-03-01T00:00:00Z", vectorstore.RepoIDKey: "api",
+			"related_files": []interface{}{"internal/mcp/handlers.go"}, "ticket_ids": []interface{}{"PROJ-1"},
+		}, true},
+		{map[string]interface{}{
+			"source_type": "github_pr", "updated_at": "2023-12-31T21:00:00Z", vectorstore.RepoIDKey: "api",
+			"related_files": []interface{}{"internal/mcp/handlers.go"}, "ticket_ids": []interface{}{"PROJ-1"},
+		}, false},
+		{map[string]interface{}{
+			"source_type": "commit", "date": "2024-03-01T00:00:00Z", vectorstore.RepoIDKey: "api",
+			"file_path": "internal/mcp/handlers.go", "ticket_ids": []interface{}{"PROJ-1"},
+		}, false},
+		{map[string]interface{}{
+			"date": "2024-03-01T00:00:00Z", vectorstore.RepoIDKey: "api",
+			"file_path": "internal/mcp/handlers.go", "ticket_ids": []interface{}{"PROJ-1"},
+		}, true},
+	} {
+		assert.Equal(t, tt.want, filter.Match(tt.metadata), "%v", tt.metadata)
+	}
+}
+
+func TestSearchFilters_MetadataFilterClassifications(t *testing.T) {
+	docs := map[string]map[string]interface{}{
+		"src":    {"classification": "source"},
+		"legacy": {},
+		"pb":     {"classification": "generated"},
+		"lib":    {"classification": "vendored"},
+	}
+	matching := func(filter vectorstore.Filter) []string {
+		require.NoError(t, filter.Validate())
+		var ids []string
+		for _, id := range []string{"src", "legacy", "pb", "lib"} {
+			if filter.Match(docs[id]) {
+				ids = append(ids, id)
+			}
+		}
+		return ids
+	}
+
+	// Default excludes non-source files; unclassified documents count as source
+	var none *SearchFilters
+	assert.Equal(t, []string{"src", "legacy"}, matching(none.MetadataFilter()))
+	assert.Equal(t, []string{"src", "legacy"}, matching((&SearchFilters{}).MetadataFilter()))
+
+	// Explicit classifications opt into other classes
+	assert.Equal(t, []string{"pb"}, matching((&SearchFilters{Classifications: []string{"generated"}}).MetadataFilter()))
+	assert.Equal(t, []string{"src", "legacy", "lib"},
+		matching((&SearchFilters{Classifications: []string{"source", "vendored"}}).MetadataFilter()))
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -26,10 +27,13 @@ type Server struct {
 	connectorManager *connectors.ConnectorManager
 	embedder         embedding.Embedder
 	searchCache      *search.SearchCache
+	pipeline         *search.Pipeline
 	metrics          *observability.MetricsCollector
 	errorHandler     *observability.ErrorHandler
 	jsonrpcSrv       *protocol.Server
 	indexer          indexer.IndexController
+	rootPath         string
+	changedFiles     changedFilesCache
 }
 
 // NewServer creates a new MCP server
@@ -54,6 +58,7 @@ func NewServer(
 		connectorManager: connectorManager,
 		embedder:         embedder,
 		searchCache:      searchCache,
+		pipeline:         search.NewPipeline(vectorStore, embedder, search.NewHybridFusion(), nil),
 		metrics:          metrics,
 		errorHandler:     errorHandler,
 		indexer:          indexer,
@@ -65,6 +70,19 @@ func NewServer(
 	return s
 }
 
+// SetReranker makes searches rescore their top results with r; nil disables
+// reranking.
+func (s *Server) SetReranker(r search.Reranker) {
+	s.pipeline.Reranker = r
+}
+
+// SetRootPath sets the directory index_control indexes and checks and
+// context.grep resolves indexed files against; empty means the working
+// directory.
+func (s *Server) SetRootPath(root string) {
+	s.rootPath = root
+}
+
 // Handle implements protocol.Handler interface
 func (s *Server) Handle(method string, params json.RawMessage) (interface{}, error) {
 	ctx := context.Background()
@@ -179,39 +197,45 @@ func (s *Server) handleToolsCall(ctx context.Context, params json.RawMessage) (i
 		}
 	}
 
+	return s.CallTool(ctx, req.Name, req.Arguments)
+}
+
+// CallTool runs the named tool with its JSON arguments. Transports other than
+// stdio call it so every transport handles tools the same way.
+func (s *Server) CallTool(ctx context.Context, name string, args json.RawMessage) (interface{}, error) {
 	// Add tool context for tracing and logging
-	ctx = observability.WithToolContext(ctx, req.Name, "1.0.0")
+	ctx = observability.WithToolContext(ctx, name, "1.0.0")
 
-	switch req.Name {
+	switch name {
 	case ToolContextSearch:
-		return s.handleContextSearch(ctx, req.Arguments)
+		return s.handleContextSearch(ctx, args)
 	case ToolContextGetRelatedInfo:
-		return s.handleGetRelatedInfo(ctx, req.Arguments)
+		return s.handleGetRelatedInfo(ctx, args)
 	case ToolContextIndexControl:
-		return s.handleIndexControl(ctx, req.Arguments)
+		return s.handleIndexControl(ctx, args)
 	case ToolContextConnectorManagement:
-		return s.handleConnectorManagement(ctx, req.Arguments)
+		return s.handleConnectorManagement(ctx, args)
 	case ToolContextExplain:
-		return s.handleContextExplain(ctx, req.Arguments)
+		return s.handleContextExplain(ctx, args)
 	case ToolContextGrep:
-		return s.handleContextGrep(ctx, req.Arguments)
+		return s.handleContextGrep(ctx, args)
 	case ToolGitHubSyncStatus:
-		return s.handleGitHubSyncStatus(ctx, req.Arguments)
+		return s.handleGitHubSyncStatus(ctx, args)
 	case ToolGitHubSyncTrigger:
-		return s.handleGitHubSyncTrigger(ctx, req.Arguments)
+		return s.handleGitHubSyncTrigger(ctx, args)
 	default:
 		errorCtx := observability.ExtractErrorContext(ctx, "tools/call")
 		errorCtx.ErrorType = "tool_not_found"
 		errorCtx.ErrorCode = protocol.MethodNotFound
-		errorCtx.ToolName = req.Name
+		errorCtx.ToolName = name
 
 		if s.errorHandler != nil {
-			s.errorHandler.HandleError(ctx, fmt.Errorf("unknown tool: %s", req.Name), errorCtx)
+			s.errorHandler.HandleError(ctx, fmt.Errorf("unknown tool: %s", name), errorCtx)
 		}
 
 		return nil, &protocol.Error{
 			Code:    protocol.MethodNotFound,
-			Message: fmt.Sprintf("unknown tool: %s", req.Name),
+			Message: fmt.Sprintf("unknown tool: %s", name),
 		}
 	}
 }
@@ -388,6 +412,11 @@ func (s *Server) handleResourcesRead(ctx context.Context, params json.RawMessage
 	// Determine MIME type
 	mimeType := s.getMimeType(filePath)
 
+	// Notebooks are stored as one chunk per cell; stitch them back into readable source
+	if strings.ToLower(filepath.Ext(filePath)) == ".ipynb" {
+		return s.renderNotebookChunks(req.URI, chunks), nil
+	}
+
 	// For single chunk files, return the content directly
 	if len(chunks) == 1 {
 		return map[string]interface{}{
@@ -428,6 +457,54 @@ func (s *Server) handleResourcesRead(ctx context.Context, params json.RawMessage
 	}, nil
 }
 
+// renderNotebookChunks joins notebook cell chunks, ordered by start line, into
+// a single percent-format source document.
+func (s *Server) renderNotebookChunks(uri string, chunks []vectorstore.Document) map[string]interface{} {
+	sorted := make([]vectorstore.Document, len(chunks))
+	copy(sorted, chunks)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return metadataInt(sorted[i].Metadata["start_line"]) < metadataInt(sorted[j].Metadata["start_line"])
+	})
+
+	var text string
+	if len(sorted) == 1 {
+		// A single chunk is either one cell or the raw notebook (chunking failed)
+		rendered, err := indexer.RenderNotebook(sorted[0].Content)
+		if err != nil {
+			rendered = sorted[0].Content
+		}
+		text = rendered
+	} else {
+		parts := make([]string, 0, len(sorted))
+		for _, chunk := range sorted {
+			parts = append(parts, chunk.Content)
+		}
+		text = strings.Join(parts, "\n\n")
+	}
+
+	return map[string]interface{}{
+		"contents": []map[string]interface{}{
+			{
+				"uri":      uri,
+				"mimeType": "text/plain",
+				"text":     text,
+			},
+		},
+	}
+}
+
+// metadataInt reads a numeric metadata value that may have been decoded from JSON.
+func metadataInt(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case float64:
+		return int(n)
+	default:
+		return 0
+	}
+}
+
 // validateFilePath validates that a file path is safe and doesn't contain traversal attempts
 func (s *Server) validateFilePath(filePath string) error {
 	// Check for empty path
@@ -471,6 +548,8 @@ func (s *Server) getMimeType(filePath string) string {
 		return "text/x-c"
 	case ".md":
 		return "text/markdown"
+	case ".ipynb":
+		return "application/x-ipynb+json"
 	case ".txt":
 		return "text/plain"
 	case ".json":
@@ -236,6 +236,23 @@ func TestServer_Handle_ToolsList(t *testing.T) {
 	assert.True(t, toolNames[ToolGitHubSyncTrigger])
 }
 
+func TestServer_CallTool(t *testing.T) {
+	store := vectorstore.NewMemoryStore()
+	server := NewServer(nil, nil, store, newMockConnectorStore(), &mockEmbedder{}, nil, nil, &mockIndexer{})
+
+	// Transports without a stream reach the same tool handlers
+	result, err := server.CallTool(context.Background(), ToolContextIndexControl, json.RawMessage(`{"action": "list_repos"}`))
+	require.NoError(t, err)
+	response, ok := result.(IndexControlResponse)
+	require.True(t, ok)
+	assert.Equal(t, "ok", response.Status)
+
+	_, err = server.CallTool(context.Background(), "context.unknown", nil)
+	var protoErr *protocol.Error
+	require.ErrorAs(t, err, &protoErr)
+	assert.Equal(t, protocol.MethodNotFound, protoErr.Code)
+}
+
 func TestServer_Handle_ContextSearch(t *testing.T) {
 	reader := strings.NewReader("")
 	writer := &bytes.Buffer{}
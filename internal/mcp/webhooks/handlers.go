@@ -190,8 +190,8 @@ func (wh *WebhookHandler) handleIssueEvent(ctx context.Context, connector *conne
 		UpdatedAt: time.Now(),
 	}
 
-	// Store in vector store
-	return wh.vectorStore.Upsert(ctx, doc)
+	// Store in the GitHub collection
+	return wh.upsert(ctx, doc)
 }
 
 // handlePullRequestEvent handles pull request-related webhook events
@@ -267,8 +267,8 @@ func (wh *WebhookHandler) handlePullRequestEvent(ctx context.Context, connector
 		UpdatedAt: time.Now(),
 	}
 
-	// Store in vector store
-	return wh.vectorStore.Upsert(ctx, doc)
+	// Store in the GitHub collection
+	return wh.upsert(ctx, doc)
 }
 
 // handleDiscussionEvent handles discussion-related webhook events
@@ -318,8 +318,22 @@ func (wh *WebhookHandler) handleDiscussionEvent(ctx context.Context, connector *
 		UpdatedAt: time.Now(),
 	}
 
-	// Store in vector store
-	return wh.vectorStore.Upsert(ctx, doc)
+	// Store in the GitHub collection
+	return wh.upsert(ctx, doc)
+}
+
+// upsert stores a GitHub document in the GitHub collection, apart from the
+// indexed code.
+func (wh *WebhookHandler) upsert(ctx context.Context, doc vectorstore.Document) error {
+	github, err := vectorstore.OpenCollection(ctx, wh.vectorStore, vectorstore.Collection{
+		Name:           vectorstore.GitHubCollection,
+		EmbeddingModel: wh.embedder.Model(),
+		Dimensions:     wh.embedder.Dimensions(),
+	})
+	if err != nil {
+		return err
+	}
+	return github.Upsert(ctx, doc)
 }
 
 // handlePushEvent handles push events (for code changes)
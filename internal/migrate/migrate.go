@@ -0,0 +1,334 @@
+// Package migrate applies versioned schema migrations to SQLite databases.
+//
+// Each component of a database (the vector store, the connector store) owns
+// an ordered list of migrations. Applied versions are recorded per component
+// in the schema_migrations table, and every migration runs in its own
+// transaction together with its record, so a failed migration leaves the
+// database at the previous version.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Migration is one schema change. It either runs SQL statements or, for
+// changes SQL cannot express such as data conversions, a function.
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+	Up      func(ctx context.Context, tx *sql.Tx) error
+}
+
+// Status describes a migration and whether the database has applied it.
+type Status struct {
+	Version   int       `json:"version"`
+	Name      string    `json:"name"`
+	Applied   bool      `json:"applied"`
+	AppliedAt time.Time `json:"applied_at"` // Zero for versions adopted from PRAGMA user_version
+}
+
+// Migrator applies the migrations of one component of a database.
+type Migrator struct {
+	db         *sql.DB
+	component  string
+	migrations []Migration
+
+	// UserVersion mirrors the applied version into PRAGMA user_version, and
+	// adopts it as the applied version of databases migrated before the
+	// schema_migrations table existed.
+	UserVersion bool
+
+	// DatabasePath enables a backup of the database file, written next to
+	// it, before pending migrations are applied. Components that have not
+	// applied a migration have no tables to protect and are not backed up,
+	// unless UserVersion is set and the database predates schema_migrations.
+	DatabasePath string
+}
+
+// migrationFile matches the names of SQL migration files: the version, an
+// underscore, and the name.
+var migrationFile = regexp.MustCompile(`^(\d+)_(\w+)\.sql$`)
+
+// Load reads the SQL migrations in the root of fsys, named like
+// 0001_create_connectors.sql.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("read migrations: %w", err)
+	}
+
+	var migrations []Migration
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %q: %w", entry.Name(), err)
+		}
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("read migration %s: %w", entry.Name(), err)
+		}
+		migrations = append(migrations, Migration{Version: version, Name: match[2], SQL: string(data)})
+	}
+	return migrations, nil
+}
+
+// New returns a migrator for the migrations of a component. Versions must
+// be positive and unique; they are applied in ascending order.
+func New(db *sql.DB, component string, migrations ...Migration) (*Migrator, error) {
+	if component == "" {
+		return nil, fmt.Errorf("migration component cannot be empty")
+	}
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	for i, m := range sorted {
+		if m.Version <= 0 {
+			return nil, fmt.Errorf("migration %q has invalid version %d", m.Name, m.Version)
+		}
+		if i > 0 && sorted[i-1].Version == m.Version {
+			return nil, fmt.Errorf("duplicate migration version %d", m.Version)
+		}
+		if (m.SQL == "") == (m.Up == nil) {
+			return nil, fmt.Errorf("migration %d needs either SQL or an Up function", m.Version)
+		}
+	}
+	return &Migrator{db: db, component: component, migrations: sorted}, nil
+}
+
+// Component returns the name the migrations are recorded under.
+func (m *Migrator) Component() string {
+	return m.component
+}
+
+// Latest returns the highest version known to the migrator.
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Version returns the highest version the database has applied.
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+	version := 0
+	for v := range applied {
+		version = max(version, v)
+	}
+	return version, nil
+}
+
+// Status lists the known migrations with whether each is applied. It does
+// not modify the database.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]Status, len(m.migrations))
+	for i, migration := range m.migrations {
+		appliedAt, ok := applied[migration.Version]
+		statuses[i] = Status{Version: migration.Version, Name: migration.Name, Applied: ok, AppliedAt: appliedAt}
+	}
+	return statuses, nil
+}
+
+// Up applies the pending migrations in order and returns them. The
+// database is backed up first when DatabasePath is set.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	version := 0
+	for v := range applied {
+		version = max(version, v)
+	}
+	if version > m.Latest() {
+		return nil, fmt.Errorf("%s schema version %d is newer than the supported version %d", m.component, version, m.Latest())
+	}
+
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	if len(pending) == 0 {
+		return nil, nil
+	}
+
+	if m.DatabasePath != "" && (version > 0 || m.UserVersion) {
+		if err := Backup(ctx, m.db, BackupPath(m.DatabasePath, m.component, version)); err != nil {
+			return nil, err
+		}
+	}
+	if err := m.recordAdopted(ctx, applied); err != nil {
+		return nil, err
+	}
+	for _, migration := range pending {
+		if err := m.apply(ctx, migration); err != nil {
+			return nil, fmt.Errorf("apply %s migration %d (%s): %w", m.component, migration.Version, migration.Name, err)
+		}
+	}
+	return pending, nil
+}
+
+// apply runs a migration and records it in one transaction.
+func (m *Migrator) apply(ctx context.Context, migration Migration) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if migration.SQL != "" {
+		if _, err := tx.ExecContext(ctx, migration.SQL); err != nil {
+			return err
+		}
+	} else if err := migration.Up(ctx, tx); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO schema_migrations (component, version, name, applied_at) VALUES (?, ?, ?, ?)",
+		m.component, migration.Version, migration.Name, time.Now().Unix(),
+	); err != nil {
+		return fmt.Errorf("record migration: %w", err)
+	}
+	if m.UserVersion {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", migration.Version)); err != nil {
+			return fmt.Errorf("write schema version: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// applied returns the applied versions with the time they were applied. It
+// falls back to PRAGMA user_version when UserVersion is set and the
+// component has no recorded migrations.
+func (m *Migrator) applied(ctx context.Context) (map[int]time.Time, error) {
+	var exists bool
+	if err := m.db.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations')",
+	).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("inspect schema migrations: %w", err)
+	}
+
+	applied := make(map[int]time.Time)
+	if exists {
+		rows, err := m.db.QueryContext(ctx,
+			"SELECT version, applied_at FROM schema_migrations WHERE component = ?", m.component)
+		if err != nil {
+			return nil, fmt.Errorf("query schema migrations: %w", err)
+		}
+		for rows.Next() {
+			var version int
+			var appliedAt int64
+			if err := rows.Scan(&version, &appliedAt); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("scan schema migration: %w", err)
+			}
+			applied[version] = time.Unix(appliedAt, 0)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("iterate schema migrations: %w", err)
+		}
+	}
+	if len(applied) > 0 || !m.UserVersion {
+		return applied, nil
+	}
+
+	var userVersion int
+	if err := m.db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&userVersion); err != nil {
+		return nil, fmt.Errorf("read schema version: %w", err)
+	}
+	for version := 1; version <= userVersion; version++ {
+		applied[version] = time.Time{}
+	}
+	return applied, nil
+}
+
+// recordAdopted creates the schema_migrations table and records the
+// versions adopted from PRAGMA user_version.
+func (m *Migrator) recordAdopted(ctx context.Context, applied map[int]time.Time) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			component TEXT NOT NULL,
+			version INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			applied_at INTEGER NOT NULL,
+			PRIMARY KEY (component, version)
+		)`); err != nil {
+		return fmt.Errorf("create schema migrations table: %w", err)
+	}
+
+	now := time.Now().Unix()
+	for _, migration := range m.migrations {
+		appliedAt, ok := applied[migration.Version]
+		if !ok || !appliedAt.IsZero() {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx,
+			"INSERT OR IGNORE INTO schema_migrations (component, version, name, applied_at) VALUES (?, ?, ?, ?)",
+			m.component, migration.Version, migration.Name, now,
+		); err != nil {
+			return fmt.Errorf("record adopted migration: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// BackupPath returns where Up backs up a database before migrating a
+// component from a version.
+func BackupPath(databasePath, component string, version int) string {
+	return fmt.Sprintf("%s.%s-v%d.bak", databasePath, component, version)
+}
+
+// Backup writes a consistent copy of the database to path, replacing an
+// existing file only once the copy is complete.
+func Backup(ctx context.Context, db *sql.DB, path string) error {
+	tmp := path + ".tmp"
+	if err := os.Remove(tmp); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove stale backup: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, "VACUUM INTO ?", tmp); err != nil {
+		return fmt.Errorf("back up database: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("back up database: %w", err)
+	}
+	return nil
+}
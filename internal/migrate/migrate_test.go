@@ -0,0 +1,193 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	_ "modernc.org/sqlite"
+)
+
+func openDB(t *testing.T, path string) *sql.DB {
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func testMigrations() []Migration {
+	return []Migration{
+		{Version: 2, Name: "add_body", SQL: "ALTER TABLE notes ADD COLUMN body TEXT"},
+		{Version: 1, Name: "create_notes", SQL: "CREATE TABLE notes (id INTEGER PRIMARY KEY, title TEXT)"},
+		{Version: 3, Name: "seed", Up: func(ctx context.Context, tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, "INSERT INTO notes (title, body) VALUES ('hello', 'world')")
+			return err
+		}},
+	}
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load(fstest.MapFS{
+		"0002_add_body.sql":     {Data: []byte("ALTER TABLE notes ADD COLUMN body TEXT")},
+		"0001_create_notes.sql": {Data: []byte("CREATE TABLE notes (id INTEGER PRIMARY KEY)")},
+		"README.md":             {Data: []byte("not a migration")},
+	})
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	assert.Equal(t, Migration{Version: 1, Name: "create_notes", SQL: "CREATE TABLE notes (id INTEGER PRIMARY KEY)"}, migrations[0])
+	assert.Equal(t, 2, migrations[1].Version)
+
+	_, err = Load(fstest.MapFS{"create_notes.sql": {Data: []byte("SELECT 1")}})
+	assert.Error(t, err)
+}
+
+func TestNew_Validates(t *testing.T) {
+	db := openDB(t, ":memory:")
+	for name, migrations := range map[string][]Migration{
+		"zero version": {{Version: 0, Name: "a", SQL: "SELECT 1"}},
+		"duplicate":    {{Version: 1, Name: "a", SQL: "SELECT 1"}, {Version: 1, Name: "b", SQL: "SELECT 1"}},
+		"no body":      {{Version: 1, Name: "a"}},
+	} {
+		_, err := New(db, "notes", migrations...)
+		assert.Error(t, err, name)
+	}
+	_, err := New(db, "", testMigrations()...)
+	assert.Error(t, err)
+}
+
+func TestMigrator_Up(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t, ":memory:")
+	m, err := New(db, "notes", testMigrations()...)
+	require.NoError(t, err)
+	assert.Equal(t, 3, m.Latest())
+
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, 3)
+	assert.Equal(t, "create_notes", statuses[0].Name)
+	assert.False(t, statuses[0].Applied)
+
+	applied, err := m.Up(ctx)
+	require.NoError(t, err)
+	require.Len(t, applied, 3)
+	assert.Equal(t, []int{1, 2, 3}, []int{applied[0].Version, applied[1].Version, applied[2].Version})
+
+	var body string
+	require.NoError(t, db.QueryRowContext(ctx, "SELECT body FROM notes").Scan(&body))
+	assert.Equal(t, "world", body)
+	version, err := m.Version(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, version)
+
+	// Applying again is a no-op
+	applied, err = m.Up(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	// Components are versioned independently
+	other, err := New(db, "other", Migration{Version: 1, Name: "create_other", SQL: "CREATE TABLE other (id INTEGER)"})
+	require.NoError(t, err)
+	applied, err = other.Up(ctx)
+	require.NoError(t, err)
+	assert.Len(t, applied, 1)
+}
+
+func TestMigrator_UpRollsBackFailedMigration(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t, ":memory:")
+	migrations := append(testMigrations(), Migration{Version: 4, Name: "broken", Up: func(ctx context.Context, tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM notes"); err != nil {
+			return err
+		}
+		return fmt.Errorf("conversion failed")
+	}})
+	m, err := New(db, "notes", migrations...)
+	require.NoError(t, err)
+
+	_, err = m.Up(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "broken")
+
+	version, err := m.Version(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, version)
+	var count int
+	require.NoError(t, db.QueryRowContext(ctx, "SELECT COUNT(*) FROM notes").Scan(&count))
+	assert.Equal(t, 1, count, "the failed migration is rolled back")
+}
+
+func TestMigrator_UserVersion(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t, ":memory:")
+
+	// A database migrated to version 2 before schema_migrations existed
+	for _, stmt := range []string{
+		"CREATE TABLE notes (id INTEGER PRIMARY KEY, title TEXT, body TEXT)",
+		"PRAGMA user_version = 2",
+	} {
+		_, err := db.ExecContext(ctx, stmt)
+		require.NoError(t, err)
+	}
+
+	m, err := New(db, "notes", testMigrations()...)
+	require.NoError(t, err)
+	m.UserVersion = true
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err)
+	assert.True(t, statuses[1].Applied)
+	assert.True(t, statuses[1].AppliedAt.IsZero())
+	assert.False(t, statuses[2].Applied)
+
+	applied, err := m.Up(ctx)
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+	assert.Equal(t, "seed", applied[0].Name)
+
+	var userVersion, recorded int
+	require.NoError(t, db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&userVersion))
+	assert.Equal(t, 3, userVersion)
+	require.NoError(t, db.QueryRowContext(ctx, "SELECT COUNT(*) FROM schema_migrations").Scan(&recorded))
+	assert.Equal(t, 3, recorded)
+
+	// Databases from newer versions are left alone
+	_, err = db.ExecContext(ctx, "INSERT INTO schema_migrations VALUES ('notes', 4, 'future', 0)")
+	require.NoError(t, err)
+	_, err = m.Up(ctx)
+	assert.ErrorContains(t, err, "newer")
+}
+
+func TestMigrator_Backup(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "notes.db")
+	db := openDB(t, path)
+
+	m, err := New(db, "notes", testMigrations()[1])
+	require.NoError(t, err)
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, "INSERT INTO notes (title) VALUES ('kept')")
+	require.NoError(t, err)
+
+	m, err = New(db, "notes", testMigrations()...)
+	require.NoError(t, err)
+	m.DatabasePath = path
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+
+	backupPath := BackupPath(path, "notes", 1)
+	assert.Equal(t, path+".notes-v1.bak", backupPath)
+	backup := openDB(t, backupPath)
+	var count, version int
+	require.NoError(t, backup.QueryRowContext(ctx, "SELECT COUNT(*) FROM notes").Scan(&count))
+	assert.Equal(t, 1, count)
+	require.NoError(t, backup.QueryRowContext(ctx, "SELECT MAX(version) FROM schema_migrations").Scan(&version))
+	assert.Equal(t, 1, version, "the backup holds the database before migrating")
+}
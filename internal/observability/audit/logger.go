@@ -256,6 +256,23 @@ func (l *Logger) LogRateLimitHit(ctx context.Context, ipAddr, path, method strin
 	l.Log(event)
 }
 
+// LogSecretDetected logs a secret found while indexing content. Only a masked
+// preview of the secret is recorded.
+func (l *Logger) LogSecretDetected(ctx context.Context, filePath, ruleID string, line int, preview, action string) {
+	event := NewAuditEventBuilder(EventTypeSecretDetected, CategorySecurity).
+		WithOutcome(OutcomeSuccess).
+		WithResource("file", filePath, action).
+		WithDetails(map[string]interface{}{
+			"rule_id": ruleID,
+			"line":    line,
+			"preview": preview,
+		}).
+		WithSystem(l.config.ServiceName, l.config.ServiceVersion, l.config.Environment, "").
+		Build()
+
+	l.Log(event)
+}
+
 // LogConfigChange logs a configuration change event
 func (l *Logger) LogConfigChange(ctx context.Context, userID, action, resource string, oldValue, newValue interface{}) {
 	event := NewAuditEventBuilder(EventTypeConfigChange, CategoryConfiguration).
@@ -32,6 +32,9 @@ const (
 	// Security-relevant errors
 	EventTypeSecurityError EventType = "security.error"
 
+	// Secret detection during indexing
+	EventTypeSecretDetected EventType = "security.secret_detected"
+
 	// Administrative events
 	EventTypeAdminLogin  EventType = "admin.login"
 	EventTypeAdminAction EventType = "admin.action"
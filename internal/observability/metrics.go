@@ -31,6 +31,10 @@ type MetricsCollector struct {
 	EmbeddingCacheMisses prometheus.Counter
 	EmbeddingErrorsTotal *prometheus.CounterVec
 
+	// Rerank metrics
+	RerankRequests *prometheus.CounterVec
+	RerankDuration *prometheus.HistogramVec
+
 	// Search cache metrics
 	SearchCacheHits   prometheus.Counter
 	SearchCacheMisses prometheus.Counter
@@ -216,6 +220,25 @@ func NewMetricsCollectorWithRegistry(namespace string, reg prometheus.Registerer
 			[]string{"provider", "error_type"},
 		),
 
+		// Rerank metrics
+		RerankRequests: autoCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "rerank_requests_total",
+				Help:      "Total number of rerank requests by provider and status",
+			},
+			[]string{"provider", "status"},
+		),
+		RerankDuration: autoHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Name:      "rerank_duration_seconds",
+				Help:      "Rerank duration in seconds",
+				Buckets:   []float64{.01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+			},
+			[]string{"provider"},
+		),
+
 		// Vector store metrics
 		VectorSearchRequests: autoCounterVec(
 			prometheus.CounterOpts{
@@ -373,6 +396,13 @@ func (m *MetricsCollector) RecordEmbeddingError(provider, errorType string) {
 	m.EmbeddingErrorsTotal.WithLabelValues(provider, errorType).Inc()
 }
 
+// RecordRerank records metrics for a rerank request. Status is success,
+// fallback or error.
+func (m *MetricsCollector) RecordRerank(provider, status string, duration time.Duration) {
+	m.RerankRequests.WithLabelValues(provider, status).Inc()
+	m.RerankDuration.WithLabelValues(provider).Observe(duration.Seconds())
+}
+
 // RecordVectorSearch records metrics for a vector search request.
 func (m *MetricsCollector) RecordVectorSearch(searchType, status string, duration time.Duration, resultCount int) {
 	m.VectorSearchRequests.WithLabelValues(searchType, status).Inc()
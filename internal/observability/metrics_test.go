@@ -424,6 +424,17 @@ func TestRecordEmbedding(t *testing.T) {
 	}
 }
 
+func TestRecordRerank(t *testing.T) {
+	collector := NewMetricsCollectorWithRegistry("test", prometheus.NewRegistry())
+
+	collector.RecordRerank("http", "success", 30*time.Millisecond)
+	collector.RecordRerank("http", "fallback", 10*time.Second)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(collector.RerankRequests.WithLabelValues("http", "success")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(collector.RerankRequests.WithLabelValues("http", "fallback")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(collector.RerankRequests.WithLabelValues("http", "error")))
+}
+
 func TestRecordEmbeddingCache(t *testing.T) {
 	collector, _ := newTestMetricsCollector(t)
 
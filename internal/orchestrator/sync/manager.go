@@ -338,6 +338,16 @@ func (sm *SyncManager) syncGitHub(ctx context.Context, connector interface{}) (*
 		return nil, fmt.Errorf("connector is not a GitHub connector")
 	}
 
+	// GitHub documents are kept apart from the indexed code
+	store, err := vectorstore.OpenCollection(ctx, sm.vectorStore, vectorstore.Collection{
+		Name:           vectorstore.GitHubCollection,
+		EmbeddingModel: sm.embedder.Model(),
+		Dimensions:     sm.embedder.Dimensions(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	result := &SyncResult{}
 	var errors []string
 
@@ -376,7 +386,7 @@ func (sm *SyncManager) syncGitHub(ctx context.Context, connector interface{}) (*
 			doc.Vector = embedding.Vector
 
 			// Store in vector store
-			if err := sm.vectorStore.Upsert(ctx, doc); err != nil {
+			if err := store.Upsert(ctx, doc); err != nil {
 				errors = append(errors, fmt.Sprintf("Failed to store issue %d: %v", issue.Number, err))
 			}
 		}
@@ -422,7 +432,7 @@ func (sm *SyncManager) syncGitHub(ctx context.Context, connector interface{}) (*
 			doc.Vector = embedding.Vector
 
 			// Store in vector store
-			if err := sm.vectorStore.Upsert(ctx, doc); err != nil {
+			if err := store.Upsert(ctx, doc); err != nil {
 				errors = append(errors, fmt.Sprintf("Failed to store PR %d: %v", pr.Number, err))
 			}
 		}
@@ -0,0 +1,231 @@
+package rerank
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ferg-cod3s/conexus/internal/config/options"
+	"github.com/ferg-cod3s/conexus/internal/search"
+)
+
+// DefaultAPIKeyEnv is the environment variable the HTTP reranker reads its
+// API key from when the configuration names none.
+const DefaultAPIKeyEnv = "CONEXUS_RERANK_API_KEY"
+
+// Request formats of rerank APIs.
+const (
+	// FormatDocuments sends {"model", "query", "documents"}, as Cohere,
+	// Jina, Voyage, vLLM and Infinity expect.
+	FormatDocuments = "documents"
+	// FormatTEI sends {"query", "texts"}, as Hugging Face Text Embeddings
+	// Inference expects.
+	FormatTEI = "tei"
+)
+
+// maxErrorBody is the length of a response body quoted in errors.
+const maxErrorBody = 512
+
+// HTTPScorer scores documents with a rerank API: the query and documents are
+// posted to the endpoint, which returns a relevance score per document
+// index.
+type HTTPScorer struct {
+	endpoint string
+	model    string
+	apiKey   string
+	format   string
+	client   *http.Client
+}
+
+// NewHTTPScorer creates a scorer for the rerank API at endpoint. An empty
+// format means FormatDocuments. Timeouts come from the request context.
+func NewHTTPScorer(endpoint, model, apiKey, format string) (*HTTPScorer, error) {
+	if endpoint == "" {
+		return nil, fmt.Errorf("rerank endpoint url is required")
+	}
+	switch format {
+	case "":
+		format = FormatDocuments
+	case FormatDocuments, FormatTEI:
+	default:
+		return nil, fmt.Errorf("unknown rerank format %q (want %s or %s)", format, FormatDocuments, FormatTEI)
+	}
+	return &HTTPScorer{
+		endpoint: endpoint,
+		model:    model,
+		apiKey:   apiKey,
+		format:   format,
+		client:   &http.Client{},
+	}, nil
+}
+
+// rerankRequest is the body posted to the endpoint; the fields used depend
+// on the format.
+type rerankRequest struct {
+	Model     string   `json:"model,omitempty"`
+	Query     string   `json:"query"`
+	Documents []string `json:"documents,omitempty"`
+	Texts     []string `json:"texts,omitempty"`
+}
+
+// rerankScore is one scored document of a response. APIs name the score
+// relevance_score or score.
+type rerankScore struct {
+	Index          int      `json:"index"`
+	RelevanceScore *float32 `json:"relevance_score"`
+	Score          *float32 `json:"score"`
+}
+
+// Score posts query and documents to the endpoint.
+func (s *HTTPScorer) Score(ctx context.Context, query string, documents []string) ([]float32, error) {
+	body := rerankRequest{Query: query}
+	if s.format == FormatTEI {
+		body.Texts = documents
+	} else {
+		body.Model = s.model
+		body.Documents = documents
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("post rerank request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("rerank endpoint returned %s: %s", resp.Status, truncate(string(data), maxErrorBody))
+	}
+
+	entries, err := parseScores(data)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make([]float32, len(documents))
+	scored := make([]bool, len(documents))
+	for _, entry := range entries {
+		if entry.Index < 0 || entry.Index >= len(documents) {
+			return nil, fmt.Errorf("rerank response has index %d for %d documents", entry.Index, len(documents))
+		}
+		switch {
+		case entry.RelevanceScore != nil:
+			scores[entry.Index] = *entry.RelevanceScore
+		case entry.Score != nil:
+			scores[entry.Index] = *entry.Score
+		default:
+			return nil, fmt.Errorf("rerank response has no score for index %d", entry.Index)
+		}
+		scored[entry.Index] = true
+	}
+	for i, ok := range scored {
+		if !ok {
+			return nil, fmt.Errorf("rerank response has no score for index %d", i)
+		}
+	}
+	return scores, nil
+}
+
+// parseScores reads the scored documents of a response: a "results" or
+// "data" array, or a bare array.
+func parseScores(data []byte) ([]rerankScore, error) {
+	var entries []rerankScore
+	if err := json.Unmarshal(data, &entries); err == nil {
+		return entries, nil
+	}
+
+	var wrapped struct {
+		Results []rerankScore `json:"results"`
+		Data    []rerankScore `json:"data"`
+	}
+	if err := json.Unmarshal(data, &wrapped); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	if wrapped.Results != nil {
+		return wrapped.Results, nil
+	}
+	if wrapped.Data != nil {
+		return wrapped.Data, nil
+	}
+	return nil, fmt.Errorf("rerank response has no results")
+}
+
+// truncate shortens s to n bytes.
+func truncate(s string, n int) string {
+	s = strings.TrimSpace(s)
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+// HTTPProvider implements Provider for rerank APIs served over HTTP.
+type HTTPProvider struct{}
+
+// Name returns the provider identifier.
+func (p *HTTPProvider) Name() string {
+	return "http"
+}
+
+// Create instantiates an HTTP reranker. The configuration takes:
+//   - url: the rerank endpoint (required)
+//   - model: the model name sent with each request
+//   - format: "documents" (default) or "tei"
+//   - api_key, api_key_file naming a file holding it, or api_key_env naming
+//     the variable holding it (default: CONEXUS_RERANK_API_KEY)
+//   - fallback: "lexical" (default) or "none"
+//   - top_n, batch_size and timeout (a duration such as "5s", or seconds)
+func (p *HTTPProvider) Create(config map[string]interface{}) (search.Reranker, error) {
+	values := make(map[string]string)
+	for _, key := range []string{"url", "model", "format", "fallback"} {
+		value, err := options.String(config, key)
+		if err != nil {
+			return nil, err
+		}
+		values[key] = value
+	}
+
+	apiKey, err := options.APIKey(config, DefaultAPIKeyEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	scorer, err := NewHTTPScorer(values["url"], values["model"], apiKey, values["format"])
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := optionsFromConfig(p.Name(), config)
+	if err != nil {
+		return nil, err
+	}
+	switch values["fallback"] {
+	case "", "lexical":
+		opts.Fallback = LexicalScorer{}
+	case "none":
+	default:
+		return nil, fmt.Errorf("unknown rerank fallback %q (want lexical or none)", values["fallback"])
+	}
+
+	return New(scorer, opts), nil
+}
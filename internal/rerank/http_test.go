@@ -0,0 +1,167 @@
+package rerank
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rerankServer stands in for a rerank API answering with respond. The
+// returned function lists the requests received so far.
+func rerankServer(t *testing.T, respond func(w http.ResponseWriter, req rerankRequest)) (*httptest.Server, func() []rerankRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []rerankRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rerankRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		mu.Lock()
+		requests = append(requests, req)
+		mu.Unlock()
+		respond(w, req)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []rerankRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]rerankRequest(nil), requests...)
+	}
+}
+
+func TestHTTPScorer_Documents(t *testing.T) {
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		var req rerankRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "rerank-v1", req.Model)
+		assert.Equal(t, "auth", req.Query)
+		assert.Equal(t, []string{"a", "b"}, req.Documents)
+		// Results are ordered by relevance, not by index
+		w.Write([]byte(`{"results": [{"index": 1, "relevance_score": 0.9}, {"index": 0, "relevance_score": 0.2}]}`))
+	}))
+	defer server.Close()
+
+	scorer, err := NewHTTPScorer(server.URL, "rerank-v1", "secret", "")
+	require.NoError(t, err)
+	scores, err := scorer.Score(context.Background(), "auth", []string{"a", "b"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []float32{0.2, 0.9}, scores)
+	assert.Equal(t, "Bearer secret", auth)
+}
+
+func TestHTTPScorer_TEI(t *testing.T) {
+	server, requests := rerankServer(t, func(w http.ResponseWriter, req rerankRequest) {
+		w.Write([]byte(`[{"index": 0, "score": 0.5}, {"index": 1, "score": 0.7}]`))
+	})
+
+	scorer, err := NewHTTPScorer(server.URL, "ignored", "", FormatTEI)
+	require.NoError(t, err)
+	scores, err := scorer.Score(context.Background(), "q", []string{"a", "b"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []float32{0.5, 0.7}, scores)
+	assert.Equal(t, []string{"a", "b"}, requests()[0].Texts)
+	assert.Empty(t, requests()[0].Model)
+}
+
+func TestHTTPScorer_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		errMsg string
+	}{
+		{"bad status", http.StatusTooManyRequests, "slow down", "429 Too Many Requests: slow down"},
+		{"invalid json", http.StatusOK, "{", "decode response"},
+		{"no results", http.StatusOK, `{"model": "m"}`, "no results"},
+		{"index out of range", http.StatusOK, `[{"index": 2, "score": 1}]`, "index 2 for 1 documents"},
+		{"missing score", http.StatusOK, `[{"index": 0}]`, "no score for index 0"},
+		{"missing document", http.StatusOK, `[]`, "no score for index 0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			scorer, err := NewHTTPScorer(server.URL, "", "", "")
+			require.NoError(t, err)
+			_, err = scorer.Score(context.Background(), "q", []string{"a"})
+			assert.ErrorContains(t, err, tt.errMsg)
+		})
+	}
+
+	_, err := NewHTTPScorer("", "", "", "")
+	assert.Error(t, err)
+	_, err = NewHTTPScorer("http://localhost", "", "", "xml")
+	assert.Error(t, err)
+}
+
+func TestHTTPProvider_Create(t *testing.T) {
+	t.Setenv("TEST_RERANK_KEY", "from-env")
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		var req rerankRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		scores := make([]map[string]interface{}, len(req.Documents))
+		for i, d := range req.Documents {
+			scores[i] = map[string]interface{}{"index": i, "relevance_score": float32(len(d))}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": scores})
+	}))
+	defer server.Close()
+
+	reranker, err := (&HTTPProvider{}).Create(map[string]interface{}{
+		"url":         server.URL,
+		"api_key_env": "TEST_RERANK_KEY",
+		"batch_size":  float64(2),
+	})
+	require.NoError(t, err)
+
+	reranked, err := reranker.Rerank(context.Background(), "q", results("a", "ccc", "bb"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ccc", "bb", "a"}, ids(reranked))
+	assert.Equal(t, "Bearer from-env", auth)
+
+	_, err = (&HTTPProvider{}).Create(map[string]interface{}{})
+	assert.ErrorContains(t, err, "url is required")
+	_, err = (&HTTPProvider{}).Create(map[string]interface{}{"url": server.URL, "fallback": "random"})
+	assert.ErrorContains(t, err, "unknown rerank fallback")
+	_, err = (&HTTPProvider{}).Create(map[string]interface{}{"url": server.URL, "api_key_file": "/nonexistent/key"})
+	assert.ErrorContains(t, err, "read api_key_file")
+}
+
+func TestHTTPProvider_Timeout(t *testing.T) {
+	server, requests := rerankServer(t, func(w http.ResponseWriter, req rerankRequest) {
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte(`[]`))
+	})
+
+	// With the lexical fallback a slow endpoint still yields a ranking
+	reranker, err := (&HTTPProvider{}).Create(map[string]interface{}{"url": server.URL, "timeout": "20ms"})
+	require.NoError(t, err)
+	reranked, err := reranker.Rerank(context.Background(), "session token", results("config loader", "session token store"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"session token store", "config loader"}, ids(reranked))
+	assert.Len(t, requests(), 1)
+
+	// Without one the results keep their order
+	reranker, err = (&HTTPProvider{}).Create(map[string]interface{}{"url": server.URL, "timeout": "20ms", "fallback": "none"})
+	require.NoError(t, err)
+	reranked, err = reranker.Rerank(context.Background(), "session token", results("config loader", "session token store"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"config loader", "session token store"}, ids(reranked))
+}
@@ -0,0 +1,89 @@
+package rerank
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"github.com/ferg-cod3s/conexus/internal/search"
+)
+
+// phraseBonus is the part of a lexical score earned by containing the whole
+// query; term overlap earns the rest.
+const phraseBonus = 0.2
+
+// LexicalScorer scores documents by the share of query terms they contain,
+// with a bonus for containing the query verbatim. Identifiers are split into
+// words, so "parseQuery" matches "parse the query". It needs no model and is
+// the fallback of the HTTP reranker.
+type LexicalScorer struct{}
+
+// Score returns scores in [0, 1].
+func (LexicalScorer) Score(ctx context.Context, query string, documents []string) ([]float32, error) {
+	queryTerms := lexicalTerms(query)
+	phrase := strings.ToLower(strings.TrimSpace(query))
+
+	scores := make([]float32, len(documents))
+	for i, document := range documents {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if len(queryTerms) == 0 {
+			continue
+		}
+
+		documentTerms := lexicalTerms(document)
+		matched := 0
+		for term := range queryTerms {
+			if documentTerms[term] {
+				matched++
+			}
+		}
+		score := (1 - phraseBonus) * float32(matched) / float32(len(queryTerms))
+		if phrase != "" && strings.Contains(strings.ToLower(document), phrase) {
+			score += phraseBonus
+		}
+		scores[i] = score
+	}
+	return scores, nil
+}
+
+// lexicalTerms returns the lowercase words of text, with camelCase and
+// PascalCase identifiers split into their words.
+func lexicalTerms(text string) map[string]bool {
+	terms := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		runes := []rune(word)
+		start := 0
+		for i := 1; i < len(runes); i++ {
+			prev, r := runes[i-1], runes[i]
+			if unicode.IsUpper(r) && (unicode.IsLower(prev) || unicode.IsDigit(prev) ||
+				unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				terms[strings.ToLower(string(runes[start:i]))] = true
+				start = i
+			}
+		}
+		terms[strings.ToLower(string(runes[start:]))] = true
+	}
+	return terms
+}
+
+// LexicalProvider implements Provider for the lexical reranker.
+type LexicalProvider struct{}
+
+// Name returns the provider identifier.
+func (p *LexicalProvider) Name() string {
+	return "lexical"
+}
+
+// Create instantiates a lexical reranker. It accepts the top_n and
+// batch_size options.
+func (p *LexicalProvider) Create(config map[string]interface{}) (search.Reranker, error) {
+	opts, err := optionsFromConfig(p.Name(), config)
+	if err != nil {
+		return nil, err
+	}
+	return New(LexicalScorer{}, opts), nil
+}
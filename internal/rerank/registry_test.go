@@ -0,0 +1,61 @@
+package rerank
+
+import (
+	"testing"
+
+	"github.com/ferg-cod3s/conexus/internal/search"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockTestProvider is a test provider implementation.
+type mockTestProvider struct {
+	name string
+}
+
+func (p *mockTestProvider) Name() string {
+	return p.name
+}
+
+func (p *mockTestProvider) Create(config map[string]interface{}) (search.Reranker, error) {
+	return New(LexicalScorer{}, Options{}), nil
+}
+
+func TestRegistry_Register(t *testing.T) {
+	r := NewRegistry()
+
+	require.NoError(t, r.Register(&mockTestProvider{name: "test"}))
+	assert.Equal(t, []string{"test"}, r.List())
+
+	assert.ErrorContains(t, r.Register(nil), "nil provider")
+	assert.ErrorContains(t, r.Register(&mockTestProvider{}), "name cannot be empty")
+	assert.ErrorContains(t, r.Register(&mockTestProvider{name: "test"}), "already registered")
+}
+
+func TestRegistry_Get(t *testing.T) {
+	r := NewRegistry()
+	r.MustRegister(&mockTestProvider{name: "test"})
+
+	provider, err := r.Get("test")
+	require.NoError(t, err)
+	assert.Equal(t, "test", provider.Name())
+
+	_, err = r.Get("missing")
+	assert.ErrorContains(t, err, "not found")
+
+	r.Unregister("test")
+	assert.Empty(t, r.List())
+}
+
+func TestGlobalRegistry(t *testing.T) {
+	assert.Equal(t, []string{"http", "lexical"}, List())
+
+	provider, err := Get("lexical")
+	require.NoError(t, err)
+	reranker, err := provider.Create(map[string]interface{}{"top_n": float64(5)})
+	require.NoError(t, err)
+	assert.Equal(t, 5, reranker.(*Reranker).opts.TopN)
+
+	_, err = provider.Create(map[string]interface{}{"top_n": "five"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,165 @@
+// Package rerank provides pluggable rerankers that rescore search results
+// against the query, such as cross-encoders served over HTTP.
+package rerank
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ferg-cod3s/conexus/internal/config/options"
+	"github.com/ferg-cod3s/conexus/internal/search"
+)
+
+// Defaults of a Reranker.
+const (
+	DefaultTopN      = 50
+	DefaultBatchSize = 32
+	DefaultTimeout   = 10 * time.Second
+)
+
+// Scorer scores documents by relevance to a query, like a cross-encoder
+// reading each query and document pair. Higher scores are more relevant.
+type Scorer interface {
+	// Score returns one score per document, in order.
+	Score(ctx context.Context, query string, documents []string) ([]float32, error)
+}
+
+// Provider is a factory for creating rerankers with specific configurations.
+type Provider interface {
+	// Name returns the provider identifier (e.g., "http", "lexical").
+	Name() string
+
+	// Create instantiates a reranker with the given configuration.
+	Create(config map[string]interface{}) (search.Reranker, error)
+}
+
+// MetricsRecorder records rerank requests. It is satisfied by
+// observability.MetricsCollector.
+type MetricsRecorder interface {
+	RecordRerank(provider, status string, duration time.Duration)
+}
+
+// Options configures a Reranker.
+type Options struct {
+	Provider  string        // Name recorded in metrics
+	TopN      int           // Leading results rescored; the rest follow in their order (default: DefaultTopN)
+	BatchSize int           // Documents per Score call (default: DefaultBatchSize)
+	Timeout   time.Duration // Limit of each Score call (default: DefaultTimeout)
+	Fallback  Scorer        // Scores the results when the scorer fails; nil keeps their order
+}
+
+// Reranker implements search.Reranker with a Scorer: the top results are
+// scored in batches and reordered by their new scores.
+type Reranker struct {
+	scorer  Scorer
+	opts    Options
+	metrics MetricsRecorder
+}
+
+// New creates a reranker scoring with scorer.
+func New(scorer Scorer, opts Options) *Reranker {
+	if opts.TopN <= 0 {
+		opts.TopN = DefaultTopN
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	return &Reranker{scorer: scorer, opts: opts}
+}
+
+// SetMetrics records the reranker's requests with m.
+func (r *Reranker) SetMetrics(m MetricsRecorder) {
+	r.metrics = m
+}
+
+// Rerank rescores the first TopN results and sorts them by their new score.
+// A result's RerankedFrom is its rank before reranking. When the scorer
+// fails the fallback scores the results instead, if there is one; when that
+// fails too the results are returned in their order, so a failing reranker
+// never fails the search. Failures are recorded in the metrics.
+func (r *Reranker) Rerank(ctx context.Context, query string, results []search.Result) ([]search.Result, error) {
+	if len(results) == 0 {
+		return results, nil
+	}
+	start := time.Now()
+
+	n := min(len(results), r.opts.TopN)
+	documents := make([]string, n)
+	for i := range documents {
+		documents[i] = results[i].Document.Content
+	}
+
+	status := "success"
+	scores, err := r.score(ctx, r.scorer, query, documents)
+	if err != nil && r.opts.Fallback != nil {
+		status = "fallback"
+		scores, err = r.score(ctx, r.opts.Fallback, query, documents)
+	}
+	if err != nil {
+		r.record("error", start)
+		return results, nil
+	}
+	r.record(status, start)
+
+	reranked := make([]search.Result, len(results))
+	copy(reranked, results)
+	for i := range reranked[:n] {
+		reranked[i].Score = scores[i]
+		reranked[i].RerankedFrom = i
+	}
+	sort.SliceStable(reranked[:n], func(i, j int) bool {
+		return reranked[i].Score > reranked[j].Score
+	})
+	return reranked, nil
+}
+
+// score scores documents in batches, each within the timeout.
+func (r *Reranker) score(ctx context.Context, scorer Scorer, query string, documents []string) ([]float32, error) {
+	scores := make([]float32, 0, len(documents))
+	for start := 0; start < len(documents); start += r.opts.BatchSize {
+		batch := documents[start:min(start+r.opts.BatchSize, len(documents))]
+
+		batchCtx, cancel := context.WithTimeout(ctx, r.opts.Timeout)
+		batchScores, err := scorer.Score(batchCtx, query, batch)
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+		if len(batchScores) != len(batch) {
+			return nil, fmt.Errorf("scorer returned %d scores for %d documents", len(batchScores), len(batch))
+		}
+		scores = append(scores, batchScores...)
+	}
+	return scores, nil
+}
+
+// record records a request with the metrics, if any.
+func (r *Reranker) record(status string, start time.Time) {
+	if r.metrics != nil {
+		r.metrics.RecordRerank(r.opts.Provider, status, time.Since(start))
+	}
+}
+
+// optionsFromConfig reads the Options shared by all providers from config.
+func optionsFromConfig(provider string, config map[string]interface{}) (Options, error) {
+	opts := Options{Provider: provider}
+	var err error
+	if opts.TopN, err = options.Int(config, "top_n"); err != nil {
+		return Options{}, err
+	}
+	if opts.BatchSize, err = options.Int(config, "batch_size"); err != nil {
+		return Options{}, err
+	}
+	if opts.Timeout, err = options.Duration(config, "timeout"); err != nil {
+		return Options{}, err
+	}
+	if opts.TopN < 0 || opts.BatchSize < 0 || opts.Timeout < 0 {
+		return Options{}, fmt.Errorf("top_n, batch_size and timeout cannot be negative")
+	}
+	return opts, nil
+}
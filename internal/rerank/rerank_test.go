@@ -0,0 +1,174 @@
+package rerank
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ferg-cod3s/conexus/internal/search"
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scorerFunc adapts a function to Scorer.
+type scorerFunc func(ctx context.Context, query string, documents []string) ([]float32, error)
+
+func (f scorerFunc) Score(ctx context.Context, query string, documents []string) ([]float32, error) {
+	return f(ctx, query, documents)
+}
+
+// recordedRequest is a request seen by fakeMetrics.
+type recordedRequest struct {
+	provider, status string
+}
+
+type fakeMetrics struct {
+	requests []recordedRequest
+}
+
+func (m *fakeMetrics) RecordRerank(provider, status string, duration time.Duration) {
+	m.requests = append(m.requests, recordedRequest{provider, status})
+}
+
+func results(contents ...string) []search.Result {
+	var results []search.Result
+	for i, content := range contents {
+		results = append(results, search.Result{
+			Document:     vectorstore.Document{ID: content, Content: content},
+			Score:        1 - float32(i)/10,
+			RerankedFrom: -1,
+		})
+	}
+	return results
+}
+
+func ids(results []search.Result) []string {
+	var ids []string
+	for _, r := range results {
+		ids = append(ids, r.Document.ID)
+	}
+	return ids
+}
+
+func TestReranker_Rerank(t *testing.T) {
+	scores := map[string]float32{"a": 0.1, "b": 0.9, "c": 0.5}
+	scorer := scorerFunc(func(ctx context.Context, query string, documents []string) ([]float32, error) {
+		var out []float32
+		for _, d := range documents {
+			out = append(out, scores[d])
+		}
+		return out, nil
+	})
+	metrics := &fakeMetrics{}
+	r := New(scorer, Options{Provider: "test", TopN: 3})
+	r.SetMetrics(metrics)
+
+	reranked, err := r.Rerank(context.Background(), "q", results("a", "b", "c", "d"))
+	require.NoError(t, err)
+
+	// Only the top three are rescored; the rest keep their order
+	assert.Equal(t, []string{"b", "c", "a", "d"}, ids(reranked))
+	assert.Equal(t, float32(0.9), reranked[0].Score)
+	assert.Equal(t, 1, reranked[0].RerankedFrom)
+	assert.Equal(t, -1, reranked[3].RerankedFrom)
+	assert.Equal(t, []recordedRequest{{"test", "success"}}, metrics.requests)
+
+	empty, err := r.Rerank(context.Background(), "q", nil)
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+}
+
+func TestReranker_Batches(t *testing.T) {
+	var batches [][]string
+	scorer := scorerFunc(func(ctx context.Context, query string, documents []string) ([]float32, error) {
+		batches = append(batches, documents)
+		return make([]float32, len(documents)), nil
+	})
+
+	_, err := New(scorer, Options{BatchSize: 2}).Rerank(context.Background(), "q", results("a", "b", "c", "d", "e"))
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"a", "b"}, {"c", "d"}, {"e"}}, batches)
+}
+
+func TestReranker_Fallback(t *testing.T) {
+	failing := scorerFunc(func(ctx context.Context, query string, documents []string) ([]float32, error) {
+		return nil, errors.New("unavailable")
+	})
+	metrics := &fakeMetrics{}
+
+	r := New(failing, Options{Provider: "test", Fallback: LexicalScorer{}})
+	r.SetMetrics(metrics)
+	reranked, err := r.Rerank(context.Background(), "parse query", results("unrelated", "parseQuery helper"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"parseQuery helper", "unrelated"}, ids(reranked))
+
+	// Without a fallback the results keep their order
+	r = New(failing, Options{Provider: "test"})
+	r.SetMetrics(metrics)
+	reranked, err = r.Rerank(context.Background(), "parse query", results("unrelated", "parseQuery helper"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"unrelated", "parseQuery helper"}, ids(reranked))
+
+	assert.Equal(t, []recordedRequest{{"test", "fallback"}, {"test", "error"}}, metrics.requests)
+}
+
+func TestReranker_ScoreCountMismatch(t *testing.T) {
+	scorer := scorerFunc(func(ctx context.Context, query string, documents []string) ([]float32, error) {
+		return []float32{1}, nil
+	})
+	metrics := &fakeMetrics{}
+	r := New(scorer, Options{Provider: "test"})
+	r.SetMetrics(metrics)
+	reranked, err := r.Rerank(context.Background(), "q", results("a", "b"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, ids(reranked))
+	assert.Equal(t, []recordedRequest{{"test", "error"}}, metrics.requests)
+}
+
+func TestLexicalScorer(t *testing.T) {
+	scores, err := LexicalScorer{}.Score(context.Background(), "parse query", []string{
+		"func parseQuery(s string)",
+		"parse query strings",
+		"parse the input",
+		"nothing relevant",
+	})
+	require.NoError(t, err)
+
+	assert.InDelta(t, 0.8, scores[0], 1e-6)
+	assert.InDelta(t, 1.0, scores[1], 1e-6)
+	assert.InDelta(t, 0.4, scores[2], 1e-6)
+	assert.Zero(t, scores[3])
+}
+
+func TestLexicalTerms(t *testing.T) {
+	terms := lexicalTerms("HTTPServer.handleRequest v2Client")
+	for _, term := range []string{"http", "server", "handle", "request", "v2", "client"} {
+		assert.True(t, terms[term], term)
+	}
+}
+
+func TestOptionsFromConfig(t *testing.T) {
+	opts, err := optionsFromConfig("p", map[string]interface{}{
+		"top_n":      float64(20),
+		"batch_size": 8,
+		"timeout":    "2s",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Options{Provider: "p", TopN: 20, BatchSize: 8, Timeout: 2 * time.Second}, opts)
+
+	opts, err = optionsFromConfig("p", map[string]interface{}{"timeout": 1.5})
+	require.NoError(t, err)
+	assert.Equal(t, 1500*time.Millisecond, opts.Timeout)
+
+	for _, config := range []map[string]interface{}{
+		{"top_n": 1.5},
+		{"batch_size": -1},
+		{"timeout": "soon"},
+		{"timeout": true},
+	} {
+		_, err := optionsFromConfig("p", config)
+		assert.Error(t, err, "%v", config)
+	}
+}
@@ -0,0 +1,152 @@
+package search
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ferg-cod3s/conexus/internal/embedding"
+)
+
+// DefaultMMRLambda balances relevance against novelty, leaning on relevance.
+const DefaultMMRLambda = 0.7
+
+// DiversityOptions tunes the diversification stage, which keeps a page of
+// results from being adjacent chunks of one file.
+type DiversityOptions struct {
+	Lambda           float32 // MMR trade-off in (0, 1]; 1 keeps the relevance order, 0 means DefaultMMRLambda
+	MaxPerFile       int     // Results kept per file_path; 0 means no cap
+	MaxPerSourceType int     // Results kept per source_type; 0 means no cap
+}
+
+// Validate checks that o's parameters are in range.
+func (o DiversityOptions) Validate() error {
+	if o.Lambda < 0 || o.Lambda > 1 {
+		return fmt.Errorf("mmr lambda must be between 0 and 1, got %g", o.Lambda)
+	}
+	if o.MaxPerFile < 0 || o.MaxPerSourceType < 0 {
+		return fmt.Errorf("result caps cannot be negative")
+	}
+	return nil
+}
+
+// Diversify reorders results by maximal marginal relevance: each position
+// goes to the result maximizing
+//
+//	λ * relevance - (1-λ) * max similarity to the results already chosen
+//
+// where relevance is the score relative to the best one and similarity the cosine
+// of the stored document vectors. Results without a vector, or with one of
+// another dimension, count as dissimilar. Results beyond the per-file or
+// per-source-type caps are dropped. Scores are left unchanged.
+func Diversify(results []Result, opts DiversityOptions) []Result {
+	lambda := opts.Lambda
+	if lambda == 0 {
+		lambda = DefaultMMRLambda
+	}
+
+	relevance := normalizeScores(results)
+	// Highest similarity of each result to those already chosen
+	redundancy := make([]float32, len(results))
+	done := make([]bool, len(results))
+	perFile := make(map[string]int)
+	perSourceType := make(map[string]int)
+
+	diversified := make([]Result, 0, len(results))
+	for {
+		best := -1
+		var bestScore float32
+		for i, r := range results {
+			if done[i] {
+				continue
+			}
+			// Counts only grow, so a capped result stays capped
+			if capped(r, "file_path", perFile, opts.MaxPerFile) ||
+				capped(r, "source_type", perSourceType, opts.MaxPerSourceType) {
+				done[i] = true
+				continue
+			}
+			score := lambda*relevance[i] - (1-lambda)*redundancy[i]
+			if best < 0 || score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		done[best] = true
+		chosen := results[best]
+		if chosen.RerankedFrom == -1 {
+			chosen.RerankedFrom = best
+		}
+		diversified = append(diversified, chosen)
+		count(chosen, "file_path", perFile)
+		count(chosen, "source_type", perSourceType)
+
+		if lambda == 1 {
+			continue
+		}
+		for i, r := range results {
+			if done[i] {
+				continue
+			}
+			if sim := cosineSimilarity(chosen.Document.Vector, r.Document.Vector); sim > redundancy[i] {
+				redundancy[i] = sim
+			}
+		}
+	}
+	return diversified
+}
+
+// capped reports whether the metadata value of key of r has reached limit.
+func capped(r Result, key string, counts map[string]int, limit int) bool {
+	if limit <= 0 {
+		return false
+	}
+	value, ok := r.Document.Metadata[key].(string)
+	return ok && value != "" && counts[value] >= limit
+}
+
+// count records r against the metadata value of key.
+func count(r Result, key string, counts map[string]int) {
+	if value, ok := r.Document.Metadata[key].(string); ok && value != "" {
+		counts[value]++
+	}
+}
+
+// normalizeScores scales the scores of results by the best one, so they are
+// comparable with cosine similarities whatever the fusion mode. Unlike min-max
+// scaling, this keeps the weakest candidate's relevance above zero.
+func normalizeScores(results []Result) []float32 {
+	var best float32
+	for _, r := range results {
+		best = max(best, r.Score)
+	}
+	normalized := make([]float32, len(results))
+	for i, r := range results {
+		if best > 0 {
+			normalized[i] = max(r.Score, 0) / best
+		} else {
+			normalized[i] = 1
+		}
+	}
+	return normalized
+}
+
+// cosineSimilarity returns the cosine of a and b, or 0 when they cannot be
+// compared.
+func cosineSimilarity(a, b embedding.Vector) float32 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return float32(dot / (math.Sqrt(normA) * math.Sqrt(normB)))
+}
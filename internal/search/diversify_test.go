@@ -0,0 +1,104 @@
+package search
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ferg-cod3s/conexus/internal/embedding"
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func chunk(id, file string, score float32, vector ...float32) Result {
+	return Result{
+		Document: vectorstore.Document{
+			ID:       id,
+			Vector:   embedding.Vector(vector),
+			Metadata: map[string]interface{}{"file_path": file, "source_type": "file"},
+		},
+		Score:        score,
+		RerankedFrom: -1,
+	}
+}
+
+// adjacentChunks are three near-identical chunks of one file outranking a
+// distinct match from another.
+func adjacentChunks() []Result {
+	return []Result{
+		chunk("auth.go:1", "auth.go", 0.95, 1, 0, 0),
+		chunk("auth.go:2", "auth.go", 0.94, 0.99, 0.1, 0),
+		chunk("auth.go:3", "auth.go", 0.93, 0.98, 0.15, 0),
+		chunk("session.go:1", "session.go", 0.85, 0, 1, 0),
+	}
+}
+
+func TestDiversify_MMR(t *testing.T) {
+	results := Diversify(adjacentChunks(), DiversityOptions{})
+
+	// The distinct chunk moves up past the near duplicates
+	assert.Equal(t, []string{"auth.go:1", "session.go:1", "auth.go:2", "auth.go:3"}, resultIDs(results))
+	assert.Equal(t, 3, results[1].RerankedFrom)
+	assert.Equal(t, float32(0.85), results[1].Score)
+}
+
+func TestDiversify_LambdaOneKeepsOrder(t *testing.T) {
+	results := Diversify(adjacentChunks(), DiversityOptions{Lambda: 1})
+	assert.Equal(t, []string{"auth.go:1", "auth.go:2", "auth.go:3", "session.go:1"}, resultIDs(results))
+}
+
+func TestDiversify_Caps(t *testing.T) {
+	results := Diversify(adjacentChunks(), DiversityOptions{Lambda: 1, MaxPerFile: 2})
+	assert.Equal(t, []string{"auth.go:1", "auth.go:2", "session.go:1"}, resultIDs(results))
+
+	results = Diversify(adjacentChunks(), DiversityOptions{Lambda: 1, MaxPerSourceType: 1})
+	assert.Equal(t, []string{"auth.go:1"}, resultIDs(results))
+}
+
+func TestDiversify_WithoutVectors(t *testing.T) {
+	// Results without vectors are never redundant, so relevance decides
+	results := []Result{
+		chunk("a", "a.go", 0.9),
+		chunk("b", "b.go", 0.5),
+		chunk("c", "c.go", 0.7),
+	}
+	assert.Equal(t, []string{"a", "c", "b"}, resultIDs(Diversify(results, DiversityOptions{})))
+	assert.Empty(t, Diversify(nil, DiversityOptions{}))
+}
+
+func TestDiversityOptions_Validate(t *testing.T) {
+	assert.NoError(t, DiversityOptions{}.Validate())
+	assert.NoError(t, DiversityOptions{Lambda: 0.5, MaxPerFile: 2}.Validate())
+	assert.Error(t, DiversityOptions{Lambda: 1.2}.Validate())
+	assert.Error(t, DiversityOptions{MaxPerFile: -1}.Validate())
+}
+
+func TestPipeline_Search_Diversity(t *testing.T) {
+	var candidates int
+	store := &mockVectorStore{
+		searchVectorFunc: func(ctx context.Context, vector embedding.Vector, opts vectorstore.SearchOptions) ([]vectorstore.SearchResult, error) {
+			candidates = opts.Limit
+			var results []vectorstore.SearchResult
+			for _, r := range adjacentChunks() {
+				results = append(results, vectorstore.SearchResult{Document: r.Document, Score: r.Score})
+			}
+			return results, nil
+		},
+	}
+
+	pipeline := NewPipeline(store, &mockEmbedder{}, NewHybridFusion(), nil)
+	results, err := pipeline.Search(context.Background(), Query{
+		Text:       "auth",
+		Limit:      2,
+		HybridMode: HybridModeDense,
+		Diversity:  &DiversityOptions{MaxPerFile: 1},
+	})
+	require.NoError(t, err)
+
+	// Diversification chooses from a larger candidate pool
+	assert.Equal(t, 8, candidates)
+	assert.Equal(t, []string{"auth.go:1", "session.go:1"}, resultIDs(results))
+
+	_, err = pipeline.Search(context.Background(), Query{Text: "auth", Diversity: &DiversityOptions{Lambda: -1}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,262 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+)
+
+// Fusion defaults, matching the vector store's hybrid search.
+const (
+	DefaultAlpha = 0.5 // Equal weight for sparse and dense results
+	DefaultRRFK  = 60  // Standard RRF rank constant
+)
+
+// FusionOptions tunes how a FusionStrategy combines sparse and dense results.
+type FusionOptions struct {
+	Mode  HybridMode // How to combine the lists; empty means HybridModeRRF
+	Alpha *float32   // Weight of dense results in [0, 1]; nil means DefaultAlpha
+	K     int        // RRF rank constant; 0 means DefaultRRFK
+}
+
+// ParseHybridMode returns the mode named by s, which may be empty for the
+// default mode.
+func ParseHybridMode(s string) (HybridMode, error) {
+	switch mode := HybridMode(s); mode {
+	case "":
+		return HybridModeRRF, nil
+	case HybridModeRRF, HybridModeWeighted, HybridModeSparse, HybridModeDense:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown hybrid mode %q (want rrf, weighted, sparse or dense)", s)
+	}
+}
+
+// usesSparse reports whether the mode ranks BM25 results.
+func (m HybridMode) usesSparse() bool {
+	return m != HybridModeDense
+}
+
+// usesDense reports whether the mode ranks vector results.
+func (m HybridMode) usesDense() bool {
+	return m != HybridModeSparse
+}
+
+// fused reports whether the mode combines both lists, so its scores are not
+// those of either search.
+func (m HybridMode) fused() bool {
+	return m.usesSparse() && m.usesDense()
+}
+
+// Method returns the vector store's name for the search method of the mode.
+func (m HybridMode) Method() string {
+	switch m {
+	case HybridModeSparse:
+		return "bm25"
+	case HybridModeDense:
+		return "vector"
+	default:
+		return "hybrid"
+	}
+}
+
+// withDefaults fills in the zero fields of o.
+func (o FusionOptions) withDefaults() FusionOptions {
+	if o.Mode == "" {
+		o.Mode = HybridModeRRF
+	}
+	if o.Alpha == nil {
+		alpha := float32(DefaultAlpha)
+		o.Alpha = &alpha
+	}
+	if o.K == 0 {
+		o.K = DefaultRRFK
+	}
+	return o
+}
+
+// Validate checks that o names a known mode and its parameters are in range.
+func (o FusionOptions) Validate() error {
+	if _, err := ParseHybridMode(string(o.Mode)); err != nil {
+		return err
+	}
+	if o.Alpha != nil && (*o.Alpha < 0 || *o.Alpha > 1) {
+		return fmt.Errorf("alpha must be between 0 and 1, got %g", *o.Alpha)
+	}
+	if o.K < 0 {
+		return fmt.Errorf("rrf k cannot be negative, got %d", o.K)
+	}
+	return nil
+}
+
+// HybridFusion implements every HybridMode:
+//   - rrf scores α/(k+rank_dense) + (1-α)/(k+rank_sparse), with 1-based ranks
+//     and no contribution from a list that misses the document.
+//   - weighted min-max normalizes the scores of each list to [0, 1] and
+//     scores α*dense + (1-α)*sparse, so BM25 and cosine scores are comparable.
+//   - sparse and dense return their list with its own scores.
+type HybridFusion struct{}
+
+// NewHybridFusion creates the fusion strategy for all hybrid modes.
+func NewHybridFusion() *HybridFusion {
+	return &HybridFusion{}
+}
+
+// Fuse combines sparse and dense results according to opts.
+func (f *HybridFusion) Fuse(ctx context.Context, sparseResults, denseResults []vectorstore.SearchResult, opts FusionOptions) ([]Result, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	opts = opts.withDefaults()
+
+	// Single modes ignore the other list
+	switch opts.Mode {
+	case HybridModeSparse:
+		denseResults = nil
+	case HybridModeDense:
+		sparseResults = nil
+	}
+
+	candidates := newCandidates(sparseResults, denseResults)
+	switch opts.Mode {
+	case HybridModeSparse:
+		candidates.score(func(c *candidate) float32 { return c.SparseScore })
+	case HybridModeDense:
+		candidates.score(func(c *candidate) float32 { return c.DenseScore })
+	case HybridModeRRF:
+		k, alpha := float32(opts.K), *opts.Alpha
+		candidates.score(func(c *candidate) float32 {
+			var score float32
+			if c.denseRank > 0 {
+				score += alpha / (k + float32(c.denseRank))
+			}
+			if c.sparseRank > 0 {
+				score += (1 - alpha) / (k + float32(c.sparseRank))
+			}
+			return score
+		})
+	case HybridModeWeighted:
+		sparseNorm := minMaxNormalizer(storeScores(sparseResults))
+		denseNorm := minMaxNormalizer(storeScores(denseResults))
+		alpha := *opts.Alpha
+		candidates.score(func(c *candidate) float32 {
+			var score float32
+			if c.denseRank > 0 {
+				score += alpha * denseNorm(c.DenseScore)
+			}
+			if c.sparseRank > 0 {
+				score += (1 - alpha) * sparseNorm(c.SparseScore)
+			}
+			return score
+		})
+	}
+	return candidates.results(), nil
+}
+
+// candidate is a document found by either search with its rank in each list
+// (0 when absent).
+type candidate struct {
+	Result
+	sparseRank int
+	denseRank  int
+}
+
+// candidates collects the documents of both lists in order of first
+// appearance.
+type candidates struct {
+	order []*candidate
+	byID  map[string]*candidate
+}
+
+func newCandidates(sparseResults, denseResults []vectorstore.SearchResult) *candidates {
+	c := &candidates{byID: make(map[string]*candidate, len(sparseResults)+len(denseResults))}
+	for i, r := range sparseResults {
+		if cand := c.add(r.Document); cand.sparseRank == 0 {
+			cand.sparseRank = i + 1
+			cand.SparseScore = r.Score
+		}
+	}
+	for i, r := range denseResults {
+		if cand := c.add(r.Document); cand.denseRank == 0 {
+			cand.denseRank = i + 1
+			cand.DenseScore = r.Score
+		}
+	}
+	return c
+}
+
+// add returns the candidate for doc, creating it when first seen.
+func (c *candidates) add(doc vectorstore.Document) *candidate {
+	if cand, ok := c.byID[doc.ID]; ok {
+		return cand
+	}
+	cand := &candidate{Result: Result{Document: doc, RerankedFrom: -1}}
+	c.byID[doc.ID] = cand
+	c.order = append(c.order, cand)
+	return cand
+}
+
+// score sets the final score of every candidate.
+func (c *candidates) score(fn func(*candidate) float32) {
+	for _, cand := range c.order {
+		cand.Score = fn(cand)
+	}
+}
+
+// results returns the candidates by descending score. Ties keep the order in
+// which the documents were found.
+func (c *candidates) results() []Result {
+	results := make([]Result, len(c.order))
+	for i, cand := range c.order {
+		results[i] = cand.Result
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	return results
+}
+
+// minMaxNormalizer returns a function mapping scores to [0, 1], from the
+// lowest to the highest. When all scores are equal each one is as good as the
+// best and maps to 1.
+func minMaxNormalizer(scores []float32) func(float32) float32 {
+	if len(scores) == 0 {
+		return func(float32) float32 { return 0 }
+	}
+	lo, hi := scores[0], scores[0]
+	for _, score := range scores[1:] {
+		lo = min(lo, score)
+		hi = max(hi, score)
+	}
+	if hi == lo {
+		return func(float32) float32 { return 1 }
+	}
+	return func(score float32) float32 {
+		return (score - lo) / (hi - lo)
+	}
+}
+
+// storeScores returns the scores of results.
+func storeScores(results []vectorstore.SearchResult) []float32 {
+	scores := make([]float32, len(results))
+	for i, r := range results {
+		scores[i] = r.Score
+	}
+	return scores
+}
+
+// SearchResults converts pipeline results to vector store results, naming
+// the method of the mode that produced them.
+func SearchResults(results []Result, mode HybridMode) []vectorstore.SearchResult {
+	converted := make([]vectorstore.SearchResult, len(results))
+	for i, r := range results {
+		converted[i] = vectorstore.SearchResult{
+			Document: r.Document,
+			Score:    r.Score,
+			Method:   mode.Method(),
+		}
+	}
+	return converted
+}
@@ -0,0 +1,239 @@
+package search
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ferg-cod3s/conexus/internal/embedding"
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scored(scores map[string]float32, ids ...string) []vectorstore.SearchResult {
+	results := make([]vectorstore.SearchResult, len(ids))
+	for i, id := range ids {
+		results[i] = vectorstore.SearchResult{Document: vectorstore.Document{ID: id}, Score: scores[id]}
+	}
+	return results
+}
+
+func resultIDs(results []Result) []string {
+	ids := make([]string, len(results))
+	for i, r := range results {
+		ids[i] = r.Document.ID
+	}
+	return ids
+}
+
+func TestParseHybridMode(t *testing.T) {
+	mode, err := ParseHybridMode("")
+	require.NoError(t, err)
+	assert.Equal(t, HybridModeRRF, mode)
+
+	mode, err = ParseHybridMode("weighted")
+	require.NoError(t, err)
+	assert.Equal(t, HybridModeWeighted, mode)
+
+	_, err = ParseHybridMode("linear")
+	assert.Error(t, err)
+}
+
+func float32Ptr(v float32) *float32 {
+	return &v
+}
+
+func TestFusionOptions_Validate(t *testing.T) {
+	assert.NoError(t, FusionOptions{}.Validate())
+	assert.NoError(t, FusionOptions{Mode: HybridModeWeighted, Alpha: float32Ptr(1), K: 10}.Validate())
+	assert.Error(t, FusionOptions{Mode: "linear"}.Validate())
+	assert.Error(t, FusionOptions{Alpha: float32Ptr(-0.1)}.Validate())
+	assert.Error(t, FusionOptions{Alpha: float32Ptr(1.5)}.Validate())
+	assert.NoError(t, FusionOptions{Alpha: float32Ptr(0)}.Validate())
+	assert.Error(t, FusionOptions{K: -1}.Validate())
+}
+
+func TestHybridFusion_RRF(t *testing.T) {
+	fusion := NewHybridFusion()
+	sparse := scored(map[string]float32{"a": 0.9, "b": 0.5}, "a", "b")
+	dense := scored(map[string]float32{"b": 0.8, "c": 0.7}, "b", "c")
+
+	results, err := fusion.Fuse(context.Background(), sparse, dense, FusionOptions{})
+	require.NoError(t, err)
+
+	// b is in both lists; a leads sparse, c trails dense
+	assert.Equal(t, []string{"b", "a", "c"}, resultIDs(results))
+	assert.InDelta(t, 0.5/61+0.5/62, results[0].Score, 1e-6)
+	assert.InDelta(t, 0.5/61, results[1].Score, 1e-6)
+	assert.InDelta(t, 0.5/62, results[2].Score, 1e-6)
+
+	// Provenance keeps the raw scores
+	assert.Equal(t, float32(0.5), results[0].SparseScore)
+	assert.Equal(t, float32(0.8), results[0].DenseScore)
+	assert.Equal(t, -1, results[0].RerankedFrom)
+
+	// Alpha and k shift the balance towards dense results
+	results, err = fusion.Fuse(context.Background(), sparse, dense, FusionOptions{Alpha: float32Ptr(0.9), K: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b", "c", "a"}, resultIDs(results))
+	assert.InDelta(t, 0.9/2+0.1/3, results[0].Score, 1e-6)
+
+	// An alpha of 0 weighs only the sparse ranking
+	results, err = fusion.Fuse(context.Background(), sparse, dense, FusionOptions{Alpha: float32Ptr(0), K: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, resultIDs(results))
+	assert.InDelta(t, 0, results[2].Score, 1e-6)
+}
+
+func TestHybridFusion_Weighted(t *testing.T) {
+	fusion := NewHybridFusion()
+	// BM25 and cosine scores are on different scales
+	sparse := scored(map[string]float32{"a": 12, "b": 4, "c": 2}, "a", "b", "c")
+	dense := scored(map[string]float32{"c": 0.9, "b": 0.6, "d": 0.3}, "c", "b", "d")
+
+	results, err := fusion.Fuse(context.Background(), sparse, dense, FusionOptions{Mode: HybridModeWeighted})
+	require.NoError(t, err)
+
+	scores := make(map[string]float32)
+	for _, r := range results {
+		scores[r.Document.ID] = r.Score
+	}
+	assert.InDelta(t, 0.5*1.0, scores["a"], 1e-6)
+	assert.InDelta(t, 0.5*0.2+0.5*0.5, scores["b"], 1e-6)
+	assert.InDelta(t, 0.5*0+0.5*1.0, scores["c"], 1e-6)
+	assert.InDelta(t, 0, scores["d"], 1e-6)
+	assert.Equal(t, "d", results[len(results)-1].Document.ID)
+
+	// A heavy alpha favours the vector ranking
+	results, err = fusion.Fuse(context.Background(), sparse, dense, FusionOptions{Mode: HybridModeWeighted, Alpha: float32Ptr(0.9)})
+	require.NoError(t, err)
+	assert.Equal(t, "c", results[0].Document.ID)
+}
+
+func TestHybridFusion_WeightedEqualScores(t *testing.T) {
+	sparse := scored(map[string]float32{"a": 3, "b": 3}, "a", "b")
+
+	results, err := NewHybridFusion().Fuse(context.Background(), sparse, nil, FusionOptions{Mode: HybridModeWeighted})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.InDelta(t, 0.5, results[0].Score, 1e-6)
+	assert.InDelta(t, 0.5, results[1].Score, 1e-6)
+	assert.Equal(t, []string{"a", "b"}, resultIDs(results))
+}
+
+func TestHybridFusion_SingleModes(t *testing.T) {
+	fusion := NewHybridFusion()
+	sparse := scored(map[string]float32{"a": 0.4}, "a")
+	dense := scored(map[string]float32{"b": 0.8}, "b")
+
+	results, err := fusion.Fuse(context.Background(), sparse, dense, FusionOptions{Mode: HybridModeSparse})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, resultIDs(results))
+	assert.Equal(t, float32(0.4), results[0].Score)
+
+	results, err = fusion.Fuse(context.Background(), sparse, dense, FusionOptions{Mode: HybridModeDense})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b"}, resultIDs(results))
+	assert.Equal(t, float32(0.8), results[0].Score)
+}
+
+func TestSearchResults(t *testing.T) {
+	results := []Result{{Document: vectorstore.Document{ID: "a"}, Score: 0.5}}
+
+	converted := SearchResults(results, HybridModeSparse)
+	require.Len(t, converted, 1)
+	assert.Equal(t, "a", converted[0].Document.ID)
+	assert.Equal(t, float32(0.5), converted[0].Score)
+	assert.Equal(t, "bm25", converted[0].Method)
+
+	assert.Equal(t, "hybrid", SearchResults(results, "")[0].Method)
+}
+
+func TestPipeline_Search_SparseModeSkipsEmbedding(t *testing.T) {
+	store := &mockVectorStore{
+		searchBM25Func: func(ctx context.Context, query string, opts vectorstore.SearchOptions) ([]vectorstore.SearchResult, error) {
+			return scored(map[string]float32{"a": 0.7}, "a"), nil
+		},
+	}
+	embedder := &mockEmbedder{
+		embedFunc: func(ctx context.Context, text string) (*embedding.Embedding, error) {
+			t.Fatal("sparse search should not embed the query")
+			return nil, nil
+		},
+	}
+
+	pipeline := NewPipeline(store, embedder, NewHybridFusion(), nil)
+	results, err := pipeline.Search(context.Background(), Query{Text: "auth", HybridMode: HybridModeSparse})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, resultIDs(results))
+}
+
+func TestPipeline_Search_OffsetAndFilter(t *testing.T) {
+	ids := []string{"a", "b", "c", "d", "e"}
+	scores := map[string]float32{"a": 0.9, "b": 0.8, "c": 0.7, "d": 0.6, "e": 0.5}
+	var seen vectorstore.SearchOptions
+	store := &mockVectorStore{
+		searchVectorFunc: func(ctx context.Context, vector embedding.Vector, opts vectorstore.SearchOptions) ([]vectorstore.SearchResult, error) {
+			seen = opts
+			return scored(scores, ids...), nil
+		},
+	}
+
+	pipeline := NewPipeline(store, &mockEmbedder{}, NewHybridFusion(), nil)
+	filter := vectorstore.Eq("language", "go")
+	results, err := pipeline.Search(context.Background(), Query{
+		Text:       "auth",
+		Filter:     filter,
+		Limit:      2,
+		Offset:     2,
+		HybridMode: HybridModeDense,
+	})
+	require.NoError(t, err)
+
+	// Candidates cover the offset, and the window starts after it
+	assert.Equal(t, 8, seen.Limit)
+	assert.Equal(t, 0, seen.Offset)
+	assert.Equal(t, filter, seen.Filter)
+	assert.Equal(t, []string{"c", "d"}, resultIDs(results))
+
+	results, err = pipeline.Search(context.Background(), Query{Text: "auth", Offset: 10, HybridMode: HybridModeDense})
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestPipeline_Search_FusedThreshold(t *testing.T) {
+	var sparseThreshold, denseThreshold float32
+	store := &mockVectorStore{
+		searchBM25Func: func(ctx context.Context, query string, opts vectorstore.SearchOptions) ([]vectorstore.SearchResult, error) {
+			sparseThreshold = opts.Threshold
+			return scored(map[string]float32{"a": 10, "b": 1}, "a", "b"), nil
+		},
+		searchVectorFunc: func(ctx context.Context, vector embedding.Vector, opts vectorstore.SearchOptions) ([]vectorstore.SearchResult, error) {
+			denseThreshold = opts.Threshold
+			return scored(map[string]float32{"a": 0.9, "b": 0.1}, "a", "b"), nil
+		},
+	}
+
+	pipeline := NewPipeline(store, &mockEmbedder{}, NewHybridFusion(), nil)
+	results, err := pipeline.Search(context.Background(), Query{
+		Text:       "auth",
+		Threshold:  0.5,
+		HybridMode: HybridModeWeighted,
+	})
+	require.NoError(t, err)
+
+	// Raw scores are not compared to the threshold, the fused ones are
+	assert.Zero(t, sparseThreshold)
+	assert.Zero(t, denseThreshold)
+	assert.Equal(t, []string{"a"}, resultIDs(results))
+}
+
+func TestPipeline_Search_InvalidFusion(t *testing.T) {
+	pipeline := NewPipeline(&mockVectorStore{}, &mockEmbedder{}, NewHybridFusion(), nil)
+
+	_, err := pipeline.Search(context.Background(), Query{Text: "auth", HybridMode: "linear"})
+	assert.Error(t, err)
+
+	_, err = pipeline.Search(context.Background(), Query{Text: "auth", Alpha: float32Ptr(2)})
+	assert.Error(t, err)
+}
@@ -16,9 +16,19 @@ import (
 type Query struct {
 	Text       string                 // Search query text
 	Filters    map[string]interface{} // Metadata filters
-	Limit      int                    // Maximum results to return
+	Filter     vectorstore.Filter     // Typed metadata filter, combined with Filters
+	Limit      int                    // Maximum results to return (default: 10)
+	Offset     int                    // Results to skip, for pagination
 	Threshold  float32                // Minimum relevance score
-	HybridMode HybridMode             // How to combine sparse and dense results
+	HybridMode HybridMode             // How to combine sparse and dense results (default: rrf)
+	Alpha      *float32               // Weight of dense results in fused modes (default: 0.5)
+	K          int                    // RRF rank constant (default: 60)
+	Diversity  *DiversityOptions      // Diversify the results by MMR and caps (default: off)
+}
+
+// FusionOptions returns the fusion parameters of the query.
+func (q Query) FusionOptions() FusionOptions {
+	return FusionOptions{Mode: q.HybridMode, Alpha: q.Alpha, K: q.K}
 }
 
 // HybridMode controls how sparse (BM25) and dense (vector) results are combined.
@@ -56,10 +66,11 @@ type Reranker interface {
 // FusionStrategy combines multiple ranked lists into a single ranking.
 type FusionStrategy interface {
 	// Fuse combines sparse and dense search results.
-	Fuse(ctx context.Context, sparseResults, denseResults []vectorstore.SearchResult, mode HybridMode) ([]Result, error)
+	Fuse(ctx context.Context, sparseResults, denseResults []vectorstore.SearchResult, opts FusionOptions) ([]Result, error)
 }
 
-// Pipeline orchestrates the full retrieval pipeline: search → fuse → rerank.
+// Pipeline orchestrates the full retrieval pipeline: search → fuse → rerank →
+// diversify.
 type Pipeline struct {
 	Store    vectorstore.VectorStore
 	Embedder embedding.Embedder
@@ -77,31 +88,61 @@ func NewPipeline(store vectorstore.VectorStore, embedder embedding.Embedder, fus
 	}
 }
 
-// Search executes the full search pipeline.
+// Search executes the full search pipeline. The query is only embedded when
+// its mode ranks vector results. In sparse and dense modes the threshold
+// applies to the store's scores, in fused modes to the fused score.
 func (p *Pipeline) Search(ctx context.Context, query Query) ([]Result, error) {
-	// Generate query embedding
-	emb, err := p.Embedder.Embed(ctx, query.Text)
-	if err != nil {
+	fusion := query.FusionOptions()
+	if err := fusion.Validate(); err != nil {
 		return nil, err
 	}
+	if query.Diversity != nil {
+		if err := query.Diversity.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	mode := fusion.withDefaults().Mode
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+	offset := query.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	// Get more candidates for fusion and reranking, and more again for
+	// diversification to choose from
+	candidates := (limit + offset) * 2
+	if query.Diversity != nil {
+		candidates *= 2
+	}
 
-	// Perform hybrid search based on mode
 	searchOpts := vectorstore.SearchOptions{
-		Limit:     query.Limit * 2, // Get more candidates for reranking
-		Threshold: query.Threshold,
-		Filters:   query.Filters,
+		Limit:   candidates,
+		Filters: query.Filters,
+		Filter:  query.Filter,
+	}
+	if !mode.fused() {
+		searchOpts.Threshold = query.Threshold
 	}
 
 	var sparseResults, denseResults []vectorstore.SearchResult
+	var err error
 
-	if query.HybridMode == HybridModeSparse || query.HybridMode == HybridModeRRF || query.HybridMode == HybridModeWeighted {
+	if mode.usesSparse() {
 		sparseResults, err = p.Store.SearchBM25(ctx, query.Text, searchOpts)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	if query.HybridMode == HybridModeDense || query.HybridMode == HybridModeRRF || query.HybridMode == HybridModeWeighted {
+	if mode.usesDense() {
+		emb, err := p.Embedder.Embed(ctx, query.Text)
+		if err != nil {
+			return nil, err
+		}
 		denseResults, err = p.Store.SearchVector(ctx, emb.Vector, searchOpts)
 		if err != nil {
 			return nil, err
@@ -109,11 +150,21 @@ func (p *Pipeline) Search(ctx context.Context, query Query) ([]Result, error) {
 	}
 
 	// Fuse results
-	results, err := p.Fusion.Fuse(ctx, sparseResults, denseResults, query.HybridMode)
+	results, err := p.Fusion.Fuse(ctx, sparseResults, denseResults, fusion)
 	if err != nil {
 		return nil, err
 	}
 
+	if mode.fused() && query.Threshold > 0 {
+		kept := results[:0]
+		for _, r := range results {
+			if r.Score >= query.Threshold {
+				kept = append(kept, r)
+			}
+		}
+		results = kept
+	}
+
 	// Apply reranking if configured
 	if p.Reranker != nil {
 		results, err = p.Reranker.Rerank(ctx, query.Text, results)
@@ -122,9 +173,17 @@ func (p *Pipeline) Search(ctx context.Context, query Query) ([]Result, error) {
 		}
 	}
 
-	// Trim to requested limit
-	if len(results) > query.Limit {
-		results = results[:query.Limit]
+	if query.Diversity != nil {
+		results = Diversify(results, *query.Diversity)
+	}
+
+	// Page through the requested window
+	if offset >= len(results) {
+		return []Result{}, nil
+	}
+	results = results[offset:]
+	if len(results) > limit {
+		results = results[:limit]
 	}
 
 	return results, nil
@@ -125,6 +125,18 @@ func (m *mockVectorStore) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+func (m *mockVectorStore) DeleteByFilter(ctx context.Context, filter vectorstore.Filter) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockVectorStore) UpdateMetadataByFilter(ctx context.Context, filter vectorstore.Filter, update vectorstore.MetadataUpdate) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockVectorStore) Scan(ctx context.Context, filter vectorstore.Filter) (vectorstore.DocumentIterator, error) {
+	return vectorstore.SliceIterator(nil), nil
+}
+
 func (m *mockVectorStore) Count(ctx context.Context) (int64, error) {
 	return 0, nil
 }
@@ -176,12 +188,12 @@ func (m *mockEmbedder) Model() string {
 }
 
 type mockFusion struct {
-	fuseFunc func(ctx context.Context, sparseResults, denseResults []vectorstore.SearchResult, mode HybridMode) ([]Result, error)
+	fuseFunc func(ctx context.Context, sparseResults, denseResults []vectorstore.SearchResult, opts FusionOptions) ([]Result, error)
 }
 
-func (m *mockFusion) Fuse(ctx context.Context, sparseResults, denseResults []vectorstore.SearchResult, mode HybridMode) ([]Result, error) {
+func (m *mockFusion) Fuse(ctx context.Context, sparseResults, denseResults []vectorstore.SearchResult, opts FusionOptions) ([]Result, error) {
 	if m.fuseFunc != nil {
-		return m.fuseFunc(ctx, sparseResults, denseResults, mode)
+		return m.fuseFunc(ctx, sparseResults, denseResults, opts)
 	}
 
 	// Simple fusion: combine both result sets
@@ -0,0 +1,204 @@
+// Package secrets detects and redacts credentials in text before it is indexed.
+package secrets
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Rule describes a single class of secret to detect.
+type Rule struct {
+	ID          string         // Stable identifier used in findings and redaction markers
+	Description string         // Human-readable description
+	Pattern     *regexp.Regexp // Pattern matching the secret (or a line containing it)
+	SecretGroup int            // Capture group holding the secret value (0 = whole match)
+	MinEntropy  float64        // Minimum Shannon entropy of the secret value (0 = no check)
+}
+
+// Finding records a detected secret. The raw value is never stored.
+type Finding struct {
+	RuleID      string `json:"rule_id"`
+	Description string `json:"description"`
+	FilePath    string `json:"file_path,omitempty"`
+	Line        int    `json:"line"`
+	EndLine     int    `json:"end_line"`
+	Preview     string `json:"preview"`          // Masked preview of the secret
+	Action      string `json:"action,omitempty"` // "redacted" or "quarantined"
+
+	start int // Byte offset of the secret within the scanned content
+	end   int
+}
+
+// DefaultRules returns the built-in detection rules.
+func DefaultRules() []Rule {
+	return []Rule{
+		{
+			ID:          "aws_access_key_id",
+			Description: "AWS access key ID",
+			Pattern:     regexp.MustCompile(`\b((?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA|ANVA)[0-9A-Z]{16})\b`),
+			SecretGroup: 1,
+		},
+		{
+			ID:          "aws_secret_access_key",
+			Description: "AWS secret access key",
+			Pattern:     regexp.MustCompile(`(?i)aws_?secret_?(?:access_?)?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})\b`),
+			SecretGroup: 1,
+			MinEntropy:  3.5,
+		},
+		{
+			ID:          "github_token",
+			Description: "GitHub token",
+			Pattern:     regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{22,255})\b`),
+			SecretGroup: 1,
+		},
+		{
+			ID:          "slack_token",
+			Description: "Slack token",
+			Pattern:     regexp.MustCompile(`\b(xox[abposr]-[A-Za-z0-9-]{10,})\b`),
+			SecretGroup: 1,
+		},
+		{
+			ID:          "jwt",
+			Description: "JSON Web Token",
+			Pattern:     regexp.MustCompile(`\b(eyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{10,})`),
+			SecretGroup: 1,
+		},
+		{
+			ID:          "private_key",
+			Description: "Private key block",
+			Pattern:     regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )*PRIVATE KEY(?: BLOCK)?-----[\s\S]*?-----END (?:[A-Z]+ )*PRIVATE KEY(?: BLOCK)?-----`),
+		},
+		{
+			ID:          "generic_secret",
+			Description: "High-entropy value assigned to a secret-like name",
+			Pattern:     regexp.MustCompile(`(?i)(?:api[_-]?key|secret|token|passwd|password|credential|auth[_-]?key)[A-Za-z0-9_-]*["']?\s*(?::=|[:=])\s*["']?([A-Za-z0-9/+_.=-]{16,})`),
+			SecretGroup: 1,
+			MinEntropy:  3.5,
+		},
+	}
+}
+
+// Scanner finds secrets in text using a set of rules.
+type Scanner struct {
+	rules []Rule
+}
+
+// NewScanner creates a scanner with the default rules.
+func NewScanner() *Scanner {
+	return NewScannerWithRules(DefaultRules())
+}
+
+// NewScannerWithRules creates a scanner with custom rules.
+func NewScannerWithRules(rules []Rule) *Scanner {
+	return &Scanner{rules: rules}
+}
+
+// Scan returns all secrets found in content, ordered by position.
+// Overlapping matches are reported once, keeping the earliest rule.
+func (s *Scanner) Scan(content string) []Finding {
+	var findings []Finding
+
+	for _, rule := range s.rules {
+		for _, m := range rule.Pattern.FindAllStringSubmatchIndex(content, -1) {
+			group := rule.SecretGroup
+			if 2*group+1 >= len(m) || m[2*group] < 0 {
+				continue
+			}
+			start, end := m[2*group], m[2*group+1]
+			value := content[start:end]
+
+			if rule.MinEntropy > 0 && ShannonEntropy(value) < rule.MinEntropy {
+				continue
+			}
+			if overlaps(findings, start, end) {
+				continue
+			}
+
+			findings = append(findings, Finding{
+				RuleID:      rule.ID,
+				Description: rule.Description,
+				Line:        lineAt(content, start),
+				EndLine:     lineAt(content, end),
+				Preview:     mask(value),
+				start:       start,
+				end:         end,
+			})
+		}
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		return findings[i].start < findings[j].start
+	})
+	return findings
+}
+
+// Redact replaces each finding in content with a marker naming the rule.
+// Line breaks inside a secret are preserved so line numbers stay stable.
+// The findings must come from scanning the same content.
+func Redact(content string, findings []Finding) string {
+	if len(findings) == 0 {
+		return content
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, f := range findings {
+		if f.start < last || f.end > len(content) {
+			continue
+		}
+		sb.WriteString(content[last:f.start])
+		sb.WriteString(fmt.Sprintf("[REDACTED:%s]", f.RuleID))
+		sb.WriteString(strings.Repeat("\n", strings.Count(content[f.start:f.end], "\n")))
+		last = f.end
+	}
+	sb.WriteString(content[last:])
+	return sb.String()
+}
+
+// ShannonEntropy returns the Shannon entropy of s in bits per character.
+func ShannonEntropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+
+	counts := make(map[rune]int)
+	total := 0
+	for _, r := range s {
+		counts[r]++
+		total++
+	}
+
+	entropy := 0.0
+	for _, c := range counts {
+		p := float64(c) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// overlaps reports whether [start, end) intersects an existing finding.
+func overlaps(findings []Finding, start, end int) bool {
+	for _, f := range findings {
+		if start < f.end && f.start < end {
+			return true
+		}
+	}
+	return false
+}
+
+// lineAt returns the 1-based line number of a byte offset.
+func lineAt(content string, offset int) int {
+	return strings.Count(content[:offset], "\n") + 1
+}
+
+// mask keeps only the first few characters of a secret for identification.
+func mask(value string) string {
+	visible := 4
+	if len(value) <= visible*2 {
+		visible = 1
+	}
+	return value[:visible] + strings.Repeat("*", 8)
+}
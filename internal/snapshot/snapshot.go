@@ -0,0 +1,364 @@
+// Package snapshot exports and imports portable index bundles, so an index
+// built once (e.g. nightly in CI) can be shared instead of rebuilt on every
+// machine.
+//
+// A bundle is a gzip-compressed tar archive. Its first entry is manifest.json,
+// which records the format version and the embedder that produced the
+// vectors. The store's files (documents, vectors, full-text index and HNSW
+// graph) follow under store/, then the indexer's Merkle and git history state.
+package snapshot
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ferg-cod3s/conexus/internal/embedding"
+	"github.com/ferg-cod3s/conexus/internal/indexer"
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+)
+
+// FormatVersion is the bundle format written by Export. Import accepts
+// bundles up to this version.
+const FormatVersion = 1
+
+// Bundle entry names.
+const (
+	manifestEntry     = "manifest.json"
+	storeEntryPrefix  = "store/"
+	stateEntry        = "state/merkle.json"
+	historyStateEntry = "state/history"
+)
+
+// maxStateSize caps the state entries read into memory on import.
+const maxStateSize = 256 << 20
+
+// ErrEmbedderMismatch is returned by Import when a bundle was built with a
+// different embedder than the one configured locally.
+var ErrEmbedderMismatch = errors.New("embedder mismatch")
+
+// EmbedderIdentity identifies the embedder that produced a bundle's vectors.
+type EmbedderIdentity struct {
+	Provider   string `json:"provider"`
+	Model      string `json:"model"`
+	Dimensions int    `json:"dimensions"`
+}
+
+// IdentityOf returns the identity of an embedder created by provider.
+func IdentityOf(provider string, embedder embedding.Embedder) EmbedderIdentity {
+	return EmbedderIdentity{
+		Provider:   provider,
+		Model:      embedder.Model(),
+		Dimensions: embedder.Dimensions(),
+	}
+}
+
+// String formats the identity for error messages.
+func (e EmbedderIdentity) String() string {
+	return fmt.Sprintf("%s/%s (%d dimensions)", e.Provider, e.Model, e.Dimensions)
+}
+
+// Manifest describes a bundle.
+type Manifest struct {
+	FormatVersion int              `json:"format_version"`
+	CreatedAt     time.Time        `json:"created_at"`
+	Embedder      EmbedderIdentity `json:"embedder"`
+	Documents     int64            `json:"documents"`
+	Files         []string         `json:"files"` // Entries following the manifest
+}
+
+// CheckEmbedder reports whether vectors in the bundle are compatible with
+// the given embedder.
+func (m *Manifest) CheckEmbedder(local EmbedderIdentity) error {
+	if m.Embedder != local {
+		return fmt.Errorf("%w: bundle was built with %s, local embedder is %s", ErrEmbedderMismatch, m.Embedder, local)
+	}
+	return nil
+}
+
+// ExportOptions configures Export.
+type ExportOptions struct {
+	Store     vectorstore.VectorStore // Must implement vectorstore.SnapshotStore
+	Embedder  EmbedderIdentity        // Embedder that produced the stored vectors
+	StatePath string                  // Indexer Merkle state file; optional
+}
+
+// Export writes a bundle of the store and indexer state to w.
+func Export(ctx context.Context, w io.Writer, opts ExportOptions) (*Manifest, error) {
+	store, ok := opts.Store.(vectorstore.SnapshotStore)
+	if !ok {
+		return nil, fmt.Errorf("vector store does not support snapshots")
+	}
+
+	dir, err := os.MkdirTemp("", "conexus-export-")
+	if err != nil {
+		return nil, fmt.Errorf("create temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := store.Snapshot(ctx, dir); err != nil {
+		return nil, fmt.Errorf("snapshot store: %w", err)
+	}
+	count, err := opts.Store.Count(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("count documents: %w", err)
+	}
+
+	// Entry name -> file on disk, in bundle order
+	var entries [][2]string
+	storeFiles, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read snapshot dir: %w", err)
+	}
+	for _, f := range storeFiles {
+		entries = append(entries, [2]string{storeEntryPrefix + f.Name(), filepath.Join(dir, f.Name())})
+	}
+	if opts.StatePath != "" {
+		for _, entry := range stateEntries(opts.StatePath) {
+			if info, err := os.Stat(entry[1]); err == nil && info.Mode().IsRegular() {
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	manifest := &Manifest{
+		FormatVersion: FormatVersion,
+		CreatedAt:     time.Now().UTC(),
+		Embedder:      opts.Embedder,
+		Documents:     count,
+	}
+	for _, entry := range entries {
+		manifest.Files = append(manifest.Files, entry[0])
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal manifest: %w", err)
+	}
+	if err := writeEntry(tw, manifestEntry, int64(len(manifestJSON)), bytes.NewReader(manifestJSON)); err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if err := writeFileEntry(tw, entry[0], entry[1]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("close archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("close compressor: %w", err)
+	}
+	return manifest, nil
+}
+
+// ImportOptions configures Import.
+type ImportOptions struct {
+	Store     vectorstore.VectorStore // Must implement vectorstore.SnapshotStore
+	Embedder  EmbedderIdentity        // Local embedder; must match the bundle's
+	StatePath string                  // Where to write the indexer Merkle state
+}
+
+// Import replaces the store's contents and the indexer state with a bundle
+// read from r. The manifest is checked before anything is written: bundles of
+// a newer format or built with a different embedder are rejected.
+func Import(ctx context.Context, r io.Reader, opts ImportOptions) (*Manifest, error) {
+	store, ok := opts.Store.(vectorstore.SnapshotStore)
+	if !ok {
+		return nil, fmt.Errorf("vector store does not support snapshots")
+	}
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("open bundle: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	manifest, err := readManifest(tr)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.FormatVersion < 1 || manifest.FormatVersion > FormatVersion {
+		return nil, fmt.Errorf("unsupported bundle format version %d (supported: 1-%d)", manifest.FormatVersion, FormatVersion)
+	}
+	if err := manifest.CheckEmbedder(opts.Embedder); err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "conexus-import-")
+	if err != nil {
+		return nil, fmt.Errorf("create temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	state := make(map[string][]byte)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read bundle: %w", err)
+		}
+
+		switch name := hdr.Name; {
+		case strings.HasPrefix(name, storeEntryPrefix):
+			// Only plain file names are extracted, never paths
+			base := strings.TrimPrefix(name, storeEntryPrefix)
+			if base == "" || base != path.Base(base) || base == "." || base == ".." || strings.Contains(base, `\`) {
+				return nil, fmt.Errorf("invalid bundle entry %q", name)
+			}
+			if err := extractFile(tr, filepath.Join(dir, base)); err != nil {
+				return nil, err
+			}
+		case name == stateEntry || name == historyStateEntry:
+			data, err := io.ReadAll(io.LimitReader(tr, maxStateSize+1))
+			if err != nil {
+				return nil, fmt.Errorf("read %s: %w", name, err)
+			}
+			if len(data) > maxStateSize {
+				return nil, fmt.Errorf("bundle entry %s is too large", name)
+			}
+			state[name] = data
+		default:
+			return nil, fmt.Errorf("unexpected bundle entry %q", name)
+		}
+	}
+
+	if err := store.Restore(ctx, dir); err != nil {
+		return nil, fmt.Errorf("restore store: %w", err)
+	}
+
+	if opts.StatePath != "" {
+		for _, entry := range stateEntries(opts.StatePath) {
+			name, file := entry[0], entry[1]
+			data, ok := state[name]
+			if !ok {
+				// State from an earlier index would not match the restored documents
+				if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+					return nil, fmt.Errorf("remove %s: %w", file, err)
+				}
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+				return nil, fmt.Errorf("create state dir: %w", err)
+			}
+			if err := os.WriteFile(file, data, 0600); err != nil {
+				return nil, fmt.Errorf("write %s: %w", file, err)
+			}
+		}
+
+		// Repositories are indexed from scratch against the restored documents
+		if err := os.RemoveAll(indexer.RepoStateDir(opts.StatePath)); err != nil {
+			return nil, fmt.Errorf("remove repository state: %w", err)
+		}
+	}
+
+	return manifest, nil
+}
+
+// stateEntries pairs the bundle entries for indexer state with their files.
+func stateEntries(statePath string) [][2]string {
+	return [][2]string{
+		{stateEntry, statePath},
+		{historyStateEntry, indexer.HistoryStatePath(statePath)},
+	}
+}
+
+// ReadManifest reads the manifest of a bundle without importing it.
+func ReadManifest(r io.Reader) (*Manifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("open bundle: %w", err)
+	}
+	defer gz.Close()
+	return readManifest(tar.NewReader(gz))
+}
+
+// readManifest reads the manifest, which must be the first entry.
+func readManifest(tr *tar.Reader) (*Manifest, error) {
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("read bundle: %w", err)
+	}
+	if hdr.Name != manifestEntry {
+		return nil, fmt.Errorf("not a conexus index bundle: first entry is %q", hdr.Name)
+	}
+
+	var manifest Manifest
+	if err := json.NewDecoder(io.LimitReader(tr, 1<<20)).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("decode manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// writeFileEntry adds the file at path to the archive as name.
+func writeFileEntry(tw *tar.Writer, name, file string) error {
+	// #nosec G304 - Files come from the snapshot directory or configured state path
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", file, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("stat %s: %w", file, err)
+	}
+	return writeEntry(tw, name, info.Size(), f)
+}
+
+// writeEntry adds a regular file entry to the archive.
+func writeEntry(tw *tar.Writer, name string, size int64, r io.Reader) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    size,
+		ModTime: time.Now(),
+		Format:  tar.FormatPAX,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("write %s header: %w", name, err)
+	}
+	if _, err := io.CopyN(tw, r, size); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	return nil
+}
+
+// extractFile writes the current archive entry to path.
+func extractFile(tr *tar.Reader, file string) error {
+	// #nosec G304 - Path is a validated base name inside the import temp dir
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", file, err)
+	}
+	// #nosec G110 - Bundles are produced by Export from trusted indexes
+	if _, err := io.Copy(f, tr); err != nil {
+		// #nosec G104 - Best-effort cleanup in error path, primary error already captured
+		f.Close()
+		return fmt.Errorf("extract %s: %w", file, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close %s: %w", file, err)
+	}
+	return nil
+}
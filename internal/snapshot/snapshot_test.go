@@ -0,0 +1,168 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ferg-cod3s/conexus/internal/embedding"
+	"github.com/ferg-cod3s/conexus/internal/indexer"
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+	"github.com/ferg-cod3s/conexus/internal/vectorstore/sqlite"
+)
+
+func newStore(t *testing.T) *sqlite.Store {
+	t.Helper()
+	store, err := sqlite.NewStore(":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, store.Close()) })
+	return store
+}
+
+func exportFixture(t *testing.T) ([]byte, EmbedderIdentity) {
+	t.Helper()
+	ctx := context.Background()
+	identity := IdentityOf("mock", embedding.NewMock(3))
+
+	store := newStore(t)
+	require.NoError(t, store.UpsertBatch(ctx, []vectorstore.Document{
+		{ID: "a", Content: "func loadConfig()", Vector: embedding.Vector{0.1, 0.2, 0.3},
+			Metadata: map[string]interface{}{"file_path": "config.go"}},
+		{ID: "b", Content: "func main()", Vector: embedding.Vector{0.3, 0.2, 0.1},
+			Metadata: map[string]interface{}{"file_path": "main.go"}},
+	}))
+
+	statePath := filepath.Join(t.TempDir(), "indexer_state.json")
+	require.NoError(t, os.WriteFile(statePath, []byte(`{"root":null}`), 0600))
+	require.NoError(t, os.WriteFile(indexer.HistoryStatePath(statePath), []byte("abc\n"), 0600))
+
+	var buf bytes.Buffer
+	manifest, err := Export(ctx, &buf, ExportOptions{Store: store, Embedder: identity, StatePath: statePath})
+	require.NoError(t, err)
+	assert.Equal(t, FormatVersion, manifest.FormatVersion)
+	assert.Equal(t, int64(2), manifest.Documents)
+	assert.Equal(t, []string{"store/index.db", stateEntry, historyStateEntry}, manifest.Files)
+	return buf.Bytes(), identity
+}
+
+func TestExportImport_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	bundle, identity := exportFixture(t)
+
+	manifest, err := ReadManifest(bytes.NewReader(bundle))
+	require.NoError(t, err)
+	assert.Equal(t, identity, manifest.Embedder)
+
+	target := newStore(t)
+	require.NoError(t, target.Upsert(ctx, vectorstore.Document{ID: "local", Content: "old", Vector: embedding.Vector{1, 0, 0}}))
+	statePath := filepath.Join(t.TempDir(), "data", "indexer_state.json")
+
+	_, err = Import(ctx, bytes.NewReader(bundle), ImportOptions{Store: target, Embedder: identity, StatePath: statePath})
+	require.NoError(t, err)
+
+	count, err := target.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	results, err := target.SearchBM25(ctx, "loadConfig", vectorstore.SearchOptions{Limit: 5})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "a", results[0].Document.ID)
+
+	state, err := os.ReadFile(statePath)
+	require.NoError(t, err)
+	assert.Equal(t, `{"root":null}`, string(state))
+	history, err := os.ReadFile(indexer.HistoryStatePath(statePath))
+	require.NoError(t, err)
+	assert.Equal(t, "abc\n", string(history))
+}
+
+func TestImport_RejectsMismatchedEmbedder(t *testing.T) {
+	ctx := context.Background()
+	bundle, identity := exportFixture(t)
+	target := newStore(t)
+
+	other := identity
+	other.Dimensions = 768
+	_, err := Import(ctx, bytes.NewReader(bundle), ImportOptions{Store: target, Embedder: other})
+	assert.ErrorIs(t, err, ErrEmbedderMismatch)
+
+	other = identity
+	other.Model = "other-model"
+	_, err = Import(ctx, bytes.NewReader(bundle), ImportOptions{Store: target, Embedder: other})
+	assert.ErrorIs(t, err, ErrEmbedderMismatch)
+
+	// Nothing was written
+	count, err := target.Count(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func writeBundle(t *testing.T, entries map[string]string, order []string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, name := range order {
+		require.NoError(t, writeEntry(tw, name, int64(len(entries[name])), bytes.NewReader([]byte(entries[name]))))
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestImport_RejectsInvalidBundles(t *testing.T) {
+	ctx := context.Background()
+	identity := IdentityOf("mock", embedding.NewMock(3))
+	target := newStore(t)
+	manifest := `{"format_version": 1, "embedder": {"provider": "mock", "model": "mock-3", "dimensions": 3}}`
+
+	tests := []struct {
+		name    string
+		entries map[string]string
+		order   []string
+	}{
+		{
+			name:    "newer format",
+			entries: map[string]string{manifestEntry: `{"format_version": 99}`},
+			order:   []string{manifestEntry},
+		},
+		{
+			name:    "missing manifest",
+			entries: map[string]string{"store/index.db": "x"},
+			order:   []string{"store/index.db"},
+		},
+		{
+			name:    "path traversal",
+			entries: map[string]string{manifestEntry: manifest, "store/../evil": "x"},
+			order:   []string{manifestEntry, "store/../evil"},
+		},
+		{
+			name:    "unknown entry",
+			entries: map[string]string{manifestEntry: manifest, "extra.txt": "x"},
+			order:   []string{manifestEntry, "extra.txt"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bundle := writeBundle(t, tt.entries, tt.order)
+			_, err := Import(ctx, bytes.NewReader(bundle), ImportOptions{Store: target, Embedder: identity})
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := Import(ctx, bytes.NewReader([]byte("not gzip")), ImportOptions{Store: target, Embedder: identity})
+	assert.Error(t, err)
+}
+
+func TestExport_RequiresSnapshotStore(t *testing.T) {
+	_, err := Export(context.Background(), &bytes.Buffer{}, ExportOptions{Store: vectorstore.NewMemoryStore()})
+	assert.Error(t, err)
+}
@@ -0,0 +1,157 @@
+package vectorstore
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MetadataUpdate changes the metadata of the documents matched by
+// UpdateMetadataByFilter. Rewrites are applied first, then Set, then Unset.
+type MetadataUpdate struct {
+	Set     map[string]interface{} // Keys set to a value
+	Unset   []string               // Keys removed
+	Rewrite []PrefixRewrite        // String values whose prefix is replaced
+}
+
+// PrefixRewrite replaces the prefix From of the string value of Key with To,
+// such as the directory of file paths after a rename. Values without the
+// prefix are left alone.
+type PrefixRewrite struct {
+	Key  string
+	From string
+	To   string
+}
+
+// IsEmpty reports whether u changes nothing.
+func (u MetadataUpdate) IsEmpty() bool {
+	return len(u.Set) == 0 && len(u.Unset) == 0 && len(u.Rewrite) == 0
+}
+
+// Validate checks that u is well formed.
+func (u MetadataUpdate) Validate() error {
+	if u.IsEmpty() {
+		return fmt.Errorf("metadata update changes nothing")
+	}
+	for _, key := range u.Keys() {
+		if key == "" {
+			return fmt.Errorf("metadata update needs a key")
+		}
+		if strings.Contains(key, `"`) {
+			return fmt.Errorf("metadata key %q cannot contain quotes", key)
+		}
+	}
+	for _, rewrite := range u.Rewrite {
+		if rewrite.From == "" {
+			return fmt.Errorf("rewrite of %q needs a prefix", rewrite.Key)
+		}
+	}
+	return nil
+}
+
+// Keys returns the keys u changes.
+func (u MetadataUpdate) Keys() []string {
+	var keys []string
+	for _, rewrite := range u.Rewrite {
+		keys = append(keys, rewrite.Key)
+	}
+	for key := range u.Set {
+		keys = append(keys, key)
+	}
+	return append(keys, u.Unset...)
+}
+
+// Apply returns a copy of metadata with u applied, and whether it differs.
+func (u MetadataUpdate) Apply(metadata map[string]interface{}) (map[string]interface{}, bool) {
+	updated := make(map[string]interface{}, len(metadata)+len(u.Set))
+	for k, v := range metadata {
+		updated[k] = v
+	}
+
+	changed := false
+	for _, rewrite := range u.Rewrite {
+		if s, ok := updated[rewrite.Key].(string); ok && strings.HasPrefix(s, rewrite.From) {
+			updated[rewrite.Key] = rewrite.To + strings.TrimPrefix(s, rewrite.From)
+			changed = changed || rewrite.From != rewrite.To
+		}
+	}
+	for k, v := range u.Set {
+		updated[k] = v
+		changed = true
+	}
+	for _, k := range u.Unset {
+		if _, ok := updated[k]; ok {
+			delete(updated, k)
+			changed = true
+		}
+	}
+	return updated, changed
+}
+
+// DocumentIterator streams the documents returned by Scan. Like sql.Rows,
+// Next advances to the next document and Err reports the error that stopped
+// the iteration; the iterator must be closed.
+type DocumentIterator interface {
+	// Next advances to the next document and reports whether there is one.
+	Next() bool
+
+	// Document returns the current document.
+	Document() Document
+
+	// Err returns the error that ended the iteration, if any.
+	Err() error
+
+	// Close releases the iterator's resources.
+	Close() error
+}
+
+// SliceIterator returns an iterator over docs.
+func SliceIterator(docs []Document) DocumentIterator {
+	return &sliceIterator{docs: docs, pos: -1}
+}
+
+type sliceIterator struct {
+	docs []Document
+	pos  int
+}
+
+func (it *sliceIterator) Next() bool {
+	if it.pos+1 >= len(it.docs) {
+		it.pos = len(it.docs)
+		return false
+	}
+	it.pos++
+	return true
+}
+
+func (it *sliceIterator) Document() Document {
+	return it.docs[it.pos]
+}
+
+func (it *sliceIterator) Err() error {
+	return nil
+}
+
+func (it *sliceIterator) Close() error {
+	it.docs = nil
+	return nil
+}
+
+// mappedIterator converts each document of an underlying iterator.
+type mappedIterator struct {
+	DocumentIterator
+	convert func(Document) Document
+}
+
+func (it *mappedIterator) Document() Document {
+	return it.convert(it.DocumentIterator.Document())
+}
+
+// Collect reads the remaining documents of it and closes it.
+func Collect(it DocumentIterator) ([]Document, error) {
+	defer it.Close()
+	var docs []Document
+	for it.Next() {
+		docs = append(docs, it.Document())
+	}
+	return docs, it.Err()
+}
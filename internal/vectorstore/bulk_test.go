@@ -0,0 +1,107 @@
+package vectorstore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func bulkDocs() []Document {
+	return []Document{
+		repoDoc("a", "alpha", map[string]interface{}{"file_path": "old/a.go", "connector_id": "gh"}),
+		repoDoc("b", "beta", map[string]interface{}{"file_path": "old/b.go"}),
+		repoDoc("c", "gamma", map[string]interface{}{"file_path": "new/c.go", "connector_id": "gh"}),
+	}
+}
+
+func TestMemoryStore_BulkOperations(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	require.NoError(t, store.UpsertBatch(ctx, bulkDocs()))
+
+	_, err := store.DeleteByFilter(ctx, Filter{})
+	assert.Error(t, err, "an empty filter would delete everything")
+	_, err = store.UpdateMetadataByFilter(ctx, Exists("file_path"), MetadataUpdate{})
+	assert.Error(t, err)
+
+	// Re-tag a renamed directory
+	updated, err := store.UpdateMetadataByFilter(ctx, Prefix("file_path", "old/"), MetadataUpdate{
+		Rewrite: []PrefixRewrite{{Key: "file_path", From: "old/", To: "moved/"}},
+		Set:     map[string]interface{}{"renamed": true},
+		Unset:   []string{"connector_id"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), updated)
+	doc, err := store.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"file_path": "moved/a.go", "renamed": true}, doc.Metadata)
+
+	docs, err := Collect(mustScan(t, store, Prefix("file_path", "moved/")))
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+	assert.Equal(t, "a", docs[0].ID)
+	assert.Equal(t, "b", docs[1].ID)
+
+	deleted, err := store.DeleteByFilter(ctx, Eq("connector_id", "gh"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	count, err := store.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestScopedStores_BulkOperations(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	require.NoError(t, store.AddRepository(ctx, Repository{ID: "web", RootPath: "/src/web"}))
+	require.NoError(t, store.CreateCollection(ctx, Collection{Name: "issues"}))
+	require.NoError(t, store.UpsertBatch(ctx, bulkDocs()))
+	web := ScopeToRepo(store, "web")
+	require.NoError(t, web.UpsertBatch(ctx, bulkDocs()))
+	issues := ScopeToCollection(store, "issues")
+	require.NoError(t, issues.UpsertBatch(ctx, bulkDocs()))
+
+	// Conditions on the namespace key see the unscoped documents
+	deleted, err := web.DeleteByFilter(ctx, And(Eq("file_path", "old/a.go"), Eq(RepoIDKey, nil)))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	_, err = store.Get(ctx, "a")
+	assert.NoError(t, err, "the default root is left alone")
+
+	_, err = web.UpdateMetadataByFilter(ctx, Exists("file_path"), MetadataUpdate{Set: map[string]interface{}{RepoIDKey: "api"}})
+	assert.Error(t, err, "the namespace key cannot be changed")
+	updated, err := issues.UpdateMetadataByFilter(ctx, Eq("connector_id", "gh"), MetadataUpdate{Set: map[string]interface{}{ParentIDKey: "b"}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), updated)
+	stored, err := store.Get(ctx, CollectionDocumentID("issues", "c"))
+	require.NoError(t, err)
+	assert.Equal(t, CollectionDocumentID("issues", "b"), stored.Metadata[ParentIDKey])
+
+	docs, err := Collect(mustScan(t, issues, Filter{}))
+	require.NoError(t, err)
+	require.Len(t, docs, 3)
+	assert.Equal(t, "a", docs[0].ID)
+	assert.Equal(t, "b", docs[0].Metadata[ParentIDKey])
+	assert.NotContains(t, docs[0].Metadata, CollectionKey)
+
+	count, err := store.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), count)
+}
+
+func TestFilter_WithoutKey(t *testing.T) {
+	f := And(Eq("language", "go"), Not(Exists(RepoIDKey)), Or(Eq(RepoIDKey, "web"), Prefix("file_path", "cmd/")))
+	scoped := f.withoutKey(RepoIDKey)
+	assert.True(t, scoped.Match(map[string]interface{}{"language": "go", RepoIDKey: "x", "file_path": "cmd/main.go"}))
+	assert.False(t, scoped.Match(map[string]interface{}{"language": "go", "file_path": "internal/a.go"}))
+	assert.NoError(t, scoped.Validate())
+}
+
+func mustScan(t *testing.T, store VectorStore, filter Filter) DocumentIterator {
+	t.Helper()
+	it, err := store.Scan(context.Background(), filter)
+	require.NoError(t, err)
+	return it
+}
@@ -0,0 +1,494 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ferg-cod3s/conexus/internal/embedding"
+)
+
+// CollectionKey is the metadata key holding the collection a document
+// belongs to. Documents outside any collection have none.
+const CollectionKey = "collection"
+
+// Collections conexus writes itself.
+const (
+	GitHubCollection = "github" // Issues, pull requests and discussions from GitHub connectors
+	PushedCollection = "pushed" // Content pushed through the index_control tool
+)
+
+// collectionNamePattern restricts collection names to short, path-safe names.
+var collectionNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,63}$`)
+
+// ValidateCollectionName reports whether name can name a collection.
+func ValidateCollectionName(name string) error {
+	if !collectionNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid collection name %q: use 1-64 lowercase letters, digits, '.', '_' or '-'", name)
+	}
+	return nil
+}
+
+// Collection is a named keyspace of documents embedded with its own model,
+// such as GitHub issues or content pushed through the index tool.
+type Collection struct {
+	Name           string        `json:"name"`
+	EmbeddingModel string        `json:"embedding_model,omitempty"` // Model the collection's vectors are embedded with
+	Dimensions     int           `json:"dimensions,omitempty"`      // Required vector size; 0 accepts any
+	Retention      time.Duration `json:"retention,omitempty"`       // Age after which documents expire; 0 keeps them
+	CreatedAt      time.Time     `json:"created_at"`
+	Documents      int64         `json:"documents"` // Filled in when read from the store
+}
+
+// CollectionStore keeps track of collections and their documents. Documents
+// are written, searched and counted through ScopeToCollection.
+type CollectionStore interface {
+	// CreateCollection records a collection, replacing the settings of one
+	// with the same name. The creation time of an existing collection is kept.
+	CreateCollection(ctx context.Context, collection Collection) error
+
+	// GetCollection returns a collection, or nil if it is not recorded.
+	GetCollection(ctx context.Context, name string) (*Collection, error)
+
+	// ListCollections returns all collections sorted by name.
+	ListCollections(ctx context.Context) ([]Collection, error)
+
+	// DeleteCollection deletes a collection and all of its documents.
+	DeleteCollection(ctx context.Context, name string) error
+
+	// ExpireCollection deletes the documents of a collection last updated
+	// before the given time and returns how many were deleted.
+	ExpireCollection(ctx context.Context, name string, before time.Time) (int64, error)
+}
+
+// CollectionDocumentID returns the store ID of a collection document. The
+// "@" keeps it apart from repository documents, whose IDs cannot start with it.
+func CollectionDocumentID(collection, id string) string {
+	return "@" + collection + "/" + id
+}
+
+// DefaultCorpus restricts filter to the documents outside any collection,
+// unless it has a condition on CollectionKey itself. Stores apply it to
+// unscoped searches, scans and bulk writes, so code search and indexing never
+// see collection documents; ScopeToCollection and SearchCollections name the
+// collections they reach.
+func DefaultCorpus(filter Filter) Filter {
+	if filter.hasKey(CollectionKey) {
+		return filter
+	}
+	return And(filter, Eq(CollectionKey, nil))
+}
+
+// AllCorpora matches the documents of every collection and those outside
+// any. Added to the filter of an unscoped read, it lets the read reach
+// collection documents.
+func AllCorpora() Filter {
+	return Or(Eq(CollectionKey, nil), Exists(CollectionKey))
+}
+
+// ExcludesCollections reports whether filter only matches documents outside
+// any collection because it requires CollectionKey to be unset, as the
+// filters returned by DefaultCorpus do.
+func ExcludesCollections(filter Filter) bool {
+	unset := func(f Filter) bool {
+		return f.Op == FilterEq && f.Key == CollectionKey && f.Value == nil
+	}
+	if unset(filter) {
+		return true
+	}
+	if filter.Op == FilterAnd {
+		for _, operand := range filter.Filters {
+			if unset(operand) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// OpenCollection returns ScopeToCollection(store, collection.Name), first
+// creating the collection with the given settings if it does not exist. An
+// existing collection keeps its settings.
+func OpenCollection(ctx context.Context, store VectorStore, collection Collection) (VectorStore, error) {
+	collections, ok := store.(CollectionStore)
+	if !ok {
+		return nil, fmt.Errorf("vector store does not support collections")
+	}
+	existing, err := collections.GetCollection(ctx, collection.Name)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		if err := collections.CreateCollection(ctx, collection); err != nil {
+			return nil, fmt.Errorf("create collection %s: %w", collection.Name, err)
+		}
+	}
+	return ScopeToCollection(store, collection.Name), nil
+}
+
+// ApplyRetention expires the documents of every collection with a retention
+// period that are older than it at now, and returns how many were deleted.
+func ApplyRetention(ctx context.Context, store CollectionStore, now time.Time) (int64, error) {
+	collections, err := store.ListCollections(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var expired int64
+	for _, c := range collections {
+		if c.Retention <= 0 {
+			continue
+		}
+		n, err := store.ExpireCollection(ctx, c.Name, now.Add(-c.Retention))
+		if err != nil {
+			return expired, fmt.Errorf("expire collection %s: %w", c.Name, err)
+		}
+		expired += n
+	}
+	return expired, nil
+}
+
+// CollectionQuery selects a collection searched by SearchCollections.
+type CollectionQuery struct {
+	Collection string           // Collection name; "" searches the documents outside any collection
+	Vector     embedding.Vector // Query embedded with the collection's model; nil searches by keyword only
+	Weight     float32          // Multiplier of the collection's scores; 0 means 1
+}
+
+// SearchCollections runs a hybrid search in each of several collections and
+// merges the results by their weighted scores. Each collection is searched
+// with its own query vector, since collections may use different embedding
+// models; the query text is shared. Weights multiply the scores of a
+// collection's own search, so collections are best searched the same way:
+// all with vectors, or all by keyword only. opts applies to every collection,
+// and its threshold to the weighted scores. Results carry the collection they
+// were found in.
+func SearchCollections(ctx context.Context, store VectorStore, query string, collections []CollectionQuery, opts SearchOptions) ([]SearchResult, error) {
+	if len(collections) == 0 {
+		return nil, fmt.Errorf("no collections to search")
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+
+	perCollection := opts
+	perCollection.Limit = limit + opts.Offset
+	perCollection.Offset = 0
+	perCollection.Threshold = 0
+
+	var merged []SearchResult
+	for _, q := range collections {
+		var target VectorStore = store
+		if q.Collection == "" {
+			perCollection.Filter = And(opts.Filter, Eq(CollectionKey, nil))
+		} else {
+			target = ScopeToCollection(store, q.Collection)
+			perCollection.Filter = opts.Filter
+		}
+
+		var results []SearchResult
+		var err error
+		switch {
+		case len(q.Vector) == 0:
+			results, err = target.SearchBM25(ctx, query, perCollection)
+		case query == "":
+			results, err = target.SearchVector(ctx, q.Vector, perCollection)
+		default:
+			results, err = target.SearchHybrid(ctx, query, q.Vector, perCollection)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("search collection %q: %w", q.Collection, err)
+		}
+
+		weight := q.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		for _, result := range results {
+			result.Score *= weight
+			if opts.Threshold > 0 && result.Score < opts.Threshold {
+				continue
+			}
+			result.Collection = q.Collection
+			merged = append(merged, result)
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Score > merged[j].Score
+	})
+	if opts.Offset >= len(merged) {
+		return []SearchResult{}, nil
+	}
+	merged = merged[opts.Offset:]
+	if len(merged) > limit {
+		merged = merged[:limit]
+	}
+	return merged, nil
+}
+
+// ScopeToCollection returns a view of store holding only the documents of a
+// collection. Document IDs and parent links are namespaced by the collection
+// name and documents are tagged with CollectionKey on write; both are removed
+// again on read. Writes and vector searches are checked against the
+// collection's dimensions. The store must implement CollectionStore and the
+// collection must exist.
+func ScopeToCollection(store VectorStore, name string) VectorStore {
+	return &collectionScopedStore{store: store, name: name}
+}
+
+// collectionScopedStore implements ScopeToCollection. Collections hold pushed
+// content rather than indexed files, so file listings are not supported.
+type collectionScopedStore struct {
+	store VectorStore
+	name  string
+}
+
+func (c *collectionScopedStore) prefix() string {
+	return CollectionDocumentID(c.name, "")
+}
+
+// collection returns the settings of the collection.
+func (c *collectionScopedStore) collection(ctx context.Context) (*Collection, error) {
+	collections, ok := c.store.(CollectionStore)
+	if !ok {
+		return nil, fmt.Errorf("vector store does not support collections")
+	}
+	collection, err := collections.GetCollection(ctx, c.name)
+	if err != nil {
+		return nil, err
+	}
+	if collection == nil {
+		return nil, fmt.Errorf("collection %s not found", c.name)
+	}
+	return collection, nil
+}
+
+// checkDimensions reports a vector whose size the collection does not accept.
+func checkDimensions(collection *Collection, vector embedding.Vector) error {
+	if collection.Dimensions > 0 && len(vector) != collection.Dimensions {
+		return fmt.Errorf("collection %s expects %d dimensions, got %d", collection.Name, collection.Dimensions, len(vector))
+	}
+	return nil
+}
+
+// scope converts a collection document into its stored form.
+func (c *collectionScopedStore) scope(doc Document) Document {
+	metadata := make(map[string]interface{}, len(doc.Metadata)+1)
+	for k, v := range doc.Metadata {
+		metadata[k] = v
+	}
+	if parentID, ok := metadata[ParentIDKey].(string); ok && parentID != "" {
+		metadata[ParentIDKey] = CollectionDocumentID(c.name, parentID)
+	}
+	metadata[CollectionKey] = c.name
+
+	doc.ID = CollectionDocumentID(c.name, doc.ID)
+	doc.Metadata = metadata
+	return doc
+}
+
+// unscope converts a stored document back into the form it was written in.
+func (c *collectionScopedStore) unscope(doc Document) Document {
+	metadata := make(map[string]interface{}, len(doc.Metadata))
+	for k, v := range doc.Metadata {
+		if k != CollectionKey {
+			metadata[k] = v
+		}
+	}
+	if parentID, ok := metadata[ParentIDKey].(string); ok {
+		metadata[ParentIDKey] = strings.TrimPrefix(parentID, c.prefix())
+	}
+
+	doc.ID = strings.TrimPrefix(doc.ID, c.prefix())
+	doc.Metadata = metadata
+	return doc
+}
+
+func (c *collectionScopedStore) unscopeResults(results []SearchResult) []SearchResult {
+	for i := range results {
+		results[i].Document = c.unscope(results[i].Document)
+	}
+	return results
+}
+
+// scopeFilter restricts a filter to the collection. Documents read through
+// the view do not set CollectionKey, so conditions on it are evaluated as unset.
+func (c *collectionScopedStore) scopeFilter(filter Filter) Filter {
+	return And(filter.withoutKey(CollectionKey), Eq(CollectionKey, c.name))
+}
+
+// scopeOptions restricts a search to the collection.
+func (c *collectionScopedStore) scopeOptions(opts SearchOptions) SearchOptions {
+	opts.Filter = c.scopeFilter(opts.MetadataFilter())
+	opts.Filters = nil
+	return opts
+}
+
+// Upsert inserts or updates a collection document.
+func (c *collectionScopedStore) Upsert(ctx context.Context, doc Document) error {
+	return c.UpsertBatch(ctx, []Document{doc})
+}
+
+// UpsertBatch inserts or updates collection documents.
+func (c *collectionScopedStore) UpsertBatch(ctx context.Context, docs []Document) error {
+	collection, err := c.collection(ctx)
+	if err != nil {
+		return err
+	}
+	scoped := make([]Document, len(docs))
+	for i, doc := range docs {
+		if err := checkDimensions(collection, doc.Vector); err != nil {
+			return fmt.Errorf("document %s: %w", doc.ID, err)
+		}
+		scoped[i] = c.scope(doc)
+	}
+	return c.store.UpsertBatch(ctx, scoped)
+}
+
+// Delete removes a collection document.
+func (c *collectionScopedStore) Delete(ctx context.Context, id string) error {
+	return c.store.Delete(ctx, CollectionDocumentID(c.name, id))
+}
+
+// DeleteByFilter removes the collection documents matching filter.
+func (c *collectionScopedStore) DeleteByFilter(ctx context.Context, filter Filter) (int64, error) {
+	if filter.IsEmpty() {
+		return 0, fmt.Errorf("filter cannot be empty")
+	}
+	return c.store.DeleteByFilter(ctx, c.scopeFilter(filter))
+}
+
+// UpdateMetadataByFilter updates the metadata of the collection documents
+// matching filter.
+func (c *collectionScopedStore) UpdateMetadataByFilter(ctx context.Context, filter Filter, update MetadataUpdate) (int64, error) {
+	if filter.IsEmpty() {
+		return 0, fmt.Errorf("filter cannot be empty")
+	}
+	scoped, err := scopeMetadataUpdate(update, CollectionKey, func(id string) string {
+		return CollectionDocumentID(c.name, id)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return c.store.UpdateMetadataByFilter(ctx, c.scopeFilter(filter), scoped)
+}
+
+// Scan streams the collection documents matching filter.
+func (c *collectionScopedStore) Scan(ctx context.Context, filter Filter) (DocumentIterator, error) {
+	it, err := c.store.Scan(ctx, c.scopeFilter(filter))
+	if err != nil {
+		return nil, err
+	}
+	return &mappedIterator{DocumentIterator: it, convert: c.unscope}, nil
+}
+
+// Get retrieves a collection document.
+func (c *collectionScopedStore) Get(ctx context.Context, id string) (*Document, error) {
+	doc, err := c.store.Get(ctx, CollectionDocumentID(c.name, id))
+	if err != nil || doc == nil {
+		return doc, err
+	}
+	unscoped := c.unscope(*doc)
+	return &unscoped, nil
+}
+
+// SearchVector searches the collection's documents by vector similarity.
+func (c *collectionScopedStore) SearchVector(ctx context.Context, vector embedding.Vector, opts SearchOptions) ([]SearchResult, error) {
+	collection, err := c.collection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDimensions(collection, vector); err != nil {
+		return nil, err
+	}
+	results, err := c.store.SearchVector(ctx, vector, c.scopeOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	return c.unscopeResults(results), nil
+}
+
+// SearchBM25 searches the collection's documents by keyword.
+func (c *collectionScopedStore) SearchBM25(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error) {
+	results, err := c.store.SearchBM25(ctx, query, c.scopeOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	return c.unscopeResults(results), nil
+}
+
+// SearchHybrid searches the collection's documents with both methods.
+func (c *collectionScopedStore) SearchHybrid(ctx context.Context, query string, vector embedding.Vector, opts SearchOptions) ([]SearchResult, error) {
+	if len(vector) > 0 {
+		collection, err := c.collection(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkDimensions(collection, vector); err != nil {
+			return nil, err
+		}
+	}
+	results, err := c.store.SearchHybrid(ctx, query, vector, c.scopeOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	return c.unscopeResults(results), nil
+}
+
+// Count returns the number of collection documents.
+func (c *collectionScopedStore) Count(ctx context.Context) (int64, error) {
+	collection, err := c.collection(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return collection.Documents, nil
+}
+
+// ListIndexedFiles is not supported by collections.
+func (c *collectionScopedStore) ListIndexedFiles(ctx context.Context) ([]string, error) {
+	return nil, fmt.Errorf("collection %s does not hold indexed files", c.name)
+}
+
+// GetFileChunks is not supported by collections.
+func (c *collectionScopedStore) GetFileChunks(ctx context.Context, filePath string) ([]Document, error) {
+	return nil, fmt.Errorf("collection %s does not hold indexed files", c.name)
+}
+
+// GetParent returns the parent of a collection document.
+func (c *collectionScopedStore) GetParent(ctx context.Context, id string) (*Document, error) {
+	hierarchy, ok := c.store.(HierarchyProvider)
+	if !ok {
+		return nil, fmt.Errorf("vector store does not support chunk hierarchy")
+	}
+	parent, err := hierarchy.GetParent(ctx, CollectionDocumentID(c.name, id))
+	if err != nil || parent == nil {
+		return parent, err
+	}
+	unscoped := c.unscope(*parent)
+	return &unscoped, nil
+}
+
+// GetChildren returns the direct children of a collection document.
+func (c *collectionScopedStore) GetChildren(ctx context.Context, id string) ([]Document, error) {
+	hierarchy, ok := c.store.(HierarchyProvider)
+	if !ok {
+		return nil, fmt.Errorf("vector store does not support chunk hierarchy")
+	}
+	children, err := hierarchy.GetChildren(ctx, CollectionDocumentID(c.name, id))
+	if err != nil {
+		return nil, err
+	}
+	for i := range children {
+		children[i] = c.unscope(children[i])
+	}
+	return children, nil
+}
+
+// Close does nothing; the underlying store is owned by the caller.
+func (c *collectionScopedStore) Close() error {
+	return nil
+}
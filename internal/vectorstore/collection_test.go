@@ -0,0 +1,154 @@
+package vectorstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ferg-cod3s/conexus/internal/embedding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScopeToCollection_IsolatesCollections(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	require.NoError(t, store.CreateCollection(ctx, Collection{Name: "issues", EmbeddingModel: "small", Dimensions: 2}))
+	require.NoError(t, store.CreateCollection(ctx, Collection{Name: "notes", Dimensions: 3}))
+
+	issues := ScopeToCollection(store, "issues")
+	notes := ScopeToCollection(store, "notes")
+
+	require.NoError(t, issues.Upsert(ctx, Document{ID: "42", Content: "login fails", Vector: embedding.Vector{1, 0},
+		Metadata: map[string]interface{}{ParentIDKey: "41"}}))
+	require.NoError(t, notes.Upsert(ctx, Document{ID: "42", Content: "login notes", Vector: embedding.Vector{1, 0, 0}}))
+	require.NoError(t, store.Upsert(ctx, Document{ID: "42", Content: "login handler", Vector: embedding.Vector{1, 0, 0}}))
+
+	// Vectors must match the collection's dimensions
+	err := issues.Upsert(ctx, Document{ID: "43", Content: "wrong size", Vector: embedding.Vector{1, 0, 0}})
+	assert.ErrorContains(t, err, "expects 2 dimensions")
+	_, err = issues.SearchVector(ctx, embedding.Vector{1, 0, 0}, SearchOptions{Limit: 10})
+	assert.Error(t, err)
+
+	doc, err := issues.Get(ctx, "42")
+	require.NoError(t, err)
+	assert.Equal(t, "login fails", doc.Content)
+	assert.Equal(t, "41", doc.Metadata[ParentIDKey])
+	assert.NotContains(t, doc.Metadata, CollectionKey)
+
+	stored, err := store.Get(ctx, CollectionDocumentID("issues", "42"))
+	require.NoError(t, err)
+	assert.Equal(t, "issues", stored.Metadata[CollectionKey])
+
+	results, err := notes.SearchBM25(ctx, "login", SearchOptions{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "login notes", results[0].Document.Content)
+	assert.Equal(t, "42", results[0].Document.ID)
+
+	// Unscoped searches and writes only reach documents outside collections
+	results, err = store.SearchBM25(ctx, "login", SearchOptions{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "login handler", results[0].Document.Content)
+	results, err = store.SearchVector(ctx, embedding.Vector{1, 0, 0}, SearchOptions{Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, results, 1)
+	deleted, err := store.DeleteByFilter(ctx, Exists(ParentIDKey))
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+
+	count, err := issues.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	count, err = store.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+
+	_, err = ScopeToCollection(store, "missing").Count(ctx)
+	assert.Error(t, err)
+	assert.Error(t, ScopeToCollection(store, "missing").Upsert(ctx, repoDoc("a", "b", nil)))
+}
+
+func TestSearchCollections_WeightsCollections(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	require.NoError(t, store.CreateCollection(ctx, Collection{Name: "issues"}))
+	require.NoError(t, ScopeToCollection(store, "issues").Upsert(ctx, repoDoc("1", "timeout in login", nil)))
+	require.NoError(t, store.Upsert(ctx, repoDoc("login.go:1", "login timeout handling", nil)))
+	require.NoError(t, store.Upsert(ctx, repoDoc("other.go:1", "unrelated code", nil)))
+
+	search := func(issuesWeight float32) []SearchResult {
+		results, err := SearchCollections(ctx, store, "login", []CollectionQuery{
+			{Collection: ""},
+			{Collection: "issues", Weight: issuesWeight},
+		}, SearchOptions{Limit: 10, Threshold: 0.001})
+		require.NoError(t, err)
+		return results
+	}
+
+	results := search(10)
+	require.Len(t, results, 2)
+	assert.Equal(t, "issues", results[0].Collection)
+	assert.Equal(t, "1", results[0].Document.ID)
+	assert.Equal(t, "", results[1].Collection)
+	assert.Equal(t, "login.go:1", results[1].Document.ID)
+
+	results = search(0.01)
+	require.Len(t, results, 2)
+	assert.Equal(t, "login.go:1", results[0].Document.ID)
+
+	_, err := SearchCollections(ctx, store, "login", nil, SearchOptions{})
+	assert.Error(t, err)
+}
+
+func TestMemoryStore_Collections(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	assert.Error(t, store.CreateCollection(ctx, Collection{Name: "Issues"}))
+	assert.Error(t, store.CreateCollection(ctx, Collection{Name: "issues", Dimensions: -1}))
+
+	require.NoError(t, store.CreateCollection(ctx, Collection{Name: "issues", Retention: time.Hour}))
+	require.NoError(t, store.CreateCollection(ctx, Collection{Name: "pushed"}))
+	issues, err := store.GetCollection(ctx, "issues")
+	require.NoError(t, err)
+	created := issues.CreatedAt
+
+	// Re-creating replaces the settings and keeps the creation time
+	require.NoError(t, store.CreateCollection(ctx, Collection{Name: "issues", EmbeddingModel: "large", Retention: time.Hour}))
+	issues, err = store.GetCollection(ctx, "issues")
+	require.NoError(t, err)
+	assert.Equal(t, "large", issues.EmbeddingModel)
+	assert.Equal(t, created, issues.CreatedAt)
+
+	old := time.Now().Add(-2 * time.Hour)
+	scoped := ScopeToCollection(store, "issues")
+	require.NoError(t, scoped.Upsert(ctx, Document{ID: "old", Content: "old issue", Vector: embedding.Vector{1}, UpdatedAt: old, CreatedAt: old}))
+	require.NoError(t, scoped.Upsert(ctx, Document{ID: "new", Content: "new issue", Vector: embedding.Vector{1}}))
+	require.NoError(t, ScopeToCollection(store, "pushed").Upsert(ctx, Document{ID: "old", Content: "old push", Vector: embedding.Vector{1}, UpdatedAt: old, CreatedAt: old}))
+
+	collections, err := store.ListCollections(ctx)
+	require.NoError(t, err)
+	require.Len(t, collections, 2)
+	assert.Equal(t, "issues", collections[0].Name)
+	assert.Equal(t, int64(2), collections[0].Documents)
+
+	// Only collections with a retention period expire documents
+	expired, err := ApplyRetention(ctx, store, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), expired)
+	_, err = scoped.Get(ctx, "old")
+	assert.Error(t, err)
+	_, err = ScopeToCollection(store, "pushed").Get(ctx, "old")
+	assert.NoError(t, err)
+
+	require.NoError(t, store.DeleteCollection(ctx, "issues"))
+	assert.Error(t, store.DeleteCollection(ctx, "issues"))
+	missing, err := store.GetCollection(ctx, "issues")
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+	count, err := store.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
@@ -0,0 +1,507 @@
+package vectorstore
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FilterOp is the operator of a Filter.
+type FilterOp string
+
+const (
+	FilterEq       FilterOp = "eq"       // Key equals Value; a nil Value matches unset keys
+	FilterIn       FilterOp = "in"       // Key equals one of Values
+	FilterPrefix   FilterOp = "prefix"   // Key is a string starting with Value
+	FilterGlob     FilterOp = "glob"     // Key is a string matching the pattern Value (*, ?, [...]; case-sensitive)
+	FilterRange    FilterOp = "range"    // Key lies between Min and Max inclusive; a nil bound is open
+	FilterExists   FilterOp = "exists"   // Key is set and not null
+	FilterContains FilterOp = "contains" // Key is an array containing Value, or a scalar equal to it
+	FilterAnd      FilterOp = "and"      // All Filters match
+	FilterOr       FilterOp = "or"       // Any of Filters matches
+	FilterNot      FilterOp = "not"      // The single element of Filters does not match
+)
+
+// Filter is a typed metadata filter: field predicates on top-level metadata
+// keys combined with boolean operators. The zero Filter matches every
+// document.
+//
+// Values compare like SQLite compares JSON values: numbers and booleans
+// numerically (true is 1), strings bytewise, and in ranges numbers order
+// before strings. Times compare as RFC 3339 strings, the form they are stored
+// in, so ranges over them need a consistent time zone.
+type Filter struct {
+	Op      FilterOp      `json:"op,omitempty"`
+	Key     string        `json:"key,omitempty"`
+	Value   interface{}   `json:"value,omitempty"`
+	Values  []interface{} `json:"values,omitempty"`
+	Min     interface{}   `json:"min,omitempty"`
+	Max     interface{}   `json:"max,omitempty"`
+	Filters []Filter      `json:"filters,omitempty"`
+}
+
+// Eq matches documents whose key equals value.
+func Eq(key string, value interface{}) Filter {
+	return Filter{Op: FilterEq, Key: key, Value: value}
+}
+
+// In matches documents whose key equals one of values.
+func In(key string, values ...interface{}) Filter {
+	return Filter{Op: FilterIn, Key: key, Values: values}
+}
+
+// InStrings is In for string values.
+func InStrings(key string, values []string) Filter {
+	return In(key, stringValues(values)...)
+}
+
+// Prefix matches documents whose key is a string starting with prefix.
+func Prefix(key, prefix string) Filter {
+	return Filter{Op: FilterPrefix, Key: key, Value: prefix}
+}
+
+// Glob matches documents whose key is a string matching pattern.
+func Glob(key, pattern string) Filter {
+	return Filter{Op: FilterGlob, Key: key, Value: pattern}
+}
+
+// Range matches documents whose key lies between min and max inclusive. A
+// nil bound is open.
+func Range(key string, min, max interface{}) Filter {
+	return Filter{Op: FilterRange, Key: key, Min: min, Max: max}
+}
+
+// Exists matches documents that set key to a non-null value.
+func Exists(key string) Filter {
+	return Filter{Op: FilterExists, Key: key}
+}
+
+// Contains matches documents whose key is an array containing value.
+func Contains(key string, value interface{}) Filter {
+	return Filter{Op: FilterContains, Key: key, Value: value}
+}
+
+// ContainsAny matches documents whose key is an array containing any of
+// values.
+func ContainsAny(key string, values ...interface{}) Filter {
+	filters := make([]Filter, len(values))
+	for i, value := range values {
+		filters[i] = Contains(key, value)
+	}
+	return Or(filters...)
+}
+
+// And matches documents matching all filters. Empty filters are dropped.
+func And(filters ...Filter) Filter {
+	var operands []Filter
+	for _, f := range filters {
+		if !f.IsEmpty() {
+			operands = append(operands, f)
+		}
+	}
+	if len(operands) == 1 {
+		return operands[0]
+	}
+	if len(operands) == 0 {
+		return Filter{}
+	}
+	return Filter{Op: FilterAnd, Filters: operands}
+}
+
+// Or matches documents matching any of filters. Without filters it matches
+// nothing.
+func Or(filters ...Filter) Filter {
+	if len(filters) == 1 {
+		return filters[0]
+	}
+	return Filter{Op: FilterOr, Filters: filters}
+}
+
+// Not matches documents not matching f.
+func Not(f Filter) Filter {
+	return Filter{Op: FilterNot, Filters: []Filter{f}}
+}
+
+// FiltersFromMap converts the map form of SearchOptions.Filters: a Filter
+// value is used as is, a list matches any of its elements, a map with "from"
+// and "to" entries matches the range between them, and any other value must
+// be equal.
+func FiltersFromMap(filters map[string]interface{}) Filter {
+	keys := make([]string, 0, len(filters))
+	for key := range filters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	operands := make([]Filter, 0, len(keys))
+	for _, key := range keys {
+		switch value := filters[key].(type) {
+		case Filter:
+			operands = append(operands, value)
+		case *Filter:
+			if value != nil {
+				operands = append(operands, *value)
+			}
+		case []string:
+			operands = append(operands, InStrings(key, value))
+		case []interface{}:
+			operands = append(operands, In(key, value...))
+		case map[string]string:
+			operands = append(operands, Range(key, openBound(value["from"]), openBound(value["to"])))
+		case map[string]interface{}:
+			operands = append(operands, Range(key, openBound(value["from"]), openBound(value["to"])))
+		default:
+			operands = append(operands, Eq(key, value))
+		}
+	}
+	return And(operands...)
+}
+
+// MetadataFilter returns Filter combined with the map form Filters.
+func (o SearchOptions) MetadataFilter() Filter {
+	return And(FiltersFromMap(o.Filters), o.Filter)
+}
+
+// IsEmpty reports whether f matches every document.
+func (f Filter) IsEmpty() bool {
+	return f.Op == "" || (f.Op == FilterAnd && len(f.Filters) == 0)
+}
+
+// withoutKey returns f as seen by documents that do not set key: each
+// predicate on key is replaced by whether it matches an unset value. Views
+// that strip a key from the documents they return filter with it.
+func (f Filter) withoutKey(key string) Filter {
+	switch f.Op {
+	case "":
+		return f
+	case FilterAnd, FilterOr, FilterNot:
+		operands := make([]Filter, len(f.Filters))
+		for i, operand := range f.Filters {
+			operands[i] = operand.withoutKey(key)
+		}
+		f.Filters = operands
+		return f
+	}
+	if f.Key != key {
+		return f
+	}
+	if f.Match(nil) {
+		return Filter{}
+	}
+	return Or()
+}
+
+// hasKey reports whether f has a predicate on key.
+func (f Filter) hasKey(key string) bool {
+	for _, operand := range f.Filters {
+		if operand.hasKey(key) {
+			return true
+		}
+	}
+	return f.Key == key && f.Op != FilterAnd && f.Op != FilterOr && f.Op != FilterNot
+}
+
+// Validate checks that f is well formed.
+func (f Filter) Validate() error {
+	switch f.Op {
+	case "":
+		return nil
+	case FilterExists:
+	case FilterEq, FilterContains:
+		if !validFilterValue(f.Value, f.Op == FilterEq) {
+			return fmt.Errorf("%s filter on %q has unsupported value %v", f.Op, f.Key, f.Value)
+		}
+	case FilterIn:
+		for _, value := range f.Values {
+			if !validFilterValue(value, false) {
+				return fmt.Errorf("in filter on %q has unsupported value %v", f.Key, value)
+			}
+		}
+	case FilterRange:
+		if !validFilterValue(f.Min, true) || !validFilterValue(f.Max, true) {
+			return fmt.Errorf("range filter on %q has unsupported bounds %v, %v", f.Key, f.Min, f.Max)
+		}
+	case FilterPrefix, FilterGlob:
+		if _, ok := f.Value.(string); !ok {
+			return fmt.Errorf("%s filter on %q needs a string", f.Op, f.Key)
+		}
+	case FilterAnd, FilterOr:
+		for _, operand := range f.Filters {
+			if err := operand.Validate(); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FilterNot:
+		if len(f.Filters) != 1 {
+			return fmt.Errorf("not filter needs one operand, got %d", len(f.Filters))
+		}
+		return f.Filters[0].Validate()
+	default:
+		return fmt.Errorf("unknown filter operator %q", f.Op)
+	}
+
+	if f.Key == "" {
+		return fmt.Errorf("%s filter needs a key", f.Op)
+	}
+	if strings.Contains(f.Key, `"`) {
+		return fmt.Errorf("filter key %q cannot contain quotes", f.Key)
+	}
+	return nil
+}
+
+// Match reports whether metadata matches f.
+func (f Filter) Match(metadata map[string]interface{}) bool {
+	switch f.Op {
+	case "":
+		return true
+	case FilterAnd:
+		for _, operand := range f.Filters {
+			if !operand.Match(metadata) {
+				return false
+			}
+		}
+		return true
+	case FilterOr:
+		for _, operand := range f.Filters {
+			if operand.Match(metadata) {
+				return true
+			}
+		}
+		return false
+	case FilterNot:
+		return len(f.Filters) == 1 && !f.Filters[0].Match(metadata)
+	}
+
+	actual := NormalizeFilterValue(metadata[f.Key])
+	switch f.Op {
+	case FilterEq:
+		return filterValuesEqual(actual, NormalizeFilterValue(f.Value))
+	case FilterIn:
+		for _, value := range f.Values {
+			if actual != nil && filterValuesEqual(actual, NormalizeFilterValue(value)) {
+				return true
+			}
+		}
+		return false
+	case FilterPrefix:
+		s, ok := actual.(string)
+		prefix, _ := f.Value.(string)
+		return ok && strings.HasPrefix(s, prefix)
+	case FilterGlob:
+		s, ok := actual.(string)
+		pattern, _ := f.Value.(string)
+		return ok && globMatch(pattern, s)
+	case FilterRange:
+		if !isScalarFilterValue(actual) {
+			return false
+		}
+		if min := NormalizeFilterValue(f.Min); min != nil && compareFilterValues(actual, min) < 0 {
+			return false
+		}
+		if max := NormalizeFilterValue(f.Max); max != nil && compareFilterValues(actual, max) > 0 {
+			return false
+		}
+		return true
+	case FilterExists:
+		return actual != nil
+	case FilterContains:
+		want := NormalizeFilterValue(f.Value)
+		list := reflect.ValueOf(metadata[f.Key])
+		if list.Kind() != reflect.Slice && list.Kind() != reflect.Array {
+			return actual != nil && filterValuesEqual(actual, want)
+		}
+		for i := 0; i < list.Len(); i++ {
+			if filterValuesEqual(NormalizeFilterValue(list.Index(i).Interface()), want) {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+// NormalizeFilterValue converts a metadata or filter value to the form it is
+// compared in: numbers and booleans to float64, times to RFC 3339 strings.
+// Other values are returned as is.
+func NormalizeFilterValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case bool:
+		if value {
+			return float64(1)
+		}
+		return float64(0)
+	case time.Time:
+		return value.Format(time.RFC3339Nano)
+	case *time.Time:
+		if value == nil {
+			return nil
+		}
+		return value.Format(time.RFC3339Nano)
+	case string, nil:
+		return v
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	default:
+		return v
+	}
+}
+
+// validFilterValue reports whether v is a number, boolean, string or time,
+// or nil when allowed.
+func validFilterValue(v interface{}, allowNil bool) bool {
+	normalized := NormalizeFilterValue(v)
+	if normalized == nil {
+		return allowNil
+	}
+	return isScalarFilterValue(normalized)
+}
+
+// isScalarFilterValue reports whether a normalized value is a number or a
+// string.
+func isScalarFilterValue(v interface{}) bool {
+	switch v.(type) {
+	case float64, string:
+		return true
+	}
+	return false
+}
+
+// filterValuesEqual compares normalized values. Lists and other composite
+// values never equal anything.
+func filterValuesEqual(a, b interface{}) bool {
+	if isScalarFilterValue(a) {
+		// a has a comparable type, so the comparison cannot panic
+		return a == b
+	}
+	return a == nil && b == nil
+}
+
+// compareFilterValues orders normalized values as SQLite does: numbers
+// before strings.
+func compareFilterValues(a, b interface{}) int {
+	af, aNumber := a.(float64)
+	bf, bNumber := b.(float64)
+	switch {
+	case aNumber && bNumber:
+		switch {
+		case af < bf:
+			return -1
+		case af > bf:
+			return 1
+		}
+		return 0
+	case aNumber:
+		return -1
+	case bNumber:
+		return 1
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// globMatch reports whether s matches a SQLite GLOB pattern: * matches any
+// run of characters, ? a single character, and [...] a character class,
+// negated by a leading ^.
+func globMatch(pattern, s string) bool {
+	p, str := []rune(pattern), []rune(s)
+	for len(p) > 0 {
+		switch p[0] {
+		case '*':
+			for len(p) > 0 && p[0] == '*' {
+				p = p[1:]
+			}
+			if len(p) == 0 {
+				return true
+			}
+			for i := 0; i <= len(str); i++ {
+				if globMatch(string(p), string(str[i:])) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(str) == 0 {
+				return false
+			}
+		case '[':
+			if len(str) == 0 {
+				return false
+			}
+			end, matched := matchClass(p, str[0])
+			if end < 0 {
+				// An unterminated class matches a literal [
+				if str[0] != '[' {
+					return false
+				}
+				break
+			}
+			if !matched {
+				return false
+			}
+			p, str = p[end+1:], str[1:]
+			continue
+		default:
+			if len(str) == 0 || p[0] != str[0] {
+				return false
+			}
+		}
+		p, str = p[1:], str[1:]
+	}
+	return len(str) == 0
+}
+
+// matchClass matches c against the character class starting at p[0] and
+// returns the index of its closing bracket, or -1 if it is unterminated.
+func matchClass(p []rune, c rune) (int, bool) {
+	i := 1
+	negate := i < len(p) && p[i] == '^'
+	if negate {
+		i++
+	}
+	matched := false
+	// A ] right after the opening bracket is a literal
+	for first := true; i < len(p) && (first || p[i] != ']'); first = false {
+		if i+2 < len(p) && p[i+1] == '-' && p[i+2] != ']' {
+			if p[i] <= c && c <= p[i+2] {
+				matched = true
+			}
+			i += 3
+			continue
+		}
+		if p[i] == c {
+			matched = true
+		}
+		i++
+	}
+	if i >= len(p) {
+		return -1, false
+	}
+	return i, matched != negate
+}
+
+// openBound returns nil for an empty range bound.
+func openBound(v interface{}) interface{} {
+	if s, ok := v.(string); ok && s == "" {
+		return nil
+	}
+	return v
+}
+
+func stringValues(values []string) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, v := range values {
+		result[i] = v
+	}
+	return result
+}
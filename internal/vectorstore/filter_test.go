@@ -0,0 +1,98 @@
+package vectorstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter_Match(t *testing.T) {
+	metadata := map[string]interface{}{
+		"file_path":  "internal/mcp/handlers.go",
+		"language":   "go",
+		"start_line": 42,
+		"score":      0.5,
+		"generated":  false,
+		"date":       "2024-03-01T12:00:00Z",
+		"ticket_ids": []interface{}{"PROJ-1", "PROJ-2"},
+		"tags":       []string{"api"},
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{"empty", Filter{}, true},
+		{"eq string", Eq("language", "go"), true},
+		{"eq mismatch", Eq("language", "rust"), false},
+		{"eq number across types", Eq("start_line", int64(42)), true},
+		{"eq bool", Eq("generated", false), true},
+		{"eq nil on unset key", Eq("repo_id", nil), true},
+		{"eq nil on set key", Eq("language", nil), false},
+		{"in", In("language", "rust", "go"), true},
+		{"in empty", In("language"), false},
+		{"prefix", Prefix("file_path", "internal/mcp/"), true},
+		{"prefix on number", Prefix("start_line", "4"), false},
+		{"glob", Glob("file_path", "internal/*/[gh]andlers.go"), true},
+		{"glob is case-sensitive", Glob("file_path", "*.GO"), false},
+		{"range", Range("start_line", 40, 50), true},
+		{"range open", Range("start_line", nil, 41), false},
+		{"range over dates", Range("date", "2024-01-01T00:00:00Z", nil), true},
+		{"range over time values", Range("date", nil, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)), false},
+		{"range on unset key", Range("end_line", nil, nil), false},
+		{"exists", Exists("score"), true},
+		{"exists unset", Exists("repo_id"), false},
+		{"contains", Contains("ticket_ids", "PROJ-2"), true},
+		{"contains typed slice", Contains("tags", "api"), true},
+		{"contains scalar", Contains("language", "go"), true},
+		{"contains missing", Contains("ticket_ids", "PROJ-3"), false},
+		{"contains any", ContainsAny("ticket_ids", "PROJ-3", "PROJ-1"), true},
+		{"and", And(Eq("language", "go"), Exists("score")), true},
+		{"or", Or(Eq("language", "rust"), Exists("score")), true},
+		{"or empty", Or(), false},
+		{"not", Not(Eq("language", "rust")), true},
+		{"not on unset key", Not(Eq("repo_id", "a")), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NoError(t, tt.filter.Validate())
+			assert.Equal(t, tt.want, tt.filter.Match(metadata))
+		})
+	}
+}
+
+func TestFilter_Validate(t *testing.T) {
+	for name, f := range map[string]Filter{
+		"unknown operator": {Op: "near", Key: "a"},
+		"missing key":      Eq("", "a"),
+		"quoted key":       Eq(`a"b`, "a"),
+		"list value":       Eq("a", []string{"b"}),
+		"prefix number":    {Op: FilterPrefix, Key: "a", Value: 1},
+		"map bound":        Range("a", map[string]string{}, nil),
+		"not arity":        {Op: FilterNot},
+		"nested":           And(Eq("a", 1), Or(Contains("b", nil), Exists("c"))),
+	} {
+		assert.Error(t, f.Validate(), name)
+	}
+}
+
+func TestFiltersFromMap(t *testing.T) {
+	f := FiltersFromMap(map[string]interface{}{
+		"language":    "go",
+		"ticket_ids":  Contains("ticket_ids", "PROJ-1"),
+		"source_type": []string{"commit", "github_pr"},
+		"date":        map[string]string{"from": "2024-01-01", "to": ""},
+	})
+	assert.Equal(t, And(
+		Range("date", "2024-01-01", nil),
+		Eq("language", "go"),
+		InStrings("source_type", []string{"commit", "github_pr"}),
+		Contains("ticket_ids", "PROJ-1"),
+	), f)
+
+	assert.True(t, FiltersFromMap(nil).IsEmpty())
+	opts := SearchOptions{Filters: map[string]interface{}{"language": "go"}, Filter: Exists("score")}
+	assert.Equal(t, And(Eq("language", "go"), Exists("score")), opts.MetadataFilter())
+}
@@ -0,0 +1,76 @@
+package vectorstore
+
+import (
+	"context"
+	"sort"
+)
+
+// Metadata keys describing the chunk hierarchy.
+const (
+	ParentIDKey   = "parent_id"   // ID of the enclosing chunk
+	ChunkLevelKey = "chunk_level" // "file" or "package" for generated summary chunks
+)
+
+// HierarchyProvider resolves parent/child links between documents.
+type HierarchyProvider interface {
+	// GetParent returns the parent of a document, or nil if it has none.
+	GetParent(ctx context.Context, id string) (*Document, error)
+
+	// GetChildren returns the direct children of a document.
+	GetChildren(ctx context.Context, id string) ([]Document, error)
+}
+
+// ExpandToParents replaces each result with its parent document so that a
+// match on a small chunk returns the enclosing span. Generated summary chunks
+// are never used as parents, results without a usable parent are kept as is,
+// and a parent matched through several children is returned once with the
+// best score. The matched child is recorded in the "matched_chunk_id" metadata.
+func ExpandToParents(ctx context.Context, store VectorStore, results []SearchResult) []SearchResult {
+	hierarchy, _ := store.(HierarchyProvider)
+
+	expanded := make([]SearchResult, 0, len(results))
+	position := make(map[string]int, len(results))
+	for _, r := range results {
+		if parent := lookupParent(ctx, store, hierarchy, r.Document); parent != nil {
+			metadata := make(map[string]interface{}, len(parent.Metadata)+1)
+			for k, v := range parent.Metadata {
+				metadata[k] = v
+			}
+			metadata["matched_chunk_id"] = r.Document.ID
+			parent.Metadata = metadata
+			r.Document = *parent
+		}
+
+		if i, seen := position[r.Document.ID]; seen {
+			if r.Score > expanded[i].Score {
+				expanded[i] = r
+			}
+			continue
+		}
+		position[r.Document.ID] = len(expanded)
+		expanded = append(expanded, r)
+	}
+
+	sort.SliceStable(expanded, func(i, j int) bool {
+		return expanded[i].Score > expanded[j].Score
+	})
+	return expanded
+}
+
+// lookupParent returns the code or section parent of doc, if any.
+func lookupParent(ctx context.Context, store VectorStore, hierarchy HierarchyProvider, doc Document) *Document {
+	var parent *Document
+	var err error
+	if hierarchy != nil {
+		parent, err = hierarchy.GetParent(ctx, doc.ID)
+	} else if parentID, ok := doc.Metadata[ParentIDKey].(string); ok && parentID != "" {
+		parent, err = store.Get(ctx, parentID)
+	}
+	if err != nil || parent == nil {
+		return nil
+	}
+	if level, ok := parent.Metadata[ChunkLevelKey].(string); ok && level != "" {
+		return nil
+	}
+	return parent
+}
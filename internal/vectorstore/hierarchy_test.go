@@ -0,0 +1,78 @@
+package vectorstore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ferg-cod3s/conexus/internal/embedding"
+)
+
+func hierarchyDoc(id, parentID string, metadata map[string]interface{}) Document {
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+	}
+	if parentID != "" {
+		metadata[ParentIDKey] = parentID
+	}
+	return Document{ID: id, Content: "content of " + id, Vector: embedding.Vector{1, 0}, Metadata: metadata}
+}
+
+func newHierarchyStore(t *testing.T) *MemoryStore {
+	store := NewMemoryStore()
+	require.NoError(t, store.UpsertBatch(context.Background(), []Document{
+		hierarchyDoc("pkg", "", map[string]interface{}{ChunkLevelKey: "package"}),
+		hierarchyDoc("file", "pkg", map[string]interface{}{ChunkLevelKey: "file"}),
+		hierarchyDoc("type", "file", map[string]interface{}{"start_line": 1}),
+		hierarchyDoc("method-b", "type", map[string]interface{}{"start_line": 9}),
+		hierarchyDoc("method-a", "type", map[string]interface{}{"start_line": 5}),
+		hierarchyDoc("func", "file", nil),
+	}))
+	return store
+}
+
+func TestMemoryStore_Hierarchy(t *testing.T) {
+	store := newHierarchyStore(t)
+	ctx := context.Background()
+
+	parent, err := store.GetParent(ctx, "method-a")
+	require.NoError(t, err)
+	require.NotNil(t, parent)
+	assert.Equal(t, "type", parent.ID)
+
+	parent, err = store.GetParent(ctx, "pkg")
+	require.NoError(t, err)
+	assert.Nil(t, parent)
+
+	children, err := store.GetChildren(ctx, "type")
+	require.NoError(t, err)
+	require.Len(t, children, 2)
+	assert.Equal(t, "method-a", children[0].ID)
+	assert.Equal(t, "method-b", children[1].ID)
+}
+
+func TestExpandToParents(t *testing.T) {
+	store := newHierarchyStore(t)
+	ctx := context.Background()
+
+	get := func(id string) Document {
+		doc, err := store.Get(ctx, id)
+		require.NoError(t, err)
+		return *doc
+	}
+
+	expanded := ExpandToParents(ctx, store, []SearchResult{
+		{Document: get("method-a"), Score: 0.6},
+		{Document: get("func"), Score: 0.8},
+		{Document: get("method-b"), Score: 0.9},
+	})
+
+	require.Len(t, expanded, 2)
+	assert.Equal(t, "type", expanded[0].Document.ID, "siblings collapse into their parent")
+	assert.Equal(t, float32(0.9), expanded[0].Score, "the best child score is kept")
+	assert.Equal(t, "method-b", expanded[0].Document.Metadata["matched_chunk_id"])
+	assert.Equal(t, "func", expanded[1].Document.ID, "summary chunks are not used as parents")
+	assert.NotContains(t, get("type").Metadata, "matched_chunk_id", "stored parent is not modified")
+}
@@ -19,13 +19,20 @@ type MemoryStore struct {
 	mu        sync.RWMutex
 	documents map[string]Document // ID -> Document mapping
 	index     []string            // Ordered list of document IDs for iteration
+
+	checkpoint *IndexCheckpoint // In-progress indexing run, if any
+
+	repos       map[string]Repository // Repository ID -> repository
+	collections map[string]Collection // Collection name -> collection
 }
 
 // NewMemoryStore creates a new in-memory vector store.
 func NewMemoryStore() *MemoryStore {
 	return &MemoryStore{
-		documents: make(map[string]Document),
-		index:     make([]string, 0),
+		documents:   make(map[string]Document),
+		index:       make([]string, 0),
+		repos:       make(map[string]Repository),
+		collections: make(map[string]Collection),
 	}
 }
 
@@ -107,8 +114,99 @@ func (m *MemoryStore) Get(ctx context.Context, id string) (*Document, error) {
 	return &doc, nil
 }
 
+// DeleteByFilter removes the documents matching a non-empty filter. Unless
+// the filter names collections, collection documents are left alone.
+func (m *MemoryStore) DeleteByFilter(ctx context.Context, filter Filter) (int64, error) {
+	if err := validateBulkFilter(filter); err != nil {
+		return 0, err
+	}
+	filter = DefaultCorpus(filter)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.removeDocuments(func(doc Document) bool {
+		return filter.Match(doc.Metadata)
+	}), nil
+}
+
+// UpdateMetadataByFilter applies update to the metadata of the documents
+// matching a non-empty filter. Unless the filter names collections,
+// collection documents are left alone.
+func (m *MemoryStore) UpdateMetadataByFilter(ctx context.Context, filter Filter, update MetadataUpdate) (int64, error) {
+	if err := validateBulkFilter(filter); err != nil {
+		return 0, err
+	}
+	filter = DefaultCorpus(filter)
+	if err := update.Validate(); err != nil {
+		return 0, fmt.Errorf("invalid update: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var updated int64
+	for _, docID := range m.index {
+		doc := m.documents[docID]
+		if !filter.Match(doc.Metadata) {
+			continue
+		}
+		metadata, changed := update.Apply(doc.Metadata)
+		if !changed {
+			continue
+		}
+		doc.Metadata = metadata
+		m.documents[docID] = doc
+		updated++
+	}
+	return updated, nil
+}
+
+// Scan returns the documents matching filter in ID order, outside any
+// collection unless the filter names collections. They are read when Scan is
+// called.
+func (m *MemoryStore) Scan(ctx context.Context, filter Filter) (DocumentIterator, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+	filter = DefaultCorpus(filter)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var docs []Document
+	for _, doc := range m.documents {
+		if filter.Match(doc.Metadata) {
+			docs = append(docs, doc)
+		}
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		return docs[i].ID < docs[j].ID
+	})
+	return SliceIterator(docs), nil
+}
+
+// validateBulkFilter checks the filter of a bulk write, which must not match
+// every document.
+func validateBulkFilter(filter Filter) error {
+	if filter.IsEmpty() {
+		return fmt.Errorf("filter cannot be empty")
+	}
+	if err := filter.Validate(); err != nil {
+		return fmt.Errorf("invalid filter: %w", err)
+	}
+	return nil
+}
+
 // SearchVector performs dense vector similarity search using cosine similarity.
+// Collection documents are searched only when the filter names collections.
 func (m *MemoryStore) SearchVector(ctx context.Context, vector embedding.Vector, opts SearchOptions) ([]SearchResult, error) {
+	filter := opts.MetadataFilter()
+	if err := filter.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+	filter = DefaultCorpus(filter)
+
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -119,7 +217,7 @@ func (m *MemoryStore) SearchVector(ctx context.Context, vector embedding.Vector,
 		doc := m.documents[docID]
 
 		// Apply metadata filters
-		if !matchesFilters(doc, opts.Filters) {
+		if !filter.Match(doc.Metadata) {
 			continue
 		}
 
@@ -157,12 +255,19 @@ func (m *MemoryStore) SearchVector(ctx context.Context, vector embedding.Vector,
 }
 
 // SearchBM25 performs sparse keyword search using simple BM25 implementation.
+// Collection documents are searched only when the filter names collections.
 // Note: This is a simplified BM25 for POC. Production should use proper text search engine.
 func (m *MemoryStore) SearchBM25(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error) {
 	if query == "" {
 		return nil, fmt.Errorf("query cannot be empty")
 	}
 
+	filter := opts.MetadataFilter()
+	if err := filter.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+	filter = DefaultCorpus(filter)
+
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -181,7 +286,7 @@ func (m *MemoryStore) SearchBM25(ctx context.Context, query string, opts SearchO
 		doc := m.documents[docID]
 
 		// Apply metadata filters
-		if !matchesFilters(doc, opts.Filters) {
+		if !filter.Match(doc.Metadata) {
 			continue
 		}
 
@@ -220,6 +325,7 @@ func (m *MemoryStore) SearchHybrid(ctx context.Context, query string, vector emb
 		Limit:     opts.Limit * 2, // Get more for fusion
 		Threshold: 0,              // Apply threshold after fusion
 		Filters:   opts.Filters,
+		Filter:    opts.Filter,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("vector search: %w", err)
@@ -230,6 +336,7 @@ func (m *MemoryStore) SearchHybrid(ctx context.Context, query string, vector emb
 		Limit:     opts.Limit * 2,
 		Threshold: 0,
 		Filters:   opts.Filters,
+		Filter:    opts.Filter,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("bm25 search: %w", err)
@@ -284,13 +391,29 @@ func (m *MemoryStore) Count(ctx context.Context) (int64, error) {
 	return int64(len(m.documents)), nil
 }
 
-// ListIndexedFiles returns a list of all unique file paths that have been indexed.
+// ListIndexedFiles returns a list of all unique file paths that have been
+// indexed in the default root.
 func (m *MemoryStore) ListIndexedFiles(ctx context.Context) ([]string, error) {
+	return m.listFiles(""), nil
+}
+
+// GetFileChunks returns all chunks for a specific file path of the default
+// root, sorted by start_line.
+func (m *MemoryStore) GetFileChunks(ctx context.Context, filePath string) ([]Document, error) {
+	return m.fileChunks("", filePath), nil
+}
+
+// listFiles returns the file paths indexed for a repository, or for the
+// default root when repoID is empty.
+func (m *MemoryStore) listFiles(repoID string) []string {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	fileSet := make(map[string]bool)
 	for _, doc := range m.documents {
+		if docRepoID(doc) != repoID || docCollection(doc) != "" {
+			continue
+		}
 		if filePath, ok := doc.Metadata["file_path"].(string); ok && filePath != "" {
 			fileSet[filePath] = true
 		}
@@ -302,16 +425,20 @@ func (m *MemoryStore) ListIndexedFiles(ctx context.Context) ([]string, error) {
 	}
 
 	sort.Strings(files)
-	return files, nil
+	return files
 }
 
-// GetFileChunks returns all chunks for a specific file path, sorted by start_line.
-func (m *MemoryStore) GetFileChunks(ctx context.Context, filePath string) ([]Document, error) {
+// fileChunks returns the chunks of a file in a repository, or in the default
+// root when repoID is empty, sorted by start_line.
+func (m *MemoryStore) fileChunks(repoID, filePath string) []Document {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	var chunks []Document
 	for _, doc := range m.documents {
+		if docRepoID(doc) != repoID || docCollection(doc) != "" {
+			continue
+		}
 		if docFilePath, ok := doc.Metadata["file_path"].(string); ok && docFilePath == filePath {
 			chunks = append(chunks, doc)
 		}
@@ -324,7 +451,269 @@ func (m *MemoryStore) GetFileChunks(ctx context.Context, filePath string) ([]Doc
 		return startLineI < startLineJ
 	})
 
-	return chunks, nil
+	return chunks
+}
+
+// docRepoID returns the repository a document belongs to, or "" for the
+// default root.
+func docRepoID(doc Document) string {
+	repoID, _ := doc.Metadata[RepoIDKey].(string)
+	return repoID
+}
+
+// AddRepository records a repository, keeping the creation time of an
+// existing one.
+func (m *MemoryStore) AddRepository(ctx context.Context, repo Repository) error {
+	if err := ValidateRepoID(repo.ID); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if existing, ok := m.repos[repo.ID]; ok {
+		repo.CreatedAt = existing.CreatedAt
+	} else if repo.CreatedAt.IsZero() {
+		repo.CreatedAt = time.Now()
+	}
+	repo.IgnorePatterns = append([]string(nil), repo.IgnorePatterns...)
+	repo.Documents = 0
+	m.repos[repo.ID] = repo
+	return nil
+}
+
+// GetRepository returns a repository, or nil if it is not recorded.
+func (m *MemoryStore) GetRepository(ctx context.Context, id string) (*Repository, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	repo, ok := m.repos[id]
+	if !ok {
+		return nil, nil
+	}
+	repo.Documents = m.countRepoDocuments(id)
+	return &repo, nil
+}
+
+// ListRepositories returns all repositories sorted by ID.
+func (m *MemoryStore) ListRepositories(ctx context.Context) ([]Repository, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	repos := make([]Repository, 0, len(m.repos))
+	for id, repo := range m.repos {
+		repo.Documents = m.countRepoDocuments(id)
+		repos = append(repos, repo)
+	}
+	sort.Slice(repos, func(i, j int) bool {
+		return repos[i].ID < repos[j].ID
+	})
+	return repos, nil
+}
+
+// countRepoDocuments counts the documents of a repository. Callers must hold m.mu.
+func (m *MemoryStore) countRepoDocuments(repoID string) int64 {
+	var count int64
+	for _, doc := range m.documents {
+		if docRepoID(doc) == repoID {
+			count++
+		}
+	}
+	return count
+}
+
+// RemoveRepository deletes a repository and all of its documents.
+func (m *MemoryStore) RemoveRepository(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.repos[id]; !ok {
+		return fmt.Errorf("repository %s not found", id)
+	}
+	delete(m.repos, id)
+	m.removeDocuments(func(doc Document) bool {
+		return docRepoID(doc) == id
+	})
+	return nil
+}
+
+// ListRepositoryFiles returns the file paths indexed for a repository.
+func (m *MemoryStore) ListRepositoryFiles(ctx context.Context, repoID string) ([]string, error) {
+	return m.listFiles(repoID), nil
+}
+
+// GetRepositoryFileChunks returns the chunks of a repository's file, sorted by start_line.
+func (m *MemoryStore) GetRepositoryFileChunks(ctx context.Context, repoID, filePath string) ([]Document, error) {
+	return m.fileChunks(repoID, filePath), nil
+}
+
+// docCollection returns the collection a document belongs to, or "" if it
+// is outside any collection.
+func docCollection(doc Document) string {
+	collection, _ := doc.Metadata[CollectionKey].(string)
+	return collection
+}
+
+// CreateCollection records a collection, keeping the creation time of an
+// existing one.
+func (m *MemoryStore) CreateCollection(ctx context.Context, collection Collection) error {
+	if err := ValidateCollectionName(collection.Name); err != nil {
+		return err
+	}
+	if collection.Dimensions < 0 || collection.Retention < 0 {
+		return fmt.Errorf("collection dimensions and retention cannot be negative")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if existing, ok := m.collections[collection.Name]; ok {
+		collection.CreatedAt = existing.CreatedAt
+	} else if collection.CreatedAt.IsZero() {
+		collection.CreatedAt = time.Now()
+	}
+	collection.Documents = 0
+	m.collections[collection.Name] = collection
+	return nil
+}
+
+// GetCollection returns a collection, or nil if it is not recorded.
+func (m *MemoryStore) GetCollection(ctx context.Context, name string) (*Collection, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	collection, ok := m.collections[name]
+	if !ok {
+		return nil, nil
+	}
+	collection.Documents = m.countCollectionDocuments(name)
+	return &collection, nil
+}
+
+// ListCollections returns all collections sorted by name.
+func (m *MemoryStore) ListCollections(ctx context.Context) ([]Collection, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	collections := make([]Collection, 0, len(m.collections))
+	for name, collection := range m.collections {
+		collection.Documents = m.countCollectionDocuments(name)
+		collections = append(collections, collection)
+	}
+	sort.Slice(collections, func(i, j int) bool {
+		return collections[i].Name < collections[j].Name
+	})
+	return collections, nil
+}
+
+// countCollectionDocuments counts the documents of a collection. Callers must hold m.mu.
+func (m *MemoryStore) countCollectionDocuments(name string) int64 {
+	var count int64
+	for _, doc := range m.documents {
+		if docCollection(doc) == name {
+			count++
+		}
+	}
+	return count
+}
+
+// DeleteCollection deletes a collection and all of its documents.
+func (m *MemoryStore) DeleteCollection(ctx context.Context, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.collections[name]; !ok {
+		return fmt.Errorf("collection %s not found", name)
+	}
+	delete(m.collections, name)
+	m.removeDocuments(func(doc Document) bool {
+		return docCollection(doc) == name
+	})
+	return nil
+}
+
+// ExpireCollection deletes the documents of a collection last updated
+// before the given time.
+func (m *MemoryStore) ExpireCollection(ctx context.Context, name string, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.collections[name]; !ok {
+		return 0, fmt.Errorf("collection %s not found", name)
+	}
+	return m.removeDocuments(func(doc Document) bool {
+		return docCollection(doc) == name && doc.UpdatedAt.Before(before)
+	}), nil
+}
+
+// removeDocuments deletes the documents matching remove and returns how many
+// were deleted. Callers must hold m.mu for writing.
+func (m *MemoryStore) removeDocuments(remove func(Document) bool) int64 {
+	var removed int64
+	kept := m.index[:0]
+	for _, docID := range m.index {
+		if remove(m.documents[docID]) {
+			delete(m.documents, docID)
+			removed++
+			continue
+		}
+		kept = append(kept, docID)
+	}
+	m.index = kept
+	return removed
+}
+
+// GetParent returns the document named by the parent_id metadata of a
+// document, or nil if it has none or the parent is not stored.
+func (m *MemoryStore) GetParent(ctx context.Context, id string) (*Document, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	doc, exists := m.documents[id]
+	if !exists {
+		return nil, fmt.Errorf("document %s not found", id)
+	}
+	parentID, _ := doc.Metadata[ParentIDKey].(string)
+	parent, exists := m.documents[parentID]
+	if !exists {
+		return nil, nil
+	}
+	return &parent, nil
+}
+
+// GetChildren returns the documents whose parent_id is id, sorted by start_line.
+func (m *MemoryStore) GetChildren(ctx context.Context, id string) ([]Document, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var children []Document
+	for _, doc := range m.documents {
+		if parentID, ok := doc.Metadata[ParentIDKey].(string); ok && parentID == id {
+			children = append(children, doc)
+		}
+	}
+
+	sort.Slice(children, func(i, j int) bool {
+		startI, startJ := metadataInt(children[i].Metadata, "start_line"), metadataInt(children[j].Metadata, "start_line")
+		if startI != startJ {
+			return startI < startJ
+		}
+		return children[i].ID < children[j].ID
+	})
+	return children, nil
+}
+
+// metadataInt reads a numeric metadata value stored either as an int or, after
+// a JSON round trip, as a float64.
+func metadataInt(metadata map[string]interface{}, key string) int {
+	switch v := metadata[key].(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	default:
+		return 0
+	}
 }
 
 // Close releases resources (no-op for memory store).
@@ -362,6 +751,97 @@ func (m *MemoryStore) Stats(ctx context.Context) (*IndexStats, error) {
 
 // Helper functions
 
+// SaveCheckpoint writes the run header, pending batch and completed files.
+func (m *MemoryStore) SaveCheckpoint(ctx context.Context, cp IndexCheckpoint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	completed := make(map[string]string, len(cp.Completed))
+	if m.checkpoint != nil && m.checkpoint.RunID == cp.RunID {
+		for path, hash := range m.checkpoint.Completed {
+			completed[path] = hash
+		}
+	}
+	for path, hash := range cp.Completed {
+		completed[path] = hash
+	}
+
+	saved := cp
+	saved.Completed = completed
+	saved.Pending = append([]string(nil), cp.Pending...)
+	saved.UpdatedAt = time.Now()
+	if saved.StartedAt.IsZero() {
+		saved.StartedAt = saved.UpdatedAt
+	}
+	m.checkpoint = &saved
+	return nil
+}
+
+// CommitCheckpointBatch replaces the documents of the given files and marks them completed.
+func (m *MemoryStore) CommitCheckpointBatch(ctx context.Context, files map[string]string, docs []Document) error {
+	for _, doc := range docs {
+		if doc.ID == "" {
+			return fmt.Errorf("document ID cannot be empty")
+		}
+		if len(doc.Vector) == 0 {
+			return fmt.Errorf("document %s vector cannot be empty", doc.ID)
+		}
+	}
+
+	m.mu.Lock()
+	if m.checkpoint == nil {
+		m.mu.Unlock()
+		return fmt.Errorf("no checkpoint in progress")
+	}
+
+	kept := m.index[:0]
+	for _, id := range m.index {
+		if path, ok := m.documents[id].Metadata["file_path"].(string); ok && docRepoID(m.documents[id]) == "" {
+			if _, replaced := files[path]; replaced {
+				delete(m.documents, id)
+				continue
+			}
+		}
+		kept = append(kept, id)
+	}
+	m.index = kept
+
+	for path, hash := range files {
+		m.checkpoint.Completed[path] = hash
+	}
+	m.checkpoint.Pending = nil
+	m.checkpoint.UpdatedAt = time.Now()
+	m.mu.Unlock()
+
+	return m.UpsertBatch(ctx, docs)
+}
+
+// LoadCheckpoint returns the current checkpoint, or nil if no run is in progress.
+func (m *MemoryStore) LoadCheckpoint(ctx context.Context) (*IndexCheckpoint, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.checkpoint == nil {
+		return nil, nil
+	}
+
+	cp := *m.checkpoint
+	cp.Completed = make(map[string]string, len(m.checkpoint.Completed))
+	for path, hash := range m.checkpoint.Completed {
+		cp.Completed[path] = hash
+	}
+	cp.Pending = append([]string(nil), m.checkpoint.Pending...)
+	return &cp, nil
+}
+
+// ClearCheckpoint removes the checkpoint once a run has completed.
+func (m *MemoryStore) ClearCheckpoint(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.checkpoint = nil
+	return nil
+}
+
 // cosineSimilarity computes the cosine similarity between two vectors.
 func cosineSimilarity(a, b embedding.Vector) float32 {
 	if len(a) != len(b) {
@@ -384,18 +864,7 @@ func cosineSimilarity(a, b embedding.Vector) float32 {
 
 // matchesFilters checks if a document matches all metadata filters.
 func matchesFilters(doc Document, filters map[string]interface{}) bool {
-	if len(filters) == 0 {
-		return true
-	}
-
-	for key, expectedValue := range filters {
-		actualValue, exists := doc.Metadata[key]
-		if !exists || actualValue != expectedValue {
-			return false
-		}
-	}
-
-	return true
+	return FiltersFromMap(filters).Match(doc.Metadata)
 }
 
 // tokenize splits text into lowercase terms.
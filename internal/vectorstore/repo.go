@@ -0,0 +1,343 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ferg-cod3s/conexus/internal/embedding"
+)
+
+// RepoIDKey is the metadata key holding the repository a document belongs to.
+// Documents of the default root have no repository ID.
+const RepoIDKey = "repo_id"
+
+// repoIDPattern restricts repository IDs to short, path-safe names.
+var repoIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,63}$`)
+
+// ValidateRepoID reports whether id can name a repository.
+func ValidateRepoID(id string) error {
+	if !repoIDPattern.MatchString(id) {
+		return fmt.Errorf("invalid repository ID %q: use 1-64 lowercase letters, digits, '.', '_' or '-'", id)
+	}
+	return nil
+}
+
+// Repository is an additional source tree indexed into its own namespace.
+type Repository struct {
+	ID             string    `json:"id"`
+	RootPath       string    `json:"root_path"`
+	IgnorePatterns []string  `json:"ignore_patterns,omitempty"` // Added to .git and the repository's .gitignore
+	CreatedAt      time.Time `json:"created_at"`
+	LastIndexedAt  time.Time `json:"last_indexed_at,omitempty"`
+	Documents      int64     `json:"documents"` // Filled in when read from the store
+}
+
+// RepositoryStore keeps track of repositories and their documents. The
+// file listing methods of VectorStore only cover the default root; the
+// methods here cover a single repository.
+type RepositoryStore interface {
+	// AddRepository records a repository, replacing one with the same ID.
+	// The creation time of an existing repository is kept.
+	AddRepository(ctx context.Context, repo Repository) error
+
+	// GetRepository returns a repository, or nil if it is not recorded.
+	GetRepository(ctx context.Context, id string) (*Repository, error)
+
+	// ListRepositories returns all repositories sorted by ID.
+	ListRepositories(ctx context.Context) ([]Repository, error)
+
+	// RemoveRepository deletes a repository and all of its documents.
+	RemoveRepository(ctx context.Context, id string) error
+
+	// ListRepositoryFiles returns the file paths indexed for a repository.
+	ListRepositoryFiles(ctx context.Context, repoID string) ([]string, error)
+
+	// GetRepositoryFileChunks returns the chunks of a repository's file,
+	// sorted by start_line.
+	GetRepositoryFileChunks(ctx context.Context, repoID, filePath string) ([]Document, error)
+}
+
+// RepoDocumentID returns the store ID of a repository document.
+func RepoDocumentID(repoID, id string) string {
+	return repoID + "/" + id
+}
+
+// ScopeToRepo returns a view of store holding only the documents of one
+// repository, so the indexer can run against it unchanged. Document IDs and
+// parent links are namespaced by the repository ID and documents are tagged
+// with RepoIDKey on write; both are removed again on read. The store must
+// implement RepositoryStore.
+func ScopeToRepo(store VectorStore, repoID string) VectorStore {
+	return &repoScopedStore{store: store, repoID: repoID}
+}
+
+// repoScopedStore implements ScopeToRepo. Checkpoints are not supported, so
+// repositories are indexed without crash recovery.
+type repoScopedStore struct {
+	store  VectorStore
+	repoID string
+}
+
+func (r *repoScopedStore) prefix() string {
+	return r.repoID + "/"
+}
+
+// scope converts a repository document into its stored form.
+func (r *repoScopedStore) scope(doc Document) Document {
+	metadata := make(map[string]interface{}, len(doc.Metadata)+1)
+	for k, v := range doc.Metadata {
+		metadata[k] = v
+	}
+	if parentID, ok := metadata[ParentIDKey].(string); ok && parentID != "" {
+		metadata[ParentIDKey] = RepoDocumentID(r.repoID, parentID)
+	}
+	metadata[RepoIDKey] = r.repoID
+
+	doc.ID = RepoDocumentID(r.repoID, doc.ID)
+	doc.Metadata = metadata
+	return doc
+}
+
+// unscope converts a stored document back into the form it was written in.
+func (r *repoScopedStore) unscope(doc Document) Document {
+	metadata := make(map[string]interface{}, len(doc.Metadata))
+	for k, v := range doc.Metadata {
+		if k != RepoIDKey {
+			metadata[k] = v
+		}
+	}
+	if parentID, ok := metadata[ParentIDKey].(string); ok {
+		metadata[ParentIDKey] = strings.TrimPrefix(parentID, r.prefix())
+	}
+
+	doc.ID = strings.TrimPrefix(doc.ID, r.prefix())
+	doc.Metadata = metadata
+	return doc
+}
+
+func (r *repoScopedStore) unscopeResults(results []SearchResult) []SearchResult {
+	for i := range results {
+		results[i].Document = r.unscope(results[i].Document)
+	}
+	return results
+}
+
+// scopeFilter restricts a filter to the repository. Documents read through
+// the view do not set RepoIDKey, so conditions on it are evaluated as unset.
+func (r *repoScopedStore) scopeFilter(filter Filter) Filter {
+	return And(filter.withoutKey(RepoIDKey), Eq(RepoIDKey, r.repoID))
+}
+
+// scopeOptions restricts a search to the repository.
+func (r *repoScopedStore) scopeOptions(opts SearchOptions) SearchOptions {
+	opts.Filter = r.scopeFilter(opts.MetadataFilter())
+	opts.Filters = nil
+	return opts
+}
+
+// scopeUpdate converts a metadata update of repository documents into one of
+// their stored form.
+func (r *repoScopedStore) scopeUpdate(update MetadataUpdate) (MetadataUpdate, error) {
+	return scopeMetadataUpdate(update, RepoIDKey, func(id string) string {
+		return RepoDocumentID(r.repoID, id)
+	})
+}
+
+func (r *repoScopedStore) repositories() (RepositoryStore, error) {
+	repos, ok := r.store.(RepositoryStore)
+	if !ok {
+		return nil, fmt.Errorf("vector store does not support repositories")
+	}
+	return repos, nil
+}
+
+// Upsert inserts or updates a repository document.
+func (r *repoScopedStore) Upsert(ctx context.Context, doc Document) error {
+	return r.store.Upsert(ctx, r.scope(doc))
+}
+
+// UpsertBatch inserts or updates repository documents.
+func (r *repoScopedStore) UpsertBatch(ctx context.Context, docs []Document) error {
+	scoped := make([]Document, len(docs))
+	for i, doc := range docs {
+		scoped[i] = r.scope(doc)
+	}
+	return r.store.UpsertBatch(ctx, scoped)
+}
+
+// Delete removes a repository document.
+func (r *repoScopedStore) Delete(ctx context.Context, id string) error {
+	return r.store.Delete(ctx, RepoDocumentID(r.repoID, id))
+}
+
+// DeleteByFilter removes the repository documents matching filter.
+func (r *repoScopedStore) DeleteByFilter(ctx context.Context, filter Filter) (int64, error) {
+	if filter.IsEmpty() {
+		return 0, fmt.Errorf("filter cannot be empty")
+	}
+	return r.store.DeleteByFilter(ctx, r.scopeFilter(filter))
+}
+
+// UpdateMetadataByFilter updates the metadata of the repository documents
+// matching filter.
+func (r *repoScopedStore) UpdateMetadataByFilter(ctx context.Context, filter Filter, update MetadataUpdate) (int64, error) {
+	if filter.IsEmpty() {
+		return 0, fmt.Errorf("filter cannot be empty")
+	}
+	scoped, err := r.scopeUpdate(update)
+	if err != nil {
+		return 0, err
+	}
+	return r.store.UpdateMetadataByFilter(ctx, r.scopeFilter(filter), scoped)
+}
+
+// Scan streams the repository documents matching filter.
+func (r *repoScopedStore) Scan(ctx context.Context, filter Filter) (DocumentIterator, error) {
+	it, err := r.store.Scan(ctx, r.scopeFilter(filter))
+	if err != nil {
+		return nil, err
+	}
+	return &mappedIterator{DocumentIterator: it, convert: r.unscope}, nil
+}
+
+// Get retrieves a repository document.
+func (r *repoScopedStore) Get(ctx context.Context, id string) (*Document, error) {
+	doc, err := r.store.Get(ctx, RepoDocumentID(r.repoID, id))
+	if err != nil || doc == nil {
+		return doc, err
+	}
+	unscoped := r.unscope(*doc)
+	return &unscoped, nil
+}
+
+// SearchVector searches the repository's documents by vector similarity.
+func (r *repoScopedStore) SearchVector(ctx context.Context, vector embedding.Vector, opts SearchOptions) ([]SearchResult, error) {
+	results, err := r.store.SearchVector(ctx, vector, r.scopeOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	return r.unscopeResults(results), nil
+}
+
+// SearchBM25 searches the repository's documents by keyword.
+func (r *repoScopedStore) SearchBM25(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error) {
+	results, err := r.store.SearchBM25(ctx, query, r.scopeOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	return r.unscopeResults(results), nil
+}
+
+// SearchHybrid searches the repository's documents with both methods.
+func (r *repoScopedStore) SearchHybrid(ctx context.Context, query string, vector embedding.Vector, opts SearchOptions) ([]SearchResult, error) {
+	results, err := r.store.SearchHybrid(ctx, query, vector, r.scopeOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	return r.unscopeResults(results), nil
+}
+
+// Count returns the number of repository documents.
+func (r *repoScopedStore) Count(ctx context.Context) (int64, error) {
+	repos, err := r.repositories()
+	if err != nil {
+		return 0, err
+	}
+	repo, err := repos.GetRepository(ctx, r.repoID)
+	if err != nil || repo == nil {
+		return 0, err
+	}
+	return repo.Documents, nil
+}
+
+// ListIndexedFiles returns the file paths indexed for the repository.
+func (r *repoScopedStore) ListIndexedFiles(ctx context.Context) ([]string, error) {
+	repos, err := r.repositories()
+	if err != nil {
+		return nil, err
+	}
+	return repos.ListRepositoryFiles(ctx, r.repoID)
+}
+
+// GetFileChunks returns the chunks of a repository file.
+func (r *repoScopedStore) GetFileChunks(ctx context.Context, filePath string) ([]Document, error) {
+	repos, err := r.repositories()
+	if err != nil {
+		return nil, err
+	}
+	docs, err := repos.GetRepositoryFileChunks(ctx, r.repoID, filePath)
+	if err != nil {
+		return nil, err
+	}
+	for i := range docs {
+		docs[i] = r.unscope(docs[i])
+	}
+	return docs, nil
+}
+
+// GetParent returns the parent of a repository document.
+func (r *repoScopedStore) GetParent(ctx context.Context, id string) (*Document, error) {
+	hierarchy, ok := r.store.(HierarchyProvider)
+	if !ok {
+		return nil, fmt.Errorf("vector store does not support chunk hierarchy")
+	}
+	parent, err := hierarchy.GetParent(ctx, RepoDocumentID(r.repoID, id))
+	if err != nil || parent == nil {
+		return parent, err
+	}
+	unscoped := r.unscope(*parent)
+	return &unscoped, nil
+}
+
+// GetChildren returns the direct children of a repository document.
+func (r *repoScopedStore) GetChildren(ctx context.Context, id string) ([]Document, error) {
+	hierarchy, ok := r.store.(HierarchyProvider)
+	if !ok {
+		return nil, fmt.Errorf("vector store does not support chunk hierarchy")
+	}
+	children, err := hierarchy.GetChildren(ctx, RepoDocumentID(r.repoID, id))
+	if err != nil {
+		return nil, err
+	}
+	for i := range children {
+		children[i] = r.unscope(children[i])
+	}
+	return children, nil
+}
+
+// Close does nothing; the underlying store is owned by the caller.
+func (r *repoScopedStore) Close() error {
+	return nil
+}
+
+// scopeMetadataUpdate converts a metadata update made through a view that
+// namespaces documents by key. The key itself cannot be changed, and parent
+// links are namespaced with scopeID.
+func scopeMetadataUpdate(update MetadataUpdate, key string, scopeID func(string) string) (MetadataUpdate, error) {
+	for _, k := range update.Keys() {
+		if k == key {
+			return MetadataUpdate{}, fmt.Errorf("metadata key %q cannot be updated", key)
+		}
+	}
+
+	scoped := MetadataUpdate{Unset: update.Unset}
+	if len(update.Set) > 0 {
+		scoped.Set = make(map[string]interface{}, len(update.Set))
+		for k, v := range update.Set {
+			if parentID, ok := v.(string); ok && k == ParentIDKey && parentID != "" {
+				v = scopeID(parentID)
+			}
+			scoped.Set[k] = v
+		}
+	}
+	for _, rewrite := range update.Rewrite {
+		if rewrite.Key == ParentIDKey {
+			rewrite.From, rewrite.To = scopeID(rewrite.From), scopeID(rewrite.To)
+		}
+		scoped.Rewrite = append(scoped.Rewrite, rewrite)
+	}
+	return scoped, nil
+}
@@ -0,0 +1,127 @@
+package vectorstore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ferg-cod3s/conexus/internal/embedding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func repoDoc(id, content string, metadata map[string]interface{}) Document {
+	return Document{ID: id, Content: content, Vector: embedding.Vector{1, 0, 0}, Metadata: metadata}
+}
+
+func TestScopeToRepo_IsolatesRepositories(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	require.NoError(t, store.AddRepository(ctx, Repository{ID: "billing", RootPath: "/src/billing"}))
+	require.NoError(t, store.AddRepository(ctx, Repository{ID: "auth", RootPath: "/src/auth"}))
+
+	billing := ScopeToRepo(store, "billing")
+	auth := ScopeToRepo(store, "auth")
+
+	// The same chunk ID and path in two repositories and the default root
+	meta := func() map[string]interface{} {
+		return map[string]interface{}{"file_path": "config.go", ParentIDKey: "config.go:file::0"}
+	}
+	require.NoError(t, billing.Upsert(ctx, repoDoc("config.go:function:Load:1", "billing config", meta())))
+	require.NoError(t, auth.Upsert(ctx, repoDoc("config.go:function:Load:1", "auth config", meta())))
+	require.NoError(t, store.Upsert(ctx, repoDoc("config.go:function:Load:1", "root config", meta())))
+
+	count, err := store.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+
+	doc, err := billing.Get(ctx, "config.go:function:Load:1")
+	require.NoError(t, err)
+	assert.Equal(t, "billing config", doc.Content)
+	assert.Equal(t, "config.go:file::0", doc.Metadata[ParentIDKey])
+	assert.NotContains(t, doc.Metadata, RepoIDKey)
+
+	stored, err := store.Get(ctx, RepoDocumentID("billing", "config.go:function:Load:1"))
+	require.NoError(t, err)
+	assert.Equal(t, "billing", stored.Metadata[RepoIDKey])
+	assert.Equal(t, "billing/config.go:file::0", stored.Metadata[ParentIDKey])
+
+	results, err := auth.SearchBM25(ctx, "config", SearchOptions{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "auth config", results[0].Document.Content)
+	assert.Equal(t, "config.go:function:Load:1", results[0].Document.ID)
+
+	count, err = auth.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// File listings are per namespace
+	files, err := billing.ListIndexedFiles(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"config.go"}, files)
+	chunks, err := billing.GetFileChunks(ctx, "config.go")
+	require.NoError(t, err)
+	require.Len(t, chunks, 1)
+	assert.Equal(t, "billing config", chunks[0].Content)
+	chunks, err = store.GetFileChunks(ctx, "config.go")
+	require.NoError(t, err)
+	require.Len(t, chunks, 1)
+	assert.Equal(t, "root config", chunks[0].Content)
+
+	require.NoError(t, billing.Delete(ctx, "config.go:function:Load:1"))
+	_, err = store.Get(ctx, "config.go:function:Load:1")
+	assert.NoError(t, err, "deleting through a scope leaves other namespaces alone")
+}
+
+func TestMemoryStore_Repositories(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	assert.Error(t, store.AddRepository(ctx, Repository{ID: "../etc"}))
+
+	require.NoError(t, store.AddRepository(ctx, Repository{ID: "web", RootPath: "/src/web", IgnorePatterns: []string{"dist"}}))
+	require.NoError(t, store.AddRepository(ctx, Repository{ID: "api", RootPath: "/src/api"}))
+	web, err := store.GetRepository(ctx, "web")
+	require.NoError(t, err)
+	created := web.CreatedAt
+
+	// Re-adding keeps the creation time
+	require.NoError(t, store.AddRepository(ctx, Repository{ID: "web", RootPath: "/src/web2"}))
+	web, err = store.GetRepository(ctx, "web")
+	require.NoError(t, err)
+	assert.Equal(t, "/src/web2", web.RootPath)
+	assert.Equal(t, created, web.CreatedAt)
+
+	require.NoError(t, ScopeToRepo(store, "web").Upsert(ctx, repoDoc("a", "web doc", nil)))
+	require.NoError(t, ScopeToRepo(store, "api").Upsert(ctx, repoDoc("a", "api doc", nil)))
+
+	repos, err := store.ListRepositories(ctx)
+	require.NoError(t, err)
+	require.Len(t, repos, 2)
+	assert.Equal(t, "api", repos[0].ID)
+	assert.Equal(t, int64(1), repos[1].Documents)
+
+	// A list filter matches any of its values
+	results, err := store.SearchBM25(ctx, "doc", SearchOptions{Limit: 10, Filters: map[string]interface{}{RepoIDKey: []string{"api", "other"}}})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "api doc", results[0].Document.Content)
+
+	require.NoError(t, store.RemoveRepository(ctx, "web"))
+	assert.Error(t, store.RemoveRepository(ctx, "web"))
+	missing, err := store.GetRepository(ctx, "web")
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+	count, err := store.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestValidateRepoID(t *testing.T) {
+	for _, id := range []string{"api", "billing-service", "web.v2", "a_1"} {
+		assert.NoError(t, ValidateRepoID(id), id)
+	}
+	for _, id := range []string{"", "API", "a/b", "..", "-x", "a b"} {
+		assert.Error(t, ValidateRepoID(id), id)
+	}
+}
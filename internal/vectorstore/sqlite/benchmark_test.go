@@ -3,11 +3,13 @@ package sqlite
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"math"
 	"math/rand"
 	"os"
 	"runtime"
+	"sort"
 	"testing"
 
 	"github.com/ferg-cod3s/conexus/internal/embedding"
@@ -260,6 +262,155 @@ func BenchmarkMemoryUsage(b *testing.B) {
 	}
 }
 
+// BenchmarkVectorEncoding compares the storage size of JSON text vectors,
+// as written before schema version 1, with the BLOB encodings, and the cost
+// of scanning and scoring all vectors as brute force search does.
+func BenchmarkVectorEncoding(b *testing.B) {
+	const numDocs, dims = 1000, 384
+
+	for _, encoding := range []string{"json", string(VectorEncodingFloat32), string(VectorEncodingFloat16)} {
+		b.Run(encoding, func(b *testing.B) {
+			store, cleanup := setupBenchmarkStore(b, 0)
+			defer cleanup()
+			ctx := context.Background()
+
+			if encoding != "json" {
+				if err := store.SetVectorEncoding(VectorEncoding(encoding)); err != nil {
+					b.Fatalf("set encoding: %v", err)
+				}
+			}
+			docs := make([]vectorstore.Document, numDocs)
+			for i := range docs {
+				docs[i] = vectorstore.Document{ID: fmt.Sprintf("doc_%d", i), Content: generateContent(i), Vector: generateVector(dims)}
+			}
+			if err := store.UpsertBatch(ctx, docs); err != nil {
+				b.Fatalf("failed to populate store: %v", err)
+			}
+			if encoding == "json" {
+				for _, doc := range docs {
+					vectorJSON, err := json.Marshal(doc.Vector)
+					if err != nil {
+						b.Fatalf("marshal vector: %v", err)
+					}
+					if _, err := store.db.ExecContext(ctx, "UPDATE documents SET vector = ? WHERE id = ?", string(vectorJSON), doc.ID); err != nil {
+						b.Fatalf("write JSON vector: %v", err)
+					}
+				}
+			}
+			if _, err := store.db.ExecContext(ctx, "VACUUM"); err != nil {
+				b.Fatalf("vacuum: %v", err)
+			}
+
+			var vectorBytes, dbBytes int64
+			if err := store.db.QueryRowContext(ctx, "SELECT SUM(length(vector)) FROM documents").Scan(&vectorBytes); err != nil {
+				b.Fatalf("measure vectors: %v", err)
+			}
+			if err := store.db.QueryRowContext(ctx, "SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()").Scan(&dbBytes); err != nil {
+				b.Fatalf("measure database: %v", err)
+			}
+
+			query := generateVector(dims)
+			b.ResetTimer()
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				rows, err := store.db.QueryContext(ctx, "SELECT vector FROM documents")
+				if err != nil {
+					b.Fatalf("query vectors: %v", err)
+				}
+				for rows.Next() {
+					var raw []byte
+					if err := rows.Scan(&raw); err != nil {
+						b.Fatalf("scan vector: %v", err)
+					}
+					var vector embedding.Vector
+					if encoding == "json" {
+						err = json.Unmarshal(raw, &vector)
+					} else {
+						vector, err = decodeVector(raw)
+					}
+					if err != nil {
+						b.Fatalf("decode vector: %v", err)
+					}
+					cosineSimilarity(query, vector)
+				}
+				rows.Close()
+			}
+
+			// Reported after the loop since ResetTimer discards earlier metrics
+			b.ReportMetric(float64(vectorBytes)/numDocs, "vector-B/doc")
+			b.ReportMetric(float64(dbBytes)/numDocs, "db-B/doc")
+		})
+	}
+}
+
+// BenchmarkHNSWQuantization compares the recall and vector memory of the
+// HNSW graph with each quantization on synthetic clustered vectors. Searches
+// re-score their candidates exactly, as SearchVector does.
+func BenchmarkHNSWQuantization(b *testing.B) {
+	const numVectors, numQueries, dims, k, ef = 5000, 100, 128, 10, 64
+
+	vectors := clusteredVectors(rand.New(rand.NewSource(1)), numVectors+numQueries, dims, 50)
+	vectors, queries := vectors[:numVectors], vectors[numVectors:]
+	want := make([][]string, len(queries))
+	for i, query := range queries {
+		want[i] = exactNeighbors(vectors, query, k)
+	}
+
+	for _, quantization := range []Quantization{QuantizationNone, QuantizationScalar, QuantizationProduct} {
+		b.Run(string(quantization), func(b *testing.B) {
+			config := DefaultHNSWConfig()
+			config.Quantization = QuantizationConfig{Type: quantization}
+			index := NewHNSWIndex(config)
+			for i, v := range vectors {
+				if err := index.Insert(fmt.Sprintf("v%d", i), v); err != nil {
+					b.Fatalf("insert: %v", err)
+				}
+			}
+
+			search := func(query embedding.Vector) []string {
+				candidates, err := index.Search(query, ef, ef)
+				if err != nil {
+					b.Fatalf("search: %v", err)
+				}
+				for i, c := range candidates {
+					var id int
+					fmt.Sscanf(c.ID, "v%d", &id)
+					candidates[i].Distance = cosineDistance(query, vectors[id])
+				}
+				sort.Slice(candidates, func(i, j int) bool { return candidates[i].Distance < candidates[j].Distance })
+				ids := make([]string, 0, k)
+				for i := 0; i < k && i < len(candidates); i++ {
+					ids = append(ids, candidates[i].ID)
+				}
+				return ids
+			}
+
+			found := 0
+			for i, query := range queries {
+				got := make(map[string]bool)
+				for _, id := range search(query) {
+					got[id] = true
+				}
+				for _, id := range want[i] {
+					if got[id] {
+						found++
+					}
+				}
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				search(queries[i%len(queries)])
+			}
+
+			// Reported after the loop since ResetTimer discards earlier metrics
+			b.ReportMetric(float64(found)/float64(len(queries)*k), "recall@10")
+			b.ReportMetric(float64(index.vectorBytes())/numVectors, "vector-B/node")
+		})
+	}
+}
+
 // setupBenchmarkStore creates a store with the specified number of documents.
 func setupBenchmarkStore(b *testing.B, numDocs int) (*Store, func()) {
 	b.Helper()
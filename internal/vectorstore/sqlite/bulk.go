@@ -0,0 +1,216 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+)
+
+// scanPageSize is the number of documents Scan reads per query.
+const scanPageSize = 500
+
+// bulkFilterSQL compiles the filter of a bulk write, which must not match
+// every document. Unless it names collections, collection documents are left
+// alone.
+func bulkFilterSQL(filter vectorstore.Filter) (string, []interface{}, error) {
+	if filter.IsEmpty() {
+		return "", nil, fmt.Errorf("filter cannot be empty")
+	}
+	return filterSQL(vectorstore.DefaultCorpus(filter), "")
+}
+
+// DeleteByFilter removes the documents matching a non-empty filter in one
+// transaction.
+func (s *Store) DeleteByFilter(ctx context.Context, filter vectorstore.Filter) (int64, error) {
+	condition, args, err := bulkFilterSQL(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	err = s.writeDocuments(ctx, func(tx *sql.Tx) ([]string, []vectorstore.Document, error) {
+		removed, err := deleteDocumentsWhere(ctx, tx, condition, args...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("delete documents: %w", err)
+		}
+		deleted = int64(len(removed))
+		return removed, nil, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
+
+// UpdateMetadataByFilter applies update to the metadata of the documents
+// matching a non-empty filter in one transaction.
+func (s *Store) UpdateMetadataByFilter(ctx context.Context, filter vectorstore.Filter, update vectorstore.MetadataUpdate) (int64, error) {
+	condition, args, err := bulkFilterSQL(filter)
+	if err != nil {
+		return 0, err
+	}
+	if err := update.Validate(); err != nil {
+		return 0, fmt.Errorf("invalid update: %w", err)
+	}
+
+	var updated int64
+	err = s.writeDocuments(ctx, func(tx *sql.Tx) ([]string, []vectorstore.Document, error) {
+		// Matches are read before any is written, as the update may change
+		// the values the filter tests
+		rows, err := tx.QueryContext(ctx, "SELECT id, metadata FROM documents WHERE "+condition, args...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("query documents: %w", err)
+		}
+		changed := make(map[string][]byte)
+		for rows.Next() {
+			var id string
+			var metadataJSON []byte
+			if err := rows.Scan(&id, &metadataJSON); err != nil {
+				rows.Close()
+				return nil, nil, fmt.Errorf("scan document: %w", err)
+			}
+			var metadata map[string]interface{}
+			if len(metadataJSON) > 0 {
+				if err := json.Unmarshal(metadataJSON, &metadata); err != nil {
+					rows.Close()
+					return nil, nil, fmt.Errorf("unmarshal metadata of %s: %w", id, err)
+				}
+			}
+			metadata, ok := update.Apply(metadata)
+			if !ok {
+				continue
+			}
+			if changed[id], err = json.Marshal(metadata); err != nil {
+				rows.Close()
+				return nil, nil, fmt.Errorf("marshal metadata of %s: %w", id, err)
+			}
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return nil, nil, fmt.Errorf("iterate documents: %w", err)
+		}
+		rows.Close()
+
+		stmt, err := tx.PrepareContext(ctx, "UPDATE documents SET metadata = ? WHERE id = ?")
+		if err != nil {
+			return nil, nil, fmt.Errorf("prepare update: %w", err)
+		}
+		defer stmt.Close()
+		for id, metadataJSON := range changed {
+			if _, err := stmt.ExecContext(ctx, metadataJSON, id); err != nil {
+				return nil, nil, fmt.Errorf("update metadata of %s: %w", id, err)
+			}
+		}
+		updated = int64(len(changed))
+		return nil, nil, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return updated, nil
+}
+
+// Scan streams the documents matching filter in ID order, outside any
+// collection unless the filter names collections. Documents are read
+// a page at a time, so callers may write to the store while iterating; a
+// document written during the scan is returned if its ID is after the
+// current one.
+func (s *Store) Scan(ctx context.Context, filter vectorstore.Filter) (vectorstore.DocumentIterator, error) {
+	condition, args, err := filterSQL(vectorstore.DefaultCorpus(filter), "")
+	if err != nil {
+		return nil, err
+	}
+	return &scanIterator{ctx: ctx, db: s.db, condition: condition, args: args}, nil
+}
+
+// scanIterator pages through the documents matching condition by ID.
+type scanIterator struct {
+	ctx       context.Context
+	db        *sql.DB
+	condition string
+	args      []interface{}
+
+	page   []vectorstore.Document
+	pos    int
+	lastID string
+	done   bool
+	err    error
+}
+
+func (it *scanIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if it.pos+1 < len(it.page) {
+		it.pos++
+		return true
+	}
+	if it.done {
+		it.page = nil
+		return false
+	}
+	if err := it.fetch(); err != nil {
+		it.err = err
+		return false
+	}
+	if len(it.page) == 0 {
+		return false
+	}
+	it.pos = 0
+	return true
+}
+
+// fetch reads the page of documents after lastID.
+func (it *scanIterator) fetch() error {
+	args := append([]interface{}{it.lastID}, it.args...)
+	args = append(args, scanPageSize)
+	rows, err := it.db.QueryContext(it.ctx,
+		`SELECT id, content, vector, metadata, created_at, updated_at
+		 FROM documents WHERE id > ? AND `+it.condition+` ORDER BY id LIMIT ?`, args...)
+	if err != nil {
+		return fmt.Errorf("query documents: %w", err)
+	}
+	defer rows.Close()
+
+	it.page = it.page[:0]
+	for rows.Next() {
+		var doc vectorstore.Document
+		var vectorBlob, metadataJSON []byte
+		var createdAt, updatedAt int64
+		if err := rows.Scan(&doc.ID, &doc.Content, &vectorBlob, &metadataJSON, &createdAt, &updatedAt); err != nil {
+			return fmt.Errorf("scan document: %w", err)
+		}
+		if err := deserializeDocument(&doc, vectorBlob, metadataJSON, createdAt, updatedAt); err != nil {
+			return fmt.Errorf("deserialize document %s: %w", doc.ID, err)
+		}
+		it.page = append(it.page, doc)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate documents: %w", err)
+	}
+
+	if len(it.page) < scanPageSize {
+		it.done = true
+	}
+	if len(it.page) > 0 {
+		it.lastID = it.page[len(it.page)-1].ID
+	}
+	return nil
+}
+
+func (it *scanIterator) Document() vectorstore.Document {
+	return it.page[it.pos]
+}
+
+func (it *scanIterator) Err() error {
+	return it.err
+}
+
+func (it *scanIterator) Close() error {
+	it.page = nil
+	it.done = true
+	return nil
+}
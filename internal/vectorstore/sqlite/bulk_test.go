@@ -0,0 +1,80 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ferg-cod3s/conexus/internal/embedding"
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+)
+
+func TestStore_BulkOperations(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewStore(":memory:")
+	require.NoError(t, err)
+	defer store.Close()
+
+	var docs []vectorstore.Document
+	for i := 0; i < 1200; i++ {
+		metadata := map[string]interface{}{"file_path": fmt.Sprintf("old/file%04d.go", i), "parent_id": "pkg"}
+		if i%2 == 0 {
+			metadata["connector_id"] = "gh"
+		}
+		docs = append(docs, vectorstore.Document{
+			ID: fmt.Sprintf("doc%04d", i), Content: "content", Vector: embedding.Vector{1, float32(i)}, Metadata: metadata,
+		})
+	}
+	require.NoError(t, store.UpsertBatch(ctx, docs))
+
+	_, err = store.DeleteByFilter(ctx, vectorstore.Filter{})
+	assert.Error(t, err)
+
+	// Scanning pages through every match in ID order
+	it, err := store.Scan(ctx, vectorstore.Eq("connector_id", "gh"))
+	require.NoError(t, err)
+	scanned, err := vectorstore.Collect(it)
+	require.NoError(t, err)
+	require.Len(t, scanned, 600)
+	assert.Equal(t, "doc0000", scanned[0].ID)
+	assert.Equal(t, "doc1198", scanned[599].ID)
+	assert.Equal(t, embedding.Vector{1, 1198}, scanned[599].Vector)
+
+	// The rewrite changes the values the filter selects by
+	updated, err := store.UpdateMetadataByFilter(ctx, vectorstore.Prefix("file_path", "old/"), vectorstore.MetadataUpdate{
+		Rewrite: []vectorstore.PrefixRewrite{{Key: "file_path", From: "old/", To: "new/"}},
+		Unset:   []string{"parent_id"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1200), updated)
+	doc, err := store.Get(ctx, "doc0007")
+	require.NoError(t, err)
+	assert.Equal(t, "new/file0007.go", doc.Metadata["file_path"])
+	assert.NotContains(t, doc.Metadata, "parent_id")
+	children, err := store.GetChildren(ctx, "pkg")
+	require.NoError(t, err)
+	assert.Empty(t, children, "the chunk graph follows metadata updates")
+	files, err := store.ListIndexedFiles(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "new/file0000.go", files[0])
+
+	deleted, err := store.DeleteByFilter(ctx, vectorstore.Eq("connector_id", "gh"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(600), deleted)
+	count, err := store.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(600), count)
+
+	// Vector search through the graph no longer finds deleted documents
+	results, err := store.SearchVector(ctx, embedding.Vector{1, 0}, vectorstore.SearchOptions{Limit: 5})
+	require.NoError(t, err)
+	for _, r := range results {
+		assert.NotEqual(t, "gh", r.Document.Metadata["connector_id"])
+	}
+	report, err := store.CheckIntegrity(ctx, 2)
+	require.NoError(t, err)
+	assert.Empty(t, report.HNSWOrphaned)
+}
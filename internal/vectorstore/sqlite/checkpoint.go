@@ -0,0 +1,201 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+)
+
+// SaveCheckpoint writes the run header, pending batch and completed files.
+// Saving a checkpoint for a different run discards the previous one.
+func (s *Store) SaveCheckpoint(ctx context.Context, cp vectorstore.IndexCheckpoint) error {
+	if cp.RunID == "" {
+		return fmt.Errorf("checkpoint run ID cannot be empty")
+	}
+
+	pendingJSON, err := json.Marshal(cp.Pending)
+	if err != nil {
+		return fmt.Errorf("marshal pending paths: %w", err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var currentRun string
+	err = tx.QueryRowContext(ctx, "SELECT run_id FROM index_checkpoint WHERE id = 1").Scan(&currentRun)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("query checkpoint: %w", err)
+	}
+	if currentRun != cp.RunID {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM index_checkpoint_files"); err != nil {
+			return fmt.Errorf("clear checkpoint files: %w", err)
+		}
+	}
+
+	now := time.Now()
+	startedAt := cp.StartedAt
+	if startedAt.IsZero() {
+		startedAt = now
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO index_checkpoint (id, run_id, root_path, pending, started_at, updated_at)
+		 VALUES (1, ?, ?, ?, ?, ?)
+		 ON CONFLICT(id) DO UPDATE SET
+		 run_id = excluded.run_id,
+		 root_path = excluded.root_path,
+		 pending = excluded.pending,
+		 started_at = CASE WHEN index_checkpoint.run_id = excluded.run_id
+			THEN index_checkpoint.started_at ELSE excluded.started_at END,
+		 updated_at = excluded.updated_at`,
+		cp.RunID, cp.RootPath, pendingJSON, startedAt.Unix(), now.Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+
+	if err := markFilesCompleted(ctx, tx, cp.Completed); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// CommitCheckpointBatch replaces all documents of the given files with docs,
+// marks the files completed and clears the pending batch in one transaction.
+func (s *Store) CommitCheckpointBatch(ctx context.Context, files map[string]string, docs []vectorstore.Document) error {
+	return s.writeDocuments(ctx, func(tx *sql.Tx) ([]string, []vectorstore.Document, error) {
+		result, err := tx.ExecContext(ctx,
+			"UPDATE index_checkpoint SET pending = NULL, updated_at = ? WHERE id = 1",
+			time.Now().Unix(),
+		)
+		if err != nil {
+			return nil, nil, fmt.Errorf("update checkpoint: %w", err)
+		}
+		if rows, err := result.RowsAffected(); err != nil {
+			return nil, nil, fmt.Errorf("get rows affected: %w", err)
+		} else if rows == 0 {
+			return nil, nil, fmt.Errorf("no checkpoint in progress")
+		}
+
+		var removed []string
+		for path := range files {
+			ids, err := queryIDs(ctx, tx,
+				"SELECT id FROM documents WHERE meta_file_path = ? AND meta_repo_id IS NULL", path,
+			)
+			if err != nil {
+				return nil, nil, fmt.Errorf("find documents for %s: %w", path, err)
+			}
+			removed = append(removed, ids...)
+
+			if _, err := tx.ExecContext(ctx,
+				"DELETE FROM documents WHERE meta_file_path = ? AND meta_repo_id IS NULL", path,
+			); err != nil {
+				return nil, nil, fmt.Errorf("delete documents for %s: %w", path, err)
+			}
+		}
+
+		for _, doc := range docs {
+			if err := s.upsertInTx(ctx, tx, doc); err != nil {
+				return nil, nil, fmt.Errorf("upsert document %s: %w", doc.ID, err)
+			}
+		}
+
+		if err := markFilesCompleted(ctx, tx, files); err != nil {
+			return nil, nil, err
+		}
+		return removed, docs, nil
+	})
+}
+
+// LoadCheckpoint returns the current checkpoint, or nil if no run is in progress.
+func (s *Store) LoadCheckpoint(ctx context.Context) (*vectorstore.IndexCheckpoint, error) {
+	var cp vectorstore.IndexCheckpoint
+	var pendingJSON []byte
+	var startedAt, updatedAt int64
+
+	err := s.db.QueryRowContext(ctx,
+		`SELECT run_id, root_path, pending, started_at, updated_at
+		 FROM index_checkpoint WHERE id = 1`,
+	).Scan(&cp.RunID, &cp.RootPath, &pendingJSON, &startedAt, &updatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query checkpoint: %w", err)
+	}
+
+	if len(pendingJSON) > 0 {
+		if err := json.Unmarshal(pendingJSON, &cp.Pending); err != nil {
+			return nil, fmt.Errorf("unmarshal pending paths: %w", err)
+		}
+	}
+	cp.StartedAt = time.Unix(startedAt, 0)
+	cp.UpdatedAt = time.Unix(updatedAt, 0)
+
+	rows, err := s.db.QueryContext(ctx, "SELECT path, hash FROM index_checkpoint_files")
+	if err != nil {
+		return nil, fmt.Errorf("query checkpoint files: %w", err)
+	}
+	defer rows.Close()
+
+	cp.Completed = make(map[string]string)
+	for rows.Next() {
+		var path, hash string
+		if err := rows.Scan(&path, &hash); err != nil {
+			return nil, fmt.Errorf("scan checkpoint file: %w", err)
+		}
+		cp.Completed[path] = hash
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+
+	return &cp, nil
+}
+
+// ClearCheckpoint removes the checkpoint once a run has completed.
+func (s *Store) ClearCheckpoint(ctx context.Context) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM index_checkpoint_files"); err != nil {
+		return fmt.Errorf("clear checkpoint files: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM index_checkpoint"); err != nil {
+		return fmt.Errorf("clear checkpoint: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// markFilesCompleted records completed files within a transaction.
+func markFilesCompleted(ctx context.Context, tx *sql.Tx, files map[string]string) error {
+	for path, hash := range files {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO index_checkpoint_files (path, hash) VALUES (?, ?)
+			 ON CONFLICT(path) DO UPDATE SET hash = excluded.hash`,
+			path, hash,
+		)
+		if err != nil {
+			return fmt.Errorf("mark %s completed: %w", path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ferg-cod3s/conexus/internal/embedding"
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+)
+
+func checkpointDoc(id, filePath string) vectorstore.Document {
+	return vectorstore.Document{
+		ID:       id,
+		Content:  "content of " + id,
+		Vector:   embedding.Vector{0.1, 0.2, 0.3},
+		Metadata: map[string]interface{}{"file_path": filePath},
+	}
+}
+
+func TestCheckpoint_NoneByDefault(t *testing.T) {
+	store := newTestStore(t)
+
+	cp, err := store.LoadCheckpoint(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, cp)
+}
+
+func TestCheckpoint_CommitBatch(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	require.NoError(t, store.SaveCheckpoint(ctx, vectorstore.IndexCheckpoint{
+		RunID:    "run-1",
+		RootPath: "/repo",
+		Pending:  []string{"a.go", "b.go"},
+	}))
+
+	cp, err := store.LoadCheckpoint(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, cp)
+	assert.Equal(t, "run-1", cp.RunID)
+	assert.Equal(t, []string{"a.go", "b.go"}, cp.Pending)
+	assert.Empty(t, cp.Completed)
+
+	// Stale chunk from a previous index of a.go must be replaced
+	require.NoError(t, store.Upsert(ctx, checkpointDoc("stale", "a.go")))
+
+	err = store.CommitCheckpointBatch(ctx,
+		map[string]string{"a.go": "hash-a", "b.go": "hash-b"},
+		[]vectorstore.Document{checkpointDoc("a1", "a.go"), checkpointDoc("b1", "b.go")},
+	)
+	require.NoError(t, err)
+
+	cp, err = store.LoadCheckpoint(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, cp.Pending)
+	assert.Equal(t, map[string]string{"a.go": "hash-a", "b.go": "hash-b"}, cp.Completed)
+
+	_, err = store.Get(ctx, "stale")
+	assert.Error(t, err)
+	count, err := store.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestCheckpoint_CommitBatchIsAtomic(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	require.NoError(t, store.SaveCheckpoint(ctx, vectorstore.IndexCheckpoint{RunID: "run-1", RootPath: "/repo"}))
+	require.NoError(t, store.Upsert(ctx, checkpointDoc("old", "a.go")))
+
+	invalid := checkpointDoc("bad", "a.go")
+	invalid.Vector = nil
+	err := store.CommitCheckpointBatch(ctx,
+		map[string]string{"a.go": "hash-a"},
+		[]vectorstore.Document{checkpointDoc("a1", "a.go"), invalid},
+	)
+	require.Error(t, err)
+
+	// Neither the deletion, the new documents nor the completion were applied
+	_, err = store.Get(ctx, "old")
+	assert.NoError(t, err)
+	_, err = store.Get(ctx, "a1")
+	assert.Error(t, err)
+	cp, err := store.LoadCheckpoint(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, cp.Completed)
+}
+
+func TestCheckpoint_CommitWithoutRun(t *testing.T) {
+	store := newTestStore(t)
+
+	err := store.CommitCheckpointBatch(context.Background(), map[string]string{"a.go": "h"}, nil)
+	assert.Error(t, err)
+}
+
+func TestCheckpoint_NewRunDiscardsCompleted(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	require.NoError(t, store.SaveCheckpoint(ctx, vectorstore.IndexCheckpoint{
+		RunID:     "run-1",
+		RootPath:  "/repo",
+		Completed: map[string]string{"a.go": "hash-a"},
+	}))
+	require.NoError(t, store.SaveCheckpoint(ctx, vectorstore.IndexCheckpoint{RunID: "run-2", RootPath: "/repo"}))
+
+	cp, err := store.LoadCheckpoint(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "run-2", cp.RunID)
+	assert.Empty(t, cp.Completed)
+}
+
+func TestCheckpoint_Clear(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	require.NoError(t, store.SaveCheckpoint(ctx, vectorstore.IndexCheckpoint{
+		RunID:     "run-1",
+		RootPath:  "/repo",
+		Completed: map[string]string{"a.go": "hash-a"},
+	}))
+	require.NoError(t, store.ClearCheckpoint(ctx))
+
+	cp, err := store.LoadCheckpoint(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, cp)
+}
+
+func TestCheckpoint_PersistsAcrossReopen(t *testing.T) {
+	path := t.TempDir() + "/checkpoint.db"
+	ctx := context.Background()
+
+	store, err := NewStore(path)
+	require.NoError(t, err)
+	require.NoError(t, store.SaveCheckpoint(ctx, vectorstore.IndexCheckpoint{RunID: "run-1", RootPath: "/repo"}))
+	require.NoError(t, store.CommitCheckpointBatch(ctx,
+		map[string]string{"a.go": "hash-a"},
+		[]vectorstore.Document{checkpointDoc("a1", "a.go")},
+	))
+	require.NoError(t, store.Close())
+
+	reopened, err := NewStore(path)
+	require.NoError(t, err)
+	defer reopened.Close()
+
+	cp, err := reopened.LoadCheckpoint(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, cp)
+	assert.Equal(t, map[string]string{"a.go": "hash-a"}, cp.Completed)
+}
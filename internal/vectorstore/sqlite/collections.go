@@ -0,0 +1,177 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+)
+
+// collectionColumns selects a collection row together with its document count.
+const collectionColumns = `
+	SELECT c.name, c.embedding_model, c.dimensions, c.retention, c.created_at,
+		(SELECT COUNT(*) FROM documents d WHERE d.meta_collection = c.name)
+	FROM collections c`
+
+// CreateCollection records a collection, keeping the creation time of an
+// existing one.
+func (s *Store) CreateCollection(ctx context.Context, collection vectorstore.Collection) error {
+	if err := vectorstore.ValidateCollectionName(collection.Name); err != nil {
+		return err
+	}
+	if collection.Dimensions < 0 || collection.Retention < 0 {
+		return fmt.Errorf("collection dimensions and retention cannot be negative")
+	}
+
+	createdAt := collection.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO collections (name, embedding_model, dimensions, retention, created_at)
+		 VALUES (?, ?, ?, ?, ?)
+		 ON CONFLICT(name) DO UPDATE SET
+			embedding_model = excluded.embedding_model,
+			dimensions = excluded.dimensions,
+			retention = excluded.retention`,
+		collection.Name, collection.EmbeddingModel, collection.Dimensions,
+		int64(collection.Retention/time.Second), createdAt.Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("save collection: %w", err)
+	}
+	return nil
+}
+
+// GetCollection returns a collection, or nil if it is not recorded.
+func (s *Store) GetCollection(ctx context.Context, name string) (*vectorstore.Collection, error) {
+	collection, err := scanCollection(s.db.QueryRowContext(ctx, collectionColumns+` WHERE c.name = ?`, name))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return collection, nil
+}
+
+// ListCollections returns all collections sorted by name.
+func (s *Store) ListCollections(ctx context.Context) ([]vectorstore.Collection, error) {
+	rows, err := s.db.QueryContext(ctx, collectionColumns+` ORDER BY c.name`)
+	if err != nil {
+		return nil, fmt.Errorf("query collections: %w", err)
+	}
+	defer rows.Close()
+
+	var collections []vectorstore.Collection
+	for rows.Next() {
+		collection, err := scanCollection(rows)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, *collection)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+	return collections, nil
+}
+
+// DeleteCollection deletes a collection and all of its documents.
+func (s *Store) DeleteCollection(ctx context.Context, name string) error {
+	return s.writeDocuments(ctx, func(tx *sql.Tx) ([]string, []vectorstore.Document, error) {
+		result, err := tx.ExecContext(ctx, "DELETE FROM collections WHERE name = ?", name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("delete collection: %w", err)
+		}
+		if rows, err := result.RowsAffected(); err != nil {
+			return nil, nil, fmt.Errorf("get rows affected: %w", err)
+		} else if rows == 0 {
+			return nil, nil, fmt.Errorf("collection %s not found", name)
+		}
+
+		removed, err := deleteDocumentsWhere(ctx, tx, "meta_collection = ?", name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("delete collection documents: %w", err)
+		}
+		return removed, nil, nil
+	})
+}
+
+// ExpireCollection deletes the documents of a collection last updated
+// before the given time.
+func (s *Store) ExpireCollection(ctx context.Context, name string, before time.Time) (int64, error) {
+	var expired int64
+	err := s.writeDocuments(ctx, func(tx *sql.Tx) ([]string, []vectorstore.Document, error) {
+		var exists bool
+		if err := tx.QueryRowContext(ctx,
+			"SELECT EXISTS(SELECT 1 FROM collections WHERE name = ?)", name,
+		).Scan(&exists); err != nil {
+			return nil, nil, fmt.Errorf("query collection: %w", err)
+		}
+		if !exists {
+			return nil, nil, fmt.Errorf("collection %s not found", name)
+		}
+
+		removed, err := deleteDocumentsWhere(ctx, tx, "meta_collection = ? AND updated_at < ?", name, before.Unix())
+		if err != nil {
+			return nil, nil, fmt.Errorf("expire collection documents: %w", err)
+		}
+		expired = int64(len(removed))
+		return removed, nil, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return expired, nil
+}
+
+// deleteDocumentsWhere deletes the documents matching condition and returns
+// their IDs.
+func deleteDocumentsWhere(ctx context.Context, tx *sql.Tx, condition string, args ...interface{}) ([]string, error) {
+	removed, err := queryIDs(ctx, tx, "SELECT id FROM documents WHERE "+condition, args...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM documents WHERE "+condition, args...); err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+// scanCollection reads a row selected with collectionColumns.
+func scanCollection(row interface{ Scan(...interface{}) error }) (*vectorstore.Collection, error) {
+	var collection vectorstore.Collection
+	var retention, createdAt int64
+
+	if err := row.Scan(&collection.Name, &collection.EmbeddingModel, &collection.Dimensions,
+		&retention, &createdAt, &collection.Documents); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("scan collection: %w", err)
+	}
+
+	collection.Retention = time.Duration(retention) * time.Second
+	collection.CreatedAt = time.Unix(createdAt, 0)
+	return &collection, nil
+}
+
+// addCollections creates the collections table and indexes the collection
+// of documents as a generated column.
+func addCollections(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS collections (
+		name TEXT PRIMARY KEY,
+		embedding_model TEXT NOT NULL DEFAULT '',
+		dimensions INTEGER NOT NULL DEFAULT 0,
+		retention INTEGER NOT NULL DEFAULT 0,  -- Seconds; 0 keeps documents
+		created_at INTEGER NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("create collections table: %w", err)
+	}
+	return addMetadataColumns(ctx, tx)
+}
@@ -0,0 +1,158 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ferg-cod3s/conexus/internal/embedding"
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+)
+
+func TestStore_Collections(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewStore(":memory:")
+	require.NoError(t, err)
+	defer store.Close()
+
+	require.NoError(t, store.CreateCollection(ctx, vectorstore.Collection{
+		Name: "issues", EmbeddingModel: "small", Dimensions: 2, Retention: 24 * time.Hour,
+	}))
+	require.NoError(t, store.CreateCollection(ctx, vectorstore.Collection{Name: "pushed"}))
+	assert.Error(t, store.CreateCollection(ctx, vectorstore.Collection{Name: "a/b"}))
+
+	issues := vectorstore.ScopeToCollection(store, "issues")
+	old := time.Now().Add(-48 * time.Hour)
+	require.NoError(t, issues.UpsertBatch(ctx, []vectorstore.Document{
+		{ID: "1", Content: "login timeout", Vector: embedding.Vector{1, 0}, CreatedAt: old, UpdatedAt: old},
+		{ID: "2", Content: "login crash", Vector: embedding.Vector{0, 1}},
+	}))
+	assert.Error(t, issues.Upsert(ctx, vectorstore.Document{ID: "3", Content: "x", Vector: embedding.Vector{1, 0, 0}}))
+	require.NoError(t, vectorstore.ScopeToCollection(store, "pushed").Upsert(ctx,
+		vectorstore.Document{ID: "1", Content: "login notes", Vector: embedding.Vector{1, 0, 0}}))
+	require.NoError(t, store.Upsert(ctx, vectorstore.Document{ID: "1", Content: "login handler", Vector: embedding.Vector{1, 0, 0}}))
+
+	collection, err := store.GetCollection(ctx, "issues")
+	require.NoError(t, err)
+	require.NotNil(t, collection)
+	assert.Equal(t, "small", collection.EmbeddingModel)
+	assert.Equal(t, 2, collection.Dimensions)
+	assert.Equal(t, 24*time.Hour, collection.Retention)
+	assert.Equal(t, int64(2), collection.Documents)
+
+	// Searches through a collection only see its documents
+	results, err := issues.SearchVector(ctx, embedding.Vector{1, 0}, vectorstore.SearchOptions{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "1", results[0].Document.ID)
+	results, err = issues.SearchHybrid(ctx, "crash", embedding.Vector{0, 1}, vectorstore.SearchOptions{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "2", results[0].Document.ID)
+
+	results, err = vectorstore.SearchCollections(ctx, store, "login", []vectorstore.CollectionQuery{
+		{Collection: "", Vector: embedding.Vector{1, 0, 0}},
+		{Collection: "pushed", Vector: embedding.Vector{1, 0, 0}, Weight: 0.5},
+		{Collection: "issues", Vector: embedding.Vector{1, 0}, Weight: 2},
+	}, vectorstore.SearchOptions{Limit: 3})
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	assert.Equal(t, "issues", results[0].Collection)
+	assert.Equal(t, "1", results[0].Document.ID)
+	assert.Equal(t, "", results[2].Collection)
+	assert.Equal(t, "1", results[2].Document.ID)
+
+	collections, err := store.ListCollections(ctx)
+	require.NoError(t, err)
+	require.Len(t, collections, 2)
+	assert.Equal(t, "pushed", collections[1].Name)
+	assert.Equal(t, int64(1), collections[1].Documents)
+
+	expired, err := vectorstore.ApplyRetention(ctx, store, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), expired)
+	count, err := issues.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	require.NoError(t, store.DeleteCollection(ctx, "issues"))
+	assert.Error(t, store.DeleteCollection(ctx, "issues"))
+	_, err = store.ExpireCollection(ctx, "issues", time.Now())
+	assert.Error(t, err)
+	count, err = store.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	report, err := store.CheckIntegrity(ctx, 0)
+	require.NoError(t, err)
+	assert.Empty(t, report.HNSWOrphaned)
+}
+
+func TestStore_CollectionsKeptApart(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewStore(":memory:")
+	require.NoError(t, err)
+	defer store.Close()
+
+	// The 2-dimensional collection is written first, so it would set the
+	// dimension of a graph shared with the 3-dimensional code documents
+	require.NoError(t, store.CreateCollection(ctx, vectorstore.Collection{Name: "issues", Dimensions: 2}))
+	issues := vectorstore.ScopeToCollection(store, "issues")
+	require.NoError(t, issues.Upsert(ctx, vectorstore.Document{
+		ID: "1", Content: "login handler crash", Vector: embedding.Vector{1, 0},
+		Metadata: map[string]interface{}{"file_path": "auth.go"},
+	}))
+
+	docs := make([]vectorstore.Document, hnswSearchThreshold+100)
+	for i := range docs {
+		docs[i] = vectorstore.Document{
+			ID:       fmt.Sprintf("code-%04d", i),
+			Content:  fmt.Sprintf("login handler %d", i),
+			Vector:   embedding.Vector{1, float32(i) / 1000, 0.5},
+			Metadata: map[string]interface{}{"file_path": fmt.Sprintf("file%d.go", i%10)},
+		}
+	}
+	docs = append(docs, vectorstore.Document{
+		ID: "auth", Content: "login handler", Vector: embedding.Vector{1, 0, 0},
+		Metadata: map[string]interface{}{"file_path": "auth.go"},
+	})
+	require.NoError(t, store.UpsertBatch(ctx, docs))
+	require.NoError(t, issues.Upsert(ctx, vectorstore.Document{ID: "2", Content: "crash", Vector: embedding.Vector{0, 1}}))
+
+	// Both corpora are searchable with their own dimension
+	results, err := store.SearchVector(ctx, embedding.Vector{1, 0, 0}, vectorstore.SearchOptions{Limit: 5})
+	require.NoError(t, err)
+	require.Len(t, results, 5)
+	assert.Equal(t, "auth", results[0].Document.ID)
+	results, err = issues.SearchVector(ctx, embedding.Vector{0, 1}, vectorstore.SearchOptions{Limit: 5})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "2", results[0].Document.ID)
+
+	// Unscoped reads and writes leave collection documents alone
+	results, err = store.SearchBM25(ctx, "crash", vectorstore.SearchOptions{Limit: 5})
+	require.NoError(t, err)
+	assert.Empty(t, results)
+	chunks, err := store.GetFileChunks(ctx, "auth.go")
+	require.NoError(t, err)
+	require.Len(t, chunks, 1)
+	assert.Equal(t, "auth", chunks[0].ID)
+	deleted, err := store.DeleteByFilter(ctx, vectorstore.InStrings("file_path", []string{"auth.go"}))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	count, err := issues.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	// Each corpus is checked against its own dimensions
+	report, err := store.CheckIntegrity(ctx, 3)
+	require.NoError(t, err)
+	assert.Empty(t, report.DimensionMismatches)
+	require.NoError(t, store.CreateCollection(ctx, vectorstore.Collection{Name: "issues", Dimensions: 4}))
+	report, err = store.CheckIntegrity(ctx, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"@issues/1", "@issues/2"}, report.DimensionMismatches)
+}
@@ -0,0 +1,129 @@
+package sqlite
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+)
+
+// indexedMetadataKeys are the metadata keys searches filter on most. Each is
+// exposed as an indexed generated column named meta_<key>, which filters use
+// instead of extracting the key from the JSON metadata.
+var indexedMetadataKeys = []string{"file_path", "language", "type", "source_type", vectorstore.RepoIDKey, vectorstore.CollectionKey}
+
+// filterSQL compiles a metadata filter to a condition on the documents table
+// and its arguments. Columns are qualified with prefix, such as "d.".
+func filterSQL(filter vectorstore.Filter, prefix string) (string, []interface{}, error) {
+	if err := filter.Validate(); err != nil {
+		return "", nil, fmt.Errorf("invalid filter: %w", err)
+	}
+	c := &filterCompiler{prefix: prefix}
+	return c.compile(filter), c.args, nil
+}
+
+// filterCompiler accumulates the arguments of a condition in the order their
+// placeholders appear.
+type filterCompiler struct {
+	prefix string
+	args   []interface{}
+}
+
+func (c *filterCompiler) compile(f vectorstore.Filter) string {
+	switch f.Op {
+	case "":
+		return "1"
+	case vectorstore.FilterAnd, vectorstore.FilterOr:
+		if len(f.Filters) == 0 {
+			if f.Op == vectorstore.FilterAnd {
+				return "1"
+			}
+			return "0"
+		}
+		conditions := make([]string, len(f.Filters))
+		for i, operand := range f.Filters {
+			conditions[i] = c.compile(operand)
+		}
+		return "(" + strings.Join(conditions, " "+strings.ToUpper(string(f.Op))+" ") + ")"
+	case vectorstore.FilterNot:
+		// A condition on an unset key is NULL rather than false
+		return "NOT IFNULL(" + c.compile(f.Filters[0]) + ", 0)"
+	case vectorstore.FilterContains:
+		c.args = append(c.args, metadataPath(f.Key), vectorstore.NormalizeFilterValue(f.Value))
+		return fmt.Sprintf("EXISTS (SELECT 1 FROM json_each(%smetadata, ?) WHERE value = ?)", c.prefix)
+	}
+
+	switch f.Op {
+	case vectorstore.FilterEq:
+		if f.Value == nil {
+			return c.field(f.Key) + " IS NULL"
+		}
+		return c.field(f.Key) + " = " + c.arg(f.Value)
+	case vectorstore.FilterIn:
+		if len(f.Values) == 0 {
+			return "0"
+		}
+		field := c.field(f.Key)
+		placeholders := make([]string, len(f.Values))
+		for i, value := range f.Values {
+			placeholders[i] = c.arg(value)
+		}
+		return fmt.Sprintf("%s IN (%s)", field, strings.Join(placeholders, ", "))
+	case vectorstore.FilterPrefix, vectorstore.FilterGlob:
+		pattern := f.Value.(string)
+		if f.Op == vectorstore.FilterPrefix {
+			pattern = escapeGlob(pattern) + "*"
+		}
+		return fmt.Sprintf("(typeof(%s) = 'text' AND %s GLOB %s)", c.field(f.Key), c.field(f.Key), c.arg(pattern))
+	case vectorstore.FilterRange:
+		var conditions []string
+		if f.Min != nil {
+			conditions = append(conditions, c.field(f.Key)+" >= "+c.arg(f.Min))
+		}
+		if f.Max != nil {
+			conditions = append(conditions, c.field(f.Key)+" <= "+c.arg(f.Max))
+		}
+		if len(conditions) == 0 {
+			return c.field(f.Key) + " IS NOT NULL"
+		}
+		return "(" + strings.Join(conditions, " AND ") + ")"
+	default: // vectorstore.FilterExists
+		return c.field(f.Key) + " IS NOT NULL"
+	}
+}
+
+// field returns the expression of a metadata key, adding its arguments.
+func (c *filterCompiler) field(key string) string {
+	for _, indexed := range indexedMetadataKeys {
+		if key == indexed {
+			return c.prefix + "meta_" + key
+		}
+	}
+	c.args = append(c.args, metadataPath(key))
+	return fmt.Sprintf("json_extract(%smetadata, ?)", c.prefix)
+}
+
+// arg adds a value argument and returns its placeholder.
+func (c *filterCompiler) arg(value interface{}) string {
+	c.args = append(c.args, vectorstore.NormalizeFilterValue(value))
+	return "?"
+}
+
+// metadataPath returns the JSON path of a top-level metadata key.
+func metadataPath(key string) string {
+	return `$."` + key + `"`
+}
+
+// escapeGlob quotes the GLOB wildcards in s.
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[':
+			b.WriteString("[" + string(r) + "]")
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
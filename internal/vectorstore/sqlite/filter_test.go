@@ -0,0 +1,174 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ferg-cod3s/conexus/internal/embedding"
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+)
+
+// filterDocs returns documents whose metadata covers the filter operators.
+func filterDocs() []vectorstore.Document {
+	metadata := []map[string]interface{}{
+		{"file_path": "internal/mcp/handlers.go", "language": "go", "start_line": 10},
+		{"file_path": "internal/mcp/schema.go", "language": "go", "start_line": 200, vectorstore.RepoIDKey: "api"},
+		{"file_path": "web/app.ts", "language": "typescript", "related_files": []string{"internal/mcp/handlers.go"}},
+		{"source_type": "commit", "date": "2024-02-01T10:00:00Z", "ticket_ids": []string{"PROJ-1", "PROJ-2"}},
+		{"source_type": "github_pr", "updated_at": "2024-05-01T10:00:00Z", "ticket_ids": []string{"PROJ-3"}},
+		{"source_type": "github_issue", "updated_at": "2023-12-01T10:00:00Z", "generated": true},
+	}
+	docs := make([]vectorstore.Document, len(metadata))
+	for i, m := range metadata {
+		docs[i] = vectorstore.Document{
+			ID:       fmt.Sprintf("doc%d", i),
+			Content:  "shared content",
+			Vector:   embedding.Vector{1, float32(i), 0.5},
+			Metadata: m,
+		}
+	}
+	return docs
+}
+
+func TestFilterSQL_MatchesGo(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	docs := filterDocs()
+	require.NoError(t, store.UpsertBatch(ctx, docs))
+
+	filters := []vectorstore.Filter{
+		vectorstore.Eq("language", "go"),
+		vectorstore.Eq(vectorstore.RepoIDKey, nil),
+		vectorstore.Eq("generated", true),
+		vectorstore.In("language", "go", "typescript"),
+		vectorstore.Prefix("source_type", "github"),
+		vectorstore.Prefix("file_path", "internal/mc"),
+		vectorstore.Glob("file_path", "*/[a-h]*.go"),
+		vectorstore.Range("start_line", 5, 100),
+		vectorstore.Range("start_line", 100, nil),
+		vectorstore.Range("updated_at", "2024-01-01T00:00:00Z", nil),
+		vectorstore.Exists("ticket_ids"),
+		vectorstore.Contains("ticket_ids", "PROJ-2"),
+		vectorstore.Contains("language", "go"),
+		vectorstore.ContainsAny("ticket_ids", "PROJ-3", "PROJ-9"),
+		vectorstore.Or(vectorstore.Eq("file_path", "internal/mcp/handlers.go"), vectorstore.Contains("related_files", "internal/mcp/handlers.go")),
+		vectorstore.Not(vectorstore.Exists("source_type")),
+		vectorstore.Not(vectorstore.Eq(vectorstore.RepoIDKey, "api")),
+		vectorstore.And(vectorstore.Eq("language", "go"), vectorstore.Not(vectorstore.Range("start_line", nil, 100))),
+		vectorstore.Or(),
+	}
+	for _, filter := range filters {
+		name, err := json.Marshal(filter)
+		require.NoError(t, err)
+		t.Run(string(name), func(t *testing.T) {
+			var want []string
+			for _, doc := range docs {
+				if filter.Match(doc.Metadata) {
+					want = append(want, doc.ID)
+				}
+			}
+
+			opts := vectorstore.SearchOptions{Limit: 10, Filter: filter}
+			bm25, err := store.SearchBM25(ctx, "shared", opts)
+			require.NoError(t, err)
+			assert.Equal(t, want, resultIDs(bm25), "BM25")
+
+			vector, err := store.SearchVector(ctx, embedding.Vector{1, 1, 1}, opts)
+			require.NoError(t, err)
+			assert.Equal(t, want, resultIDs(vector), "vector")
+		})
+	}
+
+	_, err := store.SearchBM25(ctx, "shared", vectorstore.SearchOptions{Filter: vectorstore.Prefix("", "a")})
+	assert.Error(t, err)
+}
+
+func TestFilterSQL_UsesMetadataColumns(t *testing.T) {
+	store := newTestStore(t)
+
+	condition, args, err := filterSQL(vectorstore.And(
+		vectorstore.InStrings(vectorstore.RepoIDKey, []string{"a", "b"}),
+		vectorstore.Eq("branch", "main"),
+	), "d.")
+	require.NoError(t, err)
+	assert.Equal(t, `(d.meta_repo_id IN (?, ?) AND json_extract(d.metadata, ?) = ?)`, condition)
+	assert.Equal(t, []interface{}{"a", "b", `$."branch"`, "main"}, args)
+
+	rows, err := store.db.Query("EXPLAIN QUERY PLAN SELECT id FROM documents d WHERE "+condition, args...)
+	require.NoError(t, err)
+	defer rows.Close()
+	var plan []string
+	for rows.Next() {
+		var id, parent, unused int
+		var detail string
+		require.NoError(t, rows.Scan(&id, &parent, &unused, &detail))
+		plan = append(plan, detail)
+	}
+	require.NoError(t, rows.Err())
+	assert.Contains(t, strings.Join(plan, "\n"), "idx_documents_meta_repo_id")
+}
+
+func TestMigrate_AddsMetadataColumns(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "index.db")
+
+	store, err := NewStore(path)
+	require.NoError(t, err)
+	require.NoError(t, store.UpsertBatch(ctx, filterDocs()))
+
+	// Downgrade to schema version 2, which indexed repo_id by expression
+	for _, key := range indexedMetadataKeys {
+		_, err = store.db.ExecContext(ctx, "DROP INDEX idx_documents_meta_"+key)
+		require.NoError(t, err)
+		_, err = store.db.ExecContext(ctx, "ALTER TABLE documents DROP COLUMN meta_"+key)
+		require.NoError(t, err)
+	}
+	_, err = store.db.ExecContext(ctx,
+		"CREATE INDEX idx_documents_repo_id ON documents(json_extract(metadata, '$.repo_id'))")
+	require.NoError(t, err)
+	for _, stmt := range []string{"DROP TABLE schema_migrations", "PRAGMA user_version = 2"} {
+		_, err = store.db.ExecContext(ctx, stmt)
+		require.NoError(t, err)
+	}
+	require.NoError(t, store.Close())
+
+	store, err = NewStore(path)
+	require.NoError(t, err)
+	defer store.Close()
+
+	var indexes []string
+	rows, err := store.db.QueryContext(ctx, "SELECT name FROM sqlite_master WHERE type = 'index' AND tbl_name = 'documents' AND name LIKE 'idx_documents_%'")
+	require.NoError(t, err)
+	for rows.Next() {
+		var name string
+		require.NoError(t, rows.Scan(&name))
+		indexes = append(indexes, name)
+	}
+	require.NoError(t, rows.Close())
+	assert.NotContains(t, indexes, "idx_documents_repo_id")
+	for _, key := range indexedMetadataKeys {
+		assert.Contains(t, indexes, "idx_documents_meta_"+key)
+	}
+
+	files, err := store.ListRepositoryFiles(ctx, "api")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"internal/mcp/schema.go"}, files)
+}
+
+// resultIDs returns the sorted IDs of results.
+func resultIDs(results []vectorstore.SearchResult) []string {
+	var ids []string
+	for _, r := range results {
+		ids = append(ids, r.Document.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -11,7 +11,8 @@ import (
 )
 
 // SearchBM25 performs sparse keyword search using SQLite FTS5 with BM25 ranking.
-// The query is automatically parsed and escaped for FTS5 syntax.
+// The query is automatically parsed and escaped for FTS5 syntax. Collection
+// documents are searched only when the filter names collections.
 func (s *Store) SearchBM25(ctx context.Context, query string, opts vectorstore.SearchOptions) ([]vectorstore.SearchResult, error) {
 	if query == "" {
 		return nil, fmt.Errorf("search query cannot be empty")
@@ -28,7 +29,10 @@ func (s *Store) SearchBM25(ctx context.Context, query string, opts vectorstore.S
 	fts5Query := parseFTS5Query(query)
 
 	// Build the SQL query with metadata filters
-	sqlQuery, args := buildBM25Query(fts5Query, opts.Filters, limit, offset)
+	sqlQuery, args, err := buildBM25Query(fts5Query, vectorstore.DefaultCorpus(opts.MetadataFilter()), limit, offset)
+	if err != nil {
+		return nil, err
+	}
 
 	// Execute search
 	rows, err := s.db.QueryContext(ctx, sqlQuery, args...)
@@ -41,14 +45,14 @@ func (s *Store) SearchBM25(ctx context.Context, query string, opts vectorstore.S
 	var results []vectorstore.SearchResult
 	for rows.Next() {
 		var doc vectorstore.Document
-		var vectorJSON, metadataJSON []byte
+		var vectorBlob, metadataJSON []byte
 		var createdAt, updatedAt int64
 		var score float32
 
 		err := rows.Scan(
 			&doc.ID,
 			&doc.Content,
-			&vectorJSON,
+			&vectorBlob,
 			&metadataJSON,
 			&createdAt,
 			&updatedAt,
@@ -59,7 +63,7 @@ func (s *Store) SearchBM25(ctx context.Context, query string, opts vectorstore.S
 		}
 
 		// Deserialize vector and metadata (reuse existing logic)
-		if err := deserializeDocument(&doc, vectorJSON, metadataJSON, createdAt, updatedAt); err != nil {
+		if err := deserializeDocument(&doc, vectorBlob, metadataJSON, createdAt, updatedAt); err != nil {
 			return nil, fmt.Errorf("deserialize document: %w", err)
 		}
 
@@ -91,6 +95,7 @@ func (s *Store) SearchBM25(ctx context.Context, query string, opts vectorstore.S
 // Handles:
 // - Escaping special characters
 // - Converting spaces to AND operators
+// - Expanding compound identifiers, see identifierQuery
 // - Supporting quoted phrases
 // - Supporting basic boolean operators (AND, OR, NOT)
 func parseFTS5Query(query string) string {
@@ -120,14 +125,22 @@ func parseFTS5Query(query string) string {
 	// Convert boolean operators to uppercase
 	query = normalizeOperators(query)
 
-	// If no explicit operators, convert spaces to AND
+	// Expand identifiers the way the identifiers column is indexed
+	var terms []string
+	for _, word := range splitPreservingQuotes(query) {
+		switch {
+		case strings.HasPrefix(word, `"`), word == "AND", word == "OR", word == "NOT":
+			terms = append(terms, word)
+		case identifierPattern.MatchString(word):
+			terms = append(terms, identifierQuery(word))
+		}
+	}
+
 	// If no explicit operators, convert spaces to AND
 	if !containsExplicitOperators(query) {
-		words := splitPreservingQuotes(query)
-		query = strings.Join(words, " AND ")
+		return strings.Join(terms, " AND ")
 	}
-
-	return query
+	return strings.Join(terms, " ")
 }
 
 // extractPhrases finds all quoted phrases in the query
@@ -154,7 +167,6 @@ func escapeFTS5Special(s string) string {
 		`/`, " ", // Replace slashes with spaces to separate path components
 		`(`, " ", // Replace parentheses with spaces
 		`)`, " ", // Replace parentheses with spaces
-		`-`, " ", // Replace hyphens with spaces
 	)
 	return replacer.Replace(s)
 }
@@ -210,7 +222,7 @@ func splitPreservingQuotes(query string) []string {
 }
 
 // buildBM25Query constructs the SQL query for BM25 search with filters
-func buildBM25Query(fts5Query string, filters map[string]interface{}, limit int, offset int) (string, []interface{}) {
+func buildBM25Query(fts5Query string, filter vectorstore.Filter, limit int, offset int) (string, []interface{}, error) {
 	baseQuery := `
 		SELECT 
 			d.id,
@@ -222,19 +234,19 @@ func buildBM25Query(fts5Query string, filters map[string]interface{}, limit int,
 			fts.rank as score
 		FROM documents_fts fts
 		JOIN documents d ON fts.id = d.id
-		WHERE fts.content MATCH ?
+		WHERE fts.documents_fts MATCH ?
 	`
 
 	args := []interface{}{fts5Query}
 
 	// Add metadata filters
-	if len(filters) > 0 {
-		for key, value := range filters {
-			// Use JSON extraction for metadata filtering
-			// SQLite JSON functions: json_extract(metadata, '$.key')
-			baseQuery += fmt.Sprintf(" AND json_extract(d.metadata, '$.%s') = ?", key)
-			args = append(args, value)
+	if !filter.IsEmpty() {
+		condition, conditionArgs, err := filterSQL(filter, "d.")
+		if err != nil {
+			return "", nil, err
 		}
+		baseQuery += " AND " + condition
+		args = append(args, conditionArgs...)
 	}
 
 	// Order by relevance (rank is negative, lower is better)
@@ -244,7 +256,7 @@ func buildBM25Query(fts5Query string, filters map[string]interface{}, limit int,
 	baseQuery += " LIMIT ? OFFSET ?"
 	args = append(args, limit, offset)
 
-	return baseQuery, args
+	return baseQuery, args, nil
 }
 
 // normalizeRank converts FTS5 negative rank to positive score in [0, 1]
@@ -0,0 +1,70 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+)
+
+// GetParent returns the parent of a document, or nil if it has none or the
+// parent is not stored.
+func (s *Store) GetParent(ctx context.Context, id string) (*vectorstore.Document, error) {
+	var doc vectorstore.Document
+	var vectorBlob, metadataJSON []byte
+	var createdAt, updatedAt int64
+
+	err := s.db.QueryRowContext(ctx,
+		`SELECT d.id, d.content, d.vector, d.metadata, d.created_at, d.updated_at
+		 FROM chunk_parents p JOIN documents d ON d.id = p.parent_id
+		 WHERE p.child_id = ?`,
+		id,
+	).Scan(&doc.ID, &doc.Content, &vectorBlob, &metadataJSON, &createdAt, &updatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query parent: %w", err)
+	}
+
+	if err := deserializeDocument(&doc, vectorBlob, metadataJSON, createdAt, updatedAt); err != nil {
+		return nil, fmt.Errorf("deserialize document %s: %w", doc.ID, err)
+	}
+	return &doc, nil
+}
+
+// GetChildren returns the direct children of a document, sorted by start_line.
+func (s *Store) GetChildren(ctx context.Context, id string) ([]vectorstore.Document, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT d.id, d.content, d.vector, d.metadata, d.created_at, d.updated_at
+		 FROM chunk_parents p JOIN documents d ON d.id = p.child_id
+		 WHERE p.parent_id = ?
+		 ORDER BY json_extract(d.metadata, '$.start_line'), d.id`,
+		id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query children: %w", err)
+	}
+	defer rows.Close()
+
+	var docs []vectorstore.Document
+	for rows.Next() {
+		var doc vectorstore.Document
+		var vectorBlob, metadataJSON []byte
+		var createdAt, updatedAt int64
+
+		if err := rows.Scan(&doc.ID, &doc.Content, &vectorBlob, &metadataJSON, &createdAt, &updatedAt); err != nil {
+			return nil, fmt.Errorf("scan document: %w", err)
+		}
+		if err := deserializeDocument(&doc, vectorBlob, metadataJSON, createdAt, updatedAt); err != nil {
+			return nil, fmt.Errorf("deserialize document %s: %w", doc.ID, err)
+		}
+		docs = append(docs, doc)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+	return docs, nil
+}
@@ -0,0 +1,80 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ferg-cod3s/conexus/internal/embedding"
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+)
+
+func hierarchyDoc(id, parentID string, startLine int) vectorstore.Document {
+	metadata := map[string]interface{}{"start_line": startLine}
+	if parentID != "" {
+		metadata[vectorstore.ParentIDKey] = parentID
+	}
+	return vectorstore.Document{
+		ID:       id,
+		Content:  "content of " + id,
+		Vector:   embedding.Vector{0.1, 0.2, 0.3},
+		Metadata: metadata,
+	}
+}
+
+func TestHierarchy_ParentsAndChildren(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	require.NoError(t, store.UpsertBatch(ctx, []vectorstore.Document{
+		hierarchyDoc("type", "", 1),
+		hierarchyDoc("method-b", "type", 9),
+		hierarchyDoc("method-a", "type", 5),
+	}))
+	require.NoError(t, store.Upsert(ctx, hierarchyDoc("func", "type", 20)))
+
+	parent, err := store.GetParent(ctx, "method-a")
+	require.NoError(t, err)
+	require.NotNil(t, parent)
+	assert.Equal(t, "type", parent.ID)
+
+	parent, err = store.GetParent(ctx, "type")
+	require.NoError(t, err)
+	assert.Nil(t, parent)
+
+	children, err := store.GetChildren(ctx, "type")
+	require.NoError(t, err)
+	require.Len(t, children, 3)
+	assert.Equal(t, "method-a", children[0].ID)
+	assert.Equal(t, "method-b", children[1].ID)
+	assert.Equal(t, "func", children[2].ID)
+}
+
+func TestHierarchy_FollowsUpdatesAndDeletes(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	require.NoError(t, store.UpsertBatch(ctx, []vectorstore.Document{
+		hierarchyDoc("a", "", 1),
+		hierarchyDoc("b", "", 1),
+		hierarchyDoc("child", "a", 2),
+	}))
+
+	// Re-parenting through an upsert moves the edge
+	require.NoError(t, store.UpsertBatch(ctx, []vectorstore.Document{hierarchyDoc("child", "b", 2)}))
+	children, err := store.GetChildren(ctx, "a")
+	require.NoError(t, err)
+	assert.Empty(t, children)
+	parent, err := store.GetParent(ctx, "child")
+	require.NoError(t, err)
+	require.NotNil(t, parent)
+	assert.Equal(t, "b", parent.ID)
+
+	// Deleting the child removes the edge
+	require.NoError(t, store.Delete(ctx, "child"))
+	var edges int
+	require.NoError(t, store.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM chunk_parents").Scan(&edges))
+	assert.Zero(t, edges)
+}
@@ -2,7 +2,9 @@
 package sqlite
 
 import (
+	"encoding/gob"
 	"fmt"
+	"io"
 	"math"
 	"math/rand"
 	"sort"
@@ -20,6 +22,8 @@ type HNSWConfig struct {
 	EfSearch       int     // Size of the dynamic candidate list during search (default: 32)
 	ML             float64 // Normalization factor for level generation (default: 1/ln(M))
 	MaxLevel       int     // Maximum allowed level (computed automatically)
+
+	Quantization QuantizationConfig // Compression of node vectors (default: none)
 }
 
 // DefaultHNSWConfig returns sensible defaults for HNSW
@@ -37,7 +41,8 @@ func DefaultHNSWConfig() HNSWConfig {
 // HNSWNode represents a node in the HNSW graph
 type HNSWNode struct {
 	ID        string           // Document ID
-	Vector    embedding.Vector // The vector (normalized)
+	Vector    embedding.Vector // The vector (normalized), nil once quantized
+	Code      []byte           // The quantized vector, when the index is quantized
 	Level     int              // Level in the hierarchy
 	Neighbors [][]string       // Neighbors per level [level][neighbor_ids]
 	Deleted   bool             // Soft delete flag
@@ -51,6 +56,8 @@ type HNSWIndex struct {
 	maxLevel   int                  // Current maximum level
 	mu         sync.RWMutex         // Protects concurrent access
 	vectorDim  int                  // Vector dimensionality
+	dirty      map[string]bool      // Nodes changed since the last takeChanges
+	quantizer  quantizer            // Compressor of node vectors, once trained
 }
 
 // NewHNSWIndex creates a new HNSW index
@@ -64,6 +71,7 @@ func NewHNSWIndex(config HNSWConfig) *HNSWIndex {
 		nodes:     make(map[string]*HNSWNode),
 		maxLevel:  0,
 		vectorDim: 0,
+		dirty:     make(map[string]bool),
 	}
 }
 
@@ -104,6 +112,12 @@ func (hnsw *HNSWIndex) Insert(id string, vector embedding.Vector) error {
 
 	// Insert into graph
 	hnsw.insertNode(node)
+	if hnsw.quantizer != nil {
+		node.Code = hnsw.quantizer.encode(node.Vector)
+		node.Vector = nil
+	} else if hnsw.config.Quantization.enabled() && len(hnsw.nodes) >= hnsw.config.Quantization.trainingSize() {
+		hnsw.quantize()
+	}
 
 	// Update max level
 	if level > hnsw.maxLevel {
@@ -113,7 +127,8 @@ func (hnsw *HNSWIndex) Insert(id string, vector embedding.Vector) error {
 	return nil
 }
 
-// Search finds the k nearest neighbors using HNSW
+// Search finds the k nearest neighbors using HNSW. Distances are approximate
+// when the index is quantized.
 func (hnsw *HNSWIndex) Search(queryVector embedding.Vector, k int, ef int) ([]SearchCandidate, error) {
 	hnsw.mu.RLock()
 	defer hnsw.mu.RUnlock()
@@ -123,7 +138,10 @@ func (hnsw *HNSWIndex) Search(queryVector embedding.Vector, k int, ef int) ([]Se
 	}
 
 	// Normalize query vector
-	queryNorm := hnswNormalizeVector(queryVector)
+	if hnsw.vectorDim != 0 && len(queryVector) != hnsw.vectorDim {
+		return nil, fmt.Errorf("vector dimension mismatch: expected %d, got %d", hnsw.vectorDim, len(queryVector))
+	}
+	query := hnsw.newQuery(hnswNormalizeVector(queryVector))
 
 	// Start search from entry point
 	entryPoint := hnsw.entryPoint
@@ -143,11 +161,11 @@ func (hnsw *HNSWIndex) Search(queryVector embedding.Vector, k int, ef int) ([]Se
 	// Perform greedy search from top level down to level 0
 	currentNodeID := entryPoint
 	for level := hnsw.maxLevel; level > 0; level-- {
-		currentNodeID = hnsw.searchLayer(queryNorm, currentNodeID, 1, level)
+		currentNodeID = hnsw.searchLayer(query, currentNodeID, 1, level)
 	}
 
 	// Search level 0 with full candidate list
-	candidates := hnsw.searchLayerKNN(queryNorm, currentNodeID, ef, 0)
+	candidates := hnsw.searchLayerKNN(query, currentNodeID, ef, 0)
 
 	// Sort by distance and return top k
 	sort.Slice(candidates, func(i, j int) bool {
@@ -172,6 +190,23 @@ func (hnsw *HNSWIndex) Remove(id string) error {
 	}
 
 	node.Deleted = true
+	hnsw.dirty[id] = true
+
+	// Searches cannot start from a deleted node
+	if hnsw.entryPoint == id {
+		hnsw.entryPoint = ""
+		hnsw.maxLevel = 0
+		for candidateID, candidate := range hnsw.nodes {
+			if candidate.Deleted {
+				continue
+			}
+			if hnsw.entryPoint == "" || candidate.Level > hnsw.maxLevel ||
+				(candidate.Level == hnsw.maxLevel && candidateID < hnsw.entryPoint) {
+				hnsw.entryPoint = candidateID
+				hnsw.maxLevel = candidate.Level
+			}
+		}
+	}
 	return nil
 }
 
@@ -207,6 +242,7 @@ type SearchCandidate struct {
 // insertNode inserts a node into the HNSW graph
 func (hnsw *HNSWIndex) insertNode(node *HNSWNode) {
 	// If this is the first node, make it the entry point
+	hnsw.dirty[node.ID] = true
 	if hnsw.entryPoint == "" {
 		hnsw.nodes[node.ID] = node
 		hnsw.entryPoint = node.ID
@@ -215,16 +251,17 @@ func (hnsw *HNSWIndex) insertNode(node *HNSWNode) {
 
 	// Start from entry point
 	currentNodeID := hnsw.entryPoint
+	query := hnsw.newQuery(node.Vector)
 
 	// Search for insertion points from top level down
 	for level := hnsw.maxLevel; level > node.Level; level-- {
-		currentNodeID = hnsw.searchLayer(node.Vector, currentNodeID, 1, level)
+		currentNodeID = hnsw.searchLayer(query, currentNodeID, 1, level)
 	}
 
 	// Search at each level from max(node.Level, 0) down to 0
 	for level := max(0, node.Level); level >= 0; level-- {
 		// Find efConstruction nearest neighbors at this level
-		candidates := hnsw.searchLayerKNN(node.Vector, currentNodeID, hnsw.config.EfConstruction, level)
+		candidates := hnsw.searchLayerKNN(query, currentNodeID, hnsw.config.EfConstruction, level)
 
 		// Select neighbors for this node
 		neighbors := hnsw.selectNeighbors(candidates, hnsw.config.M, level)
@@ -236,9 +273,10 @@ func (hnsw *HNSWIndex) insertNode(node *HNSWNode) {
 				node.Neighbors[level] = append(node.Neighbors[level], neighborID)
 
 				// Add connection from neighbor to new node (with pruning)
+				hnsw.dirty[neighborID] = true
 				neighborNode.Neighbors[level] = hnsw.pruneConnections(
 					append(neighborNode.Neighbors[level], node.ID),
-					node.Vector,
+					query,
 					hnsw.getMaxConnections(level),
 					level,
 				)
@@ -256,7 +294,7 @@ func (hnsw *HNSWIndex) insertNode(node *HNSWNode) {
 }
 
 // searchLayer performs a greedy search at a specific level
-func (hnsw *HNSWIndex) searchLayer(queryVector embedding.Vector, entryPointID string, ef int, level int) string {
+func (hnsw *HNSWIndex) searchLayer(query *hnswQuery, entryPointID string, ef int, level int) string {
 	visited := make(map[string]bool)
 	candidates := NewCandidateSet()
 
@@ -266,7 +304,7 @@ func (hnsw *HNSWIndex) searchLayer(queryVector embedding.Vector, entryPointID st
 		return entryPointID
 	}
 
-	candidates.Insert(entryPointID, cosineDistance(queryVector, entryPoint.Vector))
+	candidates.Insert(entryPointID, query.distance(entryPoint))
 	visited[entryPointID] = true
 
 	for !candidates.Empty() {
@@ -277,7 +315,7 @@ func (hnsw *HNSWIndex) searchLayer(queryVector embedding.Vector, entryPointID st
 		if candidates.Len() >= ef {
 			_, furthestDist := candidates.PeekFurthest()
 			closestNode := hnsw.nodes[closestID]
-			if closestNode == nil || cosineDistance(queryVector, closestNode.Vector) > furthestDist {
+			if closestNode == nil || query.distance(closestNode) > furthestDist {
 				return closestID
 			}
 		}
@@ -299,7 +337,7 @@ func (hnsw *HNSWIndex) searchLayer(queryVector embedding.Vector, entryPointID st
 				continue
 			}
 
-			distance := cosineDistance(queryVector, neighbor.Vector)
+			distance := query.distance(neighbor)
 			candidates.Insert(neighborID, distance)
 		}
 	}
@@ -312,7 +350,7 @@ func (hnsw *HNSWIndex) searchLayer(queryVector embedding.Vector, entryPointID st
 }
 
 // searchLayerKNN performs KNN search at a specific level
-func (hnsw *HNSWIndex) searchLayerKNN(queryVector embedding.Vector, entryPointID string, ef int, level int) []SearchCandidate {
+func (hnsw *HNSWIndex) searchLayerKNN(query *hnswQuery, entryPointID string, ef int, level int) []SearchCandidate {
 	visited := make(map[string]bool)
 	candidates := NewCandidateSet()
 	results := NewCandidateSet()
@@ -323,7 +361,7 @@ func (hnsw *HNSWIndex) searchLayerKNN(queryVector embedding.Vector, entryPointID
 		return []SearchCandidate{}
 	}
 
-	distance := cosineDistance(queryVector, entryPoint.Vector)
+	distance := query.distance(entryPoint)
 	candidates.Insert(entryPointID, distance)
 	results.Insert(entryPointID, distance)
 	visited[entryPointID] = true
@@ -357,7 +395,7 @@ func (hnsw *HNSWIndex) searchLayerKNN(queryVector embedding.Vector, entryPointID
 				continue
 			}
 
-			distance := cosineDistance(queryVector, neighbor.Vector)
+			distance := query.distance(neighbor)
 
 			// Add to candidates
 			candidates.Insert(neighborID, distance)
@@ -410,7 +448,7 @@ func (hnsw *HNSWIndex) selectNeighbors(candidates []SearchCandidate, M int, leve
 			}
 
 			// If candidate is closer to existing selected than to query, skip
-			distToSelected := cosineDistance(candidateNode.Vector, selectedNode.Vector)
+			distToSelected := cosineDistance(hnsw.nodeVector(candidateNode), hnsw.nodeVector(selectedNode))
 			distToQuery := candidates[i].Distance
 
 			if distToSelected < distToQuery {
@@ -428,7 +466,7 @@ func (hnsw *HNSWIndex) selectNeighbors(candidates []SearchCandidate, M int, leve
 }
 
 // pruneConnections prunes connections to maintain maximum allowed
-func (hnsw *HNSWIndex) pruneConnections(connections []string, centerVector embedding.Vector, maxConnections int, level int) []string {
+func (hnsw *HNSWIndex) pruneConnections(connections []string, center *hnswQuery, maxConnections int, level int) []string {
 	if len(connections) <= maxConnections {
 		return connections
 	}
@@ -448,7 +486,7 @@ func (hnsw *HNSWIndex) pruneConnections(connections []string, centerVector embed
 		}
 		conns[i] = connection{
 			id:   connID,
-			dist: cosineDistance(centerVector, connNode.Vector),
+			dist: center.distance(connNode),
 		}
 	}
 
@@ -605,3 +643,140 @@ func max(a, b int) int {
 	}
 	return b
 }
+
+// IDs returns the IDs of all nodes that have not been removed.
+func (hnsw *HNSWIndex) IDs() []string {
+	hnsw.mu.RLock()
+	defer hnsw.mu.RUnlock()
+
+	ids := make([]string, 0, len(hnsw.nodes))
+	for id, node := range hnsw.nodes {
+		if !node.Deleted {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// hnswGraph is the serialized form of an HNSW index.
+type hnswGraph struct {
+	Config     HNSWConfig
+	Nodes      []*HNSWNode
+	EntryPoint string
+	MaxLevel   int
+	VectorDim  int
+	Quantizer  *quantizerState
+}
+
+// Save writes the graph, including soft-deleted nodes that keep it connected.
+func (hnsw *HNSWIndex) Save(w io.Writer) error {
+	hnsw.mu.RLock()
+	defer hnsw.mu.RUnlock()
+
+	graph := hnswGraph{
+		Config:     hnsw.config,
+		Nodes:      make([]*HNSWNode, 0, len(hnsw.nodes)),
+		EntryPoint: hnsw.entryPoint,
+		MaxLevel:   hnsw.maxLevel,
+		VectorDim:  hnsw.vectorDim,
+		Quantizer:  hnsw.quantizerState(),
+	}
+	for _, node := range hnsw.nodes {
+		graph.Nodes = append(graph.Nodes, node)
+	}
+	sort.Slice(graph.Nodes, func(i, j int) bool { return graph.Nodes[i].ID < graph.Nodes[j].ID })
+
+	if err := gob.NewEncoder(w).Encode(graph); err != nil {
+		return fmt.Errorf("encode HNSW graph: %w", err)
+	}
+	return nil
+}
+
+// Load replaces the index with a graph written by Save.
+func (hnsw *HNSWIndex) Load(r io.Reader) error {
+	graph, err := decodeHNSWGraph(r)
+	if err != nil {
+		return err
+	}
+	hnsw.setGraph(graph)
+	return nil
+}
+
+// decodeHNSWGraph reads and validates a graph written by Save.
+func decodeHNSWGraph(r io.Reader) (*hnswGraph, error) {
+	var graph hnswGraph
+	if err := gob.NewDecoder(r).Decode(&graph); err != nil {
+		return nil, fmt.Errorf("decode HNSW graph: %w", err)
+	}
+
+	found := graph.EntryPoint == ""
+	for _, node := range graph.Nodes {
+		if node.ID == graph.EntryPoint {
+			found = true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("HNSW entry point %s not found", graph.EntryPoint)
+	}
+	return &graph, nil
+}
+
+// setGraph replaces the index contents with graph.
+func (hnsw *HNSWIndex) setGraph(graph *hnswGraph) {
+	nodes := make(map[string]*HNSWNode, len(graph.Nodes))
+	for _, node := range graph.Nodes {
+		nodes[node.ID] = node
+	}
+	config := graph.Config
+	if config.M == 0 {
+		config = DefaultHNSWConfig()
+	}
+
+	hnsw.mu.Lock()
+	defer hnsw.mu.Unlock()
+	hnsw.config = config
+	hnsw.nodes = nodes
+	hnsw.entryPoint = graph.EntryPoint
+	hnsw.maxLevel = graph.MaxLevel
+	hnsw.vectorDim = graph.VectorDim
+	hnsw.dirty = make(map[string]bool)
+	hnsw.quantizer = graph.Quantizer.quantizer()
+}
+
+// takeChanges returns the graph header with copies of the nodes changed since
+// the previous call, or of all nodes when all is true, and resets the change
+// tracking.
+func (hnsw *HNSWIndex) takeChanges(all bool) *hnswGraph {
+	hnsw.mu.Lock()
+	defer hnsw.mu.Unlock()
+
+	graph := &hnswGraph{
+		Config:     hnsw.config,
+		EntryPoint: hnsw.entryPoint,
+		MaxLevel:   hnsw.maxLevel,
+		VectorDim:  hnsw.vectorDim,
+		Quantizer:  hnsw.quantizerState(),
+	}
+	for id, node := range hnsw.nodes {
+		if !all && !hnsw.dirty[id] {
+			continue
+		}
+		neighbors := make([][]string, len(node.Neighbors))
+		for level, ids := range node.Neighbors {
+			neighbors[level] = append([]string(nil), ids...)
+		}
+		graph.Nodes = append(graph.Nodes, &HNSWNode{
+			ID:        node.ID,
+			Vector:    node.Vector,
+			Code:      node.Code,
+			Level:     node.Level,
+			Neighbors: neighbors,
+			Deleted:   node.Deleted,
+		})
+	}
+	sort.Slice(graph.Nodes, func(i, j int) bool { return graph.Nodes[i].ID < graph.Nodes[j].ID })
+
+	hnsw.dirty = make(map[string]bool)
+	return graph
+}
@@ -0,0 +1,307 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+)
+
+// The HNSW graph is persisted in the hnsw_nodes and hnsw_meta tables and
+// stamped with the state of the documents table it was built from: a counter
+// bumped by triggers on every document change, and the document count. The
+// graph is loaded on first use, and rebuilt from the documents only when the
+// stamp no longer matches, e.g. after a crash between a document write and
+// its graph update or a write by an older version.
+//
+// The graph holds the documents outside any collection only: collections may
+// be embedded with models of other dimensions, and are searched by brute force.
+
+// hnswStamp identifies a state of the documents table.
+type hnswStamp struct {
+	version int64
+	count   int64
+}
+
+// queryRower is implemented by *sql.DB, *sql.Conn and *sql.Tx.
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// documentsStamp returns the current stamp of the documents table.
+func documentsStamp(ctx context.Context, q queryRower, schema string) (hnswStamp, error) {
+	var stamp hnswStamp
+	err := q.QueryRowContext(ctx, fmt.Sprintf(
+		"SELECT (SELECT version FROM %[1]s.documents_version WHERE id = 1), (SELECT COUNT(*) FROM %[1]s.documents)",
+		schema,
+	)).Scan(&stamp.version, &stamp.count)
+	if err != nil {
+		return hnswStamp{}, fmt.Errorf("read documents stamp: %w", err)
+	}
+	return stamp, nil
+}
+
+// graphStamp returns the stamp the persisted graph was built from, or false
+// if no graph has been persisted.
+func graphStamp(ctx context.Context, q queryRower, schema string) (hnswStamp, bool, error) {
+	var stamp hnswStamp
+	err := q.QueryRowContext(ctx, fmt.Sprintf(
+		"SELECT documents_version, documents_count FROM %s.hnsw_meta WHERE id = 1", schema,
+	)).Scan(&stamp.version, &stamp.count)
+	if err == sql.ErrNoRows {
+		return hnswStamp{}, false, nil
+	}
+	if err != nil {
+		return hnswStamp{}, false, fmt.Errorf("read HNSW stamp: %w", err)
+	}
+	return stamp, true, nil
+}
+
+// ensureHNSW makes the in-memory graph match the documents table, loading the
+// persisted graph or rebuilding it when needed. Callers must hold hnswMu.
+func (s *Store) ensureHNSW(ctx context.Context) error {
+	stamp, err := documentsStamp(ctx, s.db, "main")
+	if err != nil {
+		return err
+	}
+	if s.hnswLoaded && stamp == s.hnswStamp {
+		return nil
+	}
+	s.hnswLoaded = false
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	loaded, err := s.loadHNSW(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if !loaded {
+		if err := s.rebuildHNSW(ctx, tx); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	s.hnswLoaded = true
+	return nil
+}
+
+// loadHNSW loads the persisted graph if it matches the documents table.
+func (s *Store) loadHNSW(ctx context.Context, tx *sql.Tx) (bool, error) {
+	stamp, err := documentsStamp(ctx, tx, "main")
+	if err != nil {
+		return false, err
+	}
+	persisted, ok, err := graphStamp(ctx, tx, "main")
+	if err != nil || !ok || persisted != stamp {
+		return false, err
+	}
+
+	// Graphs written before collections were kept out of them are rebuilt
+	var collectionNodes bool
+	if err := tx.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM hnsw_nodes JOIN documents USING (id) WHERE documents.meta_collection IS NOT NULL)",
+	).Scan(&collectionNodes); err != nil {
+		return false, fmt.Errorf("read HNSW nodes: %w", err)
+	}
+	if collectionNodes {
+		return false, nil
+	}
+
+	graph := &hnswGraph{}
+	var configJSON, quantizerBlob []byte
+	if err := tx.QueryRowContext(ctx,
+		"SELECT entry_point, max_level, vector_dim, config, quantizer FROM hnsw_meta WHERE id = 1",
+	).Scan(&graph.EntryPoint, &graph.MaxLevel, &graph.VectorDim, &configJSON, &quantizerBlob); err != nil {
+		return false, fmt.Errorf("read HNSW graph: %w", err)
+	}
+	if err := json.Unmarshal(configJSON, &graph.Config); err != nil {
+		return false, fmt.Errorf("unmarshal HNSW config: %w", err)
+	}
+	if !graph.Config.Quantization.equal(s.quantization) {
+		return false, nil
+	}
+	if graph.Quantizer, err = decodeQuantizer(quantizerBlob); err != nil {
+		return false, err
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT id, level, vector, neighbors FROM hnsw_nodes")
+	if err != nil {
+		return false, fmt.Errorf("query HNSW nodes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		node := &HNSWNode{}
+		var vectorBlob, neighborsJSON []byte
+		if err := rows.Scan(&node.ID, &node.Level, &vectorBlob, &neighborsJSON); err != nil {
+			return false, fmt.Errorf("scan HNSW node: %w", err)
+		}
+		if graph.Quantizer != nil {
+			node.Code = vectorBlob
+		} else if node.Vector, err = decodeVector(vectorBlob); err != nil {
+			return false, fmt.Errorf("decode vector of %s: %w", node.ID, err)
+		}
+		if err := json.Unmarshal(neighborsJSON, &node.Neighbors); err != nil {
+			return false, fmt.Errorf("unmarshal neighbors of %s: %w", node.ID, err)
+		}
+		graph.Nodes = append(graph.Nodes, node)
+	}
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("iterate HNSW nodes: %w", err)
+	}
+
+	s.hnswIndex.setGraph(graph)
+	s.hnswStamp = stamp
+	return true, nil
+}
+
+// rebuildHNSW builds the graph from the documents table and persists it.
+func (s *Store) rebuildHNSW(ctx context.Context, tx *sql.Tx) error {
+	config := s.hnswIndex.config
+	config.Quantization = s.quantization
+	s.hnswIndex.setGraph(&hnswGraph{Config: config})
+	if err := s.loadVectorsIntoIndex(ctx, tx); err != nil {
+		return err
+	}
+	return s.persistHNSW(ctx, tx, true)
+}
+
+// persistHNSW writes the graph nodes changed since the last call, or all
+// nodes when all is true, and stamps the graph with the documents table as
+// seen by tx. An incremental write is skipped if another connection updated
+// the graph since this store last loaded or wrote it; the next use then
+// reloads or rebuilds it.
+func (s *Store) persistHNSW(ctx context.Context, tx *sql.Tx, all bool) error {
+	if !all {
+		persisted, ok, err := graphStamp(ctx, tx, "main")
+		if err != nil {
+			return err
+		}
+		if !ok || persisted != s.hnswStamp {
+			s.hnswLoaded = false
+			return nil
+		}
+	}
+
+	stamp, err := documentsStamp(ctx, tx, "main")
+	if err != nil {
+		return err
+	}
+	graph := s.hnswIndex.takeChanges(all)
+
+	if all {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM hnsw_nodes"); err != nil {
+			return fmt.Errorf("clear HNSW nodes: %w", err)
+		}
+	}
+	for _, node := range graph.Nodes {
+		// Deleted nodes are skipped by searches, so they are not kept
+		if node.Deleted {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM hnsw_nodes WHERE id = ?", node.ID); err != nil {
+				return fmt.Errorf("delete HNSW node %s: %w", node.ID, err)
+			}
+			continue
+		}
+		neighborsJSON, err := json.Marshal(node.Neighbors)
+		if err != nil {
+			return fmt.Errorf("marshal neighbors of %s: %w", node.ID, err)
+		}
+		vectorBlob := node.Code
+		if graph.Quantizer == nil {
+			vectorBlob = encodeVector(node.Vector, s.vectorEncoding)
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO hnsw_nodes (id, level, vector, neighbors) VALUES (?, ?, ?, ?)
+			 ON CONFLICT(id) DO UPDATE SET
+			 level = excluded.level,
+			 vector = excluded.vector,
+			 neighbors = excluded.neighbors`,
+			node.ID, node.Level, vectorBlob, neighborsJSON,
+		); err != nil {
+			return fmt.Errorf("write HNSW node %s: %w", node.ID, err)
+		}
+	}
+
+	configJSON, err := json.Marshal(graph.Config)
+	if err != nil {
+		return fmt.Errorf("marshal HNSW config: %w", err)
+	}
+	quantizerBlob, err := encodeQuantizer(graph.Quantizer)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO hnsw_meta (id, entry_point, max_level, vector_dim, config, quantizer, documents_version, documents_count)
+		 VALUES (1, ?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(id) DO UPDATE SET
+		 entry_point = excluded.entry_point,
+		 max_level = excluded.max_level,
+		 vector_dim = excluded.vector_dim,
+		 config = excluded.config,
+		 quantizer = excluded.quantizer,
+		 documents_version = excluded.documents_version,
+		 documents_count = excluded.documents_count`,
+		graph.EntryPoint, graph.MaxLevel, graph.VectorDim, configJSON, quantizerBlob, stamp.version, stamp.count,
+	); err != nil {
+		return fmt.Errorf("write HNSW graph: %w", err)
+	}
+
+	s.hnswStamp = stamp
+	return nil
+}
+
+// writeDocuments runs write in a transaction and applies the documents it
+// removed and stored to the HNSW graph, which is persisted in the same
+// transaction.
+func (s *Store) writeDocuments(ctx context.Context, write func(tx *sql.Tx) (removed []string, stored []vectorstore.Document, err error)) error {
+	s.hnswMu.Lock()
+	defer s.hnswMu.Unlock()
+
+	if err := s.ensureHNSW(ctx); err != nil {
+		return fmt.Errorf("load HNSW graph: %w", err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	removed, stored, err := write(tx)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range removed {
+		// Nodes may be absent from the graph; nothing to remove then
+		_ = s.hnswIndex.Remove(id)
+	}
+	for _, doc := range stored {
+		_ = s.hnswIndex.Remove(doc.ID)
+		if collection, _ := doc.Metadata[vectorstore.CollectionKey].(string); collection != "" {
+			continue
+		}
+		// Vectors the graph rejects, such as ones of another dimension, stay
+		// reachable through brute force search and are reported by CheckIntegrity
+		_ = s.hnswIndex.Insert(doc.ID, doc.Vector)
+	}
+
+	if err := s.persistHNSW(ctx, tx, false); err != nil {
+		s.hnswLoaded = false
+		return fmt.Errorf("persist HNSW graph: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		s.hnswLoaded = false
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ferg-cod3s/conexus/internal/embedding"
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+)
+
+// persistedGraphMatches loads the persisted graph into store and reports
+// whether it matched the documents table.
+func persistedGraphMatches(t *testing.T, store *Store) bool {
+	t.Helper()
+	ctx := context.Background()
+	store.hnswMu.Lock()
+	defer store.hnswMu.Unlock()
+
+	tx, err := store.db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	loaded, err := store.loadHNSW(ctx, tx)
+	require.NoError(t, err)
+	return loaded
+}
+
+func randomDocs(rng *rand.Rand, n, dims int) []vectorstore.Document {
+	docs := make([]vectorstore.Document, n)
+	for i := range docs {
+		vector := make(embedding.Vector, dims)
+		for j := range vector {
+			vector[j] = rng.Float32()*2 - 1
+		}
+		docs[i] = vectorstore.Document{
+			ID:       fmt.Sprintf("doc%04d", i),
+			Content:  fmt.Sprintf("document %d", i),
+			Vector:   vector,
+			Metadata: map[string]interface{}{"file_path": fmt.Sprintf("file%d.go", i%10)},
+		}
+	}
+	return docs
+}
+
+func TestHNSWGraph_PersistedAcrossReopen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "index.db")
+	docs := randomDocs(rand.New(rand.NewSource(1)), 50, 8)
+
+	store, err := NewStore(path)
+	require.NoError(t, err)
+	require.NoError(t, store.UpsertBatch(ctx, docs))
+	require.NoError(t, store.Delete(ctx, "doc0000"))
+	require.NoError(t, store.Upsert(ctx, vectorstore.Document{ID: "doc0001", Content: "moved", Vector: docs[2].Vector}))
+	want := store.hnswIndex.IDs()
+	require.Len(t, want, 49)
+	require.NoError(t, store.Close())
+
+	store, err = NewStore(path)
+	require.NoError(t, err)
+	defer store.Close()
+
+	assert.Empty(t, store.hnswIndex.IDs(), "the graph is loaded lazily")
+	require.True(t, persistedGraphMatches(t, store), "no rebuild is needed")
+	assert.Equal(t, want, store.hnswIndex.IDs())
+
+	candidates, err := store.hnswIndex.Search(docs[5].Vector, 1, 32)
+	require.NoError(t, err)
+	require.Len(t, candidates, 1)
+	assert.Equal(t, "doc0005", candidates[0].ID)
+
+	// Incremental updates keep the persisted graph current
+	require.NoError(t, store.Delete(ctx, "doc0005"))
+	assert.True(t, persistedGraphMatches(t, store))
+	assert.NotContains(t, store.hnswIndex.IDs(), "doc0005")
+}
+
+func TestHNSWGraph_RebuiltOnMismatch(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "index.db")
+	docs := randomDocs(rand.New(rand.NewSource(2)), 10, 8)
+
+	store, err := NewStore(path)
+	require.NoError(t, err)
+	require.NoError(t, store.UpsertBatch(ctx, docs[:9]))
+
+	// A write that bypasses the graph, as by an older version
+	_, err = store.db.ExecContext(ctx,
+		"INSERT INTO documents (id, content, vector, created_at, updated_at) VALUES (?, ?, ?, 0, 0)",
+		docs[9].ID, docs[9].Content, encodeVector(docs[9].Vector, VectorEncodingFloat32))
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+
+	store, err = NewStore(path)
+	require.NoError(t, err)
+	defer store.Close()
+	assert.False(t, persistedGraphMatches(t, store))
+
+	report, err := store.CheckIntegrity(ctx, 8)
+	require.NoError(t, err)
+	assert.Empty(t, report.HNSWOrphaned)
+	assert.Contains(t, store.hnswIndex.IDs(), docs[9].ID)
+	assert.True(t, persistedGraphMatches(t, store), "the rebuilt graph is persisted")
+}
+
+func TestSearchVector_LargeStoreUsesGraph(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	docs := randomDocs(rand.New(rand.NewSource(3)), hnswSearchThreshold+100, 8)
+	docs[42].Metadata["file_path"] = "rare.go"
+	require.NoError(t, store.UpsertBatch(ctx, docs))
+
+	results, err := store.SearchVector(ctx, docs[7].Vector, vectorstore.SearchOptions{Limit: 3})
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	assert.Equal(t, "doc0007", results[0].Document.ID)
+	assert.InDelta(t, 1.0, results[0].Score, 1e-4)
+
+	// Filters the graph's candidates miss fall back to brute force
+	results, err = store.SearchVector(ctx, docs[7].Vector, vectorstore.SearchOptions{
+		Limit:   3,
+		Filters: map[string]interface{}{"file_path": "rare.go"},
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "doc0042", results[0].Document.ID)
+}
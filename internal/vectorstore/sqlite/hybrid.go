@@ -51,6 +51,7 @@ func (s *Store) SearchHybrid(ctx context.Context, query string, vector embedding
 		Offset:    hybridOpts.Offset,
 		Threshold: opts.Threshold,
 		Filters:   opts.Filters,
+		Filter:    opts.Filter,
 	}
 
 	var bm25Results []vectorstore.SearchResult
@@ -0,0 +1,90 @@
+package sqlite
+
+import (
+	"database/sql/driver"
+	"regexp"
+	"strings"
+	"unicode"
+
+	sqlitedriver "modernc.org/sqlite"
+)
+
+// identifierPattern matches identifiers, including snake_case, kebab-case and
+// dotted ones such as cfg.Server.Port.
+var identifierPattern = regexp.MustCompile(`[\p{L}\p{N}_]+(?:[.\-][\p{L}\p{N}_]+)*`)
+
+func init() {
+	// The FTS triggers fill the identifiers column of documents_fts with it
+	sqlitedriver.MustRegisterDeterministicScalarFunction("identifier_terms", 1,
+		func(_ *sqlitedriver.FunctionContext, args []driver.Value) (driver.Value, error) {
+			switch content := args[0].(type) {
+			case string:
+				return identifierTerms(content), nil
+			case []byte:
+				return identifierTerms(string(content)), nil
+			default:
+				return "", nil
+			}
+		})
+}
+
+// splitIdentifier returns the lowercase words of an identifier, splitting
+// camelCase and PascalCase words and snake_case, kebab-case and dotted
+// separators: parseFTS5Query yields parse, fts5, query.
+func splitIdentifier(identifier string) []string {
+	var parts []string
+	for _, segment := range strings.FieldsFunc(identifier, func(r rune) bool {
+		return r == '_' || r == '-' || r == '.'
+	}) {
+		runes := []rune(segment)
+		start := 0
+		for i := 1; i < len(runes); i++ {
+			prev, r := runes[i-1], runes[i]
+			if unicode.IsUpper(r) && (unicode.IsLower(prev) || unicode.IsDigit(prev) ||
+				unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				parts = append(parts, strings.ToLower(string(runes[start:i])))
+				start = i
+			}
+		}
+		parts = append(parts, strings.ToLower(string(runes[start:])))
+	}
+	return parts
+}
+
+// identifierTerms returns the terms indexed for the compound identifiers of
+// content: each one joined into a single word, as the default tokenizer
+// keeps camelCase identifiers, followed by its words. Terms of plain words
+// are left to the content column.
+func identifierTerms(content string) string {
+	var b strings.Builder
+	for _, identifier := range identifierPattern.FindAllString(content, -1) {
+		parts := splitIdentifier(identifier)
+		if len(parts) < 2 {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(strings.Join(parts, ""))
+		for _, part := range parts {
+			b.WriteByte(' ')
+			b.WriteString(part)
+		}
+	}
+	return b.String()
+}
+
+// identifierQuery returns the FTS5 query for a bare query word. A compound
+// identifier matches either its joined form or the phrase of its words, so
+// getUserByID, get_user_by_id and "user by id" find each other. Words FTS5
+// cannot parse as barewords, like dotted paths, are quoted.
+func identifierQuery(word string) string {
+	if identifierPattern.FindString(word) == word {
+		parts := splitIdentifier(word)
+		if len(parts) < 2 {
+			return word
+		}
+		return "(" + strings.Join(parts, "") + ` OR "` + strings.Join(parts, " ") + `")`
+	}
+	return `"` + strings.ReplaceAll(word, `"`, `""`) + `"`
+}
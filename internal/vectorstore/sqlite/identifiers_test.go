@@ -0,0 +1,98 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ferg-cod3s/conexus/internal/embedding"
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+)
+
+func TestSplitIdentifier(t *testing.T) {
+	tests := map[string][]string{
+		"parseFTS5Query":      {"parse", "fts5", "query"},
+		"HNSWIndex":           {"hnsw", "index"},
+		"NewStore":            {"new", "store"},
+		"hnswNormalizeVector": {"hnsw", "normalize", "vector"},
+		"getUserByID":         {"get", "user", "by", "id"},
+		"get_user_by_id":      {"get", "user", "by", "id"},
+		"CONEXUS_DB_PATH":     {"conexus", "db", "path"},
+		"cfg.Server.Port":     {"cfg", "server", "port"},
+		"agentic-conexus":     {"agentic", "conexus"},
+		"json_extract":        {"json", "extract"},
+		"__init__":            {"init"},
+		"port":                {"port"},
+	}
+	for identifier, want := range tests {
+		assert.Equal(t, want, splitIdentifier(identifier), identifier)
+	}
+}
+
+func TestIdentifierTerms(t *testing.T) {
+	assert.Equal(t,
+		"parsefts5query parse fts5 query cfgserverport cfg server port",
+		identifierTerms("// parseFTS5Query reads cfg.Server.Port."))
+	assert.Empty(t, identifierTerms("plain words only"))
+}
+
+func TestParseFTS5Query_Identifiers(t *testing.T) {
+	tests := map[string]string{
+		"getUserByID":            `(getuserbyid OR "get user by id")`,
+		"cfg.Server.Port":        `(cfgserverport OR "cfg server port")`,
+		"CONEXUS_DB_PATH lookup": `(conexusdbpath OR "conexus db path") AND lookup`,
+		"agentic-conexus or mcp": `(agenticconexus OR "agentic conexus") OR mcp`,
+		"handlers.go:42":         `"handlers.go:42"`,
+		"user by id":             "user AND by AND id",
+	}
+	for query, want := range tests {
+		assert.Equal(t, want, parseFTS5Query(query), query)
+	}
+}
+
+func TestSearchBM25_Identifiers(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+
+	docs := map[string]string{
+		"camel":  "func (s *Service) getUserByID(ctx context.Context, id string) (*User, error)",
+		"snake":  "def get_user_by_id(session, user_id): return session.query(User).get(user_id)",
+		"dotted": "addr := fmt.Sprintf(\"%s:%d\", cfg.Server.Host, cfg.Server.Port)",
+		"env":    "CONEXUS_DB_PATH=./data/db.sqlite conexus",
+		"plain":  "the user story mentions an id by name",
+	}
+	for id, content := range docs {
+		require.NoError(t, store.Upsert(ctx, vectorstore.Document{ID: id, Content: content, Vector: embedding.Vector{1, 0}}))
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"user by id", []string{"camel", "plain", "snake"}},
+		{"getUserByID", []string{"camel", "snake"}},
+		{"get_user_by_id", []string{"camel", "snake"}},
+		{"GetUserById", []string{"camel", "snake"}},
+		{"cfg.Server.Port", []string{"dotted"}},
+		{"server port", []string{"dotted"}},
+		{"db path", []string{"env"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			results, err := store.SearchBM25(ctx, tt.query, vectorstore.SearchOptions{Limit: 10})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, resultIDs(results))
+		})
+	}
+
+	// Updates keep the identifiers in sync
+	require.NoError(t, store.Upsert(ctx, vectorstore.Document{ID: "camel", Content: "func lookupAccount()", Vector: embedding.Vector{1, 0}}))
+	results, err := store.SearchBM25(ctx, "lookup account", vectorstore.SearchOptions{Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"camel"}, resultIDs(results))
+	results, err = store.SearchBM25(ctx, "getUserByID", vectorstore.SearchOptions{Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"snake"}, resultIDs(results))
+}
@@ -0,0 +1,148 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+)
+
+// CheckIntegrity reports documents with unexpected vector dimensions, FTS rows
+// out of sync with the documents table, and HNSW nodes with no document.
+// Documents outside any collection are checked against dimensions when it is
+// positive, and collection documents against the dimensions of their
+// collection when it sets them.
+func (s *Store) CheckIntegrity(ctx context.Context, dimensions int) (*vectorstore.IntegrityReport, error) {
+	report := &vectorstore.IntegrityReport{}
+	var err error
+
+	report.DimensionMismatches, err = queryIDs(ctx, s.db, `
+		SELECT d.id FROM documents d
+		LEFT JOIN collections c ON c.name = d.meta_collection
+		WHERE (d.meta_collection IS NULL AND ? > 0 AND `+vectorDimensionsSQL+` != ?)
+		OR (c.dimensions > 0 AND `+vectorDimensionsSQL+` != c.dimensions)
+		ORDER BY d.id`, dimensions, dimensions)
+	if err != nil {
+		return nil, fmt.Errorf("check vector dimensions: %w", err)
+	}
+
+	// EXCEPT compares the tables with a temporary b-tree rather than probing
+	// the unindexed FTS id column once per document.
+	report.FTSMissing, err = queryIDs(ctx, s.db,
+		"SELECT id FROM documents EXCEPT SELECT id FROM documents_fts ORDER BY 1")
+	if err != nil {
+		return nil, fmt.Errorf("check missing fts rows: %w", err)
+	}
+
+	unsynced, err := queryIDs(ctx, s.db, `
+		SELECT id FROM (
+			SELECT id, content FROM documents
+			EXCEPT SELECT id, content FROM documents_fts
+		) ORDER BY 1`)
+	if err != nil {
+		return nil, fmt.Errorf("check stale fts rows: %w", err)
+	}
+	missing := make(map[string]bool, len(report.FTSMissing))
+	for _, id := range report.FTSMissing {
+		missing[id] = true
+	}
+	for _, id := range unsynced {
+		if !missing[id] {
+			report.FTSStale = append(report.FTSStale, id)
+		}
+	}
+
+	report.FTSOrphaned, err = queryIDs(ctx, s.db,
+		"SELECT id FROM documents_fts EXCEPT SELECT id FROM documents ORDER BY 1")
+	if err != nil {
+		return nil, fmt.Errorf("check orphaned fts rows: %w", err)
+	}
+
+	s.hnswMu.Lock()
+	err = s.ensureHNSW(ctx)
+	nodeIDs := s.hnswIndex.IDs()
+	s.hnswMu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("load HNSW graph: %w", err)
+	}
+	for _, id := range nodeIDs {
+		var exists bool
+		if err := s.db.QueryRowContext(ctx,
+			"SELECT EXISTS(SELECT 1 FROM documents WHERE id = ?)", id,
+		).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("check hnsw node %s: %w", id, err)
+		}
+		if !exists {
+			report.HNSWOrphaned = append(report.HNSWOrphaned, id)
+		}
+	}
+
+	return report, nil
+}
+
+// RepairIntegrity fixes the problems in report. Documents with mismatched
+// dimensions are deleted so they can be re-embedded, FTS rows are rebuilt from
+// the documents table, and orphaned HNSW nodes are removed.
+func (s *Store) RepairIntegrity(ctx context.Context, report *vectorstore.IntegrityReport) error {
+	if report == nil {
+		return nil
+	}
+
+	return s.writeDocuments(ctx, func(tx *sql.Tx) ([]string, []vectorstore.Document, error) {
+		for _, id := range report.DimensionMismatches {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM documents WHERE id = ?", id); err != nil {
+				return nil, nil, fmt.Errorf("delete document %s: %w", id, err)
+			}
+		}
+
+		for _, id := range report.FTSOrphaned {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM documents_fts WHERE id = ?", id); err != nil {
+				return nil, nil, fmt.Errorf("delete fts row %s: %w", id, err)
+			}
+		}
+
+		rebuild := append(append([]string{}, report.FTSMissing...), report.FTSStale...)
+		for _, id := range rebuild {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM documents_fts WHERE id = ?", id); err != nil {
+				return nil, nil, fmt.Errorf("delete fts row %s: %w", id, err)
+			}
+			if _, err := tx.ExecContext(ctx,
+				"INSERT INTO documents_fts(id, content, identifiers) SELECT id, content, identifier_terms(content) FROM documents WHERE id = ?", id,
+			); err != nil {
+				return nil, nil, fmt.Errorf("rebuild fts row %s: %w", id, err)
+			}
+		}
+
+		return append(append([]string{}, report.DimensionMismatches...), report.HNSWOrphaned...), nil, nil
+	})
+}
+
+// queryer is implemented by *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// queryIDs runs a query returning a single ID column.
+func queryIDs(ctx context.Context, q queryer, query string, args ...interface{}) ([]string, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id sql.NullString
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan id: %w", err)
+		}
+		if id.Valid {
+			ids = append(ids, id.String)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+	return ids, nil
+}
@@ -0,0 +1,91 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ferg-cod3s/conexus/internal/embedding"
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+)
+
+func TestCheckIntegrity_Healthy(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	require.NoError(t, store.Upsert(ctx, vectorstore.Document{
+		ID: "doc1", Content: "hello world", Vector: embedding.Vector{0.1, 0.2, 0.3},
+	}))
+
+	report, err := store.CheckIntegrity(ctx, 3)
+	require.NoError(t, err)
+	assert.Empty(t, report.DimensionMismatches)
+	assert.Empty(t, report.FTSMissing)
+	assert.Empty(t, report.FTSStale)
+	assert.Empty(t, report.FTSOrphaned)
+	assert.Empty(t, report.HNSWOrphaned)
+}
+
+func TestCheckIntegrity_DetectsAndRepairs(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	for _, doc := range []vectorstore.Document{
+		{ID: "ok", Content: "alpha", Vector: embedding.Vector{0.1, 0.2, 0.3}},
+		{ID: "short", Content: "beta", Vector: embedding.Vector{0.1, 0.2}},
+		{ID: "nofts", Content: "gamma", Vector: embedding.Vector{0.1, 0.2, 0.3}},
+		{ID: "stale", Content: "delta", Vector: embedding.Vector{0.1, 0.2, 0.3}},
+	} {
+		require.NoError(t, store.Upsert(ctx, doc))
+	}
+
+	// Corrupt the FTS table behind the triggers' back
+	_, err := store.db.ExecContext(ctx, "DELETE FROM documents_fts WHERE id = 'nofts'")
+	require.NoError(t, err)
+	_, err = store.db.ExecContext(ctx, "UPDATE documents_fts SET content = 'outdated' WHERE id = 'stale'")
+	require.NoError(t, err)
+	_, err = store.db.ExecContext(ctx, "INSERT INTO documents_fts(id, content) VALUES ('ghost', 'orphan')")
+	require.NoError(t, err)
+	require.NoError(t, store.hnswIndex.Insert("ghost", embedding.Vector{0.3, 0.2, 0.1}))
+
+	report, err := store.CheckIntegrity(ctx, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"short"}, report.DimensionMismatches)
+	assert.Equal(t, []string{"nofts"}, report.FTSMissing)
+	assert.Equal(t, []string{"stale"}, report.FTSStale)
+	assert.Equal(t, []string{"ghost"}, report.FTSOrphaned)
+	assert.Equal(t, []string{"ghost"}, report.HNSWOrphaned)
+
+	require.NoError(t, store.RepairIntegrity(ctx, report))
+
+	report, err = store.CheckIntegrity(ctx, 3)
+	require.NoError(t, err)
+	assert.Empty(t, report.DimensionMismatches)
+	assert.Empty(t, report.FTSMissing)
+	assert.Empty(t, report.FTSStale)
+	assert.Empty(t, report.FTSOrphaned)
+	assert.Empty(t, report.HNSWOrphaned)
+
+	_, err = store.Get(ctx, "short")
+	assert.Error(t, err, "mismatched documents are deleted for re-embedding")
+
+	results, err := store.SearchBM25(ctx, "gamma", vectorstore.SearchOptions{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "nofts", results[0].Document.ID)
+}
+
+func TestCheckIntegrity_SkipsDimensionsWhenUnknown(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	require.NoError(t, store.Upsert(ctx, vectorstore.Document{
+		ID: "doc1", Content: "hello", Vector: embedding.Vector{0.1, 0.2},
+	}))
+
+	report, err := store.CheckIntegrity(ctx, 0)
+	require.NoError(t, err)
+	assert.Empty(t, report.DimensionMismatches)
+}
@@ -0,0 +1,186 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ferg-cod3s/conexus/internal/embedding"
+	"github.com/ferg-cod3s/conexus/internal/migrate"
+)
+
+// migrationComponent is the name the vector store migrations are recorded
+// under in schema_migrations.
+const migrationComponent = "vectorstore"
+
+// vectorConversionBatch is the number of vectors converted per query.
+const vectorConversionBatch = 500
+
+// NewMigrator returns the migrator of the vector store tables in db. Stores
+// apply it when opened; it is exported to report the status of a database.
+func NewMigrator(db *sql.DB) (*migrate.Migrator, error) {
+	return newMigrator(db, VectorEncodingFloat32)
+}
+
+// newMigrator returns the vector store migrations, converting vectors with
+// the given encoding. They upgrade the base tables created by initSchema:
+// version 1 stores vectors as encoded BLOBs instead of JSON text, version 2
+// adds the quantizer of the persisted HNSW graph, version 3 adds indexed
+// generated columns for the hot metadata keys, version 4 indexes the words of
+// compound identifiers for full-text search, version 5 adds the trigram
+// index of file texts, version 6 adds collections, and version 7 records when
+// each file text was indexed. The version is mirrored in
+// PRAGMA user_version, which recorded it before schema_migrations existed.
+func newMigrator(db *sql.DB, encoding VectorEncoding) (*migrate.Migrator, error) {
+	m, err := migrate.New(db, migrationComponent,
+		migrate.Migration{Version: 1, Name: "vector_blobs", Up: func(ctx context.Context, tx *sql.Tx) error {
+			if err := convertJSONVectors(ctx, tx, encoding); err != nil {
+				return err
+			}
+			// Graphs persisted before version 1 store vectors without an encoding tag
+			for _, stmt := range []string{"DELETE FROM hnsw_nodes", "DELETE FROM hnsw_meta"} {
+				if _, err := tx.ExecContext(ctx, stmt); err != nil {
+					return fmt.Errorf("clear HNSW graph: %w", err)
+				}
+			}
+			return nil
+		}},
+		migrate.Migration{Version: 2, Name: "hnsw_quantizer", Up: func(ctx context.Context, tx *sql.Tx) error {
+			return addColumn(ctx, tx, "hnsw_meta", "quantizer", "BLOB")
+		}},
+		migrate.Migration{Version: 3, Name: "metadata_columns", Up: addMetadataColumns},
+		migrate.Migration{Version: 4, Name: "fts_identifiers", Up: addIdentifierTerms},
+		migrate.Migration{Version: 5, Name: "grep_trigrams", Up: addGrepTrigrams},
+		migrate.Migration{Version: 6, Name: "collections", Up: addCollections},
+		migrate.Migration{Version: 7, Name: "grep_indexed_at", Up: func(ctx context.Context, tx *sql.Tx) error {
+			// Texts indexed before version 7 count as stale
+			return addColumn(ctx, tx, "grep_files", "indexed_at", "INTEGER NOT NULL DEFAULT 0")
+		}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	m.UserVersion = true
+	return m, nil
+}
+
+// migrate upgrades a database created by an older version in place, backing
+// up the database file at path first when it existed before.
+func (s *Store) migrate(ctx context.Context, path string) error {
+	m, err := newMigrator(s.db, s.vectorEncoding)
+	if err != nil {
+		return err
+	}
+	m.DatabasePath = path
+	_, err = m.Up(ctx)
+	return err
+}
+
+// addColumn adds a column to table unless it exists.
+func addColumn(ctx context.Context, tx *sql.Tx, table, column, definition string) error {
+	var exists bool
+	if err := tx.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM pragma_table_xinfo(?) WHERE name = ?)", table, column,
+	).Scan(&exists); err != nil {
+		return fmt.Errorf("inspect %s: %w", table, err)
+	}
+	if exists {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("add column %s.%s: %w", table, column, err)
+	}
+	return nil
+}
+
+// addMetadataColumns exposes each of indexedMetadataKeys as an indexed
+// generated column, replacing the expression index on repo_id.
+func addMetadataColumns(ctx context.Context, tx *sql.Tx) error {
+	for _, key := range indexedMetadataKeys {
+		column := "meta_" + key
+		definition := fmt.Sprintf("GENERATED ALWAYS AS (json_extract(metadata, '%s')) VIRTUAL", metadataPath(key))
+		if err := addColumn(ctx, tx, "documents", column, definition); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(
+			"CREATE INDEX IF NOT EXISTS idx_documents_%s ON documents(%s)", column, column,
+		)); err != nil {
+			return fmt.Errorf("index %s: %w", column, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "DROP INDEX IF EXISTS idx_documents_repo_id"); err != nil {
+		return fmt.Errorf("drop repo_id index: %w", err)
+	}
+	return nil
+}
+
+// addIdentifierTerms rebuilds documents_fts with an identifiers column that
+// holds the identifierTerms of the content, and the triggers filling it.
+func addIdentifierTerms(ctx context.Context, tx *sql.Tx) error {
+	for _, stmt := range []string{
+		"DROP TRIGGER IF EXISTS documents_ai",
+		"DROP TRIGGER IF EXISTS documents_au",
+		"DROP TABLE IF EXISTS documents_fts",
+		`CREATE VIRTUAL TABLE documents_fts USING fts5(
+			id UNINDEXED,
+			content,
+			identifiers,
+			tokenize='porter unicode61'
+		)`,
+		`CREATE TRIGGER documents_ai AFTER INSERT ON documents BEGIN
+			INSERT INTO documents_fts(id, content, identifiers) VALUES (new.id, new.content, identifier_terms(new.content));
+		END`,
+		`CREATE TRIGGER documents_au AFTER UPDATE ON documents BEGIN
+			UPDATE documents_fts SET content = new.content, identifiers = identifier_terms(new.content) WHERE id = old.id;
+		END`,
+		"INSERT INTO documents_fts(id, content, identifiers) SELECT id, content, identifier_terms(content) FROM documents",
+	} {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("rebuild full-text index: %w", err)
+		}
+	}
+	return nil
+}
+
+// convertJSONVectors rewrites the vectors stored as JSON text, as by versions
+// before schema version 1, with the given encoding.
+func convertJSONVectors(ctx context.Context, tx *sql.Tx, encoding VectorEncoding) error {
+	for {
+		rows, err := tx.QueryContext(ctx,
+			"SELECT id, vector FROM documents WHERE typeof(vector) = 'text' LIMIT ?", vectorConversionBatch)
+		if err != nil {
+			return fmt.Errorf("query JSON vectors: %w", err)
+		}
+
+		converted := make(map[string][]byte)
+		for rows.Next() {
+			var id string
+			var vectorJSON []byte
+			if err := rows.Scan(&id, &vectorJSON); err != nil {
+				rows.Close()
+				return fmt.Errorf("scan vector: %w", err)
+			}
+			var vector embedding.Vector
+			if err := json.Unmarshal(vectorJSON, &vector); err != nil {
+				rows.Close()
+				return fmt.Errorf("unmarshal vector of %s: %w", id, err)
+			}
+			converted[id] = encodeVector(vector, encoding)
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return fmt.Errorf("iterate vectors: %w", err)
+		}
+		rows.Close()
+
+		if len(converted) == 0 {
+			return nil
+		}
+		for id, blob := range converted {
+			if _, err := tx.ExecContext(ctx, "UPDATE documents SET vector = ? WHERE id = ?", blob, id); err != nil {
+				return fmt.Errorf("convert vector of %s: %w", id, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ferg-cod3s/conexus/internal/embedding"
+	"github.com/ferg-cod3s/conexus/internal/migrate"
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+)
+
+func TestMigrate_ConvertsJSONVectors(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "index.db")
+
+	store, err := NewStore(path)
+	require.NoError(t, err)
+	for _, id := range []string{"a", "b"} {
+		require.NoError(t, store.Upsert(ctx, vectorstore.Document{
+			ID: id, Content: "hello " + id, Vector: embedding.Vector{0.5, -0.25, 1},
+		}))
+	}
+
+	// Downgrade to the JSON format of schema version 0
+	_, err = store.db.ExecContext(ctx, "UPDATE documents SET vector = '[0.5,-0.25,1]'")
+	require.NoError(t, err)
+	for _, stmt := range []string{"DROP TABLE schema_migrations", "PRAGMA user_version = 0"} {
+		_, err = store.db.ExecContext(ctx, stmt)
+		require.NoError(t, err)
+	}
+	require.NoError(t, store.Close())
+
+	store, err = NewStore(path)
+	require.NoError(t, err)
+	defer store.Close()
+
+	var version int
+	require.NoError(t, store.db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version))
+	migrator, err := NewMigrator(store.db)
+	require.NoError(t, err)
+	assert.Equal(t, migrator.Latest(), version)
+	assert.FileExists(t, migrate.BackupPath(path, migrationComponent, 0), "the database is backed up before migrating")
+	statuses, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	for _, status := range statuses {
+		assert.True(t, status.Applied, status.Name)
+		assert.False(t, status.AppliedAt.IsZero(), status.Name)
+	}
+	var textVectors int
+	require.NoError(t, store.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM documents WHERE typeof(vector) != 'blob'").Scan(&textVectors))
+	assert.Zero(t, textVectors)
+
+	doc, err := store.Get(ctx, "b")
+	require.NoError(t, err)
+	assert.Equal(t, embedding.Vector{0.5, -0.25, 1}, doc.Vector)
+
+	results, err := store.SearchVector(ctx, embedding.Vector{0.5, -0.25, 1}, vectorstore.SearchOptions{Limit: 5})
+	require.NoError(t, err)
+	assert.Len(t, results, 2)
+	report, err := store.CheckIntegrity(ctx, 3)
+	require.NoError(t, err)
+	assert.Empty(t, report.HNSWOrphaned)
+	assert.Len(t, store.hnswIndex.IDs(), 2)
+}
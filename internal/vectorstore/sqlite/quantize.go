@@ -0,0 +1,466 @@
+package sqlite
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/ferg-cod3s/conexus/internal/embedding"
+)
+
+// Quantization selects how the HNSW graph keeps node vectors in memory.
+// Quantized graphs are traversed with compressed codes; searches re-score
+// their candidates exactly from the full-precision vectors in the documents
+// table.
+type Quantization string
+
+const (
+	// QuantizationNone keeps full float32 vectors (the default).
+	QuantizationNone Quantization = "none"
+	// QuantizationScalar stores one byte per dimension, scaled between the
+	// per-dimension minimum and maximum of the training vectors.
+	QuantizationScalar Quantization = "int8"
+	// QuantizationProduct splits vectors into subvectors and stores the index
+	// of the nearest of 256 k-means centroids for each, one byte per subvector.
+	QuantizationProduct Quantization = "pq"
+)
+
+// defaultQuantizerTrainingSize is the number of graph nodes the quantizer is
+// trained on. Smaller graphs keep full vectors.
+const defaultQuantizerTrainingSize = hnswSearchThreshold
+
+// pqCentroids is the number of centroids per subvector, so codes fit a byte.
+const pqCentroids = 256
+
+// pqIterations bounds the k-means iterations of product quantizer training.
+const pqIterations = 10
+
+// QuantizationConfig configures the compression of HNSW node vectors.
+type QuantizationConfig struct {
+	Type         Quantization // Compression of node vectors (default: none)
+	Subvectors   int          // Subvectors per vector for product quantization (default: dimensions/8)
+	TrainingSize int          // Nodes collected before training the quantizer (default: 1000)
+}
+
+// ParseQuantization parses a quantization name. An empty name selects
+// QuantizationNone.
+func ParseQuantization(name string) (Quantization, error) {
+	switch Quantization(name) {
+	case "", QuantizationNone:
+		return QuantizationNone, nil
+	case QuantizationScalar:
+		return QuantizationScalar, nil
+	case QuantizationProduct:
+		return QuantizationProduct, nil
+	default:
+		return "", fmt.Errorf("unknown quantization %q (want none, int8 or pq)", name)
+	}
+}
+
+// enabled reports whether node vectors are compressed.
+func (c QuantizationConfig) enabled() bool {
+	return c.Type != "" && c.Type != QuantizationNone
+}
+
+// equal reports whether c and other produce the same graph.
+func (c QuantizationConfig) equal(other QuantizationConfig) bool {
+	if !c.enabled() || !other.enabled() {
+		return c.enabled() == other.enabled()
+	}
+	return c == other
+}
+
+// trainingSize returns the number of nodes the quantizer is trained on.
+func (c QuantizationConfig) trainingSize() int {
+	if c.TrainingSize > 0 {
+		return c.TrainingSize
+	}
+	return defaultQuantizerTrainingSize
+}
+
+// quantizer compresses normalized vectors into byte codes.
+type quantizer interface {
+	encode(v embedding.Vector) []byte
+	decode(code []byte) embedding.Vector
+	// prepare returns the distances of a normalized query to codes
+	prepare(query embedding.Vector) codeDistance
+	state() *quantizerState
+}
+
+// codeDistance returns the approximate cosine distance of a query to a code.
+type codeDistance func(code []byte) float32
+
+// quantizerState is the serialized form of a trained quantizer.
+type quantizerState struct {
+	Scalar  *scalarQuantizer
+	Product *productQuantizer
+}
+
+// trainQuantizer trains a quantizer of the configured type on vectors.
+func trainQuantizer(config QuantizationConfig, vectors []embedding.Vector) quantizer {
+	switch config.Type {
+	case QuantizationScalar:
+		return trainScalarQuantizer(vectors)
+	case QuantizationProduct:
+		return trainProductQuantizer(vectors, config.Subvectors)
+	default:
+		return nil
+	}
+}
+
+// encodeQuantizer serializes a trained quantizer, or returns nil for none.
+func encodeQuantizer(q *quantizerState) ([]byte, error) {
+	if q == nil {
+		return nil, nil
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(q); err != nil {
+		return nil, fmt.Errorf("encode quantizer: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeQuantizer reads a quantizer written by encodeQuantizer.
+func decodeQuantizer(data []byte) (*quantizerState, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var q quantizerState
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&q); err != nil {
+		return nil, fmt.Errorf("decode quantizer: %w", err)
+	}
+	return &q, nil
+}
+
+// quantizer returns the quantizer described by q, or nil.
+func (q *quantizerState) quantizer() quantizer {
+	switch {
+	case q == nil:
+		return nil
+	case q.Scalar != nil:
+		return q.Scalar
+	case q.Product != nil:
+		return q.Product
+	default:
+		return nil
+	}
+}
+
+// dotDistance converts the dot product of normalized vectors to a cosine
+// distance, clamped like cosineDistance.
+func dotDistance(dot float32) float32 {
+	if dot > 1.0 {
+		dot = 1.0
+	} else if dot < -1.0 {
+		dot = -1.0
+	}
+	return 1.0 - dot
+}
+
+// scalarQuantizer maps each dimension linearly onto 256 levels.
+type scalarQuantizer struct {
+	Min   []float32 // Per-dimension minimum of the training vectors
+	Scale []float32 // Per-dimension width of a level
+}
+
+func trainScalarQuantizer(vectors []embedding.Vector) *scalarQuantizer {
+	dim := len(vectors[0])
+	q := &scalarQuantizer{Min: make([]float32, dim), Scale: make([]float32, dim)}
+	maxValues := make([]float32, dim)
+	copy(q.Min, vectors[0])
+	copy(maxValues, vectors[0])
+	for _, v := range vectors[1:] {
+		for i, f := range v {
+			if f < q.Min[i] {
+				q.Min[i] = f
+			}
+			if f > maxValues[i] {
+				maxValues[i] = f
+			}
+		}
+	}
+	for i := range q.Scale {
+		q.Scale[i] = (maxValues[i] - q.Min[i]) / 255
+		if q.Scale[i] == 0 {
+			q.Scale[i] = 1
+		}
+	}
+	return q
+}
+
+func (q *scalarQuantizer) encode(v embedding.Vector) []byte {
+	code := make([]byte, len(v))
+	for i, f := range v {
+		// Values outside the training range saturate
+		level := math.Round(float64((f - q.Min[i]) / q.Scale[i]))
+		code[i] = byte(math.Max(0, math.Min(255, level)))
+	}
+	return code
+}
+
+func (q *scalarQuantizer) decode(code []byte) embedding.Vector {
+	v := make(embedding.Vector, len(code))
+	for i, c := range code {
+		v[i] = q.Min[i] + float32(c)*q.Scale[i]
+	}
+	return v
+}
+
+func (q *scalarQuantizer) prepare(query embedding.Vector) codeDistance {
+	// query·decode(code) = Σ query[i]*Min[i] + Σ query[i]*Scale[i]*code[i]
+	var offset float32
+	weights := make([]float32, len(query))
+	for i, f := range query {
+		offset += f * q.Min[i]
+		weights[i] = f * q.Scale[i]
+	}
+	return func(code []byte) float32 {
+		dot := offset
+		for i, c := range code {
+			dot += weights[i] * float32(c)
+		}
+		return dotDistance(dot)
+	}
+}
+
+func (q *scalarQuantizer) state() *quantizerState {
+	return &quantizerState{Scalar: q}
+}
+
+// productQuantizer encodes each subvector as its nearest k-means centroid.
+type productQuantizer struct {
+	Dim        int       // Vector dimension
+	Subvectors int       // Subvectors per vector, dividing Dim
+	Centroids  int       // Centroids per subvector, at most pqCentroids
+	Codebook   []float32 // Centroid c of subvector j starts at ((j*Centroids)+c)*Dim/Subvectors
+}
+
+// pqSubvectors returns the largest divisor of dim not above requested, or
+// above dim/8 when requested is unset.
+func pqSubvectors(dim, requested int) int {
+	m := requested
+	if m <= 0 {
+		m = dim / 8
+	}
+	if m > dim {
+		m = dim
+	}
+	if m < 1 {
+		m = 1
+	}
+	for dim%m != 0 {
+		m--
+	}
+	return m
+}
+
+func trainProductQuantizer(vectors []embedding.Vector, subvectors int) *productQuantizer {
+	dim := len(vectors[0])
+	m := pqSubvectors(dim, subvectors)
+	sub := dim / m
+	k := pqCentroids
+	if len(vectors) < k {
+		k = len(vectors)
+	}
+
+	q := &productQuantizer{
+		Dim:        dim,
+		Subvectors: m,
+		Centroids:  k,
+		Codebook:   make([]float32, m*k*sub),
+	}
+	// Deterministic seeding keeps rebuilt graphs reproducible
+	rng := rand.New(rand.NewSource(1))
+	assignments := make([]int, len(vectors))
+	sums := make([]float32, k*sub)
+	counts := make([]int, k)
+
+	for j := 0; j < m; j++ {
+		centroids := q.Codebook[j*k*sub : (j+1)*k*sub]
+		for c, i := range rng.Perm(len(vectors))[:k] {
+			copy(centroids[c*sub:(c+1)*sub], vectors[i][j*sub:(j+1)*sub])
+		}
+
+		for iteration := 0; iteration < pqIterations; iteration++ {
+			changed := false
+			for i := range sums {
+				sums[i] = 0
+			}
+			for i := range counts {
+				counts[i] = 0
+			}
+			for i, v := range vectors {
+				part := v[j*sub : (j+1)*sub]
+				c := nearestCentroid(centroids, part)
+				if c != assignments[i] || iteration == 0 {
+					changed = true
+				}
+				assignments[i] = c
+				counts[c]++
+				for d, f := range part {
+					sums[c*sub+d] += f
+				}
+			}
+			if !changed {
+				break
+			}
+			// Empty clusters keep their centroid
+			for c, n := range counts {
+				if n == 0 {
+					continue
+				}
+				for d := 0; d < sub; d++ {
+					centroids[c*sub+d] = sums[c*sub+d] / float32(n)
+				}
+			}
+		}
+	}
+	return q
+}
+
+// nearestCentroid returns the index of the centroid closest to v in
+// Euclidean distance.
+func nearestCentroid(centroids []float32, v []float32) int {
+	sub := len(v)
+	best, bestDist := 0, float32(math.MaxFloat32)
+	for c := 0; c*sub < len(centroids); c++ {
+		var dist float32
+		for d, f := range v {
+			diff := f - centroids[c*sub+d]
+			dist += diff * diff
+		}
+		if dist < bestDist {
+			best, bestDist = c, dist
+		}
+	}
+	return best
+}
+
+func (q *productQuantizer) encode(v embedding.Vector) []byte {
+	sub := q.Dim / q.Subvectors
+	code := make([]byte, q.Subvectors)
+	for j := range code {
+		centroids := q.Codebook[j*q.Centroids*sub : (j+1)*q.Centroids*sub]
+		code[j] = byte(nearestCentroid(centroids, v[j*sub:(j+1)*sub]))
+	}
+	return code
+}
+
+func (q *productQuantizer) decode(code []byte) embedding.Vector {
+	sub := q.Dim / q.Subvectors
+	v := make(embedding.Vector, 0, q.Dim)
+	for j, c := range code {
+		start := (j*q.Centroids + int(c)) * sub
+		v = append(v, q.Codebook[start:start+sub]...)
+	}
+	return v
+}
+
+func (q *productQuantizer) prepare(query embedding.Vector) codeDistance {
+	// Precompute the dot product of each query subvector with every centroid,
+	// so a distance costs one lookup per subvector
+	sub := q.Dim / q.Subvectors
+	table := make([]float32, q.Subvectors*q.Centroids)
+	for j := 0; j < q.Subvectors; j++ {
+		part := query[j*sub : (j+1)*sub]
+		for c := 0; c < q.Centroids; c++ {
+			centroid := q.Codebook[(j*q.Centroids+c)*sub:]
+			var dot float32
+			for d, f := range part {
+				dot += f * centroid[d]
+			}
+			table[j*q.Centroids+c] = dot
+		}
+	}
+	return func(code []byte) float32 {
+		var dot float32
+		for j, c := range code {
+			dot += table[j*q.Centroids+int(c)]
+		}
+		return dotDistance(dot)
+	}
+}
+
+func (q *productQuantizer) state() *quantizerState {
+	return &quantizerState{Product: q}
+}
+
+// hnswQuery is a normalized vector whose distances to graph nodes are
+// computed from their codes once the index is quantized.
+type hnswQuery struct {
+	vector embedding.Vector
+	codes  codeDistance // nil while the index keeps full vectors
+}
+
+// newQuery prepares a normalized vector for the index. Callers must hold mu.
+func (hnsw *HNSWIndex) newQuery(vector embedding.Vector) *hnswQuery {
+	query := &hnswQuery{vector: vector}
+	if hnsw.quantizer != nil {
+		query.codes = hnsw.quantizer.prepare(vector)
+	}
+	return query
+}
+
+// distance returns the cosine distance of the query to node.
+func (q *hnswQuery) distance(node *HNSWNode) float32 {
+	if node.Vector != nil || q.codes == nil {
+		return cosineDistance(q.vector, node.Vector)
+	}
+	return q.codes(node.Code)
+}
+
+// nodeVector returns the vector of node, reconstructed from its code once the
+// index is quantized. Callers must hold mu.
+func (hnsw *HNSWIndex) nodeVector(node *HNSWNode) embedding.Vector {
+	if node.Vector != nil || hnsw.quantizer == nil {
+		return node.Vector
+	}
+	return hnsw.quantizer.decode(node.Code)
+}
+
+// quantize trains the configured quantizer on the current nodes and replaces
+// their vectors with codes. Callers must hold mu.
+func (hnsw *HNSWIndex) quantize() {
+	vectors := make([]embedding.Vector, 0, len(hnsw.nodes))
+	for _, node := range hnsw.nodes {
+		if !node.Deleted {
+			vectors = append(vectors, node.Vector)
+		}
+	}
+	if len(vectors) == 0 {
+		return
+	}
+
+	hnsw.quantizer = trainQuantizer(hnsw.config.Quantization, vectors)
+	if hnsw.quantizer == nil {
+		return
+	}
+	for id, node := range hnsw.nodes {
+		node.Code = hnsw.quantizer.encode(node.Vector)
+		node.Vector = nil
+		hnsw.dirty[id] = true
+	}
+}
+
+// quantizerState returns the serialized quantizer, or nil. Callers must hold
+// mu.
+func (hnsw *HNSWIndex) quantizerState() *quantizerState {
+	if hnsw.quantizer == nil {
+		return nil
+	}
+	return hnsw.quantizer.state()
+}
+
+// vectorBytes returns the memory held by node vectors and codes.
+func (hnsw *HNSWIndex) vectorBytes() int {
+	hnsw.mu.RLock()
+	defer hnsw.mu.RUnlock()
+
+	total := 0
+	for _, node := range hnsw.nodes {
+		total += 4*len(node.Vector) + len(node.Code)
+	}
+	return total
+}
@@ -0,0 +1,198 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ferg-cod3s/conexus/internal/embedding"
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+)
+
+// clusteredVectors returns n normalized vectors scattered around a number of
+// random centers, resembling embeddings of related chunks.
+func clusteredVectors(rng *rand.Rand, n, dims, clusters int) []embedding.Vector {
+	centers := make([]embedding.Vector, clusters)
+	for i := range centers {
+		centers[i] = make(embedding.Vector, dims)
+		for j := range centers[i] {
+			centers[i][j] = float32(rng.NormFloat64())
+		}
+	}
+	vectors := make([]embedding.Vector, n)
+	for i := range vectors {
+		center := centers[rng.Intn(clusters)]
+		v := make(embedding.Vector, dims)
+		for j := range v {
+			v[j] = center[j] + 0.5*float32(rng.NormFloat64())
+		}
+		vectors[i] = hnswNormalizeVector(v)
+	}
+	return vectors
+}
+
+// exactNeighbors returns the IDs of the k vectors most similar to query.
+func exactNeighbors(vectors []embedding.Vector, query embedding.Vector, k int) []string {
+	ids := make([]int, len(vectors))
+	for i := range ids {
+		ids[i] = i
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return cosineDistance(query, vectors[ids[i]]) < cosineDistance(query, vectors[ids[j]])
+	})
+	neighbors := make([]string, k)
+	for i := range neighbors {
+		neighbors[i] = fmt.Sprintf("v%d", ids[i])
+	}
+	return neighbors
+}
+
+func TestQuantizers(t *testing.T) {
+	vectors := clusteredVectors(rand.New(rand.NewSource(1)), 400, 32, 8)
+
+	tests := []struct {
+		config   QuantizationConfig
+		codeSize int
+		maxError float32
+	}{
+		{QuantizationConfig{Type: QuantizationScalar}, 32, 0.01},
+		{QuantizationConfig{Type: QuantizationProduct}, 4, 0.15},
+		{QuantizationConfig{Type: QuantizationProduct, Subvectors: 16}, 16, 0.1},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/%d", tt.config.Type, tt.config.Subvectors), func(t *testing.T) {
+			q := trainQuantizer(tt.config, vectors)
+			require.NotNil(t, q)
+
+			// The quantizer survives serialization
+			data, err := encodeQuantizer(q.state())
+			require.NoError(t, err)
+			state, err := decodeQuantizer(data)
+			require.NoError(t, err)
+			q = state.quantizer()
+
+			query := vectors[0]
+			distance := q.prepare(query)
+			var totalError float32
+			for _, v := range vectors[1:] {
+				code := q.encode(v)
+				require.Len(t, code, tt.codeSize)
+				exact := cosineDistance(query, v)
+				assert.InDelta(t, cosineDistance(query, q.decode(code)), distance(code), 1e-4)
+				diff := distance(code) - exact
+				if diff < 0 {
+					diff = -diff
+				}
+				totalError += diff
+			}
+			assert.Less(t, totalError/float32(len(vectors)-1), tt.maxError)
+		})
+	}
+}
+
+func TestPQSubvectors(t *testing.T) {
+	assert.Equal(t, 96, pqSubvectors(768, 0))
+	assert.Equal(t, 48, pqSubvectors(384, 0))
+	assert.Equal(t, 1, pqSubvectors(5, 0))
+	assert.Equal(t, 6, pqSubvectors(12, 7))
+	assert.Equal(t, 12, pqSubvectors(12, 100))
+}
+
+func TestParseQuantization(t *testing.T) {
+	for name, want := range map[string]Quantization{
+		"":     QuantizationNone,
+		"none": QuantizationNone,
+		"int8": QuantizationScalar,
+		"pq":   QuantizationProduct,
+	} {
+		got, err := ParseQuantization(name)
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+	_, err := ParseQuantization("int4")
+	assert.Error(t, err)
+}
+
+func TestHNSWIndex_Quantized(t *testing.T) {
+	vectors := clusteredVectors(rand.New(rand.NewSource(2)), 300, 32, 8)
+
+	for _, quantization := range []Quantization{QuantizationScalar, QuantizationProduct} {
+		t.Run(string(quantization), func(t *testing.T) {
+			config := DefaultHNSWConfig()
+			config.Quantization = QuantizationConfig{Type: quantization, TrainingSize: 100}
+			index := NewHNSWIndex(config)
+
+			for i, v := range vectors {
+				require.NoError(t, index.Insert(fmt.Sprintf("v%d", i), v))
+				if i == 98 {
+					assert.Equal(t, 4*32*99, index.vectorBytes(), "vectors are kept until training")
+				}
+			}
+			node, ok := index.GetNode("v0")
+			require.True(t, ok)
+			assert.Nil(t, node.Vector)
+			assert.NotEmpty(t, node.Code)
+			assert.Less(t, index.vectorBytes(), 32*300+1)
+
+			// Nodes inserted after training are found among the candidates
+			candidates, err := index.Search(vectors[250], 10, 32)
+			require.NoError(t, err)
+			ids := make([]string, len(candidates))
+			for i, c := range candidates {
+				ids[i] = c.ID
+			}
+			assert.Contains(t, ids, "v250")
+
+			_, err = index.Search(embedding.Vector{1, 0}, 10, 32)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestStore_Quantization(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "index.db")
+	docs := randomDocs(rand.New(rand.NewSource(4)), hnswSearchThreshold+100, 16)
+
+	store, err := NewStore(path)
+	require.NoError(t, err)
+	require.NoError(t, store.SetQuantization(QuantizationConfig{Type: QuantizationScalar, TrainingSize: 200}))
+	require.NoError(t, store.UpsertBatch(ctx, docs))
+
+	// Scores come from the full-precision vectors
+	results, err := store.SearchVector(ctx, docs[7].Vector, vectorstore.SearchOptions{Limit: 3})
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	assert.Equal(t, "doc0007", results[0].Document.ID)
+	assert.Equal(t, cosineSimilarity(docs[7].Vector, docs[7].Vector), results[0].Score)
+	require.NoError(t, store.Close())
+
+	store, err = NewStore(path)
+	require.NoError(t, err)
+	require.NoError(t, store.SetQuantization(QuantizationConfig{Type: QuantizationScalar, TrainingSize: 200}))
+	require.True(t, persistedGraphMatches(t, store), "the quantized graph is loaded")
+	node, ok := store.hnswIndex.GetNode("doc0007")
+	require.True(t, ok)
+	assert.Len(t, node.Code, 16)
+	assert.Nil(t, node.Vector)
+
+	// Other settings rebuild the graph
+	require.NoError(t, store.SetQuantization(QuantizationConfig{Type: QuantizationNone}))
+	assert.False(t, persistedGraphMatches(t, store))
+	results, err = store.SearchVector(ctx, docs[7].Vector, vectorstore.SearchOptions{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "doc0007", results[0].Document.ID)
+	node, ok = store.hnswIndex.GetNode("doc0007")
+	require.True(t, ok)
+	assert.Nil(t, node.Code)
+
+	assert.Error(t, store.SetQuantization(QuantizationConfig{Type: "int4"}))
+	require.NoError(t, store.Close())
+}
@@ -0,0 +1,157 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+)
+
+// repoColumns selects a repository row together with its document count.
+const repoColumns = `
+	SELECT r.id, r.root_path, r.ignore_patterns, r.created_at, r.last_indexed_at,
+		(SELECT COUNT(*) FROM documents d WHERE d.meta_repo_id = r.id)
+	FROM repos r`
+
+// AddRepository records a repository, keeping the creation time of an
+// existing one.
+func (s *Store) AddRepository(ctx context.Context, repo vectorstore.Repository) error {
+	if err := vectorstore.ValidateRepoID(repo.ID); err != nil {
+		return err
+	}
+
+	ignoreJSON, err := json.Marshal(repo.IgnorePatterns)
+	if err != nil {
+		return fmt.Errorf("marshal ignore patterns: %w", err)
+	}
+
+	createdAt := repo.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	var lastIndexedAt int64
+	if !repo.LastIndexedAt.IsZero() {
+		lastIndexedAt = repo.LastIndexedAt.Unix()
+	}
+
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO repos (id, root_path, ignore_patterns, created_at, last_indexed_at)
+		 VALUES (?, ?, ?, ?, ?)
+		 ON CONFLICT(id) DO UPDATE SET
+			root_path = excluded.root_path,
+			ignore_patterns = excluded.ignore_patterns,
+			last_indexed_at = excluded.last_indexed_at`,
+		repo.ID, repo.RootPath, ignoreJSON, createdAt.Unix(), lastIndexedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("save repository: %w", err)
+	}
+	return nil
+}
+
+// GetRepository returns a repository, or nil if it is not recorded.
+func (s *Store) GetRepository(ctx context.Context, id string) (*vectorstore.Repository, error) {
+	repo, err := scanRepository(s.db.QueryRowContext(ctx, repoColumns+` WHERE r.id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+// ListRepositories returns all repositories sorted by ID.
+func (s *Store) ListRepositories(ctx context.Context) ([]vectorstore.Repository, error) {
+	rows, err := s.db.QueryContext(ctx, repoColumns+` ORDER BY r.id`)
+	if err != nil {
+		return nil, fmt.Errorf("query repositories: %w", err)
+	}
+	defer rows.Close()
+
+	var repos []vectorstore.Repository
+	for rows.Next() {
+		repo, err := scanRepository(rows)
+		if err != nil {
+			return nil, err
+		}
+		repos = append(repos, *repo)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+	return repos, nil
+}
+
+// RemoveRepository deletes a repository and all of its documents.
+func (s *Store) RemoveRepository(ctx context.Context, id string) error {
+	return s.writeDocuments(ctx, func(tx *sql.Tx) ([]string, []vectorstore.Document, error) {
+		result, err := tx.ExecContext(ctx, "DELETE FROM repos WHERE id = ?", id)
+		if err != nil {
+			return nil, nil, fmt.Errorf("delete repository: %w", err)
+		}
+		if rows, err := result.RowsAffected(); err != nil {
+			return nil, nil, fmt.Errorf("get rows affected: %w", err)
+		} else if rows == 0 {
+			return nil, nil, fmt.Errorf("repository %s not found", id)
+		}
+
+		removed, err := queryIDs(ctx, tx,
+			"SELECT id FROM documents WHERE meta_repo_id = ?", id,
+		)
+		if err != nil {
+			return nil, nil, fmt.Errorf("find repository documents: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx,
+			"DELETE FROM documents WHERE meta_repo_id = ?", id,
+		); err != nil {
+			return nil, nil, fmt.Errorf("delete repository documents: %w", err)
+		}
+		return removed, nil, nil
+	})
+}
+
+// ListRepositoryFiles returns the file paths indexed for a repository.
+func (s *Store) ListRepositoryFiles(ctx context.Context, repoID string) ([]string, error) {
+	if repoID == "" {
+		return nil, fmt.Errorf("repository ID cannot be empty")
+	}
+	return s.listFiles(ctx, repoID)
+}
+
+// GetRepositoryFileChunks returns the chunks of a repository's file, sorted by start_line.
+func (s *Store) GetRepositoryFileChunks(ctx context.Context, repoID, filePath string) ([]vectorstore.Document, error) {
+	if repoID == "" {
+		return nil, fmt.Errorf("repository ID cannot be empty")
+	}
+	return s.fileChunks(ctx, repoID, filePath)
+}
+
+// scanRepository reads a row selected with repoColumns.
+func scanRepository(row interface{ Scan(...interface{}) error }) (*vectorstore.Repository, error) {
+	var repo vectorstore.Repository
+	var ignoreJSON []byte
+	var createdAt, lastIndexedAt int64
+
+	if err := row.Scan(&repo.ID, &repo.RootPath, &ignoreJSON, &createdAt, &lastIndexedAt, &repo.Documents); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("scan repository: %w", err)
+	}
+
+	if len(ignoreJSON) > 0 {
+		if err := json.Unmarshal(ignoreJSON, &repo.IgnorePatterns); err != nil {
+			return nil, fmt.Errorf("unmarshal ignore patterns of %s: %w", repo.ID, err)
+		}
+	}
+	repo.CreatedAt = time.Unix(createdAt, 0)
+	if lastIndexedAt > 0 {
+		repo.LastIndexedAt = time.Unix(lastIndexedAt, 0)
+	}
+	return &repo, nil
+}
@@ -0,0 +1,80 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ferg-cod3s/conexus/internal/embedding"
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+)
+
+func TestStore_Repositories(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewStore(":memory:")
+	require.NoError(t, err)
+	defer store.Close()
+
+	require.NoError(t, store.AddRepository(ctx, vectorstore.Repository{ID: "web", RootPath: "/src/web", IgnorePatterns: []string{"dist"}}))
+	require.NoError(t, store.AddRepository(ctx, vectorstore.Repository{ID: "api", RootPath: "/src/api"}))
+	assert.Error(t, store.AddRepository(ctx, vectorstore.Repository{ID: "Bad/ID"}))
+
+	doc := func(id, content string) vectorstore.Document {
+		return vectorstore.Document{ID: id, Content: content, Vector: embedding.Vector{1, 0, 0},
+			Metadata: map[string]interface{}{"file_path": "main.go", "start_line": 1}}
+	}
+	require.NoError(t, vectorstore.ScopeToRepo(store, "web").Upsert(ctx, doc("main.go:function:main:1", "web entry point")))
+	require.NoError(t, vectorstore.ScopeToRepo(store, "api").Upsert(ctx, doc("main.go:function:main:1", "api entry point")))
+	require.NoError(t, store.Upsert(ctx, doc("main.go:function:main:1", "root entry point")))
+
+	web, err := store.GetRepository(ctx, "web")
+	require.NoError(t, err)
+	require.NotNil(t, web)
+	assert.Equal(t, []string{"dist"}, web.IgnorePatterns)
+	assert.Equal(t, int64(1), web.Documents)
+	assert.True(t, web.LastIndexedAt.IsZero())
+
+	repos, err := store.ListRepositories(ctx)
+	require.NoError(t, err)
+	require.Len(t, repos, 2)
+	assert.Equal(t, "api", repos[0].ID)
+
+	// The default root's file listing does not include repositories
+	chunks, err := store.GetFileChunks(ctx, "main.go")
+	require.NoError(t, err)
+	require.Len(t, chunks, 1)
+	assert.Equal(t, "root entry point", chunks[0].Content)
+	chunks, err = store.GetRepositoryFileChunks(ctx, "api", "main.go")
+	require.NoError(t, err)
+	require.Len(t, chunks, 1)
+	assert.Equal(t, "api entry point", chunks[0].Content)
+	files, err := store.ListRepositoryFiles(ctx, "web")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"main.go"}, files)
+
+	// List filters are pushed down as IN
+	results, err := store.SearchBM25(ctx, "entry", vectorstore.SearchOptions{
+		Limit:   10,
+		Filters: map[string]interface{}{vectorstore.RepoIDKey: []string{"web", "api"}},
+	})
+	require.NoError(t, err)
+	assert.Len(t, results, 2)
+	results, err = store.SearchVector(ctx, embedding.Vector{1, 0, 0}, vectorstore.SearchOptions{
+		Limit:   10,
+		Filters: map[string]interface{}{vectorstore.RepoIDKey: []string{"web"}},
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "web entry point", results[0].Document.Content)
+
+	require.NoError(t, store.RemoveRepository(ctx, "web"))
+	assert.Error(t, store.RemoveRepository(ctx, "web"))
+	missing, err := store.GetRepository(ctx, "web")
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+	count, err := store.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
@@ -0,0 +1,158 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// snapshotDatabaseFile is the database copy written into a snapshot directory.
+const snapshotDatabaseFile = "index.db"
+
+// Snapshot writes a compacted copy of the database, including the full-text
+// index, chunk graph and persisted HNSW graph, into dir.
+func (s *Store) Snapshot(ctx context.Context, dir string) error {
+	dbPath := filepath.Join(dir, snapshotDatabaseFile)
+	if _, err := s.db.ExecContext(ctx, "VACUUM INTO ?", dbPath); err != nil {
+		return fmt.Errorf("copy database: %w", err)
+	}
+	return nil
+}
+
+// Restore replaces all documents and repositories with those of a snapshot
+// written by Snapshot. Full-text rows and the chunk graph are rebuilt by the
+// document triggers, and any indexing checkpoint is discarded since it
+// belongs to the replaced contents. The trigram index of file texts is
+// cleared, so grep searches read every file with documents until the files
+// are indexed again. JSON vectors of snapshots taken before
+// schema version 1 are converted. The snapshot's HNSW graph is kept if it
+// matches its documents; otherwise, as for older snapshots that saved the
+// graph to a separate file, the graph is rebuilt on first use.
+func (s *Store) Restore(ctx context.Context, dir string) error {
+	dbPath := filepath.Join(dir, snapshotDatabaseFile)
+	if _, err := os.Stat(dbPath); err != nil {
+		return fmt.Errorf("snapshot database: %w", err)
+	}
+
+	s.hnswMu.Lock()
+	defer s.hnswMu.Unlock()
+	s.hnswLoaded = false
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS snapshot", dbPath); err != nil {
+		return fmt.Errorf("attach snapshot: %w", err)
+	}
+	// #nosec G104 - Detach failure leaves only a read-only attachment on this connection
+	defer conn.ExecContext(context.Background(), "DETACH DATABASE snapshot")
+
+	// Snapshots taken by older versions lack the newer tables
+	hasTable := func(name string) (bool, error) {
+		var exists bool
+		if err := conn.QueryRowContext(ctx,
+			"SELECT EXISTS(SELECT 1 FROM snapshot.sqlite_master WHERE type = 'table' AND name = ?)", name,
+		).Scan(&exists); err != nil {
+			return false, fmt.Errorf("inspect snapshot: %w", err)
+		}
+		return exists, nil
+	}
+	hasRepos, err := hasTable("repos")
+	if err != nil {
+		return err
+	}
+	hasCollections, err := hasTable("collections")
+	if err != nil {
+		return err
+	}
+	hasGraph, err := hasTable("hnsw_meta")
+	if err != nil {
+		return err
+	}
+	var version int
+	if err := conn.QueryRowContext(ctx, "PRAGMA snapshot.user_version").Scan(&version); err != nil {
+		return fmt.Errorf("read snapshot schema version: %w", err)
+	}
+	if version < 1 {
+		// Graphs of older snapshots store vectors without an encoding tag
+		hasGraph = false
+	}
+	if hasGraph {
+		documents, err := documentsStamp(ctx, conn, "snapshot")
+		if err != nil {
+			return err
+		}
+		graph, ok, err := graphStamp(ctx, conn, "snapshot")
+		if err != nil {
+			return err
+		}
+		hasGraph = ok && graph == documents
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		`DELETE FROM main.documents`,
+		`INSERT INTO main.documents (id, content, vector, metadata, created_at, updated_at)
+		 SELECT id, content, vector, metadata, created_at, updated_at FROM snapshot.documents`,
+		`DELETE FROM main.repos`,
+		`DELETE FROM main.collections`,
+		`DELETE FROM main.index_checkpoint`,
+		`DELETE FROM main.index_checkpoint_files`,
+		`DELETE FROM main.hnsw_nodes`,
+		`DELETE FROM main.hnsw_meta`,
+		`DELETE FROM main.grep_files`,
+		`INSERT INTO main.grep_trigrams (grep_trigrams) VALUES ('delete-all')`,
+	}
+	if hasRepos {
+		statements = append(statements,
+			`INSERT INTO main.repos (id, root_path, ignore_patterns, created_at, last_indexed_at)
+			 SELECT id, root_path, ignore_patterns, created_at, last_indexed_at FROM snapshot.repos`)
+	}
+	if hasCollections {
+		statements = append(statements,
+			`INSERT INTO main.collections (name, embedding_model, dimensions, retention, created_at)
+			 SELECT name, embedding_model, dimensions, retention, created_at FROM snapshot.collections`)
+	}
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("restore documents: %w", err)
+		}
+	}
+	if err := convertJSONVectors(ctx, tx, s.vectorEncoding); err != nil {
+		return err
+	}
+
+	// The graph is stamped with the restored documents. Graphs of snapshots
+	// before schema version 2 are never quantized.
+	if hasGraph {
+		quantizer := "NULL"
+		if version >= 2 {
+			quantizer = "quantizer"
+		}
+		for _, stmt := range []string{
+			`INSERT INTO main.hnsw_nodes (id, level, vector, neighbors)
+			 SELECT id, level, vector, neighbors FROM snapshot.hnsw_nodes`,
+			`INSERT INTO main.hnsw_meta (id, entry_point, max_level, vector_dim, config, quantizer, documents_version, documents_count)
+			 SELECT 1, entry_point, max_level, vector_dim, config, ` + quantizer + `,
+			 (SELECT version FROM main.documents_version WHERE id = 1), (SELECT COUNT(*) FROM main.documents)
+			 FROM snapshot.hnsw_meta`,
+		} {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("restore HNSW graph: %w", err)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
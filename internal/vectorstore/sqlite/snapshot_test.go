@@ -0,0 +1,110 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ferg-cod3s/conexus/internal/embedding"
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	ctx := context.Background()
+	source := newTestStore(t)
+
+	require.NoError(t, source.UpsertBatch(ctx, []vectorstore.Document{
+		{ID: "file", Content: "package summary", Vector: embedding.Vector{0.1, 0.2, 0.3},
+			Metadata: map[string]interface{}{"file_path": "a.go"}},
+		{ID: "func", Content: "func parseConfig() error", Vector: embedding.Vector{0.3, 0.2, 0.1},
+			Metadata: map[string]interface{}{"file_path": "a.go", "parent_id": "file"}},
+	}))
+	require.NoError(t, source.AddRepository(ctx, vectorstore.Repository{ID: "web", RootPath: "/src/web"}))
+
+	dir := t.TempDir()
+	require.NoError(t, source.Snapshot(ctx, dir))
+	assert.FileExists(t, filepath.Join(dir, snapshotDatabaseFile))
+
+	target := newTestStore(t)
+	require.NoError(t, target.Upsert(ctx, vectorstore.Document{
+		ID: "stale", Content: "replaced by the snapshot", Vector: embedding.Vector{1, 0, 0},
+	}))
+	require.NoError(t, target.SaveCheckpoint(ctx, vectorstore.IndexCheckpoint{RunID: "run", RootPath: "/repo"}))
+	require.NoError(t, target.AddRepository(ctx, vectorstore.Repository{ID: "stale", RootPath: "/src/stale"}))
+
+	require.NoError(t, target.Restore(ctx, dir))
+
+	count, err := target.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	_, err = target.Get(ctx, "stale")
+	assert.Error(t, err)
+
+	// Full-text search and the chunk graph are rebuilt
+	results, err := target.SearchBM25(ctx, "parseConfig", vectorstore.SearchOptions{Limit: 5})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "func", results[0].Document.ID)
+
+	parent, err := target.GetParent(ctx, "func")
+	require.NoError(t, err)
+	require.NotNil(t, parent)
+	assert.Equal(t, "file", parent.ID)
+
+	// The snapshot's graph is kept
+	assert.True(t, persistedGraphMatches(t, target))
+	assert.Equal(t, []string{"file", "func"}, target.hnswIndex.IDs())
+
+	repos, err := target.ListRepositories(ctx)
+	require.NoError(t, err)
+	require.Len(t, repos, 1)
+	assert.Equal(t, "web", repos[0].ID)
+
+	cp, err := target.LoadCheckpoint(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, cp)
+
+	report, err := target.CheckIntegrity(ctx, 3)
+	require.NoError(t, err)
+	assert.Empty(t, report.FTSMissing)
+	assert.Empty(t, report.HNSWOrphaned)
+}
+
+func TestRestore_RebuildsStaleGraph(t *testing.T) {
+	ctx := context.Background()
+	source := newTestStore(t)
+	require.NoError(t, source.Upsert(ctx, vectorstore.Document{
+		ID: "doc", Content: "hello", Vector: embedding.Vector{0.1, 0.2, 0.3},
+	}))
+	_, err := source.db.ExecContext(ctx, "UPDATE hnsw_meta SET documents_count = 99")
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, source.Snapshot(ctx, dir))
+
+	target := newTestStore(t)
+	require.NoError(t, target.Upsert(ctx, vectorstore.Document{
+		ID: "old", Content: "replaced", Vector: embedding.Vector{1, 0, 0},
+	}))
+	require.NoError(t, target.Restore(ctx, dir))
+	assert.False(t, persistedGraphMatches(t, target))
+
+	results, err := target.SearchVector(ctx, embedding.Vector{0.1, 0.2, 0.3}, vectorstore.SearchOptions{Limit: 5})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "doc", results[0].Document.ID)
+
+	report, err := target.CheckIntegrity(ctx, 3)
+	require.NoError(t, err)
+	assert.Empty(t, report.HNSWOrphaned)
+	assert.Equal(t, []string{"doc"}, target.hnswIndex.IDs())
+	assert.True(t, persistedGraphMatches(t, target))
+}
+
+func TestRestore_MissingSnapshot(t *testing.T) {
+	store := newTestStore(t)
+	assert.Error(t, store.Restore(context.Background(), t.TempDir()))
+}
@@ -8,11 +8,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	_ "modernc.org/sqlite" // Pure Go SQLite driver
 
-	"github.com/ferg-cod3s/conexus/internal/embedding"
 	"github.com/ferg-cod3s/conexus/internal/vectorstore"
 )
 
@@ -20,6 +20,15 @@ import (
 type Store struct {
 	db        *sql.DB
 	hnswIndex *HNSWIndex
+
+	// hnswMu serializes document writes with the HNSW graph updates and
+	// guards loading the graph, which happens on first use.
+	hnswMu     sync.Mutex
+	hnswLoaded bool      // Whether hnswIndex holds the persisted graph
+	hnswStamp  hnswStamp // Documents stamp the graph was last loaded or written at
+
+	vectorEncoding VectorEncoding     // Encoding of written vectors, guarded by hnswMu
+	quantization   QuantizationConfig // Compression of graph vectors, guarded by hnswMu
 }
 
 // NewStore creates a new SQLite vector store.
@@ -33,6 +42,12 @@ func NewStore(path string) (*Store, error) {
 		}
 	}
 
+	// Only databases that existed before are backed up before migrating
+	backupPath := ""
+	if _, err := os.Stat(path); err == nil && path != ":memory:" {
+		backupPath = path
+	}
+
 	db, err := sql.Open("sqlite", path)
 	if err != nil {
 		return nil, fmt.Errorf("open database: %w", err)
@@ -47,8 +62,9 @@ func NewStore(path string) (*Store, error) {
 	hnswIndex := NewHNSWIndex(DefaultHNSWConfig())
 
 	store := &Store{
-		db:        db,
-		hnswIndex: hnswIndex,
+		db:             db,
+		hnswIndex:      hnswIndex,
+		vectorEncoding: VectorEncodingFloat32,
 	}
 
 	// Initialize schema
@@ -58,12 +74,11 @@ func NewStore(path string) (*Store, error) {
 		return nil, fmt.Errorf("init schema: %w", err)
 	}
 
-	// Load existing vectors into HNSW index (disabled for performance)
-	// if err := store.loadVectorsIntoIndex(); err != nil {
-	// 	// #nosec G104 - Best-effort cleanup in error path, primary error (schema init) already captured
-	// 	db.Close()
-	// 	return nil, fmt.Errorf("load vectors into index: %w", err)
-	// }
+	if err := store.migrate(context.Background(), backupPath); err != nil {
+		// #nosec G104 - Best-effort cleanup in error path, primary error (migration) already captured
+		db.Close()
+		return nil, fmt.Errorf("migrate database: %w", err)
+	}
 
 	return store, nil
 }
@@ -75,13 +90,14 @@ func (s *Store) initSchema() error {
 	CREATE TABLE IF NOT EXISTS documents (
 		id TEXT PRIMARY KEY,
 		content TEXT NOT NULL,
-		vector TEXT NOT NULL,  -- JSON-encoded float array
+		vector BLOB NOT NULL,  -- Encoded float array, see encodeVector
 		metadata TEXT,         -- JSON-encoded metadata
 		created_at INTEGER NOT NULL,
 		updated_at INTEGER NOT NULL
 	);
 
-	-- FTS5 virtual table for full-text search
+	-- FTS5 virtual table for full-text search; migrate adds the identifiers column
+	-- and has the triggers fill it
 	CREATE VIRTUAL TABLE IF NOT EXISTS documents_fts USING fts5(
 		id UNINDEXED,
 		content,
@@ -103,12 +119,139 @@ func (s *Store) initSchema() error {
 
 	-- Index for metadata filtering (will add JSON support later)
 	CREATE INDEX IF NOT EXISTS idx_documents_updated_at ON documents(updated_at);
+
+	-- Progress of the current indexing run (at most one row)
+	CREATE TABLE IF NOT EXISTS index_checkpoint (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		run_id TEXT NOT NULL,
+		root_path TEXT NOT NULL,
+		pending TEXT,          -- JSON-encoded paths of the batch being written
+		started_at INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL
+	);
+
+	-- Files completed by the current indexing run
+	CREATE TABLE IF NOT EXISTS index_checkpoint_files (
+		path TEXT PRIMARY KEY,
+		hash TEXT NOT NULL
+	);
+
+	-- Parent/child graph of chunks, mirrored from the parent_id metadata
+	CREATE TABLE IF NOT EXISTS chunk_parents (
+		child_id TEXT PRIMARY KEY,
+		parent_id TEXT NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_chunk_parents_parent_id ON chunk_parents(parent_id);
+
+	CREATE TRIGGER IF NOT EXISTS chunk_parents_ai AFTER INSERT ON documents
+	WHEN json_extract(new.metadata, '$.parent_id') IS NOT NULL BEGIN
+		INSERT OR REPLACE INTO chunk_parents(child_id, parent_id)
+		VALUES (new.id, json_extract(new.metadata, '$.parent_id'));
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS chunk_parents_ad AFTER DELETE ON documents BEGIN
+		DELETE FROM chunk_parents WHERE child_id = old.id;
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS chunk_parents_au AFTER UPDATE ON documents BEGIN
+		DELETE FROM chunk_parents WHERE child_id = old.id;
+		INSERT INTO chunk_parents(child_id, parent_id)
+		SELECT new.id, json_extract(new.metadata, '$.parent_id')
+		WHERE json_extract(new.metadata, '$.parent_id') IS NOT NULL;
+	END;
+
+	-- Repositories indexed alongside the default root
+	CREATE TABLE IF NOT EXISTS repos (
+		id TEXT PRIMARY KEY,
+		root_path TEXT NOT NULL,
+		ignore_patterns TEXT,  -- JSON-encoded patterns
+		created_at INTEGER NOT NULL,
+		last_indexed_at INTEGER NOT NULL DEFAULT 0
+	);
+
+	-- Counter bumped by every document change, used to validate the HNSW graph
+	CREATE TABLE IF NOT EXISTS documents_version (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		version INTEGER NOT NULL
+	);
+
+	INSERT OR IGNORE INTO documents_version (id, version) VALUES (1, 0);
+
+	CREATE TRIGGER IF NOT EXISTS documents_version_ai AFTER INSERT ON documents BEGIN
+		UPDATE documents_version SET version = version + 1 WHERE id = 1;
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS documents_version_ad AFTER DELETE ON documents BEGIN
+		UPDATE documents_version SET version = version + 1 WHERE id = 1;
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS documents_version_au AFTER UPDATE ON documents BEGIN
+		UPDATE documents_version SET version = version + 1 WHERE id = 1;
+	END;
+
+	-- Persisted HNSW graph; deleted nodes are not kept
+	CREATE TABLE IF NOT EXISTS hnsw_nodes (
+		id TEXT PRIMARY KEY,
+		level INTEGER NOT NULL,
+		vector BLOB NOT NULL,  -- Normalized vector (see encodeVector), or its code in a quantized graph
+		neighbors TEXT NOT NULL -- JSON-encoded neighbor IDs per level
+	);
+
+	-- HNSW graph header and the documents stamp it was built from (at most one row); migrate
+	-- adds the serialized quantizer of a quantized graph
+	CREATE TABLE IF NOT EXISTS hnsw_meta (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		entry_point TEXT NOT NULL,
+		max_level INTEGER NOT NULL,
+		vector_dim INTEGER NOT NULL,
+		config TEXT NOT NULL,  -- JSON-encoded HNSWConfig
+		documents_version INTEGER NOT NULL,
+		documents_count INTEGER NOT NULL
+	);
+
+	-- Populate the graph for documents stored before it existed
+	INSERT OR IGNORE INTO chunk_parents(child_id, parent_id)
+	SELECT id, json_extract(metadata, '$.parent_id') FROM documents
+	WHERE json_extract(metadata, '$.parent_id') IS NOT NULL
+	AND NOT EXISTS (SELECT 1 FROM chunk_parents);
 	`
 
 	_, err := s.db.Exec(schema)
 	return err
 }
 
+// SetVectorEncoding sets the encoding of vectors written from now on.
+// Vectors already stored keep their encoding.
+func (s *Store) SetVectorEncoding(encoding VectorEncoding) error {
+	if _, err := ParseVectorEncoding(string(encoding)); err != nil {
+		return err
+	}
+	s.hnswMu.Lock()
+	defer s.hnswMu.Unlock()
+	s.vectorEncoding = encoding
+	return nil
+}
+
+// SetQuantization sets the compression of the vectors held by the HNSW
+// graph. A persisted graph built with other settings is rebuilt on next use.
+func (s *Store) SetQuantization(config QuantizationConfig) error {
+	quantization, err := ParseQuantization(string(config.Type))
+	if err != nil {
+		return err
+	}
+	if config.Subvectors < 0 || config.TrainingSize < 0 {
+		return fmt.Errorf("quantization subvectors and training size cannot be negative")
+	}
+	config.Type = quantization
+
+	s.hnswMu.Lock()
+	defer s.hnswMu.Unlock()
+	s.quantization = config
+	s.hnswLoaded = false
+	return nil
+}
+
 // Upsert inserts or updates a document with its vector.
 func (s *Store) Upsert(ctx context.Context, doc vectorstore.Document) error {
 	if doc.ID == "" {
@@ -118,74 +261,12 @@ func (s *Store) Upsert(ctx context.Context, doc vectorstore.Document) error {
 		return fmt.Errorf("document vector cannot be empty")
 	}
 
-	// Serialize vector as JSON
-	vectorJSON, err := json.Marshal(doc.Vector)
-	if err != nil {
-		return fmt.Errorf("marshal vector: %w", err)
-	}
-
-	// Serialize metadata as JSON
-	var metadataJSON []byte
-	if doc.Metadata != nil {
-		metadataJSON, err = json.Marshal(doc.Metadata)
-		if err != nil {
-			return fmt.Errorf("marshal metadata: %w", err)
-		}
-	}
-
-	// Check if document exists
-	var exists bool
-	err = s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM documents WHERE id = ?)", doc.ID).Scan(&exists)
-	if err != nil {
-		return fmt.Errorf("check existence: %w", err)
-	}
-
-	now := time.Now().Unix()
-
-	if exists {
-		// Update existing document
-		_, err = s.db.ExecContext(ctx,
-			`UPDATE documents SET content = ?, vector = ?, metadata = ?, updated_at = ? WHERE id = ?`,
-			doc.Content, vectorJSON, metadataJSON, now, doc.ID,
-		)
-		if err != nil {
-			return fmt.Errorf("update document: %w", err)
-		}
-
-		// Update HNSW index (remove old, insert new)
-		s.hnswIndex.Remove(doc.ID)
-		if err := s.hnswIndex.Insert(doc.ID, doc.Vector); err != nil {
-			// Log error but don't fail the operation
-			fmt.Printf("Warning: failed to update HNSW index for document %s: %v\n", doc.ID, err)
-		}
-	} else {
-		// Insert new document
-		createdAt := now
-		if !doc.CreatedAt.IsZero() {
-			createdAt = doc.CreatedAt.Unix()
-		}
-		updatedAt := now
-		if !doc.UpdatedAt.IsZero() {
-			updatedAt = doc.UpdatedAt.Unix()
-		}
-
-		_, err = s.db.ExecContext(ctx,
-			`INSERT INTO documents (id, content, vector, metadata, created_at, updated_at)
-			 VALUES (?, ?, ?, ?, ?, ?)`,
-			doc.ID, doc.Content, vectorJSON, metadataJSON, createdAt, updatedAt,
-		)
-		if err != nil {
-			return fmt.Errorf("insert document: %w", err)
+	return s.writeDocuments(ctx, func(tx *sql.Tx) ([]string, []vectorstore.Document, error) {
+		if err := s.upsertInTx(ctx, tx, doc); err != nil {
+			return nil, nil, fmt.Errorf("upsert document: %w", err)
 		}
-
-		// Insert into HNSW index (disabled for performance)
-		// if err := s.hnswIndex.Insert(doc.ID, doc.Vector); err != nil {
-		// 	// Log error but don't fail the operation
-		// 	fmt.Printf("Warning: failed to insert into HNSW index for document %s: %v\n", doc.ID, err)
-		// }
-	}
-
-	return nil
+		return nil, []vectorstore.Document{doc}, nil
+	})
 }
 
 // UpsertBatch efficiently inserts or updates multiple documents in a transaction.
@@ -194,33 +275,14 @@ func (s *Store) UpsertBatch(ctx context.Context, docs []vectorstore.Document) er
 		return nil
 	}
 
-	tx, err := s.db.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("begin transaction: %w", err)
-	}
-	defer tx.Rollback()
-
-	for _, doc := range docs {
-		if err := s.upsertInTx(ctx, tx, doc); err != nil {
-			return fmt.Errorf("upsert document %s: %w", doc.ID, err)
+	return s.writeDocuments(ctx, func(tx *sql.Tx) ([]string, []vectorstore.Document, error) {
+		for _, doc := range docs {
+			if err := s.upsertInTx(ctx, tx, doc); err != nil {
+				return nil, nil, fmt.Errorf("upsert document %s: %w", doc.ID, err)
+			}
 		}
-	}
-
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit transaction: %w", err)
-	}
-
-	// Update HNSW index after successful transaction (disabled for performance)
-	// for _, doc := range docs {
-	// 	// Remove old vector (if exists) and insert new one
-	// 	s.hnswIndex.Remove(doc.ID)
-	// 	if err := s.hnswIndex.Insert(doc.ID, doc.Vector); err != nil {
-	// 		// Log error but don't fail the operation
-	// 		fmt.Printf("Warning: failed to update HNSW index for document %s: %v\n", doc.ID, err)
-	// 	}
-	// }
-
-	return nil
+		return nil, docs, nil
+	})
 }
 
 // upsertInTx performs upsert within a transaction.
@@ -232,12 +294,10 @@ func (s *Store) upsertInTx(ctx context.Context, tx *sql.Tx, doc vectorstore.Docu
 		return fmt.Errorf("document vector cannot be empty")
 	}
 
-	vectorJSON, err := json.Marshal(doc.Vector)
-	if err != nil {
-		return fmt.Errorf("marshal vector: %w", err)
-	}
+	vectorBlob := encodeVector(doc.Vector, s.vectorEncoding)
 
 	var metadataJSON []byte
+	var err error
 	if doc.Metadata != nil {
 		metadataJSON, err = json.Marshal(doc.Metadata)
 		if err != nil {
@@ -265,7 +325,7 @@ func (s *Store) upsertInTx(ctx context.Context, tx *sql.Tx, doc vectorstore.Docu
 		 vector = excluded.vector,
 		 metadata = excluded.metadata,
 		 updated_at = excluded.updated_at`,
-		doc.ID, doc.Content, vectorJSON, metadataJSON, createdAt, updatedAt,
+		doc.ID, doc.Content, vectorBlob, metadataJSON, createdAt, updatedAt,
 	)
 
 	return err
@@ -273,34 +333,36 @@ func (s *Store) upsertInTx(ctx context.Context, tx *sql.Tx, doc vectorstore.Docu
 
 // Delete removes a document by ID.
 func (s *Store) Delete(ctx context.Context, id string) error {
-	result, err := s.db.ExecContext(ctx, "DELETE FROM documents WHERE id = ?", id)
-	if err != nil {
-		return fmt.Errorf("delete document: %w", err)
-	}
+	return s.writeDocuments(ctx, func(tx *sql.Tx) ([]string, []vectorstore.Document, error) {
+		result, err := tx.ExecContext(ctx, "DELETE FROM documents WHERE id = ?", id)
+		if err != nil {
+			return nil, nil, fmt.Errorf("delete document: %w", err)
+		}
 
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("get rows affected: %w", err)
-	}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return nil, nil, fmt.Errorf("get rows affected: %w", err)
+		}
 
-	if rows == 0 {
-		return fmt.Errorf("document %s not found", id)
-	}
+		if rows == 0 {
+			return nil, nil, fmt.Errorf("document %s not found", id)
+		}
 
-	return nil
+		return []string{id}, nil, nil
+	})
 }
 
 // Get retrieves a document by ID.
 func (s *Store) Get(ctx context.Context, id string) (*vectorstore.Document, error) {
 	var doc vectorstore.Document
-	var vectorJSON, metadataJSON []byte
+	var vectorBlob, metadataJSON []byte
 	var createdAt, updatedAt int64
 
 	err := s.db.QueryRowContext(ctx,
 		`SELECT id, content, vector, metadata, created_at, updated_at 
 		 FROM documents WHERE id = ?`,
 		id,
-	).Scan(&doc.ID, &doc.Content, &vectorJSON, &metadataJSON, &createdAt, &updatedAt)
+	).Scan(&doc.ID, &doc.Content, &vectorBlob, &metadataJSON, &createdAt, &updatedAt)
 
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("document %s not found", id)
@@ -309,21 +371,10 @@ func (s *Store) Get(ctx context.Context, id string) (*vectorstore.Document, erro
 		return nil, fmt.Errorf("query document: %w", err)
 	}
 
-	// Deserialize vector
-	if err := json.Unmarshal(vectorJSON, &doc.Vector); err != nil {
-		return nil, fmt.Errorf("unmarshal vector: %w", err)
-	}
-
-	// Deserialize metadata
-	if len(metadataJSON) > 0 {
-		if err := json.Unmarshal(metadataJSON, &doc.Metadata); err != nil {
-			return nil, fmt.Errorf("unmarshal metadata: %w", err)
-		}
+	if err := deserializeDocument(&doc, vectorBlob, metadataJSON, createdAt, updatedAt); err != nil {
+		return nil, err
 	}
 
-	doc.CreatedAt = time.Unix(createdAt, 0)
-	doc.UpdatedAt = time.Unix(updatedAt, 0)
-
 	return &doc, nil
 }
 
@@ -337,16 +388,41 @@ func (s *Store) Count(ctx context.Context) (int64, error) {
 	return count, nil
 }
 
-// ListIndexedFiles returns a list of all unique file paths that have been indexed.
+// ListIndexedFiles returns a list of all unique file paths that have been
+// indexed in the default root.
 func (s *Store) ListIndexedFiles(ctx context.Context) ([]string, error) {
+	return s.listFiles(ctx, "")
+}
+
+// GetFileChunks returns all chunks for a specific file path of the default
+// root, sorted by start_line.
+func (s *Store) GetFileChunks(ctx context.Context, filePath string) ([]vectorstore.Document, error) {
+	return s.fileChunks(ctx, "", filePath)
+}
+
+// repoCondition restricts a query to the documents of a repository, or of
+// the default root when repoID is empty. Collection documents belong to
+// neither.
+func repoCondition(repoID string) (string, []interface{}) {
+	if repoID == "" {
+		return "meta_repo_id IS NULL AND meta_collection IS NULL", nil
+	}
+	return "meta_repo_id = ?", []interface{}{repoID}
+}
+
+// listFiles returns the file paths indexed for a repository, or for the
+// default root when repoID is empty.
+func (s *Store) listFiles(ctx context.Context, repoID string) ([]string, error) {
+	condition, args := repoCondition(repoID)
 	query := `
-		SELECT DISTINCT json_extract(metadata, '$.file_path') as file_path
+		SELECT DISTINCT meta_file_path
 		FROM documents
-		WHERE metadata IS NOT NULL AND json_extract(metadata, '$.file_path') IS NOT NULL
-		ORDER BY file_path
+		WHERE meta_file_path IS NOT NULL
+		AND ` + condition + `
+		ORDER BY meta_file_path
 	`
 
-	rows, err := s.db.QueryContext(ctx, query)
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("query indexed files: %w", err)
 	}
@@ -368,16 +444,19 @@ func (s *Store) ListIndexedFiles(ctx context.Context) ([]string, error) {
 	return files, nil
 }
 
-// GetFileChunks returns all chunks for a specific file path, sorted by start_line.
-func (s *Store) GetFileChunks(ctx context.Context, filePath string) ([]vectorstore.Document, error) {
+// fileChunks returns the chunks of a file in a repository, or in the default
+// root when repoID is empty, sorted by start_line.
+func (s *Store) fileChunks(ctx context.Context, repoID, filePath string) ([]vectorstore.Document, error) {
+	condition, args := repoCondition(repoID)
 	query := `
 		SELECT id, content, vector, metadata, created_at, updated_at
 		FROM documents
-		WHERE metadata IS NOT NULL AND json_extract(metadata, '$.file_path') = ?
+		WHERE meta_file_path = ?
+		AND ` + condition + `
 		ORDER BY json_extract(metadata, '$.start_line')
 	`
 
-	rows, err := s.db.QueryContext(ctx, query, filePath)
+	rows, err := s.db.QueryContext(ctx, query, append([]interface{}{filePath}, args...)...)
 	if err != nil {
 		return nil, fmt.Errorf("query file chunks: %w", err)
 	}
@@ -386,15 +465,15 @@ func (s *Store) GetFileChunks(ctx context.Context, filePath string) ([]vectorsto
 	var docs []vectorstore.Document
 	for rows.Next() {
 		var doc vectorstore.Document
-		var vectorJSON, metadataJSON []byte
+		var vectorBlob, metadataJSON []byte
 		var createdAt, updatedAt int64
 
-		err := rows.Scan(&doc.ID, &doc.Content, &vectorJSON, &metadataJSON, &createdAt, &updatedAt)
+		err := rows.Scan(&doc.ID, &doc.Content, &vectorBlob, &metadataJSON, &createdAt, &updatedAt)
 		if err != nil {
 			return nil, fmt.Errorf("scan document: %w", err)
 		}
 
-		if err := deserializeDocument(&doc, vectorJSON, metadataJSON, createdAt, updatedAt); err != nil {
+		if err := deserializeDocument(&doc, vectorBlob, metadataJSON, createdAt, updatedAt); err != nil {
 			return nil, fmt.Errorf("deserialize document %s: %w", doc.ID, err)
 		}
 
@@ -470,13 +549,16 @@ func (s *Store) Stats(ctx context.Context) (*vectorstore.IndexStats, error) {
 	return stats, nil
 }
 
-// deserializeDocument unmarshals vector and metadata JSON into a document.
-// This is shared by Get() and SearchBM25() to avoid code duplication.
-func deserializeDocument(doc *vectorstore.Document, vectorJSON, metadataJSON []byte, createdAt, updatedAt int64) error {
-	// Deserialize vector
-	if err := json.Unmarshal(vectorJSON, &doc.Vector); err != nil {
-		return fmt.Errorf("unmarshal vector: %w", err)
+// deserializeDocument decodes the vector and unmarshals the metadata JSON
+// of a document. This is shared by Get() and the searches to avoid code
+// duplication.
+func deserializeDocument(doc *vectorstore.Document, vectorBlob, metadataJSON []byte, createdAt, updatedAt int64) error {
+	// Decode vector
+	vector, err := decodeVector(vectorBlob)
+	if err != nil {
+		return fmt.Errorf("decode vector: %w", err)
 	}
+	doc.Vector = vector
 
 	// Deserialize metadata if present
 	if len(metadataJSON) > 0 {
@@ -492,12 +574,14 @@ func deserializeDocument(doc *vectorstore.Document, vectorJSON, metadataJSON []b
 	return nil
 }
 
-// loadVectorsIntoIndex loads all existing vectors from the database into the HNSW index
-func (s *Store) loadVectorsIntoIndex() error {
-	rows, err := s.db.Query(`
+// loadVectorsIntoIndex loads the vectors of the documents outside any
+// collection from the database into the HNSW index
+func (s *Store) loadVectorsIntoIndex(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, `
 		SELECT id, vector
 		FROM documents
-		WHERE vector IS NOT NULL
+		WHERE vector IS NOT NULL AND meta_collection IS NULL
+		ORDER BY id
 	`)
 	if err != nil {
 		return fmt.Errorf("query vectors: %w", err)
@@ -506,22 +590,19 @@ func (s *Store) loadVectorsIntoIndex() error {
 
 	for rows.Next() {
 		var id string
-		var vectorJSON []byte
+		var vectorBlob []byte
 
-		if err := rows.Scan(&id, &vectorJSON); err != nil {
+		if err := rows.Scan(&id, &vectorBlob); err != nil {
 			continue // Skip corrupted entries
 		}
 
-		var vector embedding.Vector
-		if err := json.Unmarshal(vectorJSON, &vector); err != nil {
+		vector, err := decodeVector(vectorBlob)
+		if err != nil {
 			continue // Skip corrupted vectors
 		}
 
-		// Insert into HNSW index
-		if err := s.hnswIndex.Insert(id, vector); err != nil {
-			// Log error but continue loading other vectors
-			fmt.Printf("Warning: failed to index vector for document %s: %v\n", id, err)
-		}
+		// Skip vectors the index rejects, such as ones of another dimension
+		_ = s.hnswIndex.Insert(id, vector)
 	}
 
 	if err := rows.Err(); err != nil {
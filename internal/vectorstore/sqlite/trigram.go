@@ -0,0 +1,187 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+)
+
+// unindexedFiles selects the files with documents whose text is not in the
+// trigram index. Documents of other repositories are relative to their roots.
+const unindexedFiles = `
+	SELECT DISTINCT meta_file_path FROM documents
+	WHERE meta_file_path IS NOT NULL AND meta_repo_id IS NULL
+		AND meta_file_path NOT IN (SELECT file_path FROM grep_files)`
+
+// IndexFileText replaces the indexed text of the file at path.
+func (s *Store) IndexFileText(ctx context.Context, path, text string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id int64
+	indexedAt := time.Now().UnixNano()
+	err = tx.QueryRowContext(ctx, "SELECT id FROM grep_files WHERE file_path = ?", path).Scan(&id)
+	switch {
+	case err == sql.ErrNoRows:
+		result, err := tx.ExecContext(ctx, "INSERT INTO grep_files (file_path, indexed_at) VALUES (?, ?)", path, indexedAt)
+		if err != nil {
+			return fmt.Errorf("insert file %s: %w", path, err)
+		}
+		if id, err = result.LastInsertId(); err != nil {
+			return fmt.Errorf("insert file %s: %w", path, err)
+		}
+	case err != nil:
+		return fmt.Errorf("query file %s: %w", path, err)
+	default:
+		if _, err := tx.ExecContext(ctx, "DELETE FROM grep_trigrams WHERE rowid = ?", id); err != nil {
+			return fmt.Errorf("delete trigrams of %s: %w", path, err)
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE grep_files SET indexed_at = ? WHERE id = ?", indexedAt, id); err != nil {
+			return fmt.Errorf("update file %s: %w", path, err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "INSERT INTO grep_trigrams (rowid, content) VALUES (?, ?)", id, text); err != nil {
+		return fmt.Errorf("index trigrams of %s: %w", path, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// RemoveFileText removes the file at path from the trigram index.
+func (s *Store) RemoveFileText(ctx context.Context, path string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range []string{
+		"DELETE FROM grep_trigrams WHERE rowid IN (SELECT id FROM grep_files WHERE file_path = ?)",
+		"DELETE FROM grep_files WHERE file_path = ?",
+	} {
+		if _, err := tx.ExecContext(ctx, stmt, path); err != nil {
+			return fmt.Errorf("remove file %s: %w", path, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// CandidateFiles returns the files that may match query, sorted by path:
+// those whose indexed text contains its literals and those whose text is
+// not indexed.
+func (s *Store) CandidateFiles(ctx context.Context, query vectorstore.TrigramQuery) ([]string, bool, error) {
+	var exists bool
+	if err := s.db.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM grep_files)
+			OR EXISTS(SELECT 1 FROM documents WHERE meta_file_path IS NOT NULL AND meta_repo_id IS NULL)`,
+	).Scan(&exists); err != nil {
+		return nil, false, fmt.Errorf("query indexed files: %w", err)
+	}
+	if !exists {
+		return nil, false, nil
+	}
+
+	indexed := "SELECT file_path FROM grep_files"
+	var args []interface{}
+	if !query.IsAll() {
+		indexed = `SELECT f.file_path FROM grep_trigrams t JOIN grep_files f ON f.id = t.rowid
+			WHERE grep_trigrams MATCH ?`
+		args = append(args, trigramMatch(query))
+	}
+
+	rows, err := s.db.QueryContext(ctx, indexed+" UNION "+unindexedFiles+" ORDER BY 1", args...)
+	if err != nil {
+		return nil, false, fmt.Errorf("query candidate files: %w", err)
+	}
+	defer rows.Close()
+
+	var files []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, false, fmt.Errorf("scan candidate file: %w", err)
+		}
+		files = append(files, path)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("iterate candidate files: %w", err)
+	}
+	return files, true, nil
+}
+
+// FileTextTimes returns when the text of each file in the trigram index was
+// indexed.
+func (s *Store) FileTextTimes(ctx context.Context) (map[string]time.Time, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT file_path, indexed_at FROM grep_files")
+	if err != nil {
+		return nil, fmt.Errorf("query indexed files: %w", err)
+	}
+	defer rows.Close()
+
+	times := make(map[string]time.Time)
+	for rows.Next() {
+		var path string
+		var indexedAt int64
+		if err := rows.Scan(&path, &indexedAt); err != nil {
+			return nil, fmt.Errorf("scan indexed file: %w", err)
+		}
+		times[path] = time.Unix(0, indexedAt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate indexed files: %w", err)
+	}
+	return times, nil
+}
+
+// trigramMatch compiles a TrigramQuery that does not match every file into
+// an FTS5 query on grep_trigrams. The trigram tokenizer matches a quoted
+// string anywhere in the text.
+func trigramMatch(query vectorstore.TrigramQuery) string {
+	switch query.Op {
+	case vectorstore.TrigramLiteral:
+		return `"` + strings.ReplaceAll(query.Literal, `"`, `""`) + `"`
+	case vectorstore.TrigramAnd, vectorstore.TrigramOr:
+		parts := make([]string, len(query.Queries))
+		for i, q := range query.Queries {
+			parts[i] = trigramMatch(q)
+		}
+		return "(" + strings.Join(parts, " "+strings.ToUpper(string(query.Op))+" ") + ")"
+	}
+	return ""
+}
+
+// addGrepTrigrams creates the trigram index of file texts. grep_trigrams is
+// contentless: it holds the trigram postings of each file under the rowid of
+// its grep_files row, and verifying matches reads the files themselves.
+func addGrepTrigrams(ctx context.Context, tx *sql.Tx) error {
+	for _, stmt := range []string{
+		`CREATE TABLE IF NOT EXISTS grep_files (
+			id INTEGER PRIMARY KEY,
+			file_path TEXT NOT NULL UNIQUE
+		)`,
+		`CREATE VIRTUAL TABLE IF NOT EXISTS grep_trigrams USING fts5(
+			content,
+			content='',
+			contentless_delete=1,
+			tokenize='trigram'
+		)`,
+	} {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("create trigram index: %w", err)
+		}
+	}
+	return nil
+}
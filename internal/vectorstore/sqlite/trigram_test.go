@@ -0,0 +1,116 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ferg-cod3s/conexus/internal/embedding"
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+)
+
+func TestTrigramMatch(t *testing.T) {
+	query, err := vectorstore.RegexpTrigramQuery(`get(User|Account)ByID\("x"`)
+	require.NoError(t, err)
+	assert.Equal(t, `("get" AND ("User" OR "Account") AND "ByID(""x""")`, trigramMatch(query))
+}
+
+func TestCandidateFiles(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+
+	candidates := func(expr string) []string {
+		t.Helper()
+		query, err := vectorstore.RegexpTrigramQuery(expr)
+		require.NoError(t, err)
+		files, ok, err := store.CandidateFiles(ctx, query)
+		require.NoError(t, err)
+		require.True(t, ok)
+		return files
+	}
+
+	_, ok, err := store.CandidateFiles(ctx, vectorstore.TrigramQuery{})
+	require.NoError(t, err)
+	assert.False(t, ok, "an empty store has no files to search")
+
+	texts := map[string]string{
+		"cmd/main.go":      "package main\n\nfunc main() {\n\tlog.Fatal(run())\n}\n",
+		"internal/user.go": "package internal\n\nfunc GetUserByID(id string) (*User, error) {\n\treturn nil, nil\n}\n",
+		"README.md":        "# Conexus\n\nRun `conexus index` to build the index.\n",
+	}
+	for path, text := range texts {
+		require.NoError(t, store.IndexFileText(ctx, path, text))
+	}
+
+	assert.Equal(t, []string{"cmd/main.go", "internal/user.go"}, candidates(`func\s+\w+\(`))
+	assert.Equal(t, []string{"internal/user.go"}, candidates("getuser(by|for)id"), "literals ignore case")
+	assert.Equal(t, []string{"README.md", "cmd/main.go"}, candidates("conexus index|log.Fatal"))
+	assert.Empty(t, candidates("handleContextGrep"))
+	assert.Equal(t, []string{"README.md", "cmd/main.go", "internal/user.go"}, candidates(`\w+`))
+
+	// Reindexing replaces a file's text
+	require.NoError(t, store.IndexFileText(ctx, "cmd/main.go", "package main\n\nfunc handleContextGrep() {}\n"))
+	assert.Equal(t, []string{"cmd/main.go"}, candidates("handleContextGrep"))
+	assert.Empty(t, candidates(`log\.Fatal`))
+
+	// Files with documents but no indexed text are always candidates
+	require.NoError(t, store.Upsert(ctx, vectorstore.Document{
+		ID: "doc", Content: "legacy", Vector: embedding.Vector{1, 0},
+		Metadata: map[string]interface{}{"file_path": "legacy.go"},
+	}))
+	require.NoError(t, store.Upsert(ctx, vectorstore.Document{
+		ID: "web/doc", Content: "other repository", Vector: embedding.Vector{1, 0},
+		Metadata: map[string]interface{}{"file_path": "web.go", vectorstore.RepoIDKey: "web"},
+	}))
+	require.NoError(t, store.Upsert(ctx, vectorstore.Document{
+		ID: "user", Content: "GetUserByID", Vector: embedding.Vector{1, 0},
+		Metadata: map[string]interface{}{"file_path": "internal/user.go"},
+	}))
+	assert.Equal(t, []string{"cmd/main.go", "legacy.go"}, candidates("handleContextGrep"))
+
+	require.NoError(t, store.RemoveFileText(ctx, "cmd/main.go"))
+	assert.Equal(t, []string{"legacy.go"}, candidates("handleContextGrep"))
+}
+
+func TestFileTextTimes(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+
+	before := time.Now()
+	require.NoError(t, store.IndexFileText(ctx, "a.go", "package a"))
+	times, err := store.FileTextTimes(ctx)
+	require.NoError(t, err)
+	require.Contains(t, times, "a.go")
+	indexed := times["a.go"]
+	assert.False(t, indexed.Before(before))
+
+	// Reindexing records the new time, removing the file forgets it
+	require.NoError(t, store.IndexFileText(ctx, "a.go", "package b"))
+	times, err = store.FileTextTimes(ctx)
+	require.NoError(t, err)
+	assert.True(t, times["a.go"].After(indexed))
+
+	require.NoError(t, store.RemoveFileText(ctx, "a.go"))
+	times, err = store.FileTextTimes(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, times)
+}
+
+func TestRestore_ClearsTrigramIndex(t *testing.T) {
+	ctx := context.Background()
+	source := newTestStore(t)
+	require.NoError(t, source.IndexFileText(ctx, "a.go", "func parseConfig() error"))
+	dir := t.TempDir()
+	require.NoError(t, source.Snapshot(ctx, dir))
+
+	target := newTestStore(t)
+	require.NoError(t, target.IndexFileText(ctx, "stale.go", "func parseConfig() error"))
+	require.NoError(t, target.Restore(ctx, dir))
+
+	_, ok, err := target.CandidateFiles(ctx, vectorstore.TrigramQuery{Op: vectorstore.TrigramLiteral, Literal: "parseConfig"})
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
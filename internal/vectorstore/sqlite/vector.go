@@ -12,8 +12,18 @@ import (
 	"github.com/ferg-cod3s/conexus/internal/vectorstore"
 )
 
+// hnswSearchThreshold is the document count above which vector searches use
+// the HNSW graph instead of a brute force scan, which samples large stores.
+const hnswSearchThreshold = 1000
+
 // SearchVector performs optimized dense vector similarity search.
-// Uses HNSW index when available, falls back to optimized brute force with early termination.
+// Large stores are searched through the HNSW graph; small ones, and filtered
+// searches the graph returns too few results for, use brute force.
+//
+// The graph holds only the documents outside any collection, which share the
+// dimension of the code embedder. Collections may embed with other models,
+// so searches whose filter names collections scan the matching documents in
+// full instead.
 func (s *Store) SearchVector(ctx context.Context, queryVector embedding.Vector, opts vectorstore.SearchOptions) ([]vectorstore.SearchResult, error) {
 	// Validate input
 	if len(queryVector) == 0 {
@@ -25,8 +35,43 @@ func (s *Store) SearchVector(ctx context.Context, queryVector embedding.Vector,
 		return nil, fmt.Errorf("query vector has zero magnitude")
 	}
 
-	// Use sampling-based brute force search (HNSW needs more optimization)
-	return s.searchVectorBruteForce(ctx, queryVector, opts)
+	unfiltered := opts.MetadataFilter().IsEmpty()
+	filter := vectorstore.DefaultCorpus(opts.MetadataFilter())
+	opts.Filter = filter
+	opts.Filters = nil
+	if !vectorstore.ExcludesCollections(filter) {
+		return s.searchVectorBruteForce(ctx, queryVector, opts, 0)
+	}
+
+	count, err := s.countDefaultCorpus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if count > hnswSearchThreshold {
+		results, err := s.searchVectorHNSW(ctx, queryVector, opts)
+		if err != nil {
+			return nil, err
+		}
+		limit := opts.Limit
+		if limit <= 0 {
+			limit = 10
+		}
+		if unfiltered || len(results) >= limit {
+			return results, nil
+		}
+	}
+
+	return s.searchVectorBruteForce(ctx, queryVector, opts, count)
+}
+
+// countDefaultCorpus counts the documents outside any collection.
+func (s *Store) countDefaultCorpus(ctx context.Context) (int64, error) {
+	var count int64
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM documents WHERE meta_collection IS NULL").Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count documents: %w", err)
+	}
+	return count, nil
 }
 
 // searchVectorHNSW performs search using the HNSW index
@@ -37,8 +82,20 @@ func (s *Store) searchVectorHNSW(ctx context.Context, queryVector embedding.Vect
 	}
 	offset := opts.Offset
 
-	// Use HNSW to find candidate documents
-	ef := max(limit*2, 32)
+	s.hnswMu.Lock()
+	err := s.ensureHNSW(ctx)
+	s.hnswMu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("load HNSW graph: %w", err)
+	}
+
+	// Use HNSW to find candidate documents, over-fetching when filters may
+	// discard some of them
+	ef := max((limit+offset)*2, 32)
+	filter := opts.MetadataFilter()
+	if !filter.IsEmpty() {
+		ef *= 4
+	}
 	candidates, err := s.hnswIndex.Search(queryVector, ef, ef)
 	if err != nil {
 		return nil, fmt.Errorf("HNSW search failed: %w", err)
@@ -54,22 +111,23 @@ func (s *Store) searchVectorHNSW(ctx context.Context, queryVector embedding.Vect
 		docIDs[i] = c.ID
 	}
 
-	results, err := s.fetchDocumentsByIDs(ctx, docIDs, opts.Filters)
+	results, err := s.fetchDocumentsByIDs(ctx, docIDs, filter)
 	if err != nil {
 		return nil, fmt.Errorf("fetch candidate documents: %w", err)
 	}
 
-	// Update scores from HNSW results
-	scoreMap := make(map[string]float32)
-	for _, c := range candidates {
-		scoreMap[c.ID] = 1.0 - c.Distance // Convert distance to similarity
-	}
-
-	for i := range results {
-		if score, exists := scoreMap[results[i].Document.ID]; exists {
-			results[i].Score = score
+	// Re-score candidates from their full-precision vectors, as graph
+	// distances are approximate when the graph is quantized
+	queryNorm := vectorMagnitude(queryVector)
+	filtered := results[:0]
+	for _, result := range results {
+		result.Score = cosineSimilarityOptimized(queryVector, result.Document.Vector, queryNorm)
+		if opts.Threshold > 0 && result.Score < opts.Threshold {
+			continue
 		}
+		filtered = append(filtered, result)
 	}
+	results = filtered
 
 	// Sort by score and apply limits
 	sort.Slice(results, func(i, j int) bool {
@@ -92,8 +150,10 @@ func (s *Store) searchVectorHNSW(ctx context.Context, queryVector embedding.Vect
 	return results[start:end], nil
 }
 
-// searchVectorBruteForce performs optimized brute force search with sampling for large datasets
-func (s *Store) searchVectorBruteForce(ctx context.Context, queryVector embedding.Vector, opts vectorstore.SearchOptions) ([]vectorstore.SearchResult, error) {
+// searchVectorBruteForce performs optimized brute force search with sampling
+// for large datasets. totalDocs is the number of documents searched from, or
+// 0 to scan all matching documents.
+func (s *Store) searchVectorBruteForce(ctx context.Context, queryVector embedding.Vector, opts vectorstore.SearchOptions, totalDocs int64) ([]vectorstore.SearchResult, error) {
 	limit := opts.Limit
 	if limit <= 0 {
 		limit = 10
@@ -103,20 +163,13 @@ func (s *Store) searchVectorBruteForce(ctx context.Context, queryVector embeddin
 	// Pre-compute query vector norm for efficiency
 	queryNorm := vectorMagnitude(queryVector)
 
-	// Get total document count to determine sampling strategy
-	var totalDocs int
-	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM documents").Scan(&totalDocs)
-	if err != nil {
-		return nil, fmt.Errorf("count documents: %w", err)
-	}
-
 	// For large datasets, use sampling to improve performance
 	// Sample enough documents to find good results without checking everything
 	sampleSize := totalDocs
 	if totalDocs > 1000 {
 		// For large datasets, sample a subset that gives us high confidence of finding good results
 		// We want to check enough documents to have a good chance of finding the top-k similar ones
-		sampleSize = (limit + offset) * 20 // Sample 20x the number we need
+		sampleSize = int64(limit+offset) * 20 // Sample 20x the number we need
 		if sampleSize > totalDocs {
 			sampleSize = totalDocs // Don't exceed total
 		}
@@ -133,17 +186,13 @@ func (s *Store) searchVectorBruteForce(ctx context.Context, queryVector embeddin
 	args := []interface{}{}
 
 	// Add metadata filters if provided
-	if len(opts.Filters) > 0 {
-		sqlQuery += " WHERE"
-		first := true
-		for key, value := range opts.Filters {
-			if !first {
-				sqlQuery += " AND"
-			}
-			sqlQuery += fmt.Sprintf(" json_extract(metadata, '$.%s') = ?", key)
-			args = append(args, value)
-			first = false
+	if filter := opts.MetadataFilter(); !filter.IsEmpty() {
+		condition, conditionArgs, err := filterSQL(filter, "")
+		if err != nil {
+			return nil, err
 		}
+		sqlQuery += " WHERE " + condition
+		args = append(args, conditionArgs...)
 	}
 
 	// Add LIMIT for sampling when needed (ORDER BY RANDOM() is expensive, just take first N)
@@ -170,13 +219,13 @@ func (s *Store) searchVectorBruteForce(ctx context.Context, queryVector embeddin
 		}
 
 		var doc vectorstore.Document
-		var vectorJSON, metadataJSON []byte
+		var vectorBlob, metadataJSON []byte
 		var createdAt, updatedAt int64
 
 		err := rows.Scan(
 			&doc.ID,
 			&doc.Content,
-			&vectorJSON,
+			&vectorBlob,
 			&metadataJSON,
 			&createdAt,
 			&updatedAt,
@@ -186,7 +235,7 @@ func (s *Store) searchVectorBruteForce(ctx context.Context, queryVector embeddin
 		}
 
 		// Deserialize vector and metadata
-		if err := deserializeDocument(&doc, vectorJSON, metadataJSON, createdAt, updatedAt); err != nil {
+		if err := deserializeDocument(&doc, vectorBlob, metadataJSON, createdAt, updatedAt); err != nil {
 			return nil, fmt.Errorf("deserialize document: %w", err)
 		}
 
@@ -240,7 +289,7 @@ func (s *Store) searchVectorBruteForce(ctx context.Context, queryVector embeddin
 }
 
 // fetchDocumentsByIDs fetches multiple documents by their IDs
-func (s *Store) fetchDocumentsByIDs(ctx context.Context, ids []string, filters map[string]interface{}) ([]vectorstore.SearchResult, error) {
+func (s *Store) fetchDocumentsByIDs(ctx context.Context, ids []string, filter vectorstore.Filter) ([]vectorstore.SearchResult, error) {
 	if len(ids) == 0 {
 		return []vectorstore.SearchResult{}, nil
 	}
@@ -259,11 +308,13 @@ func (s *Store) fetchDocumentsByIDs(ctx context.Context, ids []string, filters m
 		WHERE id IN (%s)`, strings.Join(placeholders, ","))
 
 	// Add metadata filters if provided
-	if len(filters) > 0 {
-		for key, value := range filters {
-			sqlQuery += fmt.Sprintf(" AND json_extract(metadata, '$.%s') = ?", key)
-			args = append(args, value)
+	if !filter.IsEmpty() {
+		condition, conditionArgs, err := filterSQL(filter, "")
+		if err != nil {
+			return nil, err
 		}
+		sqlQuery += " AND " + condition
+		args = append(args, conditionArgs...)
 	}
 
 	rows, err := s.db.QueryContext(ctx, sqlQuery, args...)
@@ -275,13 +326,13 @@ func (s *Store) fetchDocumentsByIDs(ctx context.Context, ids []string, filters m
 	var results []vectorstore.SearchResult
 	for rows.Next() {
 		var doc vectorstore.Document
-		var vectorJSON, metadataJSON []byte
+		var vectorBlob, metadataJSON []byte
 		var createdAt, updatedAt int64
 
 		err := rows.Scan(
 			&doc.ID,
 			&doc.Content,
-			&vectorJSON,
+			&vectorBlob,
 			&metadataJSON,
 			&createdAt,
 			&updatedAt,
@@ -291,7 +342,7 @@ func (s *Store) fetchDocumentsByIDs(ctx context.Context, ids []string, filters m
 		}
 
 		// Deserialize vector and metadata
-		if err := deserializeDocument(&doc, vectorJSON, metadataJSON, createdAt, updatedAt); err != nil {
+		if err := deserializeDocument(&doc, vectorBlob, metadataJSON, createdAt, updatedAt); err != nil {
 			return nil, fmt.Errorf("deserialize document: %w", err)
 		}
 
@@ -339,22 +390,6 @@ func cosineSimilarityOptimized(queryVector, docVector embedding.Vector, queryNor
 	return similarity
 }
 
-// matchesFilters checks if a document's metadata matches the provided filters
-func matchesFilters(metadata map[string]interface{}, filters map[string]interface{}) bool {
-	for key, expectedValue := range filters {
-		actualValue, exists := metadata[key]
-		if !exists {
-			return false
-		}
-
-		// Simple equality check (can be extended for more complex filtering)
-		if actualValue != expectedValue {
-			return false
-		}
-	}
-	return true
-}
-
 // cosineSimilarity calculates the cosine similarity between two vectors.
 // Returns a value in [0, 1] where 1 is identical and 0 is orthogonal.
 // Formula: cos(θ) = (A · B) / (||A|| * ||B||)
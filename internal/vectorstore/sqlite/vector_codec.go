@@ -0,0 +1,161 @@
+package sqlite
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/ferg-cod3s/conexus/internal/embedding"
+)
+
+// VectorEncoding selects how vectors are stored. Each stored vector starts
+// with a byte naming its encoding, so changing the encoding only affects
+// vectors written afterwards.
+type VectorEncoding string
+
+const (
+	// VectorEncodingFloat32 stores little-endian float32 values (the default).
+	VectorEncodingFloat32 VectorEncoding = "float32"
+	// VectorEncodingFloat16 stores little-endian IEEE 754 half-precision
+	// values, halving the size at a small loss of precision.
+	VectorEncodingFloat16 VectorEncoding = "float16"
+)
+
+// Encoding tags stored as the first byte of a vector.
+const (
+	vectorTagFloat32 byte = 1
+	vectorTagFloat16 byte = 2
+)
+
+// vectorDimensionsSQL computes the dimension of a stored vector.
+const vectorDimensionsSQL = "((length(vector) - 1) / CASE hex(substr(vector, 1, 1)) WHEN '02' THEN 2 ELSE 4 END)"
+
+// ParseVectorEncoding parses an encoding name. An empty name selects
+// VectorEncodingFloat32.
+func ParseVectorEncoding(name string) (VectorEncoding, error) {
+	switch VectorEncoding(name) {
+	case "", VectorEncodingFloat32:
+		return VectorEncodingFloat32, nil
+	case VectorEncodingFloat16:
+		return VectorEncodingFloat16, nil
+	default:
+		return "", fmt.Errorf("unknown vector encoding %q (want float32 or float16)", name)
+	}
+}
+
+// encodeVector encodes v with its encoding tag.
+func encodeVector(v embedding.Vector, encoding VectorEncoding) []byte {
+	if encoding == VectorEncodingFloat16 {
+		buf := make([]byte, 1+2*len(v))
+		buf[0] = vectorTagFloat16
+		for i, f := range v {
+			binary.LittleEndian.PutUint16(buf[1+2*i:], float32ToFloat16(f))
+		}
+		return buf
+	}
+
+	buf := make([]byte, 1+4*len(v))
+	buf[0] = vectorTagFloat32
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(buf[1+4*i:], math.Float32bits(f))
+	}
+	return buf
+}
+
+// decodeVector decodes a vector written by encodeVector.
+func decodeVector(buf []byte) (embedding.Vector, error) {
+	if len(buf) == 0 {
+		return nil, fmt.Errorf("empty vector")
+	}
+
+	payload := buf[1:]
+	switch buf[0] {
+	case vectorTagFloat32:
+		if len(payload)%4 != 0 {
+			return nil, fmt.Errorf("float32 vector has %d bytes", len(payload))
+		}
+		v := make(embedding.Vector, len(payload)/4)
+		for i := range v {
+			v[i] = math.Float32frombits(binary.LittleEndian.Uint32(payload[4*i:]))
+		}
+		return v, nil
+	case vectorTagFloat16:
+		if len(payload)%2 != 0 {
+			return nil, fmt.Errorf("float16 vector has %d bytes", len(payload))
+		}
+		v := make(embedding.Vector, len(payload)/2)
+		for i := range v {
+			v[i] = float16ToFloat32(binary.LittleEndian.Uint16(payload[2*i:]))
+		}
+		return v, nil
+	default:
+		return nil, fmt.Errorf("unknown vector encoding tag %d", buf[0])
+	}
+}
+
+// float32ToFloat16 converts f to half precision, rounding to nearest even.
+func float32ToFloat16(f float32) uint16 {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	exp := int32(bits>>23&0xff) - 127 + 15
+	mant := bits & 0x7fffff
+
+	switch {
+	case bits&0x7fffffff == 0:
+		return sign
+	case bits>>23&0xff == 0xff:
+		if mant != 0 {
+			return sign | 0x7e00 // NaN
+		}
+		return sign | 0x7c00 // Infinity
+	case exp >= 0x1f:
+		return sign | 0x7c00 // Overflow
+	case exp <= 0:
+		// Subnormal, or zero when too small
+		if exp < -10 {
+			return sign
+		}
+		mant |= 0x800000
+		shift := uint32(14 - exp)
+		half := mant >> shift
+		rem := mant & (1<<shift - 1)
+		halfway := uint32(1) << (shift - 1)
+		if rem > halfway || (rem == halfway && half&1 == 1) {
+			half++
+		}
+		return sign | uint16(half)
+	default:
+		half := uint32(exp)<<10 | mant>>13
+		rem := mant & 0x1fff
+		// A carry into the exponent yields the next power of two or infinity
+		if rem > 0x1000 || (rem == 0x1000 && half&1 == 1) {
+			half++
+		}
+		return sign | uint16(half)
+	}
+}
+
+// float16ToFloat32 converts a half-precision value to float32.
+func float16ToFloat32(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+
+	switch exp {
+	case 0:
+		if mant == 0 {
+			return math.Float32frombits(sign)
+		}
+		// Normalize the subnormal value
+		e := uint32(127 - 15 + 1)
+		for mant&0x400 == 0 {
+			mant <<= 1
+			e--
+		}
+		return math.Float32frombits(sign | e<<23 | (mant&0x3ff)<<13)
+	case 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	default:
+		return math.Float32frombits(sign | (exp+127-15)<<23 | mant<<13)
+	}
+}
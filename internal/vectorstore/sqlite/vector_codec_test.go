@@ -0,0 +1,95 @@
+package sqlite
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ferg-cod3s/conexus/internal/embedding"
+	"github.com/ferg-cod3s/conexus/internal/vectorstore"
+)
+
+func TestVectorCodec_RoundTrip(t *testing.T) {
+	v := embedding.Vector{0, 1, -1, 0.1, -0.333, 1e-6, 12345.678}
+
+	blob := encodeVector(v, VectorEncodingFloat32)
+	assert.Len(t, blob, 1+4*len(v))
+	decoded, err := decodeVector(blob)
+	require.NoError(t, err)
+	assert.Equal(t, v, decoded)
+
+	blob = encodeVector(v, VectorEncodingFloat16)
+	assert.Len(t, blob, 1+2*len(v))
+	decoded, err = decodeVector(blob)
+	require.NoError(t, err)
+	require.Len(t, decoded, len(v))
+	for i := range v {
+		tolerance := 1e-3 * math.Max(1, math.Abs(float64(v[i])))
+		assert.InDelta(t, v[i], decoded[i], tolerance, "value %d", i)
+	}
+}
+
+func TestFloat16Conversion(t *testing.T) {
+	cases := []struct {
+		f    float32
+		half uint16
+	}{
+		{0, 0x0000},
+		{1, 0x3c00},
+		{-2, 0xc000},
+		{65504, 0x7bff},                             // Largest finite
+		{65520, 0x7c00},                             // Rounds to infinity
+		{float32(math.Ldexp(1, -24)), 0x0001},       // Smallest subnormal
+		{float32(math.Ldexp(1, -14)), 0x0400},       // Smallest normal
+		{1 + float32(math.Ldexp(1, -11)), 0x3c00},   // Halfway rounds to even
+		{1 + 3*float32(math.Ldexp(1, -11)), 0x3c02}, // Halfway rounds to even
+		{float32(math.Inf(-1)), 0xfc00},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.half, float32ToFloat16(c.f), "%g", c.f)
+	}
+
+	for _, half := range []uint16{0x0000, 0x0001, 0x03ff, 0x0400, 0x3c00, 0x3555, 0x7bff, 0xfc00, 0x8001} {
+		assert.Equal(t, half, float32ToFloat16(float16ToFloat32(half)), "%#04x", half)
+	}
+	assert.True(t, math.IsNaN(float64(float16ToFloat32(float32ToFloat16(float32(math.NaN()))))))
+}
+
+func TestDecodeVector_Errors(t *testing.T) {
+	for _, blob := range [][]byte{nil, {9, 0, 0, 0, 0}, {vectorTagFloat32, 0, 0}, {vectorTagFloat16, 0}, []byte("[0.1,0.2]")} {
+		_, err := decodeVector(blob)
+		assert.Error(t, err, "%v", blob)
+	}
+}
+
+func TestStore_VectorEncoding(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	assert.Error(t, store.SetVectorEncoding("float64"))
+
+	require.NoError(t, store.Upsert(ctx, vectorstore.Document{ID: "f32", Content: "a", Vector: embedding.Vector{0.1, 0.2, 0.3}}))
+	require.NoError(t, store.SetVectorEncoding(VectorEncodingFloat16))
+	require.NoError(t, store.Upsert(ctx, vectorstore.Document{ID: "f16", Content: "b", Vector: embedding.Vector{0.1, 0.2, 0.3}}))
+
+	var f32Size, f16Size int
+	require.NoError(t, store.db.QueryRowContext(ctx, "SELECT length(vector) FROM documents WHERE id = 'f32'").Scan(&f32Size))
+	require.NoError(t, store.db.QueryRowContext(ctx, "SELECT length(vector) FROM documents WHERE id = 'f16'").Scan(&f16Size))
+	assert.Equal(t, 13, f32Size)
+	assert.Equal(t, 7, f16Size)
+
+	// Both encodings are read back and searchable side by side
+	doc, err := store.Get(ctx, "f16")
+	require.NoError(t, err)
+	assert.InDeltaSlice(t, []float32{0.1, 0.2, 0.3}, []float32(doc.Vector), 1e-3)
+
+	results, err := store.SearchVector(ctx, embedding.Vector{0.1, 0.2, 0.3}, vectorstore.SearchOptions{Limit: 5})
+	require.NoError(t, err)
+	assert.Len(t, results, 2)
+
+	report, err := store.CheckIntegrity(ctx, 3)
+	require.NoError(t, err)
+	assert.Empty(t, report.DimensionMismatches)
+}
@@ -30,6 +30,8 @@ type SearchResult struct {
 	Document Document // The matched document
 	Score    float32  // Relevance score (higher is better)
 	Method   string   // Search method used ("bm25", "vector", "hybrid")
+
+	Collection string // Collection the document was found in, set by SearchCollections
 }
 
 // SearchOptions configures search behavior.
@@ -37,7 +39,8 @@ type SearchOptions struct {
 	Limit     int                    // Maximum number of results
 	Offset    int                    // Number of results to skip (for pagination)
 	Threshold float32                // Minimum score threshold
-	Filters   map[string]interface{} // Metadata filters (e.g., language="go")
+	Filters   map[string]interface{} // Metadata filters (e.g., language="go"), see FiltersFromMap
+	Filter    Filter                 // Typed metadata filter, combined with Filters
 	Rerank    bool                   // Apply reranking to results
 }
 
@@ -55,6 +58,19 @@ type VectorStore interface {
 	// Get retrieves a document by ID.
 	Get(ctx context.Context, id string) (*Document, error)
 
+	// DeleteByFilter removes the documents matching a non-empty filter in one
+	// transaction and returns how many were removed.
+	DeleteByFilter(ctx context.Context, filter Filter) (int64, error)
+
+	// UpdateMetadataByFilter applies update to the metadata of the documents
+	// matching a non-empty filter in one transaction and returns how many
+	// changed. Contents, vectors and timestamps are kept.
+	UpdateMetadataByFilter(ctx context.Context, filter Filter, update MetadataUpdate) (int64, error)
+
+	// Scan streams the documents matching filter in ID order. The empty
+	// filter matches every document.
+	Scan(ctx context.Context, filter Filter) (DocumentIterator, error)
+
 	// SearchVector performs dense vector similarity search.
 	SearchVector(ctx context.Context, vector embedding.Vector, opts SearchOptions) ([]SearchResult, error)
 
@@ -91,3 +107,63 @@ type StatsProvider interface {
 	// Stats returns current index statistics.
 	Stats(ctx context.Context) (*IndexStats, error)
 }
+
+// IndexCheckpoint records the progress of an indexing run so that it can be
+// resumed after a crash without re-embedding completed files.
+type IndexCheckpoint struct {
+	RunID     string            // Identifier of the indexing run
+	RootPath  string            // Root directory being indexed
+	Completed map[string]string // Completed file path -> content hash
+	Pending   []string          // Files in the batch currently being written
+	StartedAt time.Time         // When the run started
+	UpdatedAt time.Time         // When the checkpoint was last written
+}
+
+// CheckpointStore persists indexing checkpoints atomically with documents.
+type CheckpointStore interface {
+	// SaveCheckpoint writes the run header, pending batch and completed files.
+	// Saving a checkpoint for a different run discards the previous one.
+	SaveCheckpoint(ctx context.Context, cp IndexCheckpoint) error
+
+	// CommitCheckpointBatch replaces all documents of the given files with docs,
+	// marks the files completed and clears the pending batch in one transaction.
+	CommitCheckpointBatch(ctx context.Context, files map[string]string, docs []Document) error
+
+	// LoadCheckpoint returns the current checkpoint, or nil if no run is in progress.
+	LoadCheckpoint(ctx context.Context) (*IndexCheckpoint, error)
+
+	// ClearCheckpoint removes the checkpoint once a run has completed.
+	ClearCheckpoint(ctx context.Context) error
+}
+
+// IntegrityReport lists inconsistencies between a store's internal structures.
+type IntegrityReport struct {
+	DimensionMismatches []string // Documents whose vector size differs from the expected dimension or their collection's
+	FTSMissing          []string // Documents without a full-text search row
+	FTSStale            []string // Documents whose full-text search row has different content
+	FTSOrphaned         []string // Full-text search rows without a document
+	HNSWOrphaned        []string // HNSW nodes without a document
+}
+
+// IntegrityChecker validates and repairs a store's internal structures.
+type IntegrityChecker interface {
+	// CheckIntegrity reports inconsistencies. Vector dimensions of documents
+	// outside any collection are only checked when dimensions is positive;
+	// collection documents are checked against their collection's dimensions.
+	CheckIntegrity(ctx context.Context, dimensions int) (*IntegrityReport, error)
+
+	// RepairIntegrity fixes the problems in report. Documents with mismatched
+	// dimensions are deleted so they can be re-embedded.
+	RepairIntegrity(ctx context.Context, report *IntegrityReport) error
+}
+
+// SnapshotStore copies its complete contents to and from files, so an index
+// built on one machine can be shared with others.
+type SnapshotStore interface {
+	// Snapshot writes a consistent copy of the store into the directory dir,
+	// which must exist and be empty.
+	Snapshot(ctx context.Context, dir string) error
+
+	// Restore replaces the store's contents with a copy written by Snapshot.
+	Restore(ctx context.Context, dir string) error
+}
@@ -0,0 +1,145 @@
+package vectorstore
+
+import (
+	"context"
+	"regexp/syntax"
+	"time"
+	"unicode/utf8"
+)
+
+// TrigramOp is the operator of a TrigramQuery.
+type TrigramOp string
+
+const (
+	TrigramAll     TrigramOp = "all"     // Every file may match
+	TrigramLiteral TrigramOp = "literal" // Files containing Literal, ignoring case
+	TrigramAnd     TrigramOp = "and"     // Files matching all Queries
+	TrigramOr      TrigramOp = "or"      // Files matching any of Queries
+)
+
+// minTrigramLiteral is the length in characters of the shortest literal a
+// trigram index can look up.
+const minTrigramLiteral = 3
+
+// TrigramQuery describes the text a file must contain to match a regular
+// expression. It is a necessary condition only: matching files still have to
+// be verified against the expression. The zero TrigramQuery matches every
+// file.
+type TrigramQuery struct {
+	Op      TrigramOp      `json:"op,omitempty"`
+	Literal string         `json:"literal,omitempty"`
+	Queries []TrigramQuery `json:"queries,omitempty"`
+}
+
+// IsAll reports whether q matches every file.
+func (q TrigramQuery) IsAll() bool {
+	return q.Op == "" || q.Op == TrigramAll
+}
+
+// TrigramIndex indexes the text of source files by trigram, so regular
+// expression searches only read the files that can match. Paths are those
+// recorded in the "file_path" metadata of the files' documents.
+type TrigramIndex interface {
+	// IndexFileText replaces the indexed text of the file at path.
+	IndexFileText(ctx context.Context, path, text string) error
+
+	// RemoveFileText removes the file at path from the index.
+	RemoveFileText(ctx context.Context, path string) error
+
+	// CandidateFiles returns the files that may match query: the indexed
+	// files whose text contains its literals, and the files with documents
+	// whose text has not been indexed. It returns false when the store holds
+	// no files at all.
+	CandidateFiles(ctx context.Context, query TrigramQuery) ([]string, bool, error)
+
+	// FileTextTimes returns when the text of each file in the index was
+	// indexed, so files changed since can be searched as well.
+	FileTextTimes(ctx context.Context) (map[string]time.Time, error)
+}
+
+// RegexpTrigramQuery returns the TrigramQuery of the Go regular expression
+// expr: the literals of at least three characters that any match contains.
+// Literals are compared ignoring case, so the query suits case-insensitive
+// expressions too.
+func RegexpTrigramQuery(expr string) (TrigramQuery, error) {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return TrigramQuery{}, err
+	}
+	return regexpTrigrams(re.Simplify()), nil
+}
+
+// regexpTrigrams returns the TrigramQuery of a simplified expression.
+func regexpTrigrams(re *syntax.Regexp) TrigramQuery {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return literalTrigrams(string(re.Rune))
+	case syntax.OpCapture, syntax.OpPlus:
+		return regexpTrigrams(re.Sub[0])
+	case syntax.OpRepeat:
+		if re.Min > 0 {
+			return regexpTrigrams(re.Sub[0])
+		}
+	case syntax.OpConcat:
+		// Adjacent literals form a single longer one
+		var queries []TrigramQuery
+		var run []rune
+		for _, sub := range re.Sub {
+			if sub.Op == syntax.OpLiteral {
+				run = append(run, sub.Rune...)
+				continue
+			}
+			queries = append(queries, literalTrigrams(string(run)), regexpTrigrams(sub))
+			run = nil
+		}
+		return andTrigrams(append(queries, literalTrigrams(string(run))))
+	case syntax.OpAlternate:
+		queries := make([]TrigramQuery, len(re.Sub))
+		for i, sub := range re.Sub {
+			queries[i] = regexpTrigrams(sub)
+		}
+		return orTrigrams(queries)
+	}
+	return TrigramQuery{Op: TrigramAll}
+}
+
+// literalTrigrams matches files containing s, or every file when s is too
+// short to look up.
+func literalTrigrams(s string) TrigramQuery {
+	if utf8.RuneCountInString(s) < minTrigramLiteral {
+		return TrigramQuery{Op: TrigramAll}
+	}
+	return TrigramQuery{Op: TrigramLiteral, Literal: s}
+}
+
+// andTrigrams combines queries that must all match, dropping those matching
+// every file.
+func andTrigrams(queries []TrigramQuery) TrigramQuery {
+	var kept []TrigramQuery
+	for _, q := range queries {
+		if !q.IsAll() {
+			kept = append(kept, q)
+		}
+	}
+	switch len(kept) {
+	case 0:
+		return TrigramQuery{Op: TrigramAll}
+	case 1:
+		return kept[0]
+	}
+	return TrigramQuery{Op: TrigramAnd, Queries: kept}
+}
+
+// orTrigrams combines alternative queries. Any alternative matching every
+// file makes the combination match every file.
+func orTrigrams(queries []TrigramQuery) TrigramQuery {
+	for _, q := range queries {
+		if q.IsAll() {
+			return TrigramQuery{Op: TrigramAll}
+		}
+	}
+	if len(queries) == 1 {
+		return queries[0]
+	}
+	return TrigramQuery{Op: TrigramOr, Queries: queries}
+}
@@ -0,0 +1,49 @@
+package vectorstore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegexpTrigramQuery(t *testing.T) {
+	literal := func(s string) TrigramQuery {
+		return TrigramQuery{Op: TrigramLiteral, Literal: s}
+	}
+	all := TrigramQuery{Op: TrigramAll}
+
+	tests := []struct {
+		expr string
+		want TrigramQuery
+	}{
+		{"handleContextGrep", literal("handleContextGrep")},
+		{`fmt\.Errorf\(`, literal("fmt.Errorf(")},
+		{`func\s+main`, TrigramQuery{Op: TrigramAnd, Queries: []TrigramQuery{literal("func"), literal("main")}}},
+		{"(?i)todo", literal("TODO")},
+		{"get(User|Account)ByID", TrigramQuery{Op: TrigramAnd, Queries: []TrigramQuery{
+			literal("get"),
+			{Op: TrigramOr, Queries: []TrigramQuery{literal("User"), literal("Account")}},
+			literal("ByID"),
+		}}},
+		{"(foo)+bar", TrigramQuery{Op: TrigramAnd, Queries: []TrigramQuery{literal("foo"), literal("bar")}}},
+		{"ab", all},
+		{"(foo)?bar", literal("bar")},
+		{"foo|x", all},
+		{".*", all},
+		{"[a-z]+_id", literal("_id")},
+		{"[a-z]+id", all},
+		{"^package ", literal("package ")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := RegexpTrigramQuery(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := RegexpTrigramQuery("(unclosed")
+	assert.Error(t, err)
+	assert.True(t, TrigramQuery{}.IsAll())
+}